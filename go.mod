@@ -1,6 +1,6 @@
 module sevki.org/x
 
-go 1.15
+go 1.16
 
 require (
 	github.com/BurntSushi/toml v0.3.1
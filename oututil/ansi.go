@@ -0,0 +1,56 @@
+package oututil
+
+import "strings"
+
+const esc = '\x1b'
+
+// stripANSI removes ANSI escape sequences from s: CSI sequences like
+// SGR colors, OSC sequences like OSC 8 hyperlinks, and two byte escapes.
+func stripANSI(s string) string {
+	if strings.IndexByte(s, esc) < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != esc {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		switch s[i] {
+		case '[':
+			// CSI: parameter and intermediate bytes up to a final byte
+			// in 0x40-0x7e.
+			i++
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			i++
+		case ']':
+			// OSC: terminated by BEL or ST (ESC \).
+			i++
+			for i < len(s) {
+				if s[i] == '\a' {
+					i++
+					break
+				}
+				if s[i] == esc && i+1 < len(s) && s[i+1] == '\\' {
+					i += 2
+					break
+				}
+				i++
+			}
+		case '(', ')', '*', '+':
+			// character set designation, ESC ( B
+			i += 2
+		default:
+			i++
+		}
+	}
+	return b.String()
+}
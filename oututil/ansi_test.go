@@ -0,0 +1,47 @@
+package oututil
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"plain", "plain"},
+		{"\x1b[1m\x1b[31merror\x1b[0m: x", "error: x"},
+		{"\x1b[38;5;196mred\x1b[m", "red"},
+		{"\x1b]8;;file:///src/a.c\x1b\\a.c\x1b]8;;\x1b\\:1:2:", "a.c:1:2:"},
+		{"\x1b]8;;file:///src/a.c\aa.c\x1b]8;;\a", "a.c"},
+		{"\x1b(Bdone", "done"},
+		{"trailing\x1b", "trailing"},
+		{"unterminated\x1b[31", "unterminated"},
+	}
+	for _, test := range tests {
+		if got := stripANSI(test.in); got != test.out {
+			t.Logf("stripANSI(%q) = %q, want %q", test.in, got, test.out)
+			t.Fail()
+		}
+	}
+}
+
+const clangColored = "\x1b[1mmain.c:3:5: \x1b[0m\x1b[0;1;31merror: \x1b[0m\x1b[1muse of undeclared identifier 'x'\x1b[0m\n" +
+	"    x = 1;\n" +
+	"\x1b[0;1;32m    ^\n\x1b[0m" +
+	"\x1b[1m\x1b]8;;file:///home/ci/src/util.c\x1b\\src/util.c\x1b]8;;\x1b\\:10:2: \x1b[0m\x1b[0;1;35mwarning: \x1b[0m\x1b[1munused variable 'y' [-Wunused-variable]\x1b[0m\n"
+
+const eslintColored = "\n\x1b[4m/home/ci/web/src/app.js\x1b[24m\n" +
+	"  \x1b[2m1:10\x1b[22m  \x1b[31merror\x1b[39m  'foo' is defined but never used  \x1b[2mno-unused-vars\x1b[22m\n" +
+	"\n\x1b[31m\x1b[1m✖ 1 problem (1 error, 0 warnings)\x1b[22m\x1b[39m\n"
+
+func TestParseColored(t *testing.T) {
+	checkSourceErrors(t, []SourceError{
+		{File: "main.c", Line: 3, Column: 5, Message: "use of undeclared identifier 'x'", Severity: SeverityError},
+		{File: "src/util.c", Line: 10, Column: 2, Message: "unused variable 'y'", Code: "-Wunused-variable", Severity: SeverityWarning},
+	}, ScanSourceError(clangColored))
+	checkSourceErrors(t, []SourceError{
+		{File: "/home/ci/web/src/app.js", Line: 1, Column: 10, Message: "'foo' is defined but never used", Severity: SeverityError, Code: "no-unused-vars"},
+	}, ScanSourceError(eslintColored))
+	if errs := ScanSourceError(clangColored, KeepANSI()); len(errs) == 2 && errs[0].Message == "use of undeclared identifier 'x'" {
+		t.Log("escape sequences weren't kept")
+		t.Fail()
+	}
+}
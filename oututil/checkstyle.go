@@ -0,0 +1,77 @@
+package oututil
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   string `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr,omitempty"`
+}
+
+// WriteCheckstyle writes errs as a checkstyle XML report. Files and the
+// errors in them are sorted like Sort does so reports of the same
+// errors are identical. The source of an error is its code or, if it
+// has none, the tool that reported it.
+func WriteCheckstyle(w io.Writer, errs []SourceError) error {
+	sorted := append([]SourceError(nil), errs...)
+	Sort(sorted)
+	report := checkstyleReport{Version: "4.3"}
+	for i, e := range sorted {
+		if i == 0 || e.File != sorted[i-1].File {
+			report.Files = append(report.Files, checkstyleFile{Name: e.File})
+		}
+		ce := checkstyleError{
+			Line:     e.Line,
+			Severity: checkstyleSeverity(e.Severity),
+			Message:  e.Message,
+			Source:   e.Code,
+		}
+		if e.Column > 0 {
+			ce.Column = strconv.Itoa(e.Column)
+		}
+		if ce.Source == "" {
+			ce.Source = e.Tool
+		}
+		f := &report.Files[len(report.Files)-1]
+		f.Errors = append(f.Errors, ce)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// checkstyleSeverity maps severities to checkstyle, errors without a
+// severity are errors.
+func checkstyleSeverity(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "info"
+	}
+	return "error"
+}
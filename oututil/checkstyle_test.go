@@ -0,0 +1,33 @@
+package oututil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCheckstyle(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCheckstyle(&buf, []SourceError{
+		{File: "src/b.ts", Line: 2, Column: 1, Severity: SeverityWarning, Code: "no-unused-vars", Message: "'x' is unused"},
+		{File: "src/a.c", Line: 9, Column: NoColumn, Tool: "gcc", Message: `expected ";" before '}' & <eof>`},
+		{File: "src/a.c", Line: 3, Column: 7, Severity: SeverityNote, Message: "declared here"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="src/a.c">
+    <error line="3" column="7" severity="info" message="declared here"></error>
+    <error line="9" severity="error" message="expected &#34;;&#34; before &#39;}&#39; &amp; &lt;eof&gt;" source="gcc"></error>
+  </file>
+  <file name="src/b.ts">
+    <error line="2" column="1" severity="warning" message="&#39;x&#39; is unused" source="no-unused-vars"></error>
+  </file>
+</checkstyle>
+`
+	if got := buf.String(); got != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,211 @@
+package oututil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderOptions configures RenderCodeFrame.
+type RenderOptions struct {
+	// Color colors the frame with ANSI escape codes by severity
+	Color bool
+	// TabWidth is the width tabs are expanded to, 4 if it's zero
+	TabWidth int
+	// Context is the number of lines shown above and below the error,
+	// at most the ones Annotate read
+	Context int
+	// Docs, if set, adds a footer linking to the documentation of the
+	// error's code
+	Docs DocResolver
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// RenderCodeFrame writes e the way modern compilers print diagnostics,
+// a header followed by the source lines around the error with a caret
+// under its column, or an underline up to EndColumn,
+//
+//	error[E0308]: mismatched types
+//	 --> src/main.rs:4:18
+//	  |
+//	4 |     let x: i32 = "a";
+//	  |                  ^^^
+//
+// The source lines come from Annotate, without them only the header and
+// location are written. With Docs the frame ends with
+//
+//	= docs: https://doc.rust-lang.org/error_codes/E0308.html
+func RenderCodeFrame(w io.Writer, e SourceError, opts RenderOptions) error {
+	if opts.TabWidth <= 0 {
+		opts.TabWidth = 4
+	}
+	paint := func(color, s string) string {
+		if !opts.Color || s == "" {
+			return s
+		}
+		return color + s + ansiReset
+	}
+	color := severityColor(e.Severity)
+
+	bw := bufio.NewWriter(w)
+	severity := e.Severity
+	if severity == SeverityUnknown {
+		severity = SeverityError
+	}
+	header := severity.String()
+	if e.Code != "" {
+		header += "[" + e.Code + "]"
+	}
+	fmt.Fprintf(bw, "%s%s\n", paint(color, header), paint(ansiBold, ": "+e.Message))
+
+	first, last := e.Line-opts.Context, e.Line+opts.Context
+	if first < e.ContextStart {
+		first = e.ContextStart
+	}
+	if end := e.ContextStart + len(e.ContextLines) - 1; last > end {
+		last = end
+	}
+	gutter := strings.Repeat(" ", len(strconv.Itoa(last)))
+	if e.SourceLine == "" && len(e.ContextLines) == 0 {
+		gutter = ""
+	}
+	location := e.File
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+		if e.Column > 0 {
+			location += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	fmt.Fprintf(bw, "%s%s %s\n", gutter, paint(ansiBlue, "-->"), location)
+	footer := func() error {
+		if url := docURL(opts.Docs, e); url != "" {
+			fmt.Fprintf(bw, "%s %s docs: %s\n", gutter, paint(ansiBlue, "="), url)
+		}
+		return bw.Flush()
+	}
+	if gutter == "" || e.Line < first || e.Line > last {
+		return footer()
+	}
+
+	bar := paint(ansiBlue, "|")
+	fmt.Fprintf(bw, "%s %s\n", gutter, bar)
+	for n := first; n <= last; n++ {
+		line := e.ContextLines[n-e.ContextStart]
+		number := paint(ansiBlue, fmt.Sprintf("%*d", len(gutter), n))
+		fmt.Fprintf(bw, "%s %s %s\n", number, bar, strings.TrimRight(expandTabs(line, opts.TabWidth), " "))
+		if n != e.Line || e.Column < 1 {
+			continue
+		}
+		start, width := caretSpan(e, line, opts.TabWidth)
+		fmt.Fprintf(bw, "%s %s %s%s\n", gutter, bar, strings.Repeat(" ", start), paint(color, strings.Repeat("^", width)))
+	}
+	return footer()
+}
+
+// caretSpan returns the visual column, zero-based, and width of the
+// underline of e on line.
+func caretSpan(e SourceError, line string, tabWidth int) (int, int) {
+	col := e.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	start := visualWidth(line[:col], 0, tabWidth)
+	width := 1
+	if e.EndLine == e.Line && e.EndColumn > e.Column {
+		end := e.EndColumn - 1
+		if end > len(line) {
+			end = len(line)
+		}
+		if w := visualWidth(line[col:end], start, tabWidth); w > 0 {
+			width = w
+		}
+	}
+	return start, width
+}
+
+// visualWidth returns the number of cells s takes when it starts at
+// column col.
+func visualWidth(s string, col, tabWidth int) int {
+	width := 0
+	for _, r := range s {
+		if r == '\t' {
+			width += tabWidth - (col+width)%tabWidth
+			continue
+		}
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// wideRunes are the ranges of the East Asian Wide and Fullwidth
+// characters, emoji included, that terminals draw in two cells.
+var wideRunes = []struct{ lo, hi rune }{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x4dbf}, {0x4e00, 0xa4cf}, {0xa960, 0xa97f}, {0xac00, 0xd7a3},
+	{0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe6f}, {0xff00, 0xff60},
+	{0xffe0, 0xffe6}, {0x16fe0, 0x16fe4}, {0x17000, 0x18cff}, {0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251}, {0x1f300, 0x1f64f}, {0x1f680, 0x1f6ff}, {0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f9ff}, {0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of cells r takes on a terminal.
+func runeWidth(r rune) int {
+	if r < wideRunes[0].lo {
+		return 1
+	}
+	i := sort.Search(len(wideRunes), func(i int) bool { return wideRunes[i].hi >= r })
+	if i < len(wideRunes) && wideRunes[i].lo <= r {
+		return 2
+	}
+	return 1
+}
+
+// expandTabs replaces the tabs in s with spaces up to the next tab stop.
+func expandTabs(s string, tabWidth int) string {
+	if !strings.ContainsRune(s, '\t') {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			n := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col += runeWidth(r)
+	}
+	return b.String()
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return ansiYellow
+	case SeverityNote:
+		return ansiCyan
+	}
+	return ansiRed
+}
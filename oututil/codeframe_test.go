@@ -0,0 +1,99 @@
+package oututil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderCodeFrame(t *testing.T) {
+	tests := []struct {
+		err  SourceError
+		opts RenderOptions
+		want string
+	}{
+		{
+			err: SourceError{
+				File: "src/main.rs", Line: 4, Column: 18, EndLine: 4, EndColumn: 21,
+				Severity: SeverityError, Code: "E0308", Message: "mismatched types",
+				SourceLine: `    let x: i32 = "a";`, ContextStart: 3,
+				ContextLines: []string{"fn main() {", `    let x: i32 = "a";`, "}"},
+			},
+			opts: RenderOptions{Context: 1},
+			want: `error[E0308]: mismatched types
+ --> src/main.rs:4:18
+  |
+3 | fn main() {
+4 |     let x: i32 = "a";
+  |                  ^^^
+5 | }
+`,
+		},
+		{
+			err: SourceError{
+				File: "main.go", Line: 10, Column: 3, Severity: SeverityWarning, Message: "unused",
+				SourceLine: "\t\tx := 1", ContextStart: 9, ContextLines: []string{"\tif ok {", "\t\tx := 1", "\t}"},
+			},
+			opts: RenderOptions{TabWidth: 2},
+			want: `warning: unused
+  --> main.go:10:3
+   |
+10 |     x := 1
+   |     ^
+`,
+		},
+		{
+			err: SourceError{
+				File: "a.c", Line: 1, Column: 4, EndLine: 1, EndColumn: 7, Severity: SeverityNote, Message: "here",
+				SourceLine: "é\tint", ContextStart: 1, ContextLines: []string{"é\tint"},
+			},
+			opts: RenderOptions{Color: true, TabWidth: 8},
+			want: "\x1b[1;36mnote\x1b[0m\x1b[1m: here\x1b[0m\n" +
+				" \x1b[1;34m-->\x1b[0m a.c:1:4\n" +
+				"  \x1b[1;34m|\x1b[0m\n" +
+				"\x1b[1;34m1\x1b[0m \x1b[1;34m|\x1b[0m é       int\n" +
+				"  \x1b[1;34m|\x1b[0m         \x1b[1;36m^^^\x1b[0m\n",
+		},
+		{
+			err: SourceError{
+				File: "a.go", Line: 1, Column: 8, Message: "wide",
+				SourceLine: "日本\tx", ContextStart: 1, ContextLines: []string{"日本\tx"},
+			},
+			opts: RenderOptions{TabWidth: 8},
+			want: "error: wide\n --> a.go:1:8\n  |\n1 | 日本    x\n  |         ^\n",
+		},
+		{
+			err:  SourceError{File: "gone.c", Line: 3, Column: NoColumn, Message: "stale"},
+			want: "error: stale\n--> gone.c:3\n",
+		},
+		{
+			err: SourceError{
+				File: "src/main.rs", Line: 4, Column: 18, Severity: SeverityError, Code: "E0308", Tool: "rustc",
+				Message: "mismatched types", SourceLine: `    let x: i32 = "a";`,
+				ContextStart: 4, ContextLines: []string{`    let x: i32 = "a";`},
+			},
+			opts: RenderOptions{Docs: DefaultDocs},
+			want: `error[E0308]: mismatched types
+ --> src/main.rs:4:18
+  |
+4 |     let x: i32 = "a";
+  |                  ^
+  = docs: https://doc.rust-lang.org/error_codes/E0308.html
+`,
+		},
+		{
+			err:  SourceError{File: "a.ts", Line: 3, Column: NoColumn, Code: "TS2304", Message: "cannot find name"},
+			opts: RenderOptions{Docs: TypeScriptDocs},
+			want: "error[TS2304]: cannot find name\n--> a.ts:3\n = docs: https://typescript.tv/errors/#ts2304\n",
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := RenderCodeFrame(&buf, test.err, test.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Logf("%s: was expecting\n%q\ngot\n%q", test.err.Message, test.want, got)
+			t.Fail()
+		}
+	}
+}
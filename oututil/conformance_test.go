@@ -0,0 +1,44 @@
+package oututil_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sevki.org/x/oututil"
+	"sevki.org/x/oututil/sourcetest"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected JSON of the sourcetest corpus and testdata")
+
+func parse(log string) []oututil.SourceError { return oututil.ScanSourceError(log) }
+
+func TestConformance(t *testing.T) {
+	if *update {
+		logs, err := filepath.Glob("sourcetest/corpus/*.log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range logs {
+			log, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			goldens := []sourcetest.Golden{}
+			for _, e := range parse(string(log)) {
+				goldens = append(goldens, sourcetest.GoldenOf(e))
+			}
+			data, err := json.MarshalIndent(goldens, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(strings.TrimSuffix(name, ".log")+".json", append(data, '\n'), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	sourcetest.RunConformance(t, parse)
+}
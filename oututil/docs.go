@@ -0,0 +1,153 @@
+package oututil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DocResolver maps diagnostic codes to the pages that document them.
+// Resolvers only map strings, they never look anything up over the
+// network.
+type DocResolver interface {
+	// URL returns the documentation of code reported by tool, or ""
+	// if there isn't one.
+	URL(code, tool string) string
+}
+
+// DocResolverFunc is a function that is a DocResolver.
+type DocResolverFunc func(code, tool string) string
+
+// URL returns f(code, tool).
+func (f DocResolverFunc) URL(code, tool string) string { return f(code, tool) }
+
+// DocPattern returns a resolver that formats the codes matching re into
+// format with %s, every code if re is nil.
+func DocPattern(format string, re *regexp.Regexp) DocResolver {
+	return DocResolverFunc(func(code, _ string) string {
+		if code == "" || re != nil && !re.MatchString(code) {
+			return ""
+		}
+		return fmt.Sprintf(format, code)
+	})
+}
+
+var (
+	// RustcDocs resolves rustc error codes like E0308 to the error
+	// code index.
+	RustcDocs = DocPattern("https://doc.rust-lang.org/error_codes/%s.html", regexp.MustCompile(`^E[0-9]{4}$`))
+	// TypeScriptDocs resolves TypeScript codes like TS2304. TypeScript
+	// doesn't document its codes itself, they link to typescript.tv.
+	TypeScriptDocs DocResolver = DocResolverFunc(func(code, _ string) string {
+		if !tsCode.MatchString(code) {
+			return ""
+		}
+		return "https://typescript.tv/errors/#" + strings.ToLower(code)
+	})
+	// DotnetDocs resolves the codes of the .NET analyzers, CA2000 and
+	// IDE0005, and of the C# compiler, CS0168.
+	DotnetDocs DocResolver = DocResolverFunc(func(code, _ string) string {
+		m := dotnetCode.FindStringSubmatch(code)
+		if m == nil {
+			return ""
+		}
+		return fmt.Sprintf(dotnetURLs[m[1]], strings.ToLower(code))
+	})
+)
+
+var (
+	tsCode     = regexp.MustCompile(`^TS[0-9]+$`)
+	dotnetCode = regexp.MustCompile(`^(CA|CS|IDE)[0-9]{4}$`)
+	dotnetURLs = map[string]string{
+		"CA":  "https://learn.microsoft.com/dotnet/fundamentals/code-analysis/quality-rules/%s",
+		"IDE": "https://learn.microsoft.com/dotnet/fundamentals/code-analysis/style-rules/%s",
+		"CS":  "https://learn.microsoft.com/dotnet/csharp/misc/%s",
+	}
+)
+
+var docs = struct {
+	sync.RWMutex
+	tools map[string]DocResolver
+}{tools: map[string]DocResolver{
+	"rustc":     RustcDocs,
+	"eslint":    DocPattern("https://eslint.org/docs/rules/%s", nil),
+	"tsc":       TypeScriptDocs,
+	"msvc":      DotnetDocs,
+	"msvc-link": DotnetDocs,
+}}
+
+// RegisterDocs makes DefaultDocs resolve the codes of tool with r,
+// replacing the resolver it had. A nil r removes it.
+func RegisterDocs(tool string, r DocResolver) {
+	docs.Lock()
+	defer docs.Unlock()
+	if r == nil {
+		delete(docs.tools, tool)
+		return
+	}
+	docs.tools[tool] = r
+}
+
+// DefaultDocs resolves codes with the resolver registered for their
+// tool, the builtin ones cover rustc, eslint, tsc and the .NET tools
+// that report like msvc.
+var DefaultDocs DocResolver = DocResolverFunc(func(code, tool string) string {
+	docs.RLock()
+	r, ok := docs.tools[tool]
+	docs.RUnlock()
+	if !ok || code == "" {
+		return ""
+	}
+	return r.URL(code, tool)
+})
+
+// docURL returns the documentation of e's code, "" without a resolver.
+func docURL(r DocResolver, e SourceError) string {
+	if r == nil || e.Code == "" {
+		return ""
+	}
+	return r.URL(e.Code, e.Tool)
+}
+
+type outputOptions struct {
+	docs  DocResolver
+	paths *pathMapper
+}
+
+// OutputOption configures the writers of reports.
+type OutputOption func(*outputOptions)
+
+// WithDocs links the codes of errors to the documentation r resolves
+// them to.
+func WithDocs(r DocResolver) OutputOption {
+	return func(o *outputOptions) { o.docs = r }
+}
+
+// WithPathMappings rewrites the paths of errors like MapPaths does
+// before they are written.
+func WithPathMappings(mappings []PathMapping, opts ...MapOption) OutputOption {
+	return func(o *outputOptions) { o.paths = newPathMapper(mappings, opts) }
+}
+
+func newOutputOptions(opts []OutputOption) *outputOptions {
+	o := &outputOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// mapPaths returns errs with the paths mapped by WithPathMappings.
+func (o *outputOptions) mapPaths(errs []SourceError) []SourceError {
+	if o.paths == nil {
+		return errs
+	}
+	mapped := make([]SourceError, 0, len(errs))
+	for _, e := range errs {
+		if e, ok := o.paths.mapError(e); ok {
+			mapped = append(mapped, e)
+		}
+	}
+	return mapped
+}
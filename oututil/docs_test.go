@@ -0,0 +1,52 @@
+package oututil
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestDocResolvers(t *testing.T) {
+	tests := []struct {
+		resolver   DocResolver
+		code, tool string
+		url        string
+	}{
+		{RustcDocs, "E0308", "", "https://doc.rust-lang.org/error_codes/E0308.html"},
+		{RustcDocs, "clippy::needless_return", "", ""},
+		{TypeScriptDocs, "TS2304", "", "https://typescript.tv/errors/#ts2304"},
+		{TypeScriptDocs, "E0308", "", ""},
+		{DotnetDocs, "CA2000", "", "https://learn.microsoft.com/dotnet/fundamentals/code-analysis/quality-rules/ca2000"},
+		{DotnetDocs, "IDE0005", "", "https://learn.microsoft.com/dotnet/fundamentals/code-analysis/style-rules/ide0005"},
+		{DotnetDocs, "CS0168", "", "https://learn.microsoft.com/dotnet/csharp/misc/cs0168"},
+		{DotnetDocs, "LNK2019", "", ""},
+		{DefaultDocs, "E0308", "rustc", "https://doc.rust-lang.org/error_codes/E0308.html"},
+		{DefaultDocs, "E0308", "gcc", ""},
+		{DefaultDocs, "no-undef", "eslint", "https://eslint.org/docs/rules/no-undef"},
+		{DefaultDocs, "CS0168", "msvc", "https://learn.microsoft.com/dotnet/csharp/misc/cs0168"},
+		{DefaultDocs, "", "rustc", ""},
+	}
+	for _, test := range tests {
+		if url := test.resolver.URL(test.code, test.tool); url != test.url {
+			t.Logf("%s of %s: got %q, expected %q", test.code, test.tool, url, test.url)
+			t.Fail()
+		}
+	}
+}
+
+func TestRegisterDocs(t *testing.T) {
+	RegisterDocs("acme-lint", DocPattern("https://lint.acme.test/rules/%s", regexp.MustCompile(`^ACME[0-9]+$`)))
+	defer RegisterDocs("acme-lint", nil)
+	if url := DefaultDocs.URL("ACME12", "acme-lint"); url != "https://lint.acme.test/rules/ACME12" {
+		t.Logf("got %q", url)
+		t.Fail()
+	}
+	if url := DefaultDocs.URL("E0308", "acme-lint"); url != "" {
+		t.Logf("codes the pattern doesn't match shouldn't link, got %q", url)
+		t.Fail()
+	}
+	RegisterDocs("acme-lint", nil)
+	if url := DefaultDocs.URL("ACME12", "acme-lint"); url != "" {
+		t.Logf("unregistered tools shouldn't link, got %q", url)
+		t.Fail()
+	}
+}
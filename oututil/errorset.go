@@ -0,0 +1,216 @@
+package oututil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON encoding of SourceError and
+// ErrorSetEnvelope. It changes when a field is renamed or its meaning changes,
+// not when one is added.
+const SchemaVersion = 1
+
+// ErrorSetEnvelope is a set of source errors with the version of their
+// encoding, for persisting them, see WriteErrorSet. NewErrorSet makes
+// the ErrorSet of its Errors to compare them.
+type ErrorSetEnvelope struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Tool          string        `json:"tool,omitempty"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	Errors        []SourceError `json:"errors"`
+}
+
+// ErrSchemaVersion is returned by ReadErrorSet for the sets written with
+// a SchemaVersion it can't read.
+var ErrSchemaVersion = errors.New("unsupported schema version")
+
+// WriteErrorSet writes set as indented JSON with the SchemaVersion of
+// this package and the current time as its CreatedAt if it doesn't
+// have one.
+func WriteErrorSet(w io.Writer, set ErrorSetEnvelope) error {
+	set.SchemaVersion = SchemaVersion
+	if set.CreatedAt.IsZero() {
+		set.CreatedAt = time.Now().UTC()
+	}
+	if set.Errors == nil {
+		set.Errors = []SourceError{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(set)
+}
+
+// ReadErrorSet reads the ErrorSetEnvelope WriteErrorSet wrote to r. Sets of a
+// SchemaVersion newer than this package's, or without one, are
+// rejected with ErrSchemaVersion before their errors are decoded.
+func ReadErrorSet(r io.Reader) (ErrorSetEnvelope, error) {
+	var raw struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		Tool          string          `json:"tool"`
+		CreatedAt     time.Time       `json:"createdAt"`
+		Errors        json.RawMessage `json:"errors"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return ErrorSetEnvelope{}, err
+	}
+	if raw.SchemaVersion < 1 || raw.SchemaVersion > SchemaVersion {
+		return ErrorSetEnvelope{}, fmt.Errorf("%w %d, this package reads versions 1 to %d", ErrSchemaVersion, raw.SchemaVersion, SchemaVersion)
+	}
+	set := ErrorSetEnvelope{SchemaVersion: raw.SchemaVersion, Tool: raw.Tool, CreatedAt: raw.CreatedAt}
+	if len(raw.Errors) > 0 {
+		if err := json.Unmarshal(raw.Errors, &set.Errors); err != nil {
+			return ErrorSetEnvelope{}, err
+		}
+	}
+	return set, nil
+}
+
+// jsonError is the JSON encoding of a SourceError. Fields that are
+// zero are omitted, the column too when it is NoColumn, kinds are
+// omitted for diagnostics and "frame" for stack frames and severities
+// are named like Severity.String does, omitted when unknown.
+type jsonError struct {
+	File             string        `json:"file,omitempty"`
+	Line             int           `json:"line,omitempty"`
+	Column           *int          `json:"column,omitempty"`
+	EndLine          int           `json:"endLine,omitempty"`
+	EndColumn        int           `json:"endColumn,omitempty"`
+	Message          string        `json:"message"`
+	Severity         string        `json:"severity,omitempty"`
+	SeverityInferred bool          `json:"severityInferred,omitempty"`
+	WarningAsError   bool          `json:"warningAsError,omitempty"`
+	Occurrences      int           `json:"occurrences,omitempty"`
+	Code             string        `json:"code,omitempty"`
+	Flags            []string      `json:"flags,omitempty"`
+	Project          string        `json:"project,omitempty"`
+	Function         string        `json:"function,omitempty"`
+	Kind             string        `json:"kind,omitempty"`
+	Tool             string        `json:"tool,omitempty"`
+	Dir              string        `json:"dir,omitempty"`
+	Target           string        `json:"target,omitempty"`
+	Package          string        `json:"package,omitempty"`
+	Origin           string        `json:"origin,omitempty"`
+	Snippet          []string      `json:"snippet,omitempty"`
+	Related          []SourceError `json:"related,omitempty"`
+	Fixes            []jsonFix     `json:"fixes,omitempty"`
+	SourceLine       string        `json:"sourceLine,omitempty"`
+	ContextLines     []string      `json:"contextLines,omitempty"`
+	ContextStart     int           `json:"contextStart,omitempty"`
+	Offset           int           `json:"offset,omitempty"`
+	GeneratedFile    string        `json:"generatedFile,omitempty"`
+	GeneratedLine    int           `json:"generatedLine,omitempty"`
+	Raw              string        `json:"raw,omitempty"`
+	LogLine          int           `json:"logLine,omitempty"`
+	LogOffset        int64         `json:"logOffset,omitempty"`
+	Spans            *jsonSpans    `json:"spans,omitempty"`
+	LineTruncated    bool          `json:"lineTruncated,omitempty"`
+	Suppressed       int           `json:"suppressed,omitempty"`
+	Confidence       float64       `json:"confidence,omitempty"`
+}
+
+type jsonFix struct {
+	File        string `json:"file,omitempty"`
+	StartLine   int    `json:"startLine"`
+	StartCol    int    `json:"startCol"`
+	EndLine     int    `json:"endLine"`
+	EndCol      int    `json:"endCol"`
+	Replacement string `json:"replacement"`
+}
+
+type jsonSpans struct {
+	Line     int       `json:"line"`
+	File     jsonRange `json:"file"`
+	Position jsonRange `json:"position"`
+	Message  jsonRange `json:"message"`
+}
+
+type jsonRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// MarshalJSON encodes e in the JSON encoding of SchemaVersion, with the
+// field names of SourceError starting with a lower case letter.
+func (e SourceError) MarshalJSON() ([]byte, error) {
+	j := jsonError{
+		File: e.File, Line: e.Line, EndLine: e.EndLine, EndColumn: e.EndColumn,
+		Message: e.Message, SeverityInferred: e.SeverityInferred, WarningAsError: e.WarningAsError,
+		Occurrences: e.Occurrences, Code: e.Code, Flags: e.Flags, Project: e.Project,
+		Function: e.Function, Tool: e.Tool, Dir: e.Dir, Target: e.Target, Package: e.Package,
+		Origin: e.Origin, Snippet: e.Snippet, Related: e.Related, SourceLine: e.SourceLine,
+		ContextLines: e.ContextLines, ContextStart: e.ContextStart, Offset: e.Offset,
+		GeneratedFile: e.GeneratedFile, GeneratedLine: e.GeneratedLine, Raw: e.Raw,
+		LogLine: e.LogLine, LogOffset: e.LogOffset, LineTruncated: e.LineTruncated,
+		Suppressed: e.Suppressed, Confidence: e.Confidence,
+	}
+	if e.Column != NoColumn {
+		j.Column = &e.Column
+	}
+	if e.Severity != SeverityUnknown {
+		j.Severity = e.Severity.String()
+	}
+	if e.Kind == KindStackFrame {
+		j.Kind = e.Kind.String()
+	}
+	for _, f := range e.Fixes {
+		j.Fixes = append(j.Fixes, jsonFix{f.File, f.StartLine, f.StartCol, f.EndLine, f.EndCol, f.Replacement})
+	}
+	if !e.Spans.IsEmpty() {
+		s := e.Spans
+		j.Spans = &jsonSpans{s.Line, jsonRange(s.File), jsonRange(s.Position), jsonRange(s.Message)}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes the encoding of MarshalJSON, unknown severities
+// and kinds are errors.
+func (e *SourceError) UnmarshalJSON(data []byte) error {
+	var j jsonError
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	d := SourceError{
+		File: j.File, Line: j.Line, Column: NoColumn, EndLine: j.EndLine, EndColumn: j.EndColumn,
+		Message: j.Message, SeverityInferred: j.SeverityInferred, WarningAsError: j.WarningAsError,
+		Occurrences: j.Occurrences, Code: j.Code, Flags: j.Flags, Project: j.Project,
+		Function: j.Function, Tool: j.Tool, Dir: j.Dir, Target: j.Target, Package: j.Package,
+		Origin: j.Origin, Snippet: j.Snippet, Related: j.Related, SourceLine: j.SourceLine,
+		ContextLines: j.ContextLines, ContextStart: j.ContextStart, Offset: j.Offset,
+		GeneratedFile: j.GeneratedFile, GeneratedLine: j.GeneratedLine, Raw: j.Raw,
+		LogLine: j.LogLine, LogOffset: j.LogOffset, LineTruncated: j.LineTruncated,
+		Suppressed: j.Suppressed, Confidence: j.Confidence,
+	}
+	if j.Column != nil {
+		d.Column = *j.Column
+	}
+	switch j.Severity {
+	case "":
+	case "note":
+		d.Severity = SeverityNote
+	case "warning":
+		d.Severity = SeverityWarning
+	case "error":
+		d.Severity = SeverityError
+	default:
+		return fmt.Errorf("unknown severity %q", j.Severity)
+	}
+	switch j.Kind {
+	case "", "diagnostic":
+	case "frame":
+		d.Kind = KindStackFrame
+	default:
+		return fmt.Errorf("unknown kind %q", j.Kind)
+	}
+	for _, f := range j.Fixes {
+		d.Fixes = append(d.Fixes, Fix{f.File, f.StartLine, f.StartCol, f.EndLine, f.EndCol, f.Replacement})
+	}
+	if j.Spans != nil {
+		s := j.Spans
+		d.Spans = Spans{s.Line, ByteRange(s.File), ByteRange(s.Position), ByteRange(s.Message)}
+	}
+	*e = d
+	return nil
+}
@@ -0,0 +1,91 @@
+package oututil_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sevki.org/x/oututil"
+)
+
+// errorSetGolden pins the encoding of SchemaVersion, a change to it is
+// a change to the files users persisted.
+const errorSetGolden = "testdata/errorset.json"
+
+func TestErrorSetGolden(t *testing.T) {
+	set := oututil.ErrorSetEnvelope{
+		Tool:      "gcc",
+		CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Errors: []oututil.SourceError{
+			{
+				File: "src/a.c", Line: 3, Column: 5, EndLine: 3, EndColumn: 9, Message: "unused variable 'x'",
+				Severity: oututil.SeverityWarning, SeverityInferred: true, WarningAsError: true, Occurrences: 2,
+				Code: "-Wunused-variable", Flags: []string{"-Werror"}, Project: "app.vcxproj", Tool: "gcc",
+				Dir: "/src", Target: "//app:a", Package: "app", Origin: "build.log", Snippet: []string{"  int x;"},
+				Related:    []oututil.SourceError{{File: "src/a.h", Line: 1, Column: oututil.NoColumn, Message: "included from here", Severity: oututil.SeverityNote}},
+				Fixes:      []oututil.Fix{{File: "src/a.c", StartLine: 3, StartCol: 5, EndLine: 3, EndCol: 9, Replacement: "_x"}},
+				SourceLine: "  int x;", ContextLines: []string{"{", "  int x;"}, ContextStart: 2, Offset: 40,
+				GeneratedFile: "gen/a.c", GeneratedLine: 30, Raw: "src/a.c:3:5: warning: unused variable 'x'",
+				LogLine: 7, LogOffset: 120, LineTruncated: true, Suppressed: 4, Confidence: 0.9,
+				Spans: oututil.Spans{File: oututil.ByteRange{Start: 0, End: 7}, Position: oututil.ByteRange{Start: 8, End: 11}},
+			},
+			{File: "main.go", Line: 12, Column: 0, Function: "main.main", Kind: oututil.KindStackFrame, Message: "panic: boom"},
+			{Column: oututil.NoColumn, Message: "aborting", Severity: oututil.SeverityError},
+		},
+	}
+	var b bytes.Buffer
+	if err := oututil.WriteErrorSet(&b, set); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(errorSetGolden, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(errorSetGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(golden) {
+		t.Logf("the encoding of error sets changed, was expecting\n%s\ngot\n%s", golden, b.String())
+		t.Fail()
+	}
+	read, err := oututil.ReadErrorSet(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SchemaVersion = oututil.SchemaVersion
+	if !reflect.DeepEqual(read, set) {
+		t.Logf("was expecting the golden set to decode to\n%+v\ngot\n%+v", set, read)
+		t.Fail()
+	}
+}
+
+func TestReadErrorSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version bool
+	}{
+		{"future", `{"schemaVersion": 2, "errors": [{"message": "x", "renamed": 1}]}`, true},
+		{"unversioned", `{"errors": []}`, true},
+		{"severity", `{"schemaVersion": 1, "errors": [{"message": "x", "severity": "fatal"}]}`, false},
+		{"kind", `{"schemaVersion": 1, "errors": [{"message": "x", "kind": "thread"}]}`, false},
+	}
+	for _, test := range tests {
+		_, err := oututil.ReadErrorSet(strings.NewReader(test.data))
+		if err == nil || errors.Is(err, oututil.ErrSchemaVersion) != test.version {
+			t.Logf("%s: was expecting an error, of the schema version %v, got %v", test.name, test.version, err)
+			t.Fail()
+		}
+	}
+	set, err := oututil.ReadErrorSet(strings.NewReader(`{"schemaVersion": 1, "errors": [{"file": "a.c", "line": 3, "message": "x"}]}`))
+	if err != nil || len(set.Errors) != 1 || set.Errors[0].Column != oututil.NoColumn {
+		t.Logf("was expecting a missing column to be NoColumn got %+v, %v", set, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,87 @@
+package oututil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxAnnotationMessage is the largest message GitHub accepts for an
+// annotation, 64 KB.
+const maxAnnotationMessage = 64 << 10
+
+var (
+	annotationData     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	annotationProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// WriteGitHubAnnotations writes errs as GitHub Actions workflow
+// commands so they show up as annotations on pull requests,
+//
+//	::error file=app.go,line=12,col=5,title=E0308::mismatched types
+//
+// Errors and errors without a severity become ::error, warnings
+// ::warning and notes ::notice. WithDocs adds the documentation of the
+// code to the end of the message, WithPathMappings maps the paths.
+func WriteGitHubAnnotations(w io.Writer, errs []SourceError, opts ...OutputOption) error {
+	o := newOutputOptions(opts)
+	bw := bufio.NewWriter(w)
+	for _, e := range o.mapPaths(errs) {
+		command := "error"
+		switch e.Severity {
+		case SeverityWarning:
+			command = "warning"
+		case SeverityNote:
+			command = "notice"
+		}
+		var props []string
+		prop := func(k, v string) { props = append(props, k+"="+annotationProperty.Replace(v)) }
+		if e.File != "" {
+			prop("file", slashPath(e.File))
+		}
+		if e.Line > 0 {
+			prop("line", strconv.Itoa(e.Line))
+			if e.EndLine > 0 {
+				prop("endLine", strconv.Itoa(e.EndLine))
+			}
+		}
+		if e.Column > 0 {
+			prop("col", strconv.Itoa(e.Column))
+			if e.EndColumn > 0 {
+				prop("endColumn", strconv.Itoa(e.EndColumn))
+			}
+		}
+		if e.Code != "" {
+			prop("title", e.Code)
+		}
+		msg := e.Message
+		if url := docURL(o.docs, e); url != "" {
+			see := "\n\nSee " + url
+			msg = truncate(msg, maxAnnotationMessage-len(see)) + see
+		}
+		msg = annotationData.Replace(truncate(msg, maxAnnotationMessage))
+		if len(props) > 0 {
+			fmt.Fprintf(bw, "::%s %s::%s\n", command, strings.Join(props, ","), msg)
+		} else {
+			fmt.Fprintf(bw, "::%s::%s\n", command, msg)
+		}
+	}
+	return bw.Flush()
+}
+
+// truncate cuts s to at most n bytes, ending it with an ellipsis if it
+// had to be cut. It doesn't split runes.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const ellipsis = "…"
+	i := n - len(ellipsis)
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + ellipsis
+}
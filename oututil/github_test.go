@@ -0,0 +1,77 @@
+package oututil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGitHubAnnotations(&buf, []SourceError{
+		{File: "app.go", Line: 12, Column: 5, Severity: SeverityError, Message: "undefined: foo"},
+		{File: `pkg\a,b:c.go`, Line: 3, Column: NoColumn, Severity: SeverityWarning, Code: "SA4006", Message: "100% unused\nreally"},
+		{File: "main.rs", Line: 4, Column: 18, EndLine: 4, EndColumn: 21, Severity: SeverityNote, Message: "expected due to this"},
+		{Message: "linker failed"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `::error file=app.go,line=12,col=5::undefined: foo
+::warning file=pkg/a%2Cb%3Ac.go,line=3,title=SA4006::100%25 unused%0Areally
+::notice file=main.rs,line=4,endLine=4,col=18,endColumn=21::expected due to this
+::error::linker failed
+`
+	if buf.String() != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, buf.String())
+		t.Fail()
+	}
+}
+
+func TestWriteGitHubAnnotationsDocs(t *testing.T) {
+	errs := []SourceError{
+		{File: "a.cs", Line: 7, Column: 9, Code: "CA2000", Tool: "msvc", Message: "dispose objects"},
+		{File: "a.cs", Line: 8, Column: NoColumn, Code: "MY001", Tool: "in-house", Message: "no link"},
+	}
+	var buf bytes.Buffer
+	if err := WriteGitHubAnnotations(&buf, errs, WithDocs(DefaultDocs)); err != nil {
+		t.Fatal(err)
+	}
+	want := `::error file=a.cs,line=7,col=9,title=CA2000::dispose objects%0A%0ASee https://learn.microsoft.com/dotnet/fundamentals/code-analysis/quality-rules/ca2000
+::error file=a.cs,line=8,title=MY001::no link
+`
+	if buf.String() != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, buf.String())
+		t.Fail()
+	}
+}
+
+func TestWriteGitHubAnnotationsPathMappings(t *testing.T) {
+	errs := []SourceError{
+		{File: "/workspace/pkg/a.go", Line: 3, Message: "unused variable"},
+		{File: "/opt/go/src/fmt/print.go", Line: 9, Message: "in the toolchain"},
+	}
+	var buf bytes.Buffer
+	if err := WriteGitHubAnnotations(&buf, errs, WithPathMappings([]PathMapping{{From: "/workspace"}}, DropUnmapped())); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=pkg/a.go,line=3::unused variable\n"
+	if buf.String() != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, buf.String())
+		t.Fail()
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Logf("short strings shouldn't be truncated, got %q", got)
+		t.Fail()
+	}
+	long := strings.Repeat("ü", 100)
+	got := truncate(long, 51)
+	if len(got) > 51 || !utf8.ValidString(got) || !strings.HasSuffix(got, "…") {
+		t.Logf("bad truncation %q (%d bytes)", got, len(got))
+		t.Fail()
+	}
+}
@@ -0,0 +1,114 @@
+package oututil
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitOptions struct {
+	perError bool
+}
+
+// JUnitOption configures WriteJUnit.
+type JUnitOption func(*junitOptions)
+
+// JUnitCasePerError makes WriteJUnit write a test case for every error
+// instead of one per file.
+func JUnitCasePerError() JUnitOption {
+	return func(o *junitOptions) { o.perError = true }
+}
+
+// WriteJUnit writes errs as a JUnit XML test suite named suiteName with
+// a test case per file. A test case fails when one of its errors is an
+// error or has no severity, warnings and notes are written to its
+// output. Without errors the suite has a single passing test case so
+// the report can be written unconditionally.
+func WriteJUnit(w io.Writer, errs []SourceError, suiteName string, opts ...JUnitOption) error {
+	var o junitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sorted := append([]SourceError(nil), errs...)
+	Sort(sorted)
+
+	suite := junitSuite{Name: suiteName}
+	var group []SourceError
+	for i, e := range sorted {
+		group = append(group, e)
+		if o.perError || i == len(sorted)-1 || sorted[i+1].File != e.File {
+			name := e.File
+			if o.perError {
+				name = e.Error()
+			}
+			suite.Cases = append(suite.Cases, junitCaseOf(name, suiteName, group))
+			group = nil
+		}
+	}
+	if len(suite.Cases) == 0 {
+		suite.Cases = []junitCase{{Name: suiteName, Classname: suiteName}}
+	}
+	suite.Tests = len(suite.Cases)
+	for _, c := range suite.Cases {
+		if c.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitCaseOf(name, classname string, errs []SourceError) junitCase {
+	c := junitCase{Name: name, Classname: classname}
+	var failures, output []string
+	for _, e := range errs {
+		switch e.Severity {
+		case SeverityError, SeverityUnknown:
+			failures = append(failures, e.Error())
+		default:
+			output = append(output, e.Error())
+		}
+	}
+	if len(failures) > 0 {
+		msg := failures[0]
+		if len(failures) > 1 {
+			msg = fmt.Sprintf("%d errors", len(failures))
+		}
+		c.Failure = &junitFailure{Message: msg, Type: "error", Text: strings.Join(failures, "\n")}
+	}
+	c.SystemOut = strings.Join(output, "\n")
+	return c
+}
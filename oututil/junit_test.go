@@ -0,0 +1,68 @@
+package oututil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	errs := []SourceError{
+		{File: "b.go", Line: 3, Column: 2, Severity: SeverityWarning, Message: "x declared and not used"},
+		{File: "a.go", Line: 9, Column: NoColumn, Message: "undefined: foo"},
+		{File: "a.go", Line: 4, Column: 1, Severity: SeverityError, Message: "missing return"},
+	}
+	tests := []struct {
+		name string
+		errs []SourceError
+		opts []JUnitOption
+		want string
+	}{
+		{
+			name: "per file",
+			errs: errs,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="build" tests="2" failures="1" errors="0" skipped="0">
+  <testcase name="a.go" classname="build">
+    <failure message="2 errors" type="error">a.go:4:1: error: missing return&#xA;a.go:9: undefined: foo</failure>
+  </testcase>
+  <testcase name="b.go" classname="build">
+    <system-out>b.go:3:2: warning: x declared and not used</system-out>
+  </testcase>
+</testsuite>
+`,
+		},
+		{
+			name: "per error",
+			errs: errs[1:],
+			opts: []JUnitOption{JUnitCasePerError()},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="build" tests="2" failures="2" errors="0" skipped="0">
+  <testcase name="a.go:4:1: error: missing return" classname="build">
+    <failure message="a.go:4:1: error: missing return" type="error">a.go:4:1: error: missing return</failure>
+  </testcase>
+  <testcase name="a.go:9: undefined: foo" classname="build">
+    <failure message="a.go:9: undefined: foo" type="error">a.go:9: undefined: foo</failure>
+  </testcase>
+</testsuite>
+`,
+		},
+		{
+			name: "no errors",
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="build" tests="1" failures="0" errors="0" skipped="0">
+  <testcase name="build" classname="build"></testcase>
+</testsuite>
+`,
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteJUnit(&buf, test.errs, "build", test.opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Logf("%s: was expecting\n%s\ngot\n%s", test.name, test.want, got)
+			t.Fail()
+		}
+	}
+}
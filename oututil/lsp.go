@@ -0,0 +1,89 @@
+package oututil
+
+// DiagnosticSeverity is the severity of a Diagnostic as defined by the
+// language server protocol.
+type DiagnosticSeverity int
+
+// LSP diagnostic severities
+const (
+	DiagnosticError       DiagnosticSeverity = 1
+	DiagnosticWarning     DiagnosticSeverity = 2
+	DiagnosticInformation DiagnosticSeverity = 3
+	DiagnosticHint        DiagnosticSeverity = 4
+)
+
+// Position is a zero-based line and character offset in a document.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a document, End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is a language server protocol diagnostic.
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     string             `json:"code,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// ToDiagnostics converts errs to LSP diagnostics keyed by file. Errors
+// without a file or a line are dropped. Columns are converted to UTF-16
+// for the errors that have a SourceLine, see Annotate.
+func ToDiagnostics(errs []SourceError) map[string][]Diagnostic {
+	diags := make(map[string][]Diagnostic)
+	for _, e := range errs {
+		if e.File == "" || e.Line < 1 {
+			continue
+		}
+		diags[e.File] = append(diags[e.File], Diagnostic{
+			Range:    lspRange(e),
+			Severity: lspSeverity(e.Severity),
+			Code:     e.Code,
+			Source:   e.Tool,
+			Message:  e.Message,
+		})
+	}
+	return diags
+}
+
+// lspRange converts the one-based positions of e. Without a column the
+// range covers the whole line, since its length is unknown, and without
+// an end the range is empty.
+func lspRange(e SourceError) Range {
+	span := e.Span()
+	start := Position{Line: span.Start.Line - 1}
+	if !span.Start.HasColumn() {
+		return Range{Start: start, End: Position{Line: span.Start.Line}}
+	}
+	start.Character = span.Start.Column - 1
+	if e.SourceLine != "" {
+		start.Character = utf16Column(e.SourceLine, span.Start.Column) - 1
+	}
+	end := start
+	if span.End.HasColumn() && !span.End.Before(span.Start) {
+		end = Position{Line: span.End.Line - 1, Character: span.End.Column - 1}
+		if e.SourceLine != "" && span.End.Line == span.Start.Line {
+			end.Character = utf16Column(e.SourceLine, span.End.Column) - 1
+		}
+	}
+	return Range{Start: start, End: end}
+}
+
+// lspSeverity maps severities to LSP, errors without a severity are
+// errors.
+func lspSeverity(s Severity) DiagnosticSeverity {
+	switch s {
+	case SeverityWarning:
+		return DiagnosticWarning
+	case SeverityNote:
+		return DiagnosticInformation
+	}
+	return DiagnosticError
+}
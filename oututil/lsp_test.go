@@ -0,0 +1,74 @@
+package oututil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToDiagnostics(t *testing.T) {
+	tests := []struct {
+		err  SourceError
+		want Range
+		sev  DiagnosticSeverity
+	}{
+		{
+			err:  SourceError{File: "a.go", Line: 3, Column: 5},
+			want: Range{Start: Position{2, 4}, End: Position{2, 4}},
+			sev:  DiagnosticError,
+		},
+		{
+			err:  SourceError{File: "a.go", Line: 7, Column: NoColumn, Severity: SeverityWarning},
+			want: Range{Start: Position{6, 0}, End: Position{7, 0}},
+			sev:  DiagnosticWarning,
+		},
+		{
+			err:  SourceError{File: "a.go", Line: 4, Column: 18, EndLine: 4, EndColumn: 21, Severity: SeverityNote},
+			want: Range{Start: Position{3, 17}, End: Position{3, 20}},
+			sev:  DiagnosticInformation,
+		},
+		{
+			err:  SourceError{File: "a.go", Line: 4, Column: 2, EndLine: 6, EndColumn: 1, Severity: SeverityError},
+			want: Range{Start: Position{3, 1}, End: Position{5, 0}},
+			sev:  DiagnosticError,
+		},
+		{
+			// 😀 is four bytes and two UTF-16 code units
+			err:  SourceError{File: "a.go", Line: 2, Column: 11, EndLine: 2, EndColumn: 12, SourceLine: `s := "😀" + x`},
+			want: Range{Start: Position{1, 8}, End: Position{1, 9}},
+			sev:  DiagnosticError,
+		},
+	}
+	for _, test := range tests {
+		diags := ToDiagnostics([]SourceError{test.err})["a.go"]
+		if len(diags) != 1 {
+			t.Fatalf("was expecting 1 diagnostic got %d", len(diags))
+		}
+		if diags[0].Range != test.want {
+			t.Logf("%v: was expecting range %v got %v instead", test.err, test.want, diags[0].Range)
+			t.Fail()
+		}
+		if diags[0].Severity != test.sev {
+			t.Logf("%v: was expecting severity %d got %d instead", test.err, test.sev, diags[0].Severity)
+			t.Fail()
+		}
+	}
+}
+
+func TestToDiagnosticsJSON(t *testing.T) {
+	diags := ToDiagnostics([]SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, Code: "E0308", Tool: "rustc", Message: "mismatched types"},
+		{Column: NoColumn, Message: "linker failed"},
+	})
+	if len(diags) != 1 {
+		t.Fatalf("errors without a file shouldn't be keyed, got %v", diags)
+	}
+	b, err := json.Marshal(diags["src/main.rs"][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"range":{"start":{"line":3,"character":17},"end":{"line":3,"character":17}},"severity":1,"code":"E0308","source":"rustc","message":"mismatched types"}`
+	if string(b) != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, b)
+		t.Fail()
+	}
+}
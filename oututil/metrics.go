@@ -0,0 +1,202 @@
+package oututil
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricName is the name of the samples of MetricsSnapshot.
+const MetricName = "source_errors"
+
+// MaxLabelValues is the number of values a label of MetricsSnapshot has
+// at most, "other" included: the errors of the values that are less
+// frequent than the first MaxLabelValues-1 are counted as "other".
+const MaxLabelValues = 20
+
+// Label is a label of a Sample.
+type Label struct {
+	Name, Value string
+}
+
+// Sample is a labeled count of source errors, see MetricsSnapshot.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+// Labeler returns a label of e and its value, see MetricsSnapshot.
+type Labeler func(e SourceError) (label string, value string)
+
+var (
+	// BySeverity labels errors with their severity.
+	BySeverity Labeler = func(e SourceError) (string, string) { return "severity", e.Severity.String() }
+	// ByTool labels errors with their Tool.
+	ByTool Labeler = func(e SourceError) (string, string) { return "tool", e.Tool }
+	// ByDirectory labels errors with the first directory of their
+	// file, . for files without one.
+	ByDirectory Labeler = func(e SourceError) (string, string) { return "directory", topDirectory(e) }
+)
+
+// MetricsSnapshot counts errs by the labels labelers give them, a
+// Sample per combination of their values, or a single one without
+// labelers. Label names are made valid Prometheus names, values valid
+// UTF-8, "none" when they are empty, and the rare values of labels
+// with more than MaxLabelValues "other", so that labels like file paths
+// don't make a series per file. Samples are sorted by their labels.
+func MetricsSnapshot(errs []SourceError, labelers ...Labeler) []Sample {
+	names := make([]string, len(labelers))
+	values := make([][]string, len(errs))
+	for i, e := range errs {
+		values[i] = make([]string, len(labelers))
+		for j, l := range labelers {
+			name, value := l(e)
+			names[j] = labelName(name)
+			if value = strings.ToValidUTF8(value, "�"); value == "" {
+				value = "none"
+			}
+			values[i][j] = value
+		}
+	}
+	for j := range labelers {
+		capLabel(values, j)
+	}
+
+	counts := make(map[string]*Sample)
+	var samples []*Sample
+	for _, v := range values {
+		k := strings.Join(v, "\x00")
+		s, ok := counts[k]
+		if !ok {
+			s = &Sample{Name: MetricName, Labels: make([]Label, len(names))}
+			for j, name := range names {
+				s.Labels[j] = Label{name, v[j]}
+			}
+			counts[k] = s
+			samples = append(samples, s)
+		}
+		s.Value++
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].Labels, samples[j].Labels
+		for k := range a {
+			if a[k].Value != b[k].Value {
+				return a[k].Value < b[k].Value
+			}
+		}
+		return false
+	})
+	snapshot := make([]Sample, len(samples))
+	for i, s := range samples {
+		snapshot[i] = *s
+	}
+	if len(snapshot) == 0 && len(labelers) == 0 {
+		snapshot = append(snapshot, Sample{Name: MetricName})
+	}
+	return snapshot
+}
+
+// capLabel replaces the values of label j that aren't among its
+// MaxLabelValues-1 most frequent with "other" if it has more than
+// MaxLabelValues, ties go to the first values in order.
+func capLabel(values [][]string, j int) {
+	counts := make(map[string]int)
+	for _, v := range values {
+		counts[v[j]]++
+	}
+	if len(counts) <= MaxLabelValues {
+		return
+	}
+	byCount := make([]string, 0, len(counts))
+	for value := range counts {
+		byCount = append(byCount, value)
+	}
+	sort.Slice(byCount, func(a, b int) bool {
+		if counts[byCount[a]] != counts[byCount[b]] {
+			return counts[byCount[a]] > counts[byCount[b]]
+		}
+		return byCount[a] < byCount[b]
+	})
+	kept := make(map[string]bool, MaxLabelValues)
+	for _, value := range byCount[:MaxLabelValues-1] {
+		kept[value] = true
+	}
+	for _, v := range values {
+		if !kept[v[j]] {
+			v[j] = "other"
+		}
+	}
+}
+
+// labelName makes name a valid Prometheus label name: letters, digits
+// and underscores not starting with a digit or two underscores, which
+// are reserved.
+func labelName(name string) string {
+	if name == "" {
+		return "label"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && i > 0) {
+			b[i] = '_'
+		}
+	}
+	if name = string(b); strings.HasPrefix(name, "__") {
+		name = "x" + name
+	}
+	return name
+}
+
+// topDirectory returns the first directory of the file of e.
+func topDirectory(e SourceError) string {
+	if e.File == "" {
+		return ""
+	}
+	if e.IsRemote() {
+		return e.Scheme()
+	}
+	file := slashPath(e.File)
+	if len(file) > 2 && file[1] == ':' && isLetter(file[0]) {
+		file = file[2:]
+	}
+	file = strings.TrimPrefix(file, "/")
+	i := strings.IndexByte(file, '/')
+	if i < 0 {
+		return "."
+	}
+	return file[:i]
+}
+
+// WriteTextfile writes samples in the Prometheus text exposition format,
+// for the textfile collector of the node exporter or a Pushgateway. The
+// samples are gauges, they count the errors of a log.
+func WriteTextfile(w io.Writer, samples []Sample) error {
+	var b strings.Builder
+	var last string
+	for _, s := range samples {
+		if s.Name != last {
+			fmt.Fprintf(&b, "# HELP %s Source errors parsed from a log.\n# TYPE %s gauge\n", s.Name, s.Name)
+			last = s.Name
+		}
+		b.WriteString(s.Name)
+		if len(s.Labels) > 0 {
+			b.WriteByte('{')
+			for i, l := range s.Labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, "%s=\"%s\"", l.Name, labelEscaper.Replace(l.Value))
+			}
+			b.WriteByte('}')
+		}
+		fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelEscaper escapes label values like the text format wants.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
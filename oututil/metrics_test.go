@@ -0,0 +1,66 @@
+package oututil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	errs := []SourceError{
+		{File: "src/a.c", Severity: SeverityError, Tool: "gcc"},
+		{File: `src\b.c`, Severity: SeverityError, Tool: "gcc"},
+		{File: "main.c", Severity: SeverityWarning, Tool: "gcc"},
+		{File: "/abs/x.go", Severity: SeverityWarning, Tool: "vet"},
+		{File: "lib/say \"hi\".py", Severity: SeverityError},
+	}
+	var b strings.Builder
+	if err := WriteTextfile(&b, MetricsSnapshot(errs, BySeverity, ByDirectory, ByTool)); err != nil {
+		t.Fatal(err)
+	}
+	const want = `# HELP source_errors Source errors parsed from a log.
+# TYPE source_errors gauge
+source_errors{severity="error",directory="lib",tool="none"} 1
+source_errors{severity="error",directory="src",tool="gcc"} 2
+source_errors{severity="warning",directory=".",tool="gcc"} 1
+source_errors{severity="warning",directory="abs",tool="vet"} 1
+`
+	if b.String() != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, b.String())
+		t.Fail()
+	}
+
+	b.Reset()
+	WriteTextfile(&b, MetricsSnapshot(nil))
+	if !strings.HasSuffix(b.String(), "\nsource_errors 0\n") {
+		t.Logf("was expecting a zero total got\n%s", b.String())
+		t.Fail()
+	}
+}
+
+func TestMetricsSnapshotLabels(t *testing.T) {
+	var errs []SourceError
+	for i := 0; i < 100; i++ {
+		errs = append(errs, SourceError{File: fmt.Sprintf("file%02d.c", i%30)})
+	}
+	byFile := func(e SourceError) (string, string) { return "0file-name", e.File }
+	samples := MetricsSnapshot(errs, byFile, func(e SourceError) (string, string) { return "__code", "bad\xff" })
+	if len(samples) != MaxLabelValues {
+		t.Logf("was expecting %d samples got %d", MaxLabelValues, len(samples))
+		t.Fail()
+	}
+	other := samples[len(samples)-1]
+	total := 0.0
+	for _, s := range samples {
+		total += s.Value
+		if s.Labels[0].Name != "_file_name" || s.Labels[1] != (Label{"x__code", "bad�"}) {
+			t.Logf("labels weren't sanitized: %+v", s.Labels)
+			t.Fail()
+		}
+	}
+	// the first 10 files have 4 errors, the others 3
+	if samples[0].Labels[0].Value != "file00.c" || samples[0].Value != 4 || other.Labels[0].Value != "other" || other.Value != 100-4*10-3*9 || total != 100 {
+		t.Logf("was expecting the rest to be other and 100 errors got %+v, %v", other, total)
+		t.Fail()
+	}
+}
@@ -0,0 +1,90 @@
+package oututil
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type rdDiagnostic struct {
+	Message  string     `json:"message"`
+	Location rdLocation `json:"location"`
+	Severity string     `json:"severity"`
+	Source   *rdSource  `json:"source,omitempty"`
+	Code     *rdCode    `json:"code,omitempty"`
+}
+
+type rdLocation struct {
+	Path  string   `json:"path"`
+	Range *rdRange `json:"range,omitempty"`
+}
+
+type rdRange struct {
+	Start rdPosition  `json:"start"`
+	End   *rdPosition `json:"end,omitempty"`
+}
+
+type rdPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdSource struct {
+	Name string `json:"name"`
+}
+
+type rdCode struct {
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+// WriteRDJSONL writes errs as reviewdog diagnostics, one JSON object
+// per line, reported by source. Lines and columns are one-based like
+// the ones of SourceError, the end of the range is omitted when the
+// tool didn't report one. Codes link to the documentation DefaultDocs
+// has for them.
+func WriteRDJSONL(w io.Writer, errs []SourceError, source string) error {
+	enc := json.NewEncoder(w)
+	for _, e := range errs {
+		d := rdDiagnostic{
+			Message:  e.Message,
+			Location: rdLocation{Path: e.File},
+			Severity: rdSeverity(e.Severity),
+		}
+		if source != "" {
+			d.Source = &rdSource{Name: source}
+		}
+		if e.Line > 0 {
+			r := &rdRange{Start: rdPosition{Line: e.Line}}
+			if e.Column > 0 {
+				r.Start.Column = e.Column
+			}
+			if e.EndLine >= e.Line {
+				r.End = &rdPosition{Line: e.EndLine}
+				if e.EndColumn > 0 {
+					r.End.Column = e.EndColumn
+				}
+			}
+			d.Location.Range = r
+		}
+		if e.Code != "" {
+			d.Code = &rdCode{Value: e.Code}
+			d.Code.URL = DefaultDocs.URL(e.Code, e.Tool)
+		}
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rdSeverity maps severities to reviewdog, errors without a severity
+// are errors.
+func rdSeverity(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return "WARNING"
+	case SeverityNote:
+		return "INFO"
+	}
+	return "ERROR"
+}
@@ -0,0 +1,26 @@
+package oututil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRDJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteRDJSONL(&buf, []SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, EndLine: 4, EndColumn: 21, Code: "E0308", Tool: "rustc", Severity: SeverityError, Message: "mismatched types"},
+		{File: "app.go", Line: 7, Column: NoColumn, Severity: SeverityWarning, Message: "\"x\" declared\tand not used"},
+		{File: "main.obj", Column: NoColumn, Code: "LNK2019", Tool: "msvc-link", Message: "unresolved external symbol"},
+	}, "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"message":"mismatched types","location":{"path":"src/main.rs","range":{"start":{"line":4,"column":18},"end":{"line":4,"column":21}}},"severity":"ERROR","source":{"name":"build"},"code":{"value":"E0308","url":"https://doc.rust-lang.org/error_codes/E0308.html"}}
+{"message":"\"x\" declared\tand not used","location":{"path":"app.go","range":{"start":{"line":7}}},"severity":"WARNING","source":{"name":"build"}}
+{"message":"unresolved external symbol","location":{"path":"main.obj"},"severity":"ERROR","source":{"name":"build"},"code":{"value":"LNK2019"}}
+`
+	if got := buf.String(); got != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,174 @@
+package oututil
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID      string `json:"id"`
+	HelpURI string `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	RuleIndex *int            `json:"ruleIndex,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// ToSARIF returns errs as a SARIF 2.1.0 log with a single run of tool.
+// The distinct codes of errs become the rules of the run, WithDocs sets
+// their helpUri from the first error with the code. WithPathMappings
+// maps the paths of the locations.
+func ToSARIF(errs []SourceError, tool string, opts ...OutputOption) ([]byte, error) {
+	o := newOutputOptions(opts)
+	errs = o.mapPaths(errs)
+	rules := make(map[string]int)
+	help := make(map[string]string)
+	var codes []string
+	for _, e := range errs {
+		if _, ok := rules[e.Code]; e.Code != "" && !ok {
+			rules[e.Code] = 0
+			help[e.Code] = docURL(o.docs, e)
+			codes = append(codes, e.Code)
+		}
+	}
+	sort.Strings(codes)
+	driver := sarifDriver{Name: tool}
+	for i, code := range codes {
+		rules[code] = i
+		driver.Rules = append(driver.Rules, sarifRule{ID: code, HelpURI: help[code]})
+	}
+
+	results := make([]sarifResult, 0, len(errs))
+	for _, e := range errs {
+		r := sarifResult{
+			Level:   sarifLevel(e.Severity),
+			Message: sarifMessage{Text: e.Message},
+		}
+		if e.Code != "" {
+			i := rules[e.Code]
+			r.RuleID, r.RuleIndex = e.Code, &i
+		}
+		if e.File != "" {
+			loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: fileURI(e.File)}}
+			if e.Line > 0 {
+				loc.Region = sarifRegionOf(e)
+			}
+			r.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, r)
+	}
+	return json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+}
+
+func sarifRegionOf(e SourceError) *sarifRegion {
+	r := &sarifRegion{StartLine: e.Line}
+	if e.Column > 0 {
+		r.StartColumn = e.Column
+	}
+	if e.EndLine >= e.Line {
+		r.EndLine = e.EndLine
+		if e.EndColumn > 0 && r.StartColumn > 0 {
+			r.EndColumn = e.EndColumn
+		}
+	}
+	return r
+}
+
+// sarifLevel maps severities to SARIF levels, errors without a severity
+// are errors.
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	}
+	return "error"
+}
+
+// fileURI converts a Windows or Unix path to a URI. Absolute paths
+// become file URIs and relative paths relative references, URLs are
+// URIs already.
+func fileURI(p string) string {
+	if schemeOf(p) != "" {
+		return p
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+	switch {
+	case len(p) >= 2 && p[1] == ':' && isLetter(p[0]):
+		// C:/src/a.c
+		return (&url.URL{Scheme: "file", Path: "/" + p}).String()
+	case strings.HasPrefix(p, "//"):
+		// UNC paths, //server/share/a.c
+		host := strings.SplitN(p[2:], "/", 2)
+		u := &url.URL{Scheme: "file", Host: host[0], Path: "/"}
+		if len(host) > 1 {
+			u.Path += host[1]
+		}
+		return u.String()
+	case strings.HasPrefix(p, "/"):
+		return (&url.URL{Scheme: "file", Path: p}).String()
+	}
+	return (&url.URL{Path: p}).String()
+}
+
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
@@ -0,0 +1,190 @@
+package oututil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFileURI(t *testing.T) {
+	tests := []struct{ path, uri string }{
+		{"/src/a.go", "file:///src/a.go"},
+		{`C:\src\app\main.cpp`, "file:///C:/src/app/main.cpp"},
+		{"c:/src/main.cpp", "file:///c:/src/main.cpp"},
+		{`\\server\share\a.c`, "file://server/share/a.c"},
+		{"pkg/a.go", "pkg/a.go"},
+		{"My Documents/app.c", "My%20Documents/app.c"},
+		{"/src/100%.go", "file:///src/100%25.go"},
+	}
+	for _, test := range tests {
+		if got := fileURI(test.path); got != test.uri {
+			t.Logf("fileURI(%q) = %q, want %q", test.path, got, test.uri)
+			t.Fail()
+		}
+	}
+}
+
+func TestToSARIF(t *testing.T) {
+	b, err := ToSARIF([]SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, EndLine: 4, EndColumn: 21, Severity: SeverityError, Code: "E0308", Message: "mismatched types"},
+		{File: `C:\src\util.h`, Line: 7, Column: NoColumn, Severity: SeverityWarning, Code: "C4244", Message: "possible loss of data"},
+		{File: "src/main.rs", Line: 9, Column: 1, Severity: SeverityNote, Code: "E0308", Message: "again"},
+		{File: "main.obj", Column: NoColumn, Message: "unresolved external symbol"},
+	}, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log map[string]interface{}
+	if err := json.Unmarshal(b, &log); err != nil {
+		t.Fatal(err)
+	}
+	validateSARIF(t, log)
+
+	run := log["runs"].([]interface{})[0].(map[string]interface{})
+	rules := run["tool"].(map[string]interface{})["driver"].(map[string]interface{})["rules"].([]interface{})
+	if len(rules) != 2 {
+		t.Fatalf("was expecting a rule per distinct code, got %d", len(rules))
+	}
+	results := run["results"].([]interface{})
+	levels := []string{"error", "warning", "note", "error"}
+	for i, r := range results {
+		r := r.(map[string]interface{})
+		if r["level"] != levels[i] {
+			t.Logf("result %d: was expecting level %q got %q instead", i, levels[i], r["level"])
+			t.Fail()
+		}
+	}
+	region := results[0].(map[string]interface{})["locations"].([]interface{})[0].(map[string]interface{})["physicalLocation"].(map[string]interface{})["region"].(map[string]interface{})
+	if region["startLine"] != 4.0 || region["startColumn"] != 18.0 || region["endLine"] != 4.0 || region["endColumn"] != 21.0 {
+		t.Logf("unexpected region %v", region)
+		t.Fail()
+	}
+	if _, ok := results[3].(map[string]interface{})["locations"].([]interface{})[0].(map[string]interface{})["physicalLocation"].(map[string]interface{})["region"]; ok {
+		t.Log("errors without a line shouldn't have a region")
+		t.Fail()
+	}
+}
+
+func TestToSARIFDocs(t *testing.T) {
+	b, err := ToSARIF([]SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, Code: "E0308", Tool: "rustc", Message: "mismatched types"},
+		{File: "a.c", Line: 7, Column: NoColumn, Code: "C4244", Tool: "msvc", Message: "possible loss of data"},
+	}, "demo", WithDocs(DefaultDocs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []map[string]interface{}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &log); err != nil {
+		t.Fatal(err)
+	}
+	rules := log.Runs[0].Tool.Driver.Rules
+	if rules[0]["id"] != "C4244" || rules[0]["helpUri"] != nil {
+		t.Logf("C4244 isn't documented, got %v", rules[0])
+		t.Fail()
+	}
+	if rules[1]["id"] != "E0308" || rules[1]["helpUri"] != "https://doc.rust-lang.org/error_codes/E0308.html" {
+		t.Logf("got %v for E0308", rules[1])
+		t.Fail()
+	}
+}
+
+// validateSARIF checks the constraints the SARIF 2.1.0 schema puts on
+// the subset of the format ToSARIF writes.
+func validateSARIF(t *testing.T, log map[string]interface{}) {
+	t.Helper()
+	object := func(v interface{}, path string, required ...string) map[string]interface{} {
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: is not an object", path)
+		}
+		for _, k := range required {
+			if _, ok := o[k]; !ok {
+				t.Logf("%s: missing required property %q", path, k)
+				t.Fail()
+			}
+		}
+		return o
+	}
+	positive := func(o map[string]interface{}, path string, keys ...string) {
+		for _, k := range keys {
+			if v, ok := o[k]; ok {
+				if n, ok := v.(float64); !ok || n < 1 || n != float64(int(n)) {
+					t.Logf("%s.%s: %v is not a positive integer", path, k, v)
+					t.Fail()
+				}
+			}
+		}
+	}
+	object(log, "log", "version", "runs")
+	if log["version"] != "2.1.0" {
+		t.Logf("log.version: %v is not 2.1.0", log["version"])
+		t.Fail()
+	}
+	for _, run := range log["runs"].([]interface{}) {
+		run := object(run, "run", "tool")
+		driver := object(object(run["tool"], "tool", "driver")["driver"], "driver", "name")
+		if rules, ok := driver["rules"]; ok {
+			for _, rule := range rules.([]interface{}) {
+				object(rule, "rule", "id")
+			}
+		}
+		for _, result := range run["results"].([]interface{}) {
+			result := object(result, "result", "message")
+			object(result["message"], "message", "text")
+			switch result["level"] {
+			case "none", "note", "warning", "error":
+			default:
+				t.Logf("result.level: %v is not a valid level", result["level"])
+				t.Fail()
+			}
+			if i, ok := result["ruleIndex"]; ok {
+				if n, ok := i.(float64); !ok || n < 0 {
+					t.Logf("result.ruleIndex: %v is negative", i)
+					t.Fail()
+				}
+			}
+			locations, _ := result["locations"].([]interface{})
+			for _, loc := range locations {
+				physical := object(object(loc, "location")["physicalLocation"], "physicalLocation", "artifactLocation")
+				object(physical["artifactLocation"], "artifactLocation", "uri")
+				if region, ok := physical["region"]; ok {
+					positive(object(region, "region", "startLine"), "region", "startLine", "startColumn", "endLine", "endColumn")
+				}
+			}
+		}
+	}
+}
+
+func TestToSARIFPathMappings(t *testing.T) {
+	b, err := ToSARIF([]SourceError{
+		{File: "/workspace/src/main.rs", Line: 4, Message: "mismatched types"},
+	}, "demo", WithPathMappings([]PathMapping{{From: "/workspace"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &log); err != nil {
+		t.Fatal(err)
+	}
+	if uri := log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "src/main.rs" {
+		t.Logf("was expecting src/main.rs got %q instead", uri)
+		t.Fail()
+	}
+}
@@ -3,6 +3,7 @@ package oututil
 import (
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Direction impies the text direction
@@ -15,11 +16,228 @@ const (
 	RTL
 )
 
+// Severity is the severity of a SourceError as reported by the tool
+type Severity int
+
+const (
+	// SeverityUnknown is used when the tool didn't report a severity
+	SeverityUnknown Severity = iota
+	// SeverityNote is a note, info or remark
+	SeverityNote
+	// SeverityWarning is a warning
+	SeverityWarning
+	// SeverityError is an error
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityNote:
+		return "note"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Kind tells diagnostics apart from the stack frames of a crash
+type Kind int
+
+const (
+	// KindDiagnostic is a diagnostic reported by a compiler, linter or test
+	KindDiagnostic Kind = iota
+	// KindStackFrame is a frame of a Go panic or Python traceback
+	KindStackFrame
+)
+
+func (k Kind) String() string {
+	if k == KindStackFrame {
+		return "frame"
+	}
+	return "diagnostic"
+}
+
 // SourceError represents an errror in the srouce code
 type SourceError struct {
-	File         string
+	File string
+	// Line and Column start at 1 and Column is the one the tool
+	// reported, usually in bytes, see ToRuneColumn. It is NoColumn when
+	// the tool only reported a line. Position and Span compare them
 	Line, Column int
 	Message      string
+	Severity     Severity
+	// SeverityInferred is set when Severity was guessed from the
+	// message, see InferSeverity
+	SeverityInferred bool
+	// WarningAsError is set when the tool reported a warning as an
+	// error, like dotnet build -warnaserror does with CS0168 and gcc
+	// -Werror with [-Werror=unused-variable]
+	WarningAsError bool
+	// Occurrences is the number of times the diagnostic was in the log,
+	// see KeepDuplicates. The JSON parsers leave it zero
+	Occurrences int
+	// EndLine and EndColumn are the end of the range the tool reported,
+	// they are zero when the tool only reported a position. EndColumn is
+	// exclusive, like the End of a SourceSpan; the ranges of tools with
+	// inclusive ends, like the finish of gcc's JSON, are converted.
+	EndLine, EndColumn int
+	// Code is the tool specific diagnostic code, like E0308, C2065,
+	// TS2304, an eslint rule id or the warning option of gcc and clang,
+	// it is empty if none was found
+	Code string
+	// Flags are the options gcc and clang list at the end of their
+	// warnings, like -Werror and -Wunused-variable
+	Flags []string
+	// Project is the project file msbuild appends to diagnostics
+	Project string
+	// Function is the function a stack frame belongs to
+	Function string
+	Kind     Kind
+	// Tool is the name of the format or parser that produced the
+	// SourceError
+	Tool string
+	// Dir is the directory the tool ran in if the log says so, like
+	// the "Entering directory" lines of make, see ResolvePaths
+	Dir string
+	// Target is the build target that failed if the log says so, like
+	// the rule label bazel and buck print or the outputs of ninja
+	Target string
+	// Package is the Go package of the "# package" header go build and
+	// go vet print before its diagnostics
+	Package string
+	// Origin is the name of the input the error was parsed from, see
+	// ParseAll
+	Origin string
+	// Snippet are the lines printed under the diagnostic, see Snippets
+	Snippet []string
+	// Related are the other locations the diagnostic refers to, see
+	// FoldNotes
+	Related []SourceError
+	// Fixes are the replacements the tool suggests, see FixIts and
+	// ApplyFixes
+	Fixes []Fix
+	// SourceLine is the line of File the error is on and ContextLines
+	// the lines around it starting at ContextStart, see Annotate
+	SourceLine   string
+	ContextLines []string
+	ContextStart int
+	// Offset is the byte offset of Line and Column in File, it is zero
+	// when it isn't known. Errors of tools that only report an offset
+	// have no Line, see FillLineColumn
+	Offset int
+	// GeneratedFile and GeneratedLine are the position the tool reported
+	// in generated code when File and Line were mapped to the original
+	// source, see MapThroughLineDirectives
+	GeneratedFile string
+	GeneratedLine int
+	// Raw are the lines of the log the SourceError was parsed from,
+	// LogLine and LogOffset the number, starting at 1, and the byte
+	// offset of the first one
+	Raw       string
+	LogLine   int
+	LogOffset int64
+	// Spans are where the file, position and message are in the line of
+	// Raw that has the location
+	Spans Spans
+	// LineTruncated is set when the line the error was parsed from was
+	// longer than MaxLineLength and only its start was parsed
+	LineTruncated bool
+	// Suppressed is the number of errors of File CapPerFile dropped
+	// after this one
+	Suppressed int
+	// Confidence is how likely it is that the lines the error was
+	// parsed from are a diagnostic, from 0 to 1, see MinConfidence. It
+	// is 1 for the multi-line parsers, the JSON parsers leave it zero
+	Confidence float64
+}
+
+// severityPrefixes are the message prefixes used by gcc, clang, rustc
+// and go vet style tools, longest first so "fatal error" wins over "error".
+var severityPrefixes = []struct {
+	prefix   string
+	severity Severity
+}{
+	{"fatal error", SeverityError},
+	{"error", SeverityError},
+	{"fatal", SeverityError},
+	{"warning", SeverityWarning},
+	{"warn", SeverityWarning},
+	{"remark", SeverityNote},
+	{"note", SeverityNote},
+	{"info", SeverityNote},
+	{"help", SeverityNote},
+}
+
+// parseSeverity strips a recognized severity prefix from msg. Prefixes
+// are matched case-insensitively with or without a trailing colon but
+// must be followed by whitespace or the end of the message.
+func parseSeverity(msg string) (Severity, string) {
+	severity, _, rest := parseMessage(msg)
+	if severity == SeverityUnknown {
+		return severity, msg
+	}
+	return severity, rest
+}
+
+var (
+	// severityCode matches the codes tools put after the severity,
+	//
+	//	error[E0308]: mismatched types
+	//	error CS1002: ; expected
+	severityCode = regexp.MustCompile(`^(?:\[(?P<code>[A-Za-z]+[0-9]+)\]:?|:? (?P<code>[A-Z]+[0-9]+):)(?: |$)`)
+	// leadingCode matches flake8 style codes that lead the message,
+	//
+	//	E501 line too long (82 > 79 characters)
+	leadingCode = regexp.MustCompile(`^([A-Z]{1,3}[0-9]{3,4}) `)
+	// trailingCode matches a rule id at the end of the message, to tell
+	// ids from words like [recovered] they need a digit, dash or slash
+	// or to be one of the linters,
+	//
+	//	Double quote to prevent globbing. [SC2086]
+	trailingCode = regexp.MustCompile(` \[([A-Za-z][A-Za-z0-9_./@-]*[A-Za-z0-9])\]$`)
+)
+
+// parseMessage strips the severity and code from msg.
+func parseMessage(msg string) (Severity, string, string) {
+	for _, p := range severityPrefixes {
+		if len(msg) < len(p.prefix) || !strings.EqualFold(msg[:len(p.prefix)], p.prefix) {
+			continue
+		}
+		rest := msg[len(p.prefix):]
+		if m := severityCode.FindStringSubmatch(rest); m != nil {
+			code := m[1] + m[2]
+			return p.severity, code, strings.TrimLeft(rest[len(m[0]):], " \t")
+		}
+		if strings.HasPrefix(rest, ":") {
+			rest = rest[1:]
+		} else if word := msg[:len(p.prefix)]; word != strings.ToLower(word) && word != strings.ToUpper(word) {
+			// without a colon a capitalized severity, like the one
+			// of "Error return value of f is not checked", is
+			// usually the first word of a sentence.
+			continue
+		}
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		code, rest := trimTrailingCode(rest)
+		return p.severity, code, rest
+	}
+	if m := leadingCode.FindStringSubmatch(msg); m != nil {
+		return SeverityUnknown, m[1], msg[len(m[0]):]
+	}
+	code, msg := trimTrailingCode(msg)
+	return SeverityUnknown, code, msg
+}
+
+// trimTrailingCode strips a bracketed rule id from the end of msg.
+func trimTrailingCode(msg string) (string, string) {
+	if m := trailingCode.FindStringSubmatchIndex(msg); m != nil && (strings.ContainsAny(msg[m[2]:m[3]], "0123456789-/") || linters[msg[m[2]:m[3]]]) {
+		return msg[m[2]:m[3]], msg[:m[0]]
+	}
+	return "", msg
 }
 
 // Point is a coordinate in text with a length and direction
@@ -29,9 +247,12 @@ type Point struct {
 	Dir    Direction
 }
 
+// NoColumn is the Column of a SourceError whose tool didn't report one
+const NoColumn = -1
+
 // ScanSourceError takes the log of a process and
 // returns it's sourcecode errors
-func ScanSourceError(message string) []SourceError {
+func ScanSourceError(message string, opts ...Option) []SourceError {
 	/*
 	   Parse a message that is almost the standard in error messages that are outputed by
 	   most modern compilers and tools that work with source code
@@ -45,27 +266,81 @@ func ScanSourceError(message string) []SourceError {
 	   xxx.yyy:01: some message
 	   {filename}.{fileext}:{line}: {message}
 
+	   in which case Column is set to NoColumn.
 	*/
-	var validMessage = regexp.MustCompile(`([[:alnum:]]+.[[:alnum:]]+):([0-9]+):([0-9]+)?:? (.*)`)
-	var errors []SourceError
-	messages := validMessage.FindAllStringSubmatch(message, -1)
-	for _, message := range messages {
-		e := SourceError{
-			File: message[1],
-		}
-		if line, err := strconv.Atoi(message[2]); err == nil {
-			e.Line = line
+	errors, _ := ParseReader(strings.NewReader(message), opts...)
+	return errors
+}
+
+// fromSubmatch builds a SourceError from the named captures of re.
+func fromSubmatch(re *regexp.Regexp, m []string) SourceError {
+	// a name may be used by several alternatives of the expression,
+	// the first one that matched wins.
+	names := re.SubexpNames()
+	group := func(name string) string {
+		for i, n := range names {
+			if n == name && m[i] != "" {
+				return m[i]
+			}
 		}
-		if column, err := strconv.Atoi(message[3]); err == nil {
-			e.Column = column
-		} else {
-			e.Column = -1
-			e.Message = message[3]
+		return ""
+	}
+	e := newSourceError(group("file"), group("line"), group("col"), group("message"))
+	if severity := group("severity"); severity != "" {
+		e.Severity, _ = parseSeverity(severity)
+	}
+	if code := group("code"); code != "" {
+		e.Code = code
+	}
+	e.Project = group("project")
+	if offset := group("offset"); offset != "" {
+		e.Offset, _ = strconv.Atoi(offset)
+	}
+	endline, endcol := group("endline"), group("endcol")
+	if group("rangeline") != "" {
+		endline, endcol = group("rangeline"), group("rangecol")
+	}
+	if endcol != "" {
+		e.EndLine = e.Line
+		if endline != "" {
+			e.EndLine, _ = strconv.Atoi(endline)
 		}
-		if len(message) > 3 {
-			e.Message = message[4]
+		e.EndColumn, _ = strconv.Atoi(endcol)
+	}
+	return e
+}
+
+// IsVirtual reports whether File is a name for input that doesn't come
+// from a file, like <stdin>, - or gcc's <built-in>, and can't be opened.
+func (e SourceError) IsVirtual() bool {
+	return e.File == "-" || strings.HasPrefix(e.File, "<") && strings.HasSuffix(e.File, ">")
+}
+
+// newSourceError builds a SourceError from the captured fields of a
+// diagnostic. column is empty for the two field form.
+func newSourceError(file, line, column, message string) SourceError {
+	e := SourceError{
+		File:   file,
+		Column: NoColumn,
+	}
+	e.Line, _ = strconv.Atoi(line)
+	if column != "" {
+		e.Column, _ = strconv.Atoi(column)
+	}
+	e.Severity, e.Code, e.Message = parseMessage(message)
+	if e.Severity == SeverityUnknown {
+		for _, prefix := range notePrefixes {
+			if strings.HasPrefix(e.Message, prefix) {
+				e.Severity = SeverityNote
+			}
 		}
-		errors = append(errors, e)
 	}
-	return errors
+	return e
+}
+
+// notePrefixes are the messages of notes from tools that don't print a
+// severity.
+var notePrefixes = []string{
+	// go vet and the go compiler
+	"other declaration of ",
 }
@@ -0,0 +1,125 @@
+package oututil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InputErrors are the errors reading the inputs of ParseAll by their
+// names.
+type InputErrors map[string]error
+
+func (e InputErrors) names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e InputErrors) Error() string {
+	var b strings.Builder
+	for i, name := range e.names() {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", name, e[name])
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors in the order of their names.
+func (e InputErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, name := range e.names() {
+		errs = append(errs, e[name])
+	}
+	return errs
+}
+
+// ParseAll parses inputs concurrently and returns their source errors
+// with their Origin set to the name of their input, sorted by origin
+// and then like Sort does. Errors reading an input don't discard the
+// source errors of the others, they are returned as InputErrors. Inputs
+// stop being read when ctx is done: ParseAll returns without waiting
+// for the ones blocked in a read, with the error of ctx as theirs, and
+// closes them if they are io.Closers.
+func ParseAll(ctx context.Context, inputs map[string]io.Reader, opts ...Option) ([]SourceError, error) {
+	var (
+		mu     sync.Mutex
+		all    []SourceError
+		failed = make(InputErrors)
+		// pending are the inputs still being read, nil once ParseAll
+		// returned.
+		pending = make(map[string]io.Reader, len(inputs))
+		done    = make(chan struct{})
+	)
+	for name, r := range inputs {
+		pending[name] = r
+	}
+	for name, r := range inputs {
+		go func(name string, r io.Reader) {
+			errs, err := ParseReader(ctxReader{ctx, r}, opts...)
+			for i := range errs {
+				errs[i].Origin = name
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if pending == nil {
+				return
+			}
+			all = append(all, errs...)
+			if err != nil {
+				failed[name] = err
+			}
+			delete(pending, name)
+			if len(pending) == 0 {
+				close(done)
+			}
+		}(name, r)
+	}
+	if len(inputs) > 0 {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	mu.Lock()
+	for name, r := range pending {
+		failed[name] = ctx.Err()
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	pending = nil
+	mu.Unlock()
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Origin != all[j].Origin {
+			return all[i].Origin < all[j].Origin
+		}
+		return less(all[i], all[j])
+	})
+	if len(failed) > 0 {
+		return all, failed
+	}
+	return all, nil
+}
+
+// ctxReader is a reader that fails with the error of its context once
+// it is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
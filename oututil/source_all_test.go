@@ -0,0 +1,91 @@
+package oututil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingReader returns its data and then err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestParseAll(t *testing.T) {
+	errDisk := errors.New("disk on fire")
+	errs, err := ParseAll(context.Background(), map[string]io.Reader{
+		"stdout":  strings.NewReader("b.c:9:1: error: b\na.c:3:1: error: a\n"),
+		"stderr":  strings.NewReader("a.c:1:1: warning: w\n"),
+		"shard-1": &failingReader{"z.c:1:1: error: z\n", errDisk},
+	})
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Origin+" "+e.File)
+	}
+	want := []string{"shard-1 z.c", "stderr a.c", "stdout a.c", "stdout b.c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Logf("got %q, expected %q", got, want)
+		t.Fail()
+	}
+	var inputs InputErrors
+	if !errors.As(err, &inputs) || len(inputs) != 1 || inputs["shard-1"] != errDisk {
+		t.Logf("got %v, expected the error of shard-1", err)
+		t.Fail()
+	}
+	if err != nil && err.Error() != "shard-1: disk on fire" {
+		t.Logf("got %q", err)
+		t.Fail()
+	}
+}
+
+func TestParseAllCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs, err := ParseAll(ctx, map[string]io.Reader{
+		"a": strings.NewReader("a.c:1:1: error: a\n"),
+		"b": strings.NewReader("b.c:1:1: error: b\n"),
+	})
+	inputs, ok := err.(InputErrors)
+	if len(errs) != 0 || !ok || inputs["a"] != context.Canceled || inputs["b"] != context.Canceled {
+		t.Logf("got %v, %v; expected both inputs to be canceled", errs, err)
+		t.Fail()
+	}
+}
+
+func TestParseAllBlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		// the pipe is never written to
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	errs, err := ParseAll(ctx, map[string]io.Reader{
+		"a":    strings.NewReader("a.c:1:1: error: a\n"),
+		"pipe": pr,
+	})
+	inputs, ok := err.(InputErrors)
+	if len(errs) != 1 || errs[0].Origin != "a" || !ok || len(inputs) != 1 || inputs["pipe"] != context.Canceled {
+		t.Logf("got %v, %v; expected the pipe to be canceled", errs, err)
+		t.Fail()
+	}
+	if _, err := pw.Write([]byte("x")); err != io.ErrClosedPipe {
+		t.Logf("got %v, expected the pipe to be closed", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,140 @@
+package oututil
+
+import (
+	"bytes"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// maxContextLine is the longest source line Annotate keeps, longer
+// lines, usually minified code, are truncated.
+const maxContextLine = 4 << 10
+
+// sourceFile is a file read by Annotate and the offsets its lines
+// start at.
+type sourceFile struct {
+	data  []byte
+	lines []int
+}
+
+func newSourceFile(data []byte) *sourceFile {
+	f := &sourceFile{data: data, lines: []int{0}}
+	for i, c := range data {
+		if c == '\n' && i+1 < len(data) {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+	return f
+}
+
+// line returns the nth line of f without its line ending.
+func (f *sourceFile) line(n int) (string, bool) {
+	if n < 1 || n > len(f.lines) {
+		return "", false
+	}
+	start, end := f.lines[n-1], len(f.data)
+	if n < len(f.lines) {
+		end = f.lines[n]
+	}
+	line := bytes.TrimRight(f.data[start:end], "\r\n")
+	return string(line), true
+}
+
+// position returns the line and column of the byte at offset.
+func (f *sourceFile) position(offset int) (int, int, bool) {
+	if offset < 0 || offset > len(f.data) {
+		return 0, 0, false
+	}
+	n := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+	return n, offset - f.lines[n-1] + 1, true
+}
+
+// offset returns the offset of the byte at line and column, the start
+// of the line if column is NoColumn.
+func (f *sourceFile) offset(line, column int) (int, bool) {
+	if line < 1 || line > len(f.lines) {
+		return 0, false
+	}
+	if column < 1 {
+		column = 1
+	}
+	offset := f.lines[line-1] + column - 1
+	if offset > len(f.data) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// Annotate returns a copy of errs with the line every error is on and
+// context lines above and below it read from fsys. Files are read once,
+// errors in files that can't be read or on lines past the end of their
+// file, which happens with stale logs, are left as they are. File must
+// be relative to fsys, see ResolvePaths.
+func Annotate(errs []SourceError, fsys fs.FS, context int) []SourceError {
+	files := make(map[string]*sourceFile)
+	annotated := make([]SourceError, len(errs))
+	for i, e := range errs {
+		annotated[i] = annotate(e, fsys, context, files)
+	}
+	return annotated
+}
+
+func annotate(e SourceError, fsys fs.FS, context int, files map[string]*sourceFile) SourceError {
+	if len(e.Related) > 0 {
+		related := make([]SourceError, len(e.Related))
+		for i, r := range e.Related {
+			related[i] = annotate(r, fsys, context, files)
+		}
+		e.Related = related
+	}
+	if e.IsVirtual() {
+		return e
+	}
+	f := openSourceFile(fsys, strings.TrimPrefix(slashPath(e.File), "./"), files)
+	if f == nil {
+		return e
+	}
+	line, ok := f.line(e.Line)
+	if !ok {
+		return e
+	}
+	e.Offset = f.lines[e.Line-1]
+	if e.Column > 1 {
+		col := e.Column - 1
+		if col > len(line) {
+			col = len(line)
+		}
+		e.Offset += col
+	}
+	e.SourceLine = truncate(line, maxContextLine)
+	e.ContextStart = e.Line - context
+	if e.ContextStart < 1 {
+		e.ContextStart = 1
+	}
+	e.ContextLines = nil
+	for n := e.ContextStart; n <= e.Line+context; n++ {
+		line, ok := f.line(n)
+		if !ok {
+			break
+		}
+		e.ContextLines = append(e.ContextLines, truncate(line, maxContextLine))
+	}
+	return e
+}
+
+// openSourceFile reads name from fsys the first time it's asked for, it
+// returns nil if the file can't be read.
+func openSourceFile(fsys fs.FS, name string, files map[string]*sourceFile) *sourceFile {
+	if f, ok := files[name]; ok {
+		return f
+	}
+	var f *sourceFile
+	if fs.ValidPath(name) {
+		if data, err := fs.ReadFile(fsys, name); err == nil {
+			f = newSourceFile(data)
+		}
+	}
+	files[name] = f
+	return f
+}
@@ -0,0 +1,51 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+func TestAnnotate(t *testing.T) {
+	long := strings.Repeat("x", 2*maxContextLine)
+	fsys := fstest.MapFS{
+		"main.go": {Data: []byte("package main\n\nfunc main() {\n\tx := 1\n}\n")},
+		"crlf.c":  {Data: []byte("int a;\r\nint b\r\nint c;\r\n")},
+		"min.js":  {Data: []byte(long)},
+	}
+	errs := Annotate([]SourceError{
+		{File: "main.go", Line: 4, Column: 2, Message: "x declared and not used"},
+		{File: "./crlf.c", Line: 2, Column: NoColumn, Message: "expected ';'"},
+		{File: "main.go", Line: 40, Column: 1, Message: "stale"},
+		{File: "missing.go", Line: 1, Column: 1, Message: "missing"},
+		{File: "/abs/main.go", Line: 1, Column: 1, Message: "not in fsys"},
+		{File: "min.js", Line: 1, Column: 99999, Message: "long"},
+	}, fsys, 1)
+	want := []struct {
+		line    string
+		context []string
+		start   int
+		offset  int
+	}{
+		{"\tx := 1", []string{"func main() {", "\tx := 1", "}"}, 3, 29},
+		{"int b", []string{"int a;", "int b", "int c;"}, 1, 8},
+		{},
+		{},
+		{},
+		{truncate(long, maxContextLine), []string{truncate(long, maxContextLine)}, 1, len(long)},
+	}
+	for i, e := range errs {
+		got := struct {
+			line    string
+			context []string
+			start   int
+			offset  int
+		}{e.SourceLine, e.ContextLines, e.ContextStart, e.Offset}
+		if diff, equal := messagediff.PrettyDiff(want[i], got); !equal {
+			t.Logf("%s: %s", e.Message, diff)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package oututil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Role is the part a line plays in the blocks of a BlockFormat.
+type Role int
+
+const (
+	// RoleHeader lines start a block. They capture the severity, code
+	// and message of its diagnostic, and its position if they have one.
+	RoleHeader Role = iota
+	// RoleLocation lines capture the position of the diagnostic of a
+	// block whose header doesn't have one. The first one after the
+	// header does, the ones after it are secondary spans.
+	RoleLocation
+	// RoleContext lines, like source excerpts and carets, belong to the
+	// block without capturing anything.
+	RoleContext
+	// RoleNote lines capture the message of a note of the diagnostic,
+	// see FoldNotes.
+	RoleNote
+	// RoleTerminator lines end the block.
+	RoleTerminator
+)
+
+// BlockLine is a line of a BlockFormat, a regular expression naming its
+// captures like the one of a Format.
+type BlockLine struct {
+	Role    Role
+	Pattern string
+}
+
+// BlockFormat is a diagnostic format spanning several lines, described
+// by the roles of the lines of its blocks. A block starts at a header
+// and ends at a terminator, a blank line once it is located, a line
+// that isn't one of its lines or the next header. Its diagnostic is
+// emitted as soon as it has a position; a block that ends before it is
+// located is emitted without a file, with the confidence of a match
+// without one. Header and location lines are consumed, and so are the
+// lines of a block that isn't located; the others are left to the
+// options that read the lines following a diagnostic, like Snippets and
+// CaretColumns.
+type BlockFormat struct {
+	name  string
+	lines []blockLine
+}
+
+type blockLine struct {
+	role Role
+	re   *regexp.Regexp
+}
+
+// NewBlockFormat compiles lines into a BlockFormat. Lines are tried in
+// order, the first one that matches gives a line of the log its role.
+// A BlockFormat needs a header, and its locations need to capture a
+// file.
+func NewBlockFormat(lines ...BlockLine) (BlockFormat, error) {
+	var f BlockFormat
+	headers := 0
+	for _, l := range lines {
+		re, err := regexp.Compile(l.Pattern)
+		if err != nil {
+			return BlockFormat{}, err
+		}
+		switch l.Role {
+		case RoleHeader:
+			headers++
+		case RoleLocation:
+			if re.SubexpIndex("file") < 0 {
+				return BlockFormat{}, fmt.Errorf("location %q doesn't capture a file", l.Pattern)
+			}
+		case RoleContext, RoleNote, RoleTerminator:
+		default:
+			return BlockFormat{}, fmt.Errorf("line %q has an unknown role %d", l.Pattern, l.Role)
+		}
+		f.lines = append(f.lines, blockLine{l.Role, re})
+	}
+	if headers == 0 {
+		return BlockFormat{}, errors.New("block format doesn't have a header")
+	}
+	return f, nil
+}
+
+// MustBlockFormat is like NewBlockFormat but panics if lines are
+// invalid.
+func MustBlockFormat(lines ...BlockLine) BlockFormat {
+	f, err := NewBlockFormat(lines...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Name returns the name the BlockFormat was registered with.
+func (f BlockFormat) Name() string { return f.name }
+
+// match returns the first line of f matching line and its submatch
+// indexes.
+func (f BlockFormat) match(line string) (blockLine, []int, bool) {
+	for _, l := range f.lines {
+		if idx := l.re.FindStringSubmatchIndex(line); idx != nil {
+			return l, idx, true
+		}
+	}
+	return blockLine{}, nil, false
+}
+
+var (
+	// blockFormats are ordered like formats, userBlockFormats is the
+	// number of them registered by users.
+	blockFormats     []BlockFormat
+	userBlockFormats int
+)
+
+// RegisterBlockFormat registers f under name. Block formats are tried
+// before the single line formats, the ones registered by users in
+// registration order before the builtin ones; registering a name again
+// replaces the BlockFormat in place.
+func RegisterBlockFormat(name string, f BlockFormat) {
+	f.name = name
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i := range blockFormats {
+		if blockFormats[i].name == name {
+			blockFormats[i] = f
+			return
+		}
+	}
+	blockFormats = append(blockFormats[:userBlockFormats], append([]BlockFormat{f}, blockFormats[userBlockFormats:]...)...)
+	userBlockFormats++
+}
+
+// RegisteredBlockFormats returns the registered block formats in the
+// order they are tried.
+func RegisteredBlockFormats() []BlockFormat {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]BlockFormat(nil), blockFormats...)
+}
+
+// registerBuiltinBlock appends the BlockFormat of lines to the builtin
+// ones.
+func registerBuiltinBlock(name string, lines ...BlockLine) {
+	f := MustBlockFormat(lines...)
+	f.name = name
+	blockFormats = append(blockFormats, f)
+}
+
+// blockScanner is the state machine assembling the blocks of a
+// BlockFormat.
+type blockScanner struct {
+	format BlockFormat
+	// notes is set when notes are emitted to be folded, see FoldNotes.
+	notes bool
+	// block is the diagnostic of the block being read, located is set
+	// once it has a position and was emitted.
+	block   *SourceError
+	located bool
+	// ended emits the diagnostic of a block the line being parsed ended
+	// before it was located, see sourceScanner.emitEnded.
+	ended func(SourceError)
+}
+
+func (p *blockScanner) parse(line string, emit func(SourceError)) bool {
+	l, idx, ok := p.format.match(line)
+	if !ok {
+		if strings.TrimSpace(line) == "" {
+			// the blank lines of a block that isn't located yet
+			return p.block != nil && !p.located
+		}
+		p.end()
+		return false
+	}
+	switch l.role {
+	case RoleHeader:
+		p.end()
+		e := fromSubmatch(l.re, submatches(line, idx))
+		e.Tool = p.format.name
+		p.block, p.located = &e, false
+		if e.File != "" {
+			e.Spans = spansOf(l.re, line, idx, e.Message)
+			p.locate(e, emit)
+		}
+		return true
+	case RoleLocation:
+		if p.block != nil && !p.located {
+			loc := fromSubmatch(l.re, submatches(line, idx))
+			e := *p.block
+			e.File, e.Line, e.Column = loc.File, loc.Line, loc.Column
+			e.EndLine, e.EndColumn, e.Offset = loc.EndLine, loc.EndColumn, loc.Offset
+			// the message is on the header line
+			e.Spans = spansOf(l.re, line, idx, "")
+			p.locate(e, emit)
+		}
+		return true
+	case RoleNote:
+		if p.block != nil && !p.located {
+			return true
+		}
+		if p.block != nil && p.notes {
+			m := fromSubmatch(l.re, submatches(line, idx))
+			note := *p.block
+			note.Severity, note.Message = SeverityNote, m.Message
+			note.Code, note.Raw, note.Spans = "", "", spansOf(l.re, line, idx, m.Message)
+			emit(note)
+		}
+	case RoleContext:
+		return p.block != nil && !p.located
+	case RoleTerminator:
+		consumed := p.block != nil && !p.located
+		p.end()
+		return consumed
+	}
+	return false
+}
+
+// locate emits e, the located diagnostic of the block.
+func (p *blockScanner) locate(e SourceError, emit func(SourceError)) {
+	p.block, p.located = &e, true
+	emit(e)
+}
+
+// end ends the block being read, emitting its diagnostic with ended if
+// it wasn't located.
+func (p *blockScanner) end() {
+	p.flush(p.ended)
+}
+
+// flush emits the diagnostic of a block that ended before it was
+// located, with the confidence of a match without a file.
+func (p *blockScanner) flush(emit func(SourceError)) {
+	if p.block != nil && !p.located {
+		e := *p.block
+		e.Confidence = confidenceMatch
+		if e.Severity != SeverityUnknown {
+			e.Confidence += confidenceSeverity
+		}
+		emit(e)
+	}
+	p.block, p.located = nil, false
+}
@@ -0,0 +1,120 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlockFormat(t *testing.T) {
+	restoreFormats(t)
+	RegisterBlockFormat("test-lint", MustBlockFormat(
+		BlockLine{RoleHeader, `^LINT (?P<severity>error|warning) (?P<code>L[0-9]+): (?P<message>.*)$`},
+		BlockLine{RoleLocation, `^  at (?P<file>\S+):(?P<line>[0-9]+)$`},
+		BlockLine{RoleNote, `^  hint: (?P<message>.*)$`},
+		BlockLine{RoleContext, `^  \| `},
+		BlockLine{RoleTerminator, `^END$`},
+	))
+	if registered := RegisteredBlockFormats(); registered[0].Name() != "test-lint" {
+		t.Logf("was expecting user block formats to be tried first got %q", registered[0].Name())
+		t.Fail()
+	}
+	tests := []struct {
+		name   string
+		log    string
+		opts   []Option
+		errors []SourceError
+		notes  int
+	}{
+		{
+			name: "block",
+			log:  "LINT error L12: bad key\n  | key = 1\n  at conf/app.ini:3\n  | key = 1\n  hint: rename it\nEND\nLINT warning L3: unused\n  at conf/app.ini:9\n",
+			opts: []Option{FoldNotes()},
+			errors: []SourceError{
+				{File: "conf/app.ini", Line: 3, Column: NoColumn, Message: "bad key", Severity: SeverityError, Code: "L12"},
+				{File: "conf/app.ini", Line: 9, Column: NoColumn, Message: "unused", Severity: SeverityWarning, Code: "L3"},
+			},
+			notes: 1,
+		},
+		{
+			name: "unlocated",
+			log:  "LINT error L12: bad key\nsomething else\n  at conf/app.ini:3\n",
+			errors: []SourceError{
+				{Column: NoColumn, Message: "bad key", Severity: SeverityError, Code: "L12"},
+			},
+		},
+		{
+			name: "header after header",
+			log:  "LINT error L12: bad key\n  | key = 1\nLINT warning L3: unused\n  at conf/app.ini:9\n",
+			errors: []SourceError{
+				{Column: NoColumn, Message: "bad key", Severity: SeverityError, Code: "L12"},
+				{File: "conf/app.ini", Line: 9, Column: NoColumn, Message: "unused", Severity: SeverityWarning, Code: "L3"},
+			},
+		},
+		{
+			name: "eof",
+			log:  "LINT error L12: bad key\n  | key = 1\n",
+			errors: []SourceError{
+				{Column: NoColumn, Message: "bad key", Severity: SeverityError, Code: "L12"},
+			},
+		},
+		{
+			name: "rustc summary",
+			log:  "error: aborting due to previous error\n",
+		},
+		{
+			name: "javac",
+			log:  javacLog,
+			opts: []Option{FoldNotes(), CaretColumns()},
+			errors: []SourceError{
+				{File: "src/Main.java", Line: 10, Column: 9, Message: "cannot find symbol", Severity: SeverityError},
+				{File: "src/Main.java", Line: 12, Column: 11, Message: "[deprecation] Date(String) in Date has been deprecated", Severity: SeverityWarning},
+			},
+			notes: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := ScanSourceError(test.log, test.opts...)
+			checkSourceErrors(t, test.errors, errs)
+			if len(errs) > 0 && len(errs[0].Related) != test.notes {
+				t.Logf("was expecting %d notes got %+v", test.notes, errs[0].Related)
+				t.Fail()
+			}
+		})
+	}
+
+	for _, log := range []string{"LINT error L12: bad key\n", "LINT error L12: bad key\n\nLINT warning L3: unused\n  at conf/app.ini:9\n"} {
+		errs := ScanSourceError(log)
+		if len(errs) == 0 || errs[0].Confidence != confidenceMatch+confidenceSeverity || errs[0].Raw != "LINT error L12: bad key" || errs[0].Tool != "test-lint" || errs[0].LogLine != 1 {
+			t.Logf("was expecting a best-effort error got %+v", errs)
+			t.Fail()
+		}
+	}
+}
+
+func TestBlockFormatUnlocated(t *testing.T) {
+	log := "error: linker `cc` not found\n  = note: No such file or directory (os error 2)\n\nerror: aborting due to previous error\n"
+	errs, err := ParseReader(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{{Column: NoColumn, Message: "linker `cc` not found", Severity: SeverityError}}, errs)
+	if len(errs) == 1 && errs[0].Raw != "error: linker `cc` not found\n  = note: No such file or directory (os error 2)" {
+		t.Logf("was expecting the lines of the block got %q", errs[0].Raw)
+		t.Fail()
+	}
+}
+
+func TestNewBlockFormat(t *testing.T) {
+	for _, lines := range [][]BlockLine{
+		{{RoleLocation, `^at (?P<file>\S+)$`}},
+		{{RoleHeader, `^error: (?P<message>.*)$`}, {RoleLocation, `^at (\S+)$`}},
+		{{RoleHeader, `^error: (?P<message>.*$`}},
+		{{RoleHeader, `^error$`}, {Role(9), `^x$`}},
+	} {
+		if _, err := NewBlockFormat(lines...); err == nil {
+			t.Logf("was expecting %v to be invalid", lines)
+			t.Fail()
+		}
+	}
+}
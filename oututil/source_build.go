@@ -0,0 +1,59 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// ninjaProgress matches the progress ninja prints before the
+	// command it runs,
+	//
+	//	[123/456] Building CXX object src/CMakeFiles/app.dir/main.cc.o
+	ninjaProgress = regexp.MustCompile(`^\[[0-9]+/[0-9]+\] `)
+	// targetFailed matches the lines ninja and buck print before the
+	// output of a command that failed,
+	//
+	//	FAILED: src/CMakeFiles/app.dir/main.cc.o
+	//	Action failed: //app:main (cxx_compile main.cpp)
+	//	BUILD FAILED: //app:main failed with exit code 1:
+	targetFailed = regexp.MustCompile(`^(?:FAILED|Action failed|BUILD FAILED): (\S+?):?(?: |$)`)
+	// bazelStatus matches the lines bazel prints about the actions it
+	// runs,
+	//
+	//	ERROR: /src/app/BUILD:14:1: C++ compilation of rule '//app:main' failed (Exit 1)
+	bazelStatus = regexp.MustCompile(`^(?:ERROR|WARNING|INFO|DEBUG): `)
+	bazelRule   = regexp.MustCompile(`\brule '(@?[^' ]*//[^' ]*)'`)
+)
+
+// buildStep keeps track of the target the lines of bazel, buck and ninja
+// logs belong to. It returns line without ninja's progress and whether
+// it was a line about a failed target that can't be a diagnostic.
+func (s *sourceScanner) buildStep(line string) (string, bool) {
+	if line == "" || !strings.ContainsRune("[EWIDFAB", rune(line[0])) {
+		// most lines, checked first since it's cheaper than the
+		// expressions
+		return line, false
+	}
+	switch {
+	case ninjaProgress.MatchString(line):
+		s.target = ""
+		return ninjaProgress.ReplaceAllLiteralString(line, ""), false
+	case bazelStatus.MatchString(line):
+		s.target = ""
+		if m := bazelRule.FindStringSubmatch(line); m != nil {
+			s.target = m[1]
+		}
+		return line, false
+	}
+	m := targetFailed.FindStringSubmatch(line)
+	if m == nil {
+		return line, false
+	}
+	s.target = m[1]
+	if strings.HasPrefix(line, "FAILED: Build did NOT complete") {
+		// the end of a bazel build
+		s.target = ""
+	}
+	return line, true
+}
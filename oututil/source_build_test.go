@@ -0,0 +1,73 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildTargets(t *testing.T) {
+	tests := []struct {
+		name, log string
+		expected  []SourceError
+		targets   []string
+	}{
+		{
+			name: "bazel",
+			log: `INFO: Analyzed target //app:main (0 packages loaded, 0 targets configured).
+INFO: Found 1 target...
+ERROR: /src/app/BUILD:14:1: C++ compilation of rule '//app:main' failed (Exit 1): gcc failed: error executing command /usr/bin/gcc -c app/main.cc
+app/main.cc:3:5: error: 'foo' was not declared in this scope
+    3 |     foo();
+      |     ^~~
+Target //app:main failed to build
+INFO: From Compiling lib/util.cc:
+lib/util.cc:7:9: warning: unused variable 'x' [-Wunused-variable]
+FAILED: Build did NOT complete successfully`,
+			expected: []SourceError{
+				{File: "/src/app/BUILD", Line: 14, Column: 1, Severity: SeverityError, Message: "C++ compilation of rule '//app:main' failed (Exit 1): gcc failed: error executing command /usr/bin/gcc -c app/main.cc"},
+				{File: "app/main.cc", Line: 3, Column: 5, Severity: SeverityError, Message: "'foo' was not declared in this scope"},
+				{File: "lib/util.cc", Line: 7, Column: 9, Severity: SeverityWarning, Code: "-Wunused-variable", Message: "unused variable 'x'"},
+			},
+			targets: []string{"//app:main", "//app:main", ""},
+		},
+		{
+			name: "ninja",
+			log: `[1/3] Building CXX object src/CMakeFiles/app.dir/util.cc.o
+[2/3] Building CXX object src/CMakeFiles/app.dir/main.cc.o
+FAILED: src/CMakeFiles/app.dir/main.cc.o
+/usr/bin/c++ -o src/CMakeFiles/app.dir/main.cc.o -c ../src/main.cc
+../src/main.cc:10:3: error: expected ';' after expression
+ninja: build stopped: subcommand failed.`,
+			expected: []SourceError{
+				{File: "../src/main.cc", Line: 10, Column: 3, Severity: SeverityError, Message: "expected ';' after expression"},
+			},
+			targets: []string{"src/CMakeFiles/app.dir/main.cc.o"},
+		},
+		{
+			name: "buck",
+			log: `Action failed: root//app:main (cxx_compile main.cpp)
+main.cpp:2:1: error: unknown type name 'itn'
+BUILD FAILED: //lib:util failed with exit code 1:
+lib/util.cpp:4:2: warning: unused parameter 'y'`,
+			expected: []SourceError{
+				{File: "main.cpp", Line: 2, Column: 1, Severity: SeverityError, Message: "unknown type name 'itn'"},
+				{File: "lib/util.cpp", Line: 4, Column: 2, Severity: SeverityWarning, Message: "unused parameter 'y'"},
+			},
+			targets: []string{"root//app:main", "//lib:util"},
+		},
+	}
+	for _, test := range tests {
+		errs, err := ParseReader(strings.NewReader(test.log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s", test.name)
+		checkSourceErrors(t, test.expected, errs)
+		for i, e := range errs {
+			if i < len(test.targets) && e.Target != test.targets[i] {
+				t.Logf("%s: %v: was expecting target %q got %q instead", test.name, e, test.targets[i], e.Target)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package oututil
+
+import "sort"
+
+type capOptions struct {
+	collapse bool
+	window   int
+}
+
+// CapOption configures CapPerFile.
+type CapOption func(*capOptions)
+
+// CollapseCascades makes CapPerFile fold the errors it drops that are at
+// most window lines after the one before them, the cascade a broken
+// declaration sets off, into the Related of the last error it keeps
+// instead of only counting them.
+func CollapseCascades(window int) CapOption {
+	return func(o *capOptions) { o.collapse, o.window = true, window }
+}
+
+// FirstPerFile returns the first error of every file, see CapPerFile.
+func FirstPerFile(errs []SourceError, opts ...CapOption) []SourceError {
+	return CapPerFile(errs, 1, opts...)
+}
+
+// CapPerFile returns errs with at most n errors per file, the ones that
+// come first by position, in the order they were in. The number of
+// errors of a file that were dropped is the Suppressed of its last kept
+// one by position, see also CollapseCascades. Errors without a file and
+// stack frames aren't capped, a n below 1 keeps every error.
+func CapPerFile(errs []SourceError, n int, opts ...CapOption) []SourceError {
+	if n < 1 {
+		return errs
+	}
+	var o capOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	files := make(map[string][]int)
+	for i, e := range errs {
+		if e.File != "" && e.Kind == KindDiagnostic {
+			files[e.File] = append(files[e.File], i)
+		}
+	}
+	dropped := make(map[int]bool)
+	capped := make(map[int]SourceError)
+	for _, group := range files {
+		if len(group) <= n {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			return errs[group[i]].Position().Before(errs[group[j]].Position())
+		})
+		last := errs[group[n-1]]
+		last.Related = append([]SourceError(nil), last.Related...)
+		line := last.Line
+		for _, i := range group[n:] {
+			dropped[i] = true
+			e := errs[i]
+			if o.collapse && e.Line-line <= o.window {
+				last.Related = append(last.Related, e)
+				line = e.Line
+				continue
+			}
+			last.Suppressed++
+		}
+		capped[group[n-1]] = last
+	}
+	kept := make([]SourceError, 0, len(errs)-len(dropped))
+	for i, e := range errs {
+		if dropped[i] {
+			continue
+		}
+		if c, ok := capped[i]; ok {
+			e = c
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,64 @@
+package oututil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// cascade is a broken header and the errors it sets off in the files
+// including it.
+const cascade = `foo.h:3:1: error: unknown type name 'foo_t'
+a.c:10:5: error: 'x' undeclared
+a.c:2:1: error: expected ';' before 'int'
+a.c:11:5: error: 'y' undeclared
+a.c:12:5: error: 'z' undeclared
+a.c:40:1: error: control reaches end of non-void function
+b.c:7:1: error: unknown type name 'foo_t'
+`
+
+func describeErrors(errs []SourceError) string {
+	var lines []string
+	for _, e := range errs {
+		line := fmt.Sprintf("%s:%d", e.File, e.Line)
+		if e.Suppressed > 0 {
+			line += fmt.Sprintf(" -%d", e.Suppressed)
+		}
+		for _, r := range e.Related {
+			line += fmt.Sprintf(" +%d", r.Line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, ", ")
+}
+
+func TestCapPerFile(t *testing.T) {
+	errs := append(ScanSourceError(cascade),
+		SourceError{File: "main.go", Line: 12, Kind: KindStackFrame},
+		SourceError{File: "main.go", Line: 30, Kind: KindStackFrame})
+	tests := []struct {
+		name string
+		errs []SourceError
+		want string
+	}{
+		{"first", FirstPerFile(errs), "foo.h:3, a.c:2 -4, b.c:7, main.go:12, main.go:30"},
+		{"cap", CapPerFile(errs, 2), "foo.h:3, a.c:10 -3, a.c:2, b.c:7, main.go:12, main.go:30"},
+		{"uncapped", CapPerFile(errs, 0), "foo.h:3, a.c:10, a.c:2, a.c:11, a.c:12, a.c:40, b.c:7, main.go:12, main.go:30"},
+		{"cascades", CapPerFile(errs, 2, CollapseCascades(1)), "foo.h:3, a.c:10 -1 +11 +12, a.c:2, b.c:7, main.go:12, main.go:30"},
+		{"first cascades", FirstPerFile(errs, CollapseCascades(8)), "foo.h:3, a.c:2 -1 +10 +11 +12, b.c:7, main.go:12, main.go:30"},
+	}
+	for _, test := range tests {
+		if got := describeErrors(test.errs); got != test.want {
+			t.Logf("%s: was expecting %s got %s", test.name, test.want, got)
+			t.Fail()
+		}
+	}
+	if len(errs[1].Related) != 0 || errs[1].Suppressed != 0 {
+		t.Logf("CapPerFile changed its input: %+v", errs[1])
+		t.Fail()
+	}
+	if s := Summarize(FirstPerFile(errs)); s.Suppressed != 4 || s.Total != 5 {
+		t.Logf("was expecting 4 suppressed errors got %+v", s)
+		t.Fail()
+	}
+}
@@ -0,0 +1,86 @@
+package oututil
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Decoder turns a line of a log that isn't valid UTF-8, like the ones
+// of tools writing in the console codepage of Windows, into text, see
+// WithDecoder. What it returns is made valid UTF-8 by replacing the
+// bytes that still aren't with U+FFFD.
+type Decoder func(line string) string
+
+var (
+	// Latin1 decodes the bytes of a line that aren't UTF-8 as ISO
+	// 8859-1, the sequences that are UTF-8 are kept. The bytes from 0x80
+	// to 0x9f are C1 controls, which are stripped from messages.
+	Latin1 Decoder = func(line string) string {
+		return decodeBytes(line, func(b byte) rune { return rune(b) })
+	}
+	// Windows1252 decodes the bytes of a line that aren't UTF-8 as the
+	// western codepage of Windows, the one MSVC writes its smart quotes
+	// and accented paths in, the sequences that are UTF-8 are kept.
+	Windows1252 Decoder = func(line string) string {
+		return decodeBytes(line, func(b byte) rune {
+			if b >= 0x80 && b < 0xa0 {
+				return windows1252[b-0x80]
+			}
+			return rune(b)
+		})
+	}
+	// ReplaceInvalid replaces every byte of a line that isn't UTF-8
+	// with U+FFFD.
+	ReplaceInvalid Decoder = func(line string) string {
+		return decodeBytes(line, func(byte) rune { return utf8.RuneError })
+	}
+)
+
+// windows1252 are the runes of the bytes from 0x80 to 0x9f in Windows
+// 1252, the undefined ones are U+FFFD.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// WithDecoder sets how the lines of a log that aren't valid UTF-8 are
+// decoded, Windows1252 by default, so the errors parsed from them are;
+// the number of lines that were is the Reencoded of a WithReport.
+func WithDecoder(d Decoder) Option {
+	return func(o *options) { o.decoder = d }
+}
+
+// decodeBytes replaces the bytes of line that aren't part of a UTF-8
+// sequence with the rune decode returns for them.
+func decodeBytes(line string, decode func(b byte) rune) string {
+	var b strings.Builder
+	b.Grow(len(line) + len(line)/2)
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(decode(line[i]))
+		} else {
+			b.WriteString(line[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// decode returns line as valid UTF-8.
+func (s *sourceScanner) decode(line string) string {
+	if utf8.ValidString(line) {
+		return line
+	}
+	if r := s.opts.report; r != nil {
+		r.Reencoded++
+	}
+	d := s.opts.decoder
+	if d == nil {
+		d = Windows1252
+	}
+	if line = d(line); !utf8.ValidString(line) {
+		line = ReplaceInvalid(line)
+	}
+	return line
+}
@@ -0,0 +1,70 @@
+package oututil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecoder(t *testing.T) {
+	// "C:\Users\José\main.cpp(3): warning C4244: “conversion”", in Windows 1252
+	const line = "C:\\Users\\Jos\xe9\\main.cpp(3): warning C4244: \x93conversion\x94"
+	tests := []struct {
+		name    string
+		opts    []Option
+		file    string
+		message string
+	}{
+		{"default", nil, `C:\Users\José\main.cpp`, "“conversion”"},
+		{"latin1", []Option{WithDecoder(Latin1)}, `C:\Users\José\main.cpp`, "conversion"},
+		{"windows1252", []Option{WithDecoder(Windows1252)}, `C:\Users\José\main.cpp`, "“conversion”"},
+		{"replace", []Option{WithDecoder(ReplaceInvalid)}, "C:\\Users\\Jos\ufffd\\main.cpp", "\ufffdconversion\ufffd"},
+		{"invalid decoder", []Option{WithDecoder(func(line string) string { return line })}, "C:\\Users\\Jos\ufffd\\main.cpp", "\ufffdconversion\ufffd"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var report ParseReport
+			errs, err := ParseReader(strings.NewReader(line+"\nmain.c:1:1: error: ok\n"), append(test.opts, WithReport(&report, 0))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errs) != 2 {
+				t.Fatalf("was expecting 2 errors got %+v", errs)
+			}
+			if e := errs[0]; e.File != test.file || e.Message != test.message || !utf8.ValidString(e.Raw) {
+				t.Logf("was expecting %q and %q got %q and %q", test.file, test.message, e.File, e.Message)
+				t.Fail()
+			}
+			if report.Reencoded != 1 {
+				t.Logf("was expecting a reencoded line got %d", report.Reencoded)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestDecoderKeepsUTF8(t *testing.T) {
+	// the accented name is UTF-8, the quotes after it are Windows 1252
+	errs := ScanSourceError("José/main.c:3:1: error: \x93x\x94 undeclared")
+	if len(errs) != 1 || errs[0].File != "José/main.c" || errs[0].Message != "“x” undeclared" {
+		t.Logf("got %+v", errs)
+		t.Fail()
+	}
+
+	invalid := ScanSourceError("Jos\xe9/main.c:3:1: error: x\xff undeclared")
+	var b bytes.Buffer
+	if err := WriteCheckstyle(&b, invalid); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil || !utf8.Valid(b.Bytes()) {
+		t.Logf("checkstyle output isn't valid: %v", err)
+		t.Fail()
+	}
+	if _, err := ToSARIF(invalid, "gcc"); err != nil || !utf8.ValidString(invalid[0].Message) || !utf8.ValidString(invalid[0].File) {
+		t.Logf("got %+v, %v", invalid, err)
+		t.Fail()
+	}
+}
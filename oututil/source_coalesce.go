@@ -0,0 +1,139 @@
+package oututil
+
+import (
+	"strings"
+	"unicode"
+)
+
+type coalesceOptions struct {
+	similarity float64
+	same       func(a, b SourceError) bool
+}
+
+// CoalesceOption configures Coalesce.
+type CoalesceOption func(*coalesceOptions)
+
+// MessageSimilarity sets how alike the messages of two errors without a
+// common code have to be to be the same problem, from 0 to 1: the
+// share of the words of both messages that are in both. It is 0.5 by
+// default.
+func MessageSimilarity(threshold float64) CoalesceOption {
+	return func(o *coalesceOptions) { o.similarity = threshold }
+}
+
+// SameProblem replaces the code and message heuristic of Coalesce with
+// same, which is only called for errors of the same file within the
+// window and must not depend on their order.
+func SameProblem(same func(a, b SourceError) bool) CoalesceOption {
+	return func(o *coalesceOptions) { o.same = same }
+}
+
+// Coalesce merges the diagnostics different tools reported for the same
+// problem. Errors are the same problem if they are in the same file,
+// their lines are at most window apart and they have the same code or
+// similar messages, see MessageSimilarity. The error with the highest
+// severity of every group is kept with the others appended to its
+// Related, the errors are returned sorted like Sort does.
+//
+// Errors without a file or line and stack frames are never merged.
+func Coalesce(errs []SourceError, window int, opts ...CoalesceOption) []SourceError {
+	o := coalesceOptions{similarity: 0.5}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.same == nil {
+		o.same = func(a, b SourceError) bool {
+			if a.Code != "" && a.Code == b.Code {
+				return true
+			}
+			return similarity(a.Message, b.Message) >= o.similarity
+		}
+	}
+	sorted := append([]SourceError(nil), errs...)
+	Sort(sorted)
+
+	var groups [][]SourceError
+	// open are the indexes of the groups of the current file that
+	// still start within the window
+	var open []int
+	for _, e := range sorted {
+		if e.File == "" || e.Line <= 0 || e.Kind == KindStackFrame {
+			groups = append(groups, []SourceError{e})
+			continue
+		}
+		kept := open[:0]
+		for _, g := range open {
+			if first := groups[g][0]; first.File == e.File && e.Line-first.Line <= window {
+				kept = append(kept, g)
+			}
+		}
+		open = kept
+		joined := false
+		for _, g := range open {
+			for _, m := range groups[g] {
+				if o.same(m, e) {
+					groups[g] = append(groups[g], e)
+					joined = true
+					break
+				}
+			}
+			if joined {
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, []SourceError{e})
+			open = append(open, len(groups)-1)
+		}
+	}
+
+	coalesced := make([]SourceError, 0, len(groups))
+	for _, g := range groups {
+		primary := 0
+		for i, e := range g {
+			if e.Severity > g[primary].Severity {
+				primary = i
+			}
+		}
+		e := g[primary]
+		if len(g) > 1 {
+			e.Related = append([]SourceError(nil), e.Related...)
+			for i, other := range g {
+				if i != primary {
+					e.Related = append(e.Related, other)
+				}
+			}
+		}
+		coalesced = append(coalesced, e)
+	}
+	Sort(coalesced)
+	return coalesced
+}
+
+// similarity returns the share of the words of a and b that are in
+// both, ignoring case, punctuation and numbers.
+func similarity(a, b string) float64 {
+	words := func(m string) map[string]bool {
+		set := make(map[string]bool)
+		for _, w := range strings.FieldsFunc(strings.ToLower(normalizeMessage(m)), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) {
+			set[w] = true
+		}
+		return set
+	}
+	wa, wb := words(a), words(b)
+	union := len(wa)
+	common := 0
+	for w := range wb {
+		if wa[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(common) / float64(union)
+}
@@ -0,0 +1,104 @@
+package oututil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoalesce(t *testing.T) {
+	errs := []SourceError{
+		{File: "a.go", Line: 10, Column: 2, Severity: SeverityWarning, Tool: "vet", Message: "x declared and not used"},
+		{File: "a.go", Line: 10, Column: 2, Severity: SeverityError, Tool: "go", Message: "declared and not used: x"},
+		{File: "a.go", Line: 11, Column: 2, Severity: SeverityNote, Tool: "staticcheck", Code: "SA4006", Message: "this value of x is never used"},
+		{File: "a.go", Line: 12, Column: 2, Severity: SeverityWarning, Tool: "golangci-lint", Code: "SA4006", Message: "value never used"},
+		{File: "a.go", Line: 40, Column: 1, Severity: SeverityError, Tool: "go", Message: "missing return"},
+		{File: "b.go", Line: 10, Column: 2, Severity: SeverityError, Tool: "go", Message: "declared and not used: x"},
+		{File: "a.go", Line: 10, Kind: KindStackFrame, Function: "main.main"},
+		{Message: "linker failed"},
+	}
+	got := Coalesce(errs, 2)
+	type summary struct {
+		file, tool string
+		line       int
+		related    []string
+	}
+	var sums []summary
+	for _, e := range got {
+		s := summary{file: e.File, tool: e.Tool, line: e.Line}
+		for _, r := range e.Related {
+			s.related = append(s.related, r.Tool)
+		}
+		sums = append(sums, s)
+	}
+	want := []summary{
+		{file: "", tool: ""},
+		{file: "a.go", line: 10},
+		{file: "a.go", tool: "go", line: 10, related: []string{"vet"}},
+		// the same code, the warning outranks the note
+		{file: "a.go", tool: "golangci-lint", line: 12, related: []string{"staticcheck"}},
+		{file: "a.go", tool: "go", line: 40},
+		{file: "b.go", tool: "go", line: 10},
+	}
+	if !reflect.DeepEqual(sums, want) {
+		t.Logf("got\n%v\nexpected\n%v", sums, want)
+		t.Fail()
+	}
+	if again := Coalesce(errs, 2); !reflect.DeepEqual(again, got) {
+		t.Log("coalescing the same errors twice gave different results")
+		t.Fail()
+	}
+}
+
+func TestCoalesceWindow(t *testing.T) {
+	errs := []SourceError{
+		{File: "a.c", Line: 1, Column: NoColumn, Code: "W1", Message: "one"},
+		{File: "a.c", Line: 4, Column: NoColumn, Code: "W1", Message: "two"},
+	}
+	if got := Coalesce(errs, 2); len(got) != 2 {
+		t.Logf("errors 3 lines apart were merged with a window of 2: %v", got)
+		t.Fail()
+	}
+	if got := Coalesce(errs, 3); len(got) != 1 || len(got[0].Related) != 1 {
+		t.Logf("errors 3 lines apart weren't merged with a window of 3: %v", got)
+		t.Fail()
+	}
+}
+
+func TestCoalesceOptions(t *testing.T) {
+	errs := []SourceError{
+		{File: "a.c", Line: 1, Column: NoColumn, Tool: "gcc", Message: "unused variable 'x'"},
+		{File: "a.c", Line: 1, Column: NoColumn, Tool: "clang-tidy", Message: "variable 'x' is unused"},
+	}
+	if got := Coalesce(errs, 0, MessageSimilarity(0.9)); len(got) != 2 {
+		t.Logf("a strict similarity merged %v", got)
+		t.Fail()
+	}
+	if got := Coalesce(errs, 0); len(got) != 1 {
+		t.Logf("the default similarity didn't merge %v", got)
+		t.Fail()
+	}
+	never := SameProblem(func(a, b SourceError) bool { return false })
+	if got := Coalesce(errs, 0, never); len(got) != 2 {
+		t.Logf("SameProblem was ignored, got %v", got)
+		t.Fail()
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"unused variable 'x'", "variable 'x' is unused", 0.75},
+		{"Missing return", "missing return.", 1},
+		{"line 12 too long", "line 80 too long", 1},
+		{"a b", "c d", 0},
+		{"", "", 1},
+	}
+	for _, test := range tests {
+		if got := similarity(test.a, test.b); got != test.want {
+			t.Logf("%q and %q: got %v, expected %v", test.a, test.b, got, test.want)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package oututil
+
+import "unicode/utf8"
+
+// columnPrefix returns the part of line before the byte column col,
+// moved back to the start of the character col falls in, and how many
+// bytes col is past the end of line.
+func columnPrefix(line string, col int) (string, int) {
+	n := col - 1
+	if n > len(line) {
+		return line, n - len(line)
+	}
+	for n > 0 && n < len(line) && !utf8.RuneStart(line[n]) {
+		n--
+	}
+	return line[:n], 0
+}
+
+// ToRuneColumn returns the Column of e, which it reads as a byte column
+// of line like gcc, clang and go report, in characters of line. line is
+// usually the SourceLine Annotate sets. Columns past the end of line
+// stay as far past it and NoColumn is returned as it is.
+func ToRuneColumn(e SourceError, line string) int {
+	if e.Column < 1 {
+		return e.Column
+	}
+	prefix, past := columnPrefix(line, e.Column)
+	return utf8.RuneCountInString(prefix) + past + 1
+}
+
+// ToUTF16Column is like ToRuneColumn but counts the UTF-16 code units
+// of line, the columns of the language server protocol.
+func ToUTF16Column(e SourceError, line string) int {
+	if e.Column < 1 {
+		return e.Column
+	}
+	return utf16Column(line, e.Column)
+}
+
+func utf16Column(line string, col int) int {
+	prefix, past := columnPrefix(line, col)
+	n := 0
+	for _, r := range prefix {
+		n++
+		if r >= 0x10000 {
+			// encoded as a surrogate pair
+			n++
+		}
+	}
+	return n + past + 1
+}
+
+// ToVisualColumn is like ToRuneColumn but counts the cells line takes
+// on a terminal, two for East Asian wide and fullwidth characters and
+// emoji, with tabs expanded to the next multiple of tabWidth, 4 if it's
+// zero.
+func ToVisualColumn(e SourceError, line string, tabWidth int) int {
+	if e.Column < 1 {
+		return e.Column
+	}
+	if tabWidth <= 0 {
+		tabWidth = 4
+	}
+	prefix, past := columnPrefix(line, e.Column)
+	return visualWidth(prefix, 0, tabWidth) + past + 1
+}
@@ -0,0 +1,42 @@
+package oututil
+
+import "testing"
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		line                 string
+		column               int
+		runes, utf16, visual int
+	}{
+		{line: "x := 1", column: 3, runes: 3, utf16: 3, visual: 3},
+		// é and ü are two bytes each
+		{line: `s := "héllo" + ü`, column: 15, runes: 14, utf16: 14, visual: 14},
+		// 😀 is four bytes, two UTF-16 code units and two cells
+		{line: `s := "😀" + x`, column: 11, runes: 8, utf16: 9, visual: 9},
+		{line: "\tx := 1", column: 2, runes: 2, utf16: 2, visual: 5},
+		{line: "\t\tx :=\t1", column: 7, runes: 7, utf16: 7, visual: 13},
+		{line: "a\tb", column: 3, runes: 3, utf16: 3, visual: 5},
+		// in the middle of é
+		{line: "é", column: 2, runes: 1, utf16: 1, visual: 1},
+		// one past the end of the line
+		{line: "日本", column: 8, runes: 4, utf16: 4, visual: 6},
+		// fullwidth letters take two cells, halfwidth katakana one
+		{line: "ＡｶB", column: 7, runes: 3, utf16: 3, visual: 4},
+		{line: "x", column: NoColumn, runes: NoColumn, utf16: NoColumn, visual: NoColumn},
+	}
+	for _, test := range tests {
+		e := SourceError{Line: 1, Column: test.column}
+		if got := ToRuneColumn(e, test.line); got != test.runes {
+			t.Logf("%q:%d: was expecting rune column %d got %d instead", test.line, test.column, test.runes, got)
+			t.Fail()
+		}
+		if got := ToUTF16Column(e, test.line); got != test.utf16 {
+			t.Logf("%q:%d: was expecting UTF-16 column %d got %d instead", test.line, test.column, test.utf16, got)
+			t.Fail()
+		}
+		if got := ToVisualColumn(e, test.line, 4); got != test.visual {
+			t.Logf("%q:%d: was expecting visual column %d got %d instead", test.line, test.column, test.visual, got)
+			t.Fail()
+		}
+	}
+}
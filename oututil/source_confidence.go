@@ -0,0 +1,172 @@
+package oututil
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// DefaultMinConfidence is the MinConfidence of logs that don't set one.
+// It drops the matches whose file is a host or a number, like the ones
+// of
+//
+//	connecting to example.com:443: timeout
+//	2024-01-02T12:30:45.123:99: starting build
+//
+// unless they have a severity, and keeps the others.
+const DefaultMinConfidence = 0.35
+
+// The parts of the confidence of a match, they add up to 1.
+const (
+	// confidenceMatch is the confidence of a line a format matched.
+	confidenceMatch = 0.1
+	// confidenceFile is added for files with a source extension or that
+	// are in the WithSourceFS, half of it for other extensions.
+	confidenceFile = 0.3
+	// confidenceSeverity is added for diagnostics with a severity.
+	confidenceSeverity = 0.4
+	// confidencePosition is added for positions a source file can have.
+	confidencePosition = 0.2
+)
+
+// MinConfidence drops the errors the single line formats match with a
+// Confidence below min, DefaultMinConfidence by default; a min of 0
+// keeps every match.
+func MinConfidence(min float64) Option {
+	return func(o *options) { o.minConfidence = min }
+}
+
+// WithSourceFS makes the files of the errors the single line formats
+// match that are in fsys as likely as the ones with a source extension,
+// see Confidence. Files are looked up by their slash separated path,
+// the ones that aren't valid fs paths, like absolute ones, aren't.
+func WithSourceFS(fsys fs.FS) Option {
+	return func(o *options) { o.fsys = fsys }
+}
+
+// sourceExtensions are the extensions of the files tools report
+// diagnostics in.
+var sourceExtensions = map[string]bool{}
+
+func init() {
+	for _, ext := range strings.Fields(`
+		c h cc cpp cxx c++ hh hpp hxx inl ipp m mm s asm ld
+		go mod rs py pyi pyx js jsx mjs cjs ts tsx mts cts vue svelte
+		java kt kts scala groovy gradle clj swift cs csx fs fsx vb xaml
+		csproj vbproj fsproj sln props targets rb erb php pl pm lua
+		sh bash zsh fish ps1 psm1 bat cmd sql proto graphql thrift
+		json jsonc yaml yml toml xml xsd html htm css scss sass less
+		md rst tex cmake mk make bzl bazel nix tf hcl dart ex exs erl
+		hrl hs elm ml mli zig nim d f f90 f95 r jl sol v sv vhd vhdl
+		txt cfg ini conf in dockerfile dockerignore lock`) {
+		sourceExtensions[ext] = true
+	}
+}
+
+// hostSuffixes are the extensions of host names, which tools print
+// with ports that look like line numbers.
+var hostSuffixes = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "dev": true, "app": true,
+	"edu": true, "gov": true, "co": true, "uk": true, "de": true, "local": true,
+	"localhost": true, "internal": true, "arpa": true, "cloud": true,
+}
+
+// confidence returns how likely e, which a single line format matched,
+// is a diagnostic: formats match any name with an extension followed by
+// numbers, and more than diagnostics have that.
+func (s *sourceScanner) confidence(e SourceError) float64 {
+	c := confidenceMatch + s.fileConfidence(e)
+	if e.Severity != SeverityUnknown && !e.SeverityInferred {
+		c += confidenceSeverity
+	}
+	if sanePosition(e) {
+		c += confidencePosition
+	}
+	return c
+}
+
+func (s *sourceScanner) fileConfidence(e SourceError) float64 {
+	if e.IsVirtual() || s.exists(e.File) {
+		return confidenceFile
+	}
+	name := e.File
+	if e.IsRemote() {
+		name = strings.TrimPrefix(name, e.Scheme()+"://")
+	}
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	dot := strings.LastIndexByte(name, '.')
+	if dot < 0 {
+		// bazel's BUILD files
+		return confidenceFile
+	}
+	ext := strings.ToLower(name[dot+1:])
+	switch {
+	case sourceExtensions[ext]:
+		return confidenceFile
+	case hostSuffixes[ext] || isNumber(ext) || isNumber(strings.Map(func(r rune) rune {
+		if r == '.' {
+			return -1
+		}
+		return r
+	}, name)):
+		return 0
+	}
+	return confidenceFile / 2
+}
+
+// exists reports whether file is in the WithSourceFS.
+func (s *sourceScanner) exists(file string) bool {
+	if s.opts.fsys == nil {
+		return false
+	}
+	name := path.Clean(strings.ReplaceAll(file, `\`, "/"))
+	if ok, seen := s.files[name]; seen {
+		return ok
+	}
+	ok := false
+	if fs.ValidPath(name) {
+		_, err := fs.Stat(s.opts.fsys, name)
+		ok = err == nil
+	}
+	if s.files == nil {
+		s.files = make(map[string]bool)
+	}
+	s.files[name] = ok
+	return ok
+}
+
+// maxSaneLine and maxSaneColumn are the largest positions taken for
+// ones in a source file.
+const (
+	maxSaneLine   = 10000000
+	maxSaneColumn = 100000
+)
+
+// sanePosition reports whether e has a position a source file can have.
+// Errors with only an offset have no line.
+func sanePosition(e SourceError) bool {
+	if e.Line == 0 && e.Offset > 0 {
+		return true
+	}
+	if e.Line < 1 || e.Line > maxSaneLine {
+		return false
+	}
+	if e.Column != NoColumn && (e.Column < 0 || e.Column > maxSaneColumn) {
+		return false
+	}
+	return e.EndLine == 0 || e.EndLine >= e.Line
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		line string
+		// confidence is that of the error, 0 if none should be parsed
+		confidence float64
+		opts       []Option
+	}{
+		{"main.c:3:5: error: 'x' undeclared", 1, nil},
+		{"a.go:123:6: expected '(', found 'IDENT'", 0.6, nil},
+		{"build.log:12: could not open", 0.45, nil},
+		{"12:30:45: starting build", 0, nil},
+		{"2024-01-02T12:30:45.123:99: starting build", 0, nil},
+		{"connecting to example.com:443: timeout", 0, nil},
+		{"listening on 127.0.0.1:8080: ok", 0, nil},
+		{"v1.2:3: released", 0, nil},
+		{"connecting to example.com:443: error: timeout", 0.7, nil},
+		{"connecting to example.com:443: timeout", 0.3, []Option{MinConfidence(0)}},
+		{"main.go:0:0: x", 0.4, nil},
+		{"main.go:3:5: x", 0, []Option{MinConfidence(0.9)}},
+		{"gen/api.pb:3:5: x", 0.6, []Option{WithSourceFS(fstest.MapFS{"gen/api.pb": {}})}},
+		{"gen/api.other:3:5: x", 0.45, []Option{WithSourceFS(fstest.MapFS{"gen/api.pb": {}})}},
+		{"<stdin>:3:5: x", 0.6, nil},
+		{"https://example.com/app.js:3:5: error: x", 1, nil},
+	}
+	for _, test := range tests {
+		errs, _ := ParseReader(strings.NewReader(test.line), test.opts...)
+		if test.confidence == 0 {
+			if len(errs) != 0 {
+				t.Logf("%q: was expecting no errors got %+v", test.line, errs)
+				t.Fail()
+			}
+			continue
+		}
+		if len(errs) != 1 || !near(errs[0].Confidence, test.confidence) {
+			t.Logf("%q: was expecting an error with confidence %v got %+v", test.line, test.confidence, errs)
+			t.Fail()
+		}
+	}
+}
+
+func TestConfidenceMultiLine(t *testing.T) {
+	const traceback = `Traceback (most recent call last):
+  File "app.py", line 3, in <module>
+ZeroDivisionError: division by zero
+`
+	errs, _ := ParseReader(strings.NewReader(traceback))
+	if len(errs) == 0 {
+		t.Fatal("was expecting the traceback to be parsed")
+	}
+	for _, e := range errs {
+		if e.Confidence != 1 {
+			t.Logf("was expecting a confidence of 1 got %+v", e)
+			t.Fail()
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
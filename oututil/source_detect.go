@@ -0,0 +1,58 @@
+package oututil
+
+import "io"
+
+// Detector parses logs that interleave the output of several tools and
+// keeps count of which formats matched, to help debug detection.
+type Detector struct {
+	// Matched counts the source errors produced by each format or
+	// multi-line parser.
+	Matched map[string]int
+	// Shadowed counts the lines a format matched but lost to a format
+	// with a higher priority.
+	Shadowed map[string]int
+}
+
+// ParseAuto parses a log mixing the output of several tools with every
+// registered format, recording the format that produced each
+// SourceError in its Tool field.
+func ParseAuto(r io.Reader) ([]SourceError, error) {
+	return new(Detector).Parse(r)
+}
+
+// Parse parses r like ParseReader does while counting matches.
+func (d *Detector) Parse(r io.Reader, opts ...Option) ([]SourceError, error) {
+	if d.Matched == nil {
+		d.Matched = make(map[string]int)
+	}
+	if d.Shadowed == nil {
+		d.Shadowed = make(map[string]int)
+	}
+	var errors []SourceError
+	err := Scan(r, func(e SourceError) bool {
+		d.Matched[e.Tool]++
+		errors = append(errors, e)
+		return true
+	}, append(opts, func(o *options) { o.detector = d })...)
+	return errors, err
+}
+
+// match runs all formats against line and returns the first match.
+func (d *Detector) match(formats []Format, line string) (SourceError, bool) {
+	var (
+		first SourceError
+		found bool
+	)
+	for _, f := range formats {
+		e, ok := f.Match(line)
+		if !ok {
+			continue
+		}
+		if found {
+			d.Shadowed[f.name]++
+			continue
+		}
+		first, found = e, true
+	}
+	return first, found
+}
@@ -0,0 +1,41 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAuto(t *testing.T) {
+	log := strings.Join([]string{
+		"main.c:3:5: error: 'x' undeclared",
+		"src/app.ts(10,5): error TS2304: Cannot find name 'foo'.",
+		"/home/ci/web/src/app.js",
+		"   1:10  error    'foo' is defined but never used  no-unused-vars",
+		"",
+		"main.c:4:1: warning: control reaches end of non-void function",
+	}, "\n")
+	d := new(Detector)
+	errs, err := d.Parse(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := []string{"gcc", "tsc", "eslint", "gcc"}
+	if len(errs) != len(tools) {
+		t.Fatalf("was expecting %d errors got %d instead", len(tools), len(errs))
+	}
+	for i, e := range errs {
+		if e.Tool != tools[i] {
+			t.Logf("was expecting error %d to come from %q got %q instead", i, tools[i], e.Tool)
+			t.Fail()
+		}
+	}
+	if d.Matched["gcc"] != 2 || d.Matched["tsc"] != 1 || d.Matched["eslint"] != 1 {
+		t.Logf("unexpected match counts %v", d.Matched)
+		t.Fail()
+	}
+	// the tsc line is also a valid msvc and parenthesized diagnostic.
+	if d.Shadowed["msvc"] != 1 || d.Shadowed["paren"] != 1 {
+		t.Logf("unexpected shadowed counts %v", d.Shadowed)
+		t.Fail()
+	}
+}
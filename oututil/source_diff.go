@@ -0,0 +1,153 @@
+package oututil
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sevki.org/x/reconcile"
+)
+
+// keyLines is the number of lines that share a line bucket in Key.
+const keyLines = 16
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// normalizeMessage collapses the whitespace in m and replaces numbers,
+// which are often line numbers or counts that change between builds.
+func normalizeMessage(m string) string {
+	return digits.ReplaceAllString(strings.Join(strings.Fields(m), " "), "N")
+}
+
+// Key returns an identity for e that survives small edits to its file.
+// It is made of the file, a bucket of lines around the line, the code
+// and the message with its numbers and whitespace normalized.
+func (e SourceError) Key() string {
+	return fmt.Sprintf("%s:%d:%s:%s", slashPath(e.File), e.Line/keyLines, e.Code, normalizeMessage(e.Message))
+}
+
+// Sum returns a checksum of e's position, severity, code and message so
+// a reconcile.State of errors updates the ones that moved within their
+// Key.
+func (e SourceError) Sum() []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00%d\x00%d\x00%s\x00%s\x00%s", slashPath(e.File), e.Line, e.Column, e.EndLine, e.EndColumn, e.Severity, e.Code, e.Message)
+	return h.Sum(nil)
+}
+
+var _ reconcile.Checksumed = SourceError{}
+
+// ErrorSet is a reconcile.State of source errors keyed by their Key.
+type ErrorSet map[string]SourceError
+
+var _ reconcile.State = ErrorSet{}
+
+// NewErrorSet returns the set of errs. Errors with the same Key get a
+// "#n" suffix in the order they appear.
+func NewErrorSet(errs []SourceError) ErrorSet {
+	set := make(ErrorSet, len(errs))
+	seen := make(map[string]int)
+	for _, e := range errs {
+		key := e.Key()
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		set[key] = e
+	}
+	return set
+}
+
+// Add adds v, a SourceError, to the set.
+func (s ErrorSet) Add(key string, v interface{}) { s[key] = v.(SourceError) }
+
+// Update replaces the error at key with v, a SourceError.
+func (s ErrorSet) Update(key string, v interface{}) { s[key] = v.(SourceError) }
+
+// Get returns the error at key or nil.
+func (s ErrorSet) Get(key string) interface{} {
+	if e, ok := s[key]; ok {
+		return e
+	}
+	return nil
+}
+
+// Delete deletes the error at key.
+func (s ErrorSet) Delete(key string) { delete(s, key) }
+
+// Walk calls f for the errors in the set in the order of their keys.
+func (s ErrorSet) Walk(f reconcile.StateWalkFunc) {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f(k, s[k])
+	}
+}
+
+type diffOptions struct {
+	fuzz int
+}
+
+// DiffOption configures NewErrors.
+type DiffOption func(*diffOptions)
+
+// LineFuzz sets how many lines an error can move and still be the same
+// error, it is 5 by default.
+func LineFuzz(lines int) DiffOption {
+	return func(o *diffOptions) { o.fuzz = lines }
+}
+
+// NewErrors compares the errors of two builds and returns the ones in
+// after that weren't in before and the ones in before that are gone
+// from after. Errors are the same if they have the same file, code and
+// normalized message and their lines are within the LineFuzz of each
+// other; the closest ones are paired first.
+func NewErrors(before, after []SourceError, opts ...DiffOption) (introduced, fixed []SourceError) {
+	o := diffOptions{fuzz: 5}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	type identity struct{ file, code, message string }
+	id := func(e SourceError) identity {
+		return identity{slashPath(e.File), e.Code, normalizeMessage(e.Message)}
+	}
+	unmatched := make(map[identity][]int)
+	for i, e := range before {
+		unmatched[id(e)] = append(unmatched[id(e)], i)
+	}
+	matched := make([]bool, len(before))
+	for _, e := range after {
+		k := id(e)
+		best := -1
+		for j, i := range unmatched[k] {
+			d := abs(before[i].Line - e.Line)
+			if d <= o.fuzz && (best < 0 || d < abs(before[unmatched[k][best]].Line-e.Line)) {
+				best = j
+			}
+		}
+		if best < 0 {
+			introduced = append(introduced, e)
+			continue
+		}
+		matched[unmatched[k][best]] = true
+		unmatched[k] = append(unmatched[k][:best], unmatched[k][best+1:]...)
+	}
+	for i, e := range before {
+		if !matched[i] {
+			fixed = append(fixed, e)
+		}
+	}
+	return introduced, fixed
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
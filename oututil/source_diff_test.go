@@ -0,0 +1,100 @@
+package oututil
+
+import (
+	"reflect"
+	"testing"
+
+	"sevki.org/x/reconcile"
+)
+
+func TestKey(t *testing.T) {
+	a := SourceError{File: "./a.go", Line: 3, Column: 2, Code: "SA4006", Message: "this value of  x is never used (3 times)"}
+	b := SourceError{File: "a.go", Line: 5, Column: 9, Code: "SA4006", Message: "this value of x is never used (4 times)"}
+	if a.Key() != b.Key() {
+		t.Logf("was expecting %q and %q to be the same", a.Key(), b.Key())
+		t.Fail()
+	}
+	if string(a.Sum()) == string(b.Sum()) {
+		t.Logf("was expecting the sums of %v and %v to differ", a, b)
+		t.Fail()
+	}
+	c := b
+	c.Code = "SA4009"
+	if c.Key() == b.Key() {
+		t.Logf("was expecting %q and %q to differ", c.Key(), b.Key())
+		t.Fail()
+	}
+}
+
+func TestErrorSet(t *testing.T) {
+	before := []SourceError{
+		{File: "a.go", Line: 3, Message: "x declared but not used"},
+		{File: "a.go", Line: 40, Message: "undefined: y"},
+	}
+	after := []SourceError{
+		{File: "a.go", Line: 4, Message: "x declared but not used"},
+		{File: "b.go", Line: 1, Message: "undefined: y"},
+	}
+	current := NewErrorSet(before)
+	desired := NewErrorSet(after)
+	reconcile.Reconcile(current, desired, false)
+	if len(current) != len(desired) {
+		t.Logf("was expecting %d errors got %d instead", len(desired), len(current))
+		t.Fail()
+	}
+	for k, e := range desired {
+		if !reflect.DeepEqual(current[k], e) {
+			t.Logf("%s: was expecting %v got %v instead", k, e, current[k])
+			t.Fail()
+		}
+	}
+
+	dup := NewErrorSet([]SourceError{{File: "a.go", Line: 1, Message: "m"}, {File: "a.go", Line: 2, Message: "m"}})
+	if len(dup) != 2 {
+		t.Logf("was expecting 2 errors got %v instead", dup)
+		t.Fail()
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	before := []SourceError{
+		{File: "a.go", Line: 10, Code: "errcheck", Message: "error return value not checked"},
+		{File: "a.go", Line: 30, Code: "errcheck", Message: "error return value not checked"},
+		{File: "a.go", Line: 50, Code: "unused", Message: "func f is unused"},
+		{File: "b.go", Line: 5, Code: "unused", Message: "func g is unused"},
+	}
+	after := []SourceError{
+		{File: "a.go", Line: 13, Code: "errcheck", Message: "error return value not checked"},
+		{File: "a.go", Line: 14, Code: "errcheck", Message: "error return value not checked"},
+		{File: "a.go", Line: 33, Code: "errcheck", Message: "error return value not checked"},
+		{File: "b.go", Line: 5, Code: "unused", Message: "func g is unused"},
+	}
+	tests := []struct {
+		opts              []DiffOption
+		introduced, fixed []int
+	}{
+		{introduced: []int{1}, fixed: []int{2}},
+		{opts: []DiffOption{LineFuzz(0)}, introduced: []int{0, 1, 2}, fixed: []int{0, 1, 2}},
+		{opts: []DiffOption{LineFuzz(100)}, introduced: []int{2}, fixed: []int{2}},
+	}
+	for _, test := range tests {
+		introduced, fixed := NewErrors(before, after, test.opts...)
+		if len(introduced) != len(test.introduced) || len(fixed) != len(test.fixed) {
+			t.Logf("was expecting %v introduced and %v fixed got %v and %v instead", test.introduced, test.fixed, introduced, fixed)
+			t.Fail()
+			continue
+		}
+		for i, n := range test.introduced {
+			if !reflect.DeepEqual(introduced[i], after[n]) {
+				t.Logf("was expecting %v to be introduced got %v instead", after[n], introduced[i])
+				t.Fail()
+			}
+		}
+		for i, n := range test.fixed {
+			if !reflect.DeepEqual(fixed[i], before[n]) {
+				t.Logf("was expecting %v to be fixed got %v instead", before[n], fixed[i])
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package oututil
+
+import (
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// goLineDirective matches Go's line directives,
+	//
+	//	//line parse.y:33
+	//	//line parse.y:33:5
+	goLineDirective = regexp.MustCompile(`^//line (.*?):([0-9]+)(?::[0-9]+)?\s*$`)
+	// cLineDirective matches the C preprocessor's line directives and
+	// the line markers of its output,
+	//
+	//	#line 12 "grammar.y"
+	//	# 12 "grammar.y" 2
+	//
+	// and not comments starting with a number, like "# 1. step one".
+	cLineDirective = regexp.MustCompile(`^#\s*(?:line\s+)?([0-9]+)(?:\s+"((?:[^"\\]|\\.)*)"(?:\s+[0-9]+)*)?\s*$`)
+)
+
+// lineDirective is a directive at line at saying the line that follows
+// it is line of file.
+type lineDirective struct {
+	at, line int
+	file     string
+}
+
+// lineDirectives returns the line directives of f in order.
+func lineDirectives(f *sourceFile) []lineDirective {
+	var directives []lineDirective
+	file := ""
+	for n := 1; n <= len(f.lines); n++ {
+		text, _ := f.line(n)
+		if !strings.HasPrefix(text, "//line ") && !strings.HasPrefix(text, "#") {
+			continue
+		}
+		d := lineDirective{at: n, file: file}
+		if m := goLineDirective.FindStringSubmatch(text); m != nil {
+			if m[1] != "" {
+				d.file = m[1]
+			}
+			d.line, _ = strconv.Atoi(m[2])
+		} else if m := cLineDirective.FindStringSubmatch(text); m != nil {
+			if m[2] != "" {
+				d.file = strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(m[2])
+			}
+			d.line, _ = strconv.Atoi(m[1])
+		} else {
+			continue
+		}
+		file = d.file
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// MapThroughLineDirectives returns a copy of errs with the positions in
+// generated files read from fsys mapped to the original source through
+// the //line and #line directives above them. The position that was
+// reported is kept in GeneratedFile and GeneratedLine, file names are
+// used as the directives spell them.
+func MapThroughLineDirectives(errs []SourceError, fsys fs.FS) []SourceError {
+	files := make(map[string]*sourceFile)
+	directives := make(map[string][]lineDirective)
+	mapped := make([]SourceError, len(errs))
+	for i, e := range errs {
+		mapped[i] = mapThroughLineDirectives(e, fsys, files, directives)
+	}
+	return mapped
+}
+
+func mapThroughLineDirectives(e SourceError, fsys fs.FS, files map[string]*sourceFile, directives map[string][]lineDirective) SourceError {
+	if len(e.Related) > 0 {
+		related := make([]SourceError, len(e.Related))
+		for i, r := range e.Related {
+			related[i] = mapThroughLineDirectives(r, fsys, files, directives)
+		}
+		e.Related = related
+	}
+	if e.IsVirtual() || e.Line < 1 {
+		return e
+	}
+	name := strings.TrimPrefix(slashPath(e.File), "./")
+	ds, ok := directives[name]
+	if !ok {
+		if f := openSourceFile(fsys, name, files); f != nil {
+			ds = lineDirectives(f)
+		}
+		directives[name] = ds
+	}
+	var governing *lineDirective
+	for i := range ds {
+		if ds[i].at >= e.Line {
+			break
+		}
+		governing = &ds[i]
+	}
+	if governing == nil {
+		return e
+	}
+	e.GeneratedFile, e.GeneratedLine = e.File, e.Line
+	line := governing.line + e.Line - governing.at - 1
+	if e.EndLine >= e.Line {
+		e.EndLine += line - e.Line
+	}
+	e.Line = line
+	if governing.file != "" {
+		e.File = governing.file
+	}
+	return e
+}
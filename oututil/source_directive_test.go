@@ -0,0 +1,65 @@
+package oututil
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestMapThroughLineDirectives(t *testing.T) {
+	fsys := fstest.MapFS{
+		"parse.go": {Data: []byte(`package main
+
+//line parse.y:33
+func parse() {
+	x := 1
+}
+//line :90
+var y = 2
+`)},
+		"lex.c": {Data: []byte(`#include <stdio.h>
+# 1 "lex.l"
+int a;
+#line 12 "C:\\src\\grammar.y"
+int b
+int c;
+`)},
+		"gen.i": {Data: []byte(`# 1 "a.c"
+# 7 "b.h" 1 3
+int b
+`)},
+		"steps.sh": {Data: []byte(`# 1. step one
+exit 1 2
+`)},
+	}
+	errs := MapThroughLineDirectives([]SourceError{
+		{File: "parse.go", Line: 5, Column: 2, Message: "x declared and not used"},
+		{File: "parse.go", Line: 8, Column: 5, Message: "y declared and not used"},
+		{File: "parse.go", Line: 1, Column: 1, Message: "before the directives"},
+		{File: "lex.c", Line: 3, Column: 5, Message: "a"},
+		{File: "lex.c", Line: 6, Column: 1, Message: "expected ';'"},
+		{File: "missing.c", Line: 6, Column: 1, Message: "missing"},
+		{File: "gen.i", Line: 3, Column: 6, Message: "expected ';'"},
+		{File: "steps.sh", Line: 2, Column: 1, Message: "too many arguments"},
+	}, fsys)
+	want := []struct {
+		file          string
+		line          int
+		generatedFile string
+		generatedLine int
+	}{
+		{"parse.y", 34, "parse.go", 5},
+		{"parse.y", 90, "parse.go", 8},
+		{"parse.go", 1, "", 0},
+		{"lex.l", 1, "lex.c", 3},
+		{`C:\src\grammar.y`, 13, "lex.c", 6},
+		{"missing.c", 6, "", 0},
+		{"b.h", 7, "gen.i", 3},
+		{"steps.sh", 2, "", 0},
+	}
+	for i, e := range errs {
+		if e.File != want[i].file || e.Line != want[i].line || e.GeneratedFile != want[i].generatedFile || e.GeneratedLine != want[i].generatedLine {
+			t.Logf("%s: was expecting %v got %s:%d (%s:%d) instead", e.Message, want[i], e.File, e.Line, e.GeneratedFile, e.GeneratedLine)
+			t.Fail()
+		}
+	}
+}
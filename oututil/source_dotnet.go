@@ -0,0 +1,17 @@
+package oututil
+
+import "regexp"
+
+// warningCode matches the codes of diagnostics that are warnings unless
+// they are turned into errors, like -warnaserror and /WX do: MSVC's
+// C4xxx, the analyzers' rules and the warnings of the C# compiler.
+var warningCode = regexp.MustCompile(`^(?:C4[0-9]{3}|CA[0-9]{4}|IDE[0-9]{4}|SA[0-9]{4}|CS(?:0108|0114|0162|0168|0169|0219|0414|0618|0649|1591|1998|4014|8[0-9]{3}))$`)
+
+// markWarningAsError sets WarningAsError on the errors of MSVC and
+// dotnet build whose code is a warning's.
+func markWarningAsError(e SourceError) SourceError {
+	if e.Severity == SeverityError && (e.Tool == "msvc" || e.Tool == "msvc-link") && warningCode.MatchString(e.Code) {
+		e.WarningAsError = true
+	}
+	return e
+}
@@ -0,0 +1,41 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// eslintFile matches the file name header of eslint's stylish format.
+	eslintFile = regexp.MustCompile(`^\S.*\.[[:alnum:]]+$`)
+	// eslintEntry matches the entries listed under the header,
+	//
+	//	  10:5  error  'foo' is defined but never used  no-unused-vars
+	eslintEntry = regexp.MustCompile(`^\s+(?P<line>[0-9]+):(?P<col>[0-9]+)\s+(?P<severity>error|warning)\s+` +
+		`(?P<message>.*?)(?:\s{2,}(?P<code>[@[:alnum:]/_-]+))?$`)
+)
+
+// eslintParser parses eslint's default stylish format, carrying the
+// file name of the header over to the entries below it.
+type eslintParser struct {
+	file string
+}
+
+func (p *eslintParser) parse(line string, emit func(SourceError)) bool {
+	if p.file != "" {
+		if m := eslintEntry.FindStringSubmatch(line); m != nil {
+			e := fromSubmatch(eslintEntry, m)
+			e.File = p.file
+			e.Tool = "eslint"
+			emit(e)
+			return true
+		}
+	}
+	p.file = ""
+	if eslintFile.MatchString(line) && !strings.ContainsAny(line, "()") {
+		p.file = line
+	}
+	return false
+}
+
+func (p *eslintParser) flush(emit func(SourceError)) { p.file = "" }
@@ -0,0 +1,100 @@
+package oututil
+
+import (
+	"path"
+	"strings"
+)
+
+// Filter selects source errors. The zero Filter selects everything.
+type Filter struct {
+	// MinSeverity drops errors less severe than it
+	MinSeverity Severity
+	// IncludePaths and ExcludePaths are doublestar globs, like
+	// "vendor/**" or "**/*_test.go", matched against the slash separated
+	// file of an error. If IncludePaths is set only the files matching it
+	// are kept, ExcludePaths is applied after it.
+	IncludePaths, ExcludePaths []string
+	// IncludeCodes and ExcludeCodes are path.Match patterns, like "SC2*",
+	// matched against the code of an error.
+	IncludeCodes, ExcludeCodes []string
+}
+
+// Apply returns the errors in errs f selects.
+func (f Filter) Apply(errs []SourceError) []SourceError {
+	var selected []SourceError
+	for _, e := range errs {
+		if f.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// Match reports whether f selects e.
+func (f Filter) Match(e SourceError) bool {
+	if e.Severity < f.MinSeverity {
+		return false
+	}
+	file := slashPath(e.File)
+	if len(f.IncludePaths) > 0 && !matchAny(f.IncludePaths, file, matchGlob) {
+		return false
+	}
+	if matchAny(f.ExcludePaths, file, matchGlob) {
+		return false
+	}
+	if len(f.IncludeCodes) > 0 && !matchAny(f.IncludeCodes, e.Code, matchCode) {
+		return false
+	}
+	return !matchAny(f.ExcludeCodes, e.Code, matchCode)
+}
+
+func matchAny(patterns []string, name string, match func(pattern, name string) bool) bool {
+	for _, p := range patterns {
+		if match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCode(pattern, code string) bool {
+	ok, _ := path.Match(pattern, code)
+	return ok
+}
+
+// slashPath normalizes a Windows or Unix path to a clean slash
+// separated one. URLs are left as they are.
+func slashPath(p string) string {
+	if p == "" || schemeOf(p) != "" {
+		return p
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+	return path.Clean(p)
+}
+
+// matchGlob matches name against a glob where ** matches any number of
+// path elements and the other elements are path.Match patterns.
+func matchGlob(pattern, name string) bool {
+	return matchElems(strings.Split(slashPath(pattern), "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
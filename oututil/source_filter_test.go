@@ -0,0 +1,63 @@
+package oututil
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"vendor/**", "vendor/a/b.go", true},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "pkg/vendor/a.go", false},
+		{"**/vendor/**", "pkg/vendor/a.go", true},
+		{"**/*_test.go", "a_test.go", true},
+		{"**/*_test.go", "pkg/x/a_test.go", true},
+		{"pkg/*.go", "pkg/x/a.go", false},
+		{"pkg/**/*.go", "pkg/x/y/a.go", true},
+		{"./pkg/*.go", "pkg/a.go", true},
+		{`pkg\*.go`, "pkg/a.go", true},
+	}
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, slashPath(test.name)); got != test.match {
+			t.Logf("matchGlob(%q, %q) = %t, want %t", test.pattern, test.name, got, test.match)
+			t.Fail()
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	errs := []SourceError{
+		{File: "vendor/lib/x.go", Line: 1, Severity: SeverityError},
+		{File: `C:\ci\src\vendor\lib\y.go`, Line: 1, Severity: SeverityError},
+		{File: "./pkg/a.go", Line: 1, Severity: SeverityWarning, Code: "SC2086"},
+		{File: `pkg\b.go`, Line: 2, Severity: SeverityError, Code: "SC1000"},
+		{File: "pkg/c.go", Line: 3, Severity: SeverityError, Code: "E501"},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{"empty", Filter{}, []int{0, 1, 2, 3, 4}},
+		{"severity", Filter{MinSeverity: SeverityError}, []int{0, 1, 3, 4}},
+		{"exclude vendor", Filter{ExcludePaths: []string{"vendor/**", "**/vendor/**"}}, []int{2, 3, 4}},
+		{"include pkg", Filter{IncludePaths: []string{"pkg/**"}}, []int{2, 3, 4}},
+		{"include codes", Filter{IncludeCodes: []string{"SC*"}}, []int{2, 3}},
+		{"exclude codes", Filter{MinSeverity: SeverityError, IncludePaths: []string{"pkg/*"}, ExcludeCodes: []string{"E501"}}, []int{3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.filter.Apply(errs)
+			if len(got) != len(test.want) {
+				t.Fatalf("was expecting %d errors got %d instead: %v", len(test.want), len(got), got)
+			}
+			for i, j := range test.want {
+				if got[i].File != errs[j].File {
+					t.Logf("was expecting %q got %q instead", errs[j].File, got[i].File)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package oututil
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fix is a replacement a tool suggests to fix a diagnostic. Lines and
+// columns start at 1, columns count bytes and the end is exclusive, an
+// empty range is an insertion.
+type Fix struct {
+	File                string
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Replacement         string
+}
+
+// FixIts adds the fix-its clang prints with -fdiagnostics-parseable-fixits
+// to the Fixes of the diagnostics they follow.
+func FixIts() Option {
+	return func(o *options) { o.fixIts = true }
+}
+
+// clangFixIt matches the fix-its of clang,
+//
+//	fix-it:"main.c":{10:5-10:8}:"newText"
+var clangFixIt = regexp.MustCompile(`^fix-it:"((?:[^"\\]|\\.)*)":\{([0-9]+):([0-9]+)-([0-9]+):([0-9]+)\}:"((?:[^"\\]|\\.)*)"$`)
+
+// parseFixIt parses a clang fix-it line.
+func parseFixIt(line string) (Fix, bool) {
+	m := clangFixIt.FindStringSubmatch(line)
+	if m == nil {
+		return Fix{}, false
+	}
+	f := Fix{File: unescapeClang(m[1]), Replacement: unescapeClang(m[6])}
+	f.StartLine, _ = strconv.Atoi(m[2])
+	f.StartCol, _ = strconv.Atoi(m[3])
+	f.EndLine, _ = strconv.Atoi(m[4])
+	f.EndCol, _ = strconv.Atoi(m[5])
+	return f, true
+}
+
+// unescapeClang undoes the escaping of llvm's write_escaped, which
+// escapes backslashes, quotes, tabs and newlines and writes other
+// unprintable bytes as three octal digits.
+func unescapeClang(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; {
+		case c == 'n':
+			b.WriteByte('\n')
+		case c == 't':
+			b.WriteByte('\t')
+		case c >= '0' && c <= '7' && i+2 < len(s):
+			n, err := strconv.ParseUint(s[i:i+3], 8, 8)
+			if err != nil {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteByte(byte(n))
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ApplyFixes applies the Fixes of errs to the files they are in, read
+// from fsys, and returns the patched contents by file name. Fixes that
+// are repeated are applied once, fixes with overlapping ranges are an
+// error.
+func ApplyFixes(fsys fs.FS, errs []SourceError) (map[string][]byte, error) {
+	byFile := make(map[string][]Fix)
+	for _, e := range errs {
+		for _, f := range e.Fixes {
+			name := strings.TrimPrefix(slashPath(f.File), "./")
+			byFile[name] = append(byFile[name], f)
+		}
+	}
+	patched := make(map[string][]byte, len(byFile))
+	for name, fixes := range byFile {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		if patched[name], err = applyFixes(data, fixes); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return patched, nil
+}
+
+func applyFixes(data []byte, fixes []Fix) ([]byte, error) {
+	lines := []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	offset := func(line, col int) (int, error) {
+		if line < 1 || line > len(lines) || col < 1 {
+			return 0, fmt.Errorf("%d:%d is outside of the file", line, col)
+		}
+		end := len(data)
+		if line < len(lines) {
+			end = lines[line] - 1
+		}
+		o := lines[line-1] + col - 1
+		if o > end {
+			return 0, fmt.Errorf("%d:%d is outside of the file", line, col)
+		}
+		return o, nil
+	}
+	fixes = append([]Fix(nil), fixes...)
+	sort.SliceStable(fixes, func(i, j int) bool {
+		a, b := fixes[i].Span(), fixes[j].Span()
+		if a.Start != b.Start {
+			return a.Start.Before(b.Start)
+		}
+		return a.End.Before(b.End)
+	})
+	type edit struct {
+		start, end  int
+		replacement string
+	}
+	edits := make([]edit, 0, len(fixes))
+	for i, f := range fixes {
+		span := f.Span()
+		if span.End.Before(span.Start) {
+			return nil, fmt.Errorf("fix %d:%d-%d:%d ends before it starts", f.StartLine, f.StartCol, f.EndLine, f.EndCol)
+		}
+		if i > 0 {
+			prev := fixes[i-1]
+			if f == prev {
+				continue
+			}
+			if prev.Span().Overlaps(span) {
+				return nil, fmt.Errorf("fixes at %d:%d and %d:%d overlap", prev.StartLine, prev.StartCol, f.StartLine, f.StartCol)
+			}
+		}
+		start, err := offset(f.StartLine, f.StartCol)
+		if err != nil {
+			return nil, err
+		}
+		end, err := offset(f.EndLine, f.EndCol)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit{start, end, f.Replacement})
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		b.Write(data[last:e.start])
+		b.WriteString(e.replacement)
+		last = e.end
+	}
+	b.Write(data[last:])
+	return b.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package oututil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFixIts(t *testing.T) {
+	const log = `main.c:3:10: error: expected ';' after expression
+  x = f()
+         ^
+         ;
+fix-it:"main.c":{3:10-3:10}:";"
+main.c:5:3: warning: use of undeclared identifier 'pritnf'; did you mean 'printf'?
+fix-it:"main.c":{5:3-5:9}:"printf"
+main.c:7:1: note: tab\t"quoted"
+fix-it:"main.c":{7:1-7:1}:"\t\"x\"\\\101"
+`
+	errs, err := ParseReader(strings.NewReader(log), FixIts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Fix{
+		{{File: "main.c", StartLine: 3, StartCol: 10, EndLine: 3, EndCol: 10, Replacement: ";"}},
+		{{File: "main.c", StartLine: 5, StartCol: 3, EndLine: 5, EndCol: 9, Replacement: "printf"}},
+		{{File: "main.c", StartLine: 7, StartCol: 1, EndLine: 7, EndCol: 1, Replacement: "\t\"x\"\\A"}},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("was expecting %d errors got %d instead", len(want), len(errs))
+	}
+	for i, e := range errs {
+		if !reflect.DeepEqual(e.Fixes, want[i]) {
+			t.Logf("%s: was expecting %+v got %+v instead", e.Message, want[i], e.Fixes)
+			t.Fail()
+		}
+	}
+	if errs, _ := ParseReader(strings.NewReader(log)); len(errs[0].Fixes) != 0 {
+		t.Log("was expecting fix-its to be ignored without FixIts")
+		t.Fail()
+	}
+}
+
+func TestRustcFixes(t *testing.T) {
+	const log = `{"$message_type":"diagnostic","message":"unused variable: ` + "`ünused`" + `","code":{"code":"unused_variables","explanation":null},"level":"warning","spans":[{"file_name":"src/main.rs","byte_start":20,"byte_end":27,"line_start":2,"line_end":2,"column_start":9,"column_end":15,"is_primary":true,"text":[{"text":"    let ünused = 1;","highlight_start":9,"highlight_end":15}],"label":null,"suggested_replacement":null,"suggestion_applicability":null,"expansion":null}],"children":[{"message":"if this is intentional, prefix it with an underscore","code":null,"level":"help","spans":[{"file_name":"src/main.rs","byte_start":20,"byte_end":27,"line_start":2,"line_end":2,"column_start":9,"column_end":15,"is_primary":true,"text":[{"text":"    let ünused = 1;","highlight_start":9,"highlight_end":15}],"label":null,"suggested_replacement":"_ünused","suggestion_applicability":"MachineApplicable","expansion":null}],"children":[],"rendered":null},{"message":"or remove it","code":null,"level":"help","spans":[{"file_name":"src/main.rs","byte_start":16,"byte_end":31,"line_start":2,"line_end":2,"column_start":5,"column_end":19,"is_primary":true,"text":[],"label":null,"suggested_replacement":"","suggestion_applicability":"MaybeIncorrect","expansion":null}],"children":[],"rendered":null}],"rendered":""}`
+	errs, err := ParseRustcJSON(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("was expecting 1 error got %d instead", len(errs))
+	}
+	want := []Fix{{File: "src/main.rs", StartLine: 2, StartCol: 9, EndLine: 2, EndCol: 16, Replacement: "_ünused"}}
+	if !reflect.DeepEqual(errs[0].Fixes, want) {
+		t.Logf("was expecting %+v got %+v instead", want, errs[0].Fixes)
+		t.Fail()
+	}
+	fsys := fstest.MapFS{"src/main.rs": {Data: []byte("fn main() {\n    let ünused = 1;\n}\n")}}
+	patched, err := ApplyFixes(fsys, errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(patched["src/main.rs"]); got != "fn main() {\n    let _ünused = 1;\n}\n" {
+		t.Logf("was expecting the variable to be renamed got %q instead", got)
+		t.Fail()
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.c": {Data: []byte("int main() {\n  x = f()\n  pritnf(\"hi\");\n}")},
+	}
+	semicolon := Fix{File: "main.c", StartLine: 2, StartCol: 10, EndLine: 2, EndCol: 10, Replacement: ";"}
+	printf := Fix{File: "./main.c", StartLine: 3, StartCol: 3, EndLine: 3, EndCol: 9, Replacement: "printf"}
+	tests := []struct {
+		name  string
+		fixes []Fix
+		want  string
+		err   bool
+	}{
+		{name: "insert and replace", fixes: []Fix{printf, semicolon}, want: "int main() {\n  x = f();\n  printf(\"hi\");\n}"},
+		{name: "repeated", fixes: []Fix{semicolon, semicolon}, want: "int main() {\n  x = f();\n  pritnf(\"hi\");\n}"},
+		{name: "end of file", fixes: []Fix{{File: "main.c", StartLine: 4, StartCol: 2, EndLine: 4, EndCol: 2, Replacement: "\n"}}, want: "int main() {\n  x = f()\n  pritnf(\"hi\");\n}\n"},
+		{name: "overlapping", fixes: []Fix{printf, {File: "main.c", StartLine: 3, StartCol: 5, EndLine: 3, EndCol: 12, Replacement: "x"}}, err: true},
+		{name: "same insertion point", fixes: []Fix{semicolon, {File: "main.c", StartLine: 2, StartCol: 10, EndLine: 2, EndCol: 10, Replacement: ","}}, err: true},
+		{name: "outside", fixes: []Fix{{File: "main.c", StartLine: 2, StartCol: 20, EndLine: 2, EndCol: 20}}, err: true},
+		{name: "missing file", fixes: []Fix{{File: "x.c", StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1}}, err: true},
+	}
+	for _, test := range tests {
+		patched, err := ApplyFixes(fsys, []SourceError{{Fixes: test.fixes}})
+		if (err != nil) != test.err {
+			t.Logf("%s: was expecting an error %v got %v", test.name, test.err, err)
+			t.Fail()
+			continue
+		}
+		if err == nil && string(patched["main.c"]) != test.want {
+			t.Logf("%s: was expecting %q got %q instead", test.name, test.want, patched["main.c"])
+			t.Fail()
+		}
+	}
+}
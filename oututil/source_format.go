@@ -0,0 +1,171 @@
+package oututil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Format is a single line diagnostic format described by a regular
+// expression. The expression names its captures file, line, col,
+// endline, endcol, offset, severity, code and message; everything but
+// file is optional.
+type Format struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// NewFormat compiles pattern into a Format.
+func NewFormat(pattern string) (Format, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Format{}, err
+	}
+	if re.SubexpIndex("file") < 0 {
+		return Format{}, fmt.Errorf("format %q doesn't capture a file", pattern)
+	}
+	return Format{re: re}, nil
+}
+
+// MustFormat is like NewFormat but panics if pattern is invalid.
+func MustFormat(pattern string) Format {
+	f, err := NewFormat(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Name returns the name the Format was registered with.
+func (f Format) Name() string { return f.name }
+
+// Match matches f against a single line of a log. The Spans of the
+// SourceError are in line.
+func (f Format) Match(line string) (SourceError, bool) {
+	idx := f.re.FindStringSubmatchIndex(line)
+	if idx == nil {
+		return SourceError{}, false
+	}
+	e := fromSubmatch(f.re, submatches(line, idx))
+	e.Tool = f.name
+	e.Spans = spansOf(f.re, line, idx, e.Message)
+	return e, true
+}
+
+// submatches returns the strings of the submatch indexes idx of s.
+func submatches(s string, idx []int) []string {
+	m := make([]string, len(idx)/2)
+	for i := range m {
+		if idx[2*i] >= 0 {
+			m[i] = s[idx[2*i]:idx[2*i+1]]
+		}
+	}
+	return m
+}
+
+var (
+	formatsMu sync.RWMutex
+	// formats are ordered by priority, the ones registered by users
+	// come before the builtin ones.
+	formats []Format
+	// userFormats is the number of formats registered by users.
+	userFormats int
+)
+
+// RegisterFormat registers f under name. Formats registered by users
+// are tried in registration order before the builtin ones, registering
+// a name again replaces the Format in place.
+func RegisterFormat(name string, f Format) {
+	f.name = name
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i := range formats {
+		if formats[i].name == name {
+			formats[i] = f
+			return
+		}
+	}
+	formats = append(formats[:userFormats], append([]Format{f}, formats[userFormats:]...)...)
+	userFormats++
+}
+
+// RegisteredFormats returns the registered formats in the order they
+// are tried.
+func RegisteredFormats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]Format(nil), formats...)
+}
+
+// LookupFormat returns the Format registered under name.
+func LookupFormat(name string) (Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for _, f := range formats {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// ParseWith parses input line by line trying formats in order, none of
+// the builtin multi-line parsers are used.
+func ParseWith(formats []Format, input string) []SourceError {
+	errors, _ := ParseReader(strings.NewReader(input), withFormats(formats))
+	return errors
+}
+
+// registerBuiltin appends f to the builtin formats.
+func registerBuiltin(name string, pattern string) {
+	f := MustFormat(pattern)
+	f.name = name
+	formats = append(formats, f)
+}
+
+// filePattern matches a file name with an extension, optionally
+// prefixed by a Windows drive letter.
+const filePattern = `(?P<file>(?:[A-Za-z]:)?[^\s:()"]*[[:alnum:]]\.[[:alnum:]]+)`
+
+// msvcTail matches the severity, code, message and msbuild project
+// suffix of MSVC diagnostics.
+const msvcTail = `(?P<severity>fatal error|error|warning|note) (?P<code>[A-Z]+[0-9]+): ` +
+	`(?P<message>.*?)(?: \[(?P<project>[^\[\]]*proj)\])?$`
+
+func init() {
+	// {file}:[{line},{col}] {message}
+	registerBuiltin("maven", filePattern+`:\[(?P<line>[0-9]+)(?:,(?P<col>[0-9]+))?\] (?P<message>.*)`)
+	// {file}({line},{col}): {severity} TS{code}: {message}
+	// {file}:{line}:{col} - {severity} TS{code}: {message}
+	registerBuiltin("tsc", filePattern+`(?:\((?P<line>[0-9]+),(?P<col>[0-9]+)\): |:(?P<line>[0-9]+):(?P<col>[0-9]+) - )`+
+		`(?P<severity>error|warning) (?P<code>TS[0-9]+): (?P<message>.*)`)
+	// {file}({line},{col}): {severity} {code}: {message} [{project}]
+	// {file}({line},{col},{endline},{endcol}): {severity} {code}: {message} [{project}]
+	registerBuiltin("msvc", filePattern+`\((?P<line>[0-9]+)(?:,(?P<col>[0-9]+)(?:,(?P<endline>[0-9]+),(?P<endcol>[0-9]+))?)?\): `+msvcTail)
+	// {file} : {severity} {code}: {message} [{project}]
+	registerBuiltin("msvc-link", `(?P<file>[^\s:()"]+) : `+msvcTail)
+	// {file}:{line}: {message}
+	// {file}:{line}:{col}: {message}
+	// {file}:{line}:{col}-{endcol}: {message}
+	// {file}:{line}:{col}-{endline}:{endcol}: {message}
+	// {file}:{line}:{col}:{{line}:{col}-{endline}:{endcol}}...: {message}
+	registerBuiltin("gcc", filePattern+`:(?P<line>[0-9]+):`+
+		`(?:(?P<col>[0-9]+)(?:-(?:(?P<endline>[0-9]+):)?(?P<endcol>[0-9]+))?:)?`+
+		`(?:\{[0-9]+:[0-9]+-(?P<rangeline>[0-9]+):(?P<rangecol>[0-9]+)\}(?:\{[^}]*\})*:)?`+
+		`(?: (?P<message>.*))?`)
+	// {BUILD file}:{line}:{col}: {message}
+	registerBuiltin("bazel", `^(?P<file>(?:[A-Za-z]:)?(?:[^\s:()"]*/)?(?:BUILD|BUCK|TARGETS|WORKSPACE)):`+
+		`(?P<line>[0-9]+):(?P<col>[0-9]+): (?P<message>.*)`)
+	// {file}: offset {offset}: {message}
+	// {file}: at byte offset {offset}: {message}
+	registerBuiltin("offset", filePattern+`: (?:at )?(?:byte )?offset (?P<offset>[0-9]+): (?P<message>.*)`)
+	// {file}({line}): {message}
+	// {file}({line},{col}): {message}
+	// {file}({line},{col}-{endcol}): {message}
+	// {file}({line},{col},{endline},{endcol}): {message}
+	// {file}({line},{col}-{endline},{endcol}): {message}
+	registerBuiltin("paren", filePattern+`\((?P<line>[0-9]+)`+
+		`(?:,(?P<col>[0-9]+)(?:[-,](?:(?P<endline>[0-9]+),)?(?P<endcol>[0-9]+))?)?\):?`+
+		`(?: (?P<message>.*))?`)
+}
@@ -0,0 +1,77 @@
+package oututil
+
+import "testing"
+
+func TestNewFormat(t *testing.T) {
+	if _, err := NewFormat(`(?P<line>[0-9]+): (?P<message>.*)`); err == nil {
+		t.Log("was expecting an error for a format without a file")
+		t.Fail()
+	}
+	if _, err := NewFormat(`(?P<file>[`); err == nil {
+		t.Log("was expecting an error for an invalid expression")
+		t.Fail()
+	}
+}
+
+func TestParseWith(t *testing.T) {
+	inhouse := MustFormat(`^!! (?P<severity>error|warning) in (?P<file>\S+) at line (?P<line>[0-9]+)(?:, column (?P<col>[0-9]+))?: (?P<message>.*)$`)
+	log := `!! error in build.cfg at line 3, column 7: unknown key "nmae"
+!! warning in build.cfg at line 9: deprecated option
+a.go:1:2: not matched by the in-house format`
+	checkSourceErrors(t, []SourceError{
+		{File: "build.cfg", Line: 3, Column: 7, Message: `unknown key "nmae"`, Severity: SeverityError},
+		{File: "build.cfg", Line: 9, Column: NoColumn, Message: "deprecated option", Severity: SeverityWarning},
+	}, ParseWith([]Format{inhouse}, log))
+}
+
+// restoreFormats restores the registered formats when the test ends.
+func restoreFormats(t *testing.T) {
+	formatsMu.Lock()
+	saved, savedUser := append([]Format(nil), formats...), userFormats
+	savedBlocks, savedUserBlocks := append([]BlockFormat(nil), blockFormats...), userBlockFormats
+	formatsMu.Unlock()
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		defer formatsMu.Unlock()
+		formats, userFormats = saved, savedUser
+		blockFormats, userBlockFormats = savedBlocks, savedUserBlocks
+	})
+}
+
+func TestRegisterFormat(t *testing.T) {
+	restoreFormats(t)
+	RegisterFormat("test-inhouse", MustFormat(`^@@ (?P<file>\S+)#(?P<line>[0-9]+) (?P<message>.*)$`))
+	f, ok := LookupFormat("test-inhouse")
+	if !ok || f.Name() != "test-inhouse" {
+		t.Fatal("registered format wasn't found")
+	}
+	registered := RegisteredFormats()
+	if registered[0].Name() != "test-inhouse" {
+		t.Logf("was expecting user formats to be tried first, got %q", registered[0].Name())
+		t.Fail()
+	}
+	if registered[len(registered)-1].Name() != "paren" {
+		t.Logf("was expecting builtin formats to be tried last, got %q", registered[len(registered)-1].Name())
+		t.Fail()
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "x.cfg", Line: 4, Column: NoColumn, Message: "bad value"},
+	}, ScanSourceError("@@ x.cfg#4 bad value"))
+
+	RegisterFormat("test-inhouse", MustFormat(`^@@ (?P<file>\S+)@(?P<line>[0-9]+) (?P<message>.*)$`))
+	if n := len(RegisteredFormats()); n != len(registered) {
+		t.Logf("registering a name twice should replace it, have %d formats instead of %d", n, len(registered))
+		t.Fail()
+	}
+}
+
+func TestRegisterFormatRestored(t *testing.T) {
+	t.Run("register", func(t *testing.T) {
+		restoreFormats(t)
+		RegisterFormat("test-restored", MustFormat(`^%% (?P<file>\S+) (?P<message>.*)$`))
+	})
+	if _, ok := LookupFormat("test-restored"); ok {
+		t.Log("was expecting the format to be unregistered after the test")
+		t.Fail()
+	}
+}
@@ -0,0 +1,55 @@
+//go:build go1.18
+// +build go1.18
+
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParseReader checks that the parser doesn't panic and that what it
+// reports about the log is in the log. The seeds are in testdata/fuzz.
+func FuzzParseReader(f *testing.F) {
+	f.Add("main.c:3:5: error: expected ';' before '}' token\n")
+	f.Add("error[E0308]: mismatched types\n --> src/main.rs:4:18\n")
+	f.Add("\"C:\\Program Files\\a.c\"(3): error C2143: syntax error\n")
+	f.Add("Traceback (most recent call last):\n  File \"a.py\", line 3, in <module>\nValueError: x\n")
+	f.Add("panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x1d\n")
+	f.Fuzz(func(t *testing.T, log string) {
+		for _, opts := range [][]Option{
+			nil,
+			{FoldNotes(), Snippets(), CaretColumns(), FixIts(), Xcode()},
+			{MaxLineLength(16), KeepDuplicates()},
+		} {
+			errs, _ := ParseReader(strings.NewReader(log), opts...)
+			for _, e := range errs {
+				checkFuzzedError(t, log, e)
+			}
+		}
+	})
+}
+
+func checkFuzzedError(t *testing.T, log string, e SourceError) {
+	t.Helper()
+	if e.LogOffset < 0 || e.LogOffset > int64(len(log)) {
+		t.Fatalf("offset %d is outside of the %d bytes of the log", e.LogOffset, len(log))
+	}
+	first := strings.SplitN(e.Raw, "\n", 2)[0]
+	if rest := log[e.LogOffset:]; e.Raw != "" && !strings.HasPrefix(strings.TrimPrefix(rest, byteOrderMark), first) {
+		t.Fatalf("offset %d doesn't point at %q", e.LogOffset, first)
+	}
+	if e.Spans.IsEmpty() {
+		return
+	}
+	lines := strings.Split(e.Raw, "\n")
+	if e.Spans.Line < 0 || e.Spans.Line >= len(lines) {
+		t.Fatalf("spans of line %d of %q", e.Spans.Line, e.Raw)
+	}
+	line := lines[e.Spans.Line]
+	for _, r := range []ByteRange{e.Spans.File, e.Spans.Position, e.Spans.Message} {
+		if !r.IsEmpty() && (r.Start < 0 || r.End > len(line)) {
+			t.Fatalf("span %v is outside of %q", r, line)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// warningFlags matches the options gcc and clang end their warnings
+// with, [-Wunused-variable], [-Werror=unused-variable] or
+// [-Werror,-Wunused-variable].
+var warningFlags = regexp.MustCompile(`\s\[(-W[^\s\],]*(?:,-W[^\s\],]*)*)\]$`)
+
+// markWarningFlags sets the Flags of the diagnostics of gcc and clang
+// and the WarningAsError of the ones -Werror promoted. The warning
+// option becomes the Code of errors without one, and the options are
+// trimmed from the Message unless keep is set, see KeepLinterSuffix.
+func markWarningFlags(e SourceError, keep bool) SourceError {
+	if e.Tool != "gcc" {
+		return e
+	}
+	m := warningFlags.FindStringSubmatchIndex(e.Message)
+	if m == nil {
+		return e
+	}
+	e.Flags = strings.Split(e.Message[m[2]:m[3]], ",")
+	if !keep {
+		e.Message = e.Message[:m[0]]
+	}
+	var code string
+	promoted := false
+	for _, f := range e.Flags {
+		switch {
+		case f == "-Werror":
+			promoted = true
+		case strings.HasPrefix(f, "-Werror="):
+			promoted = true
+			code = "-W" + strings.TrimPrefix(f, "-Werror=")
+		case code == "":
+			code = f
+		}
+	}
+	if promoted && e.Severity == SeverityError {
+		e.WarningAsError = true
+	}
+	if e.Code == "" {
+		e.Code = code
+	}
+	return e
+}
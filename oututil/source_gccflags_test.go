@@ -0,0 +1,65 @@
+package oututil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWarningFlags(t *testing.T) {
+	tests := []struct {
+		line     string
+		message  string
+		severity Severity
+		flags    []string
+		code     string
+		promoted bool
+	}{
+		{
+			line:     "foo.c:10:5: error: unused variable 'x' [-Werror,-Wunused-variable]",
+			message:  "unused variable 'x'",
+			severity: SeverityError, flags: []string{"-Werror", "-Wunused-variable"}, code: "-Wunused-variable", promoted: true,
+		},
+		{
+			line:     "foo.c:10:5: error: unused variable 'x' [-Werror=unused-variable]",
+			message:  "unused variable 'x'",
+			severity: SeverityError, flags: []string{"-Werror=unused-variable"}, code: "-Wunused-variable", promoted: true,
+		},
+		{
+			line:     "foo.c:4:12: warning: format specifies type 'int' [-Wformat]",
+			message:  "format specifies type 'int'",
+			severity: SeverityWarning, flags: []string{"-Wformat"}, code: "-Wformat",
+		},
+		{
+			line:     "foo.c:7:1: error: expected ';' before '}' token",
+			message:  "expected ';' before '}' token",
+			severity: SeverityError,
+		},
+		{
+			line:     "foo.c:7:1: warning: 'x' is deprecated [enabled by default]",
+			message:  "'x' is deprecated [enabled by default]",
+			severity: SeverityWarning,
+		},
+	}
+	for _, test := range tests {
+		errs := ScanSourceError(test.line)
+		if len(errs) != 1 {
+			t.Fatalf("%s: was expecting 1 error got %d", test.line, len(errs))
+		}
+		e := errs[0]
+		if e.Message != test.message {
+			t.Logf("%s: was expecting the message %q got %q", test.line, test.message, e.Message)
+			t.Fail()
+		}
+		if e.Severity != test.severity || !reflect.DeepEqual(e.Flags, test.flags) || e.Code != test.code || e.WarningAsError != test.promoted {
+			t.Logf("%s: was expecting %s %q %v promoted=%v got %s %q %v promoted=%v", test.line,
+				test.severity, test.code, test.flags, test.promoted, e.Severity, e.Code, e.Flags, e.WarningAsError)
+			t.Fail()
+		}
+	}
+
+	errs := ScanSourceError("foo.c:4:12: warning: format specifies type 'int' [-Wformat]", KeepLinterSuffix())
+	if len(errs) != 1 || errs[0].Message != "format specifies type 'int' [-Wformat]" || errs[0].Code != "-Wformat" {
+		t.Logf("was expecting KeepLinterSuffix to keep the warning option in the message got %+v", errs)
+		t.Fail()
+	}
+}
@@ -0,0 +1,96 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// goPanic matches the first line of a Go panic or runtime fatal error.
+	goPanic = regexp.MustCompile(`^(?:panic|fatal error): (?P<message>.*)$`)
+	// goGoroutine matches the header of a goroutine's stack,
+	//
+	//	goroutine 1 [running]:
+	goGoroutine = regexp.MustCompile(`^goroutine [0-9]+ \[.*\]:$`)
+	// goFrame matches the location line of a stack frame,
+	//
+	//	/home/ci/src/pkg/foo.go:88 +0x1b
+	goFrame = regexp.MustCompile(`^\t(?P<file>\S.*\.go):(?P<line>[0-9]+)(?: \+0x[0-9a-f]+)?$`)
+	// goCreatedBy matches the function that started a goroutine,
+	//
+	//	created by main.main in goroutine 1
+	goCreatedBy = regexp.MustCompile(`^created by (?P<function>\S+)(?: in goroutine [0-9]+)?$`)
+)
+
+// goPanicParser turns the stack traces of Go panics into source errors.
+// The innermost frame of each goroutine is an error and the frames that
+// called it are notes, all of them carry the panic message.
+type goPanicParser struct {
+	message  string
+	inStack  bool
+	function string
+	frames   int
+}
+
+func (p *goPanicParser) parse(line string, emit func(SourceError)) bool {
+	if m := goPanic.FindStringSubmatch(line); m != nil {
+		p.message = m[1]
+		return true
+	}
+	if goGoroutine.MatchString(line) {
+		p.inStack, p.function, p.frames = true, "", 0
+		return true
+	}
+	if m := goFrame.FindStringSubmatch(line); m != nil {
+		e := newSourceError(m[1], m[2], "", p.message)
+		e.Severity = SeverityNote
+		if p.frames == 0 {
+			e.Severity = SeverityError
+		}
+		e.Function = p.function
+		e.Kind = KindStackFrame
+		p.function = ""
+		p.frames++
+		e.Tool = "go"
+		emit(e)
+		return true
+	}
+	if !p.inStack {
+		return false
+	}
+	if strings.TrimSpace(line) == "" {
+		p.inStack = false
+		return true
+	}
+	if m := goCreatedBy.FindStringSubmatch(line); m != nil {
+		p.function = m[1]
+		return true
+	}
+	if line[0] != ' ' && line[0] != '\t' && strings.HasSuffix(line, ")") {
+		p.function = trimArguments(line)
+		return true
+	}
+	p.inStack = false
+	p.message = ""
+	return false
+}
+
+func (p *goPanicParser) flush(emit func(SourceError)) {}
+
+// trimArguments strips the argument list from a function line of a
+// stack trace, keeping the parentheses of methods like pkg.(*T).M.
+func trimArguments(line string) string {
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
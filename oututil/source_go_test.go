@@ -0,0 +1,31 @@
+package oututil
+
+import "testing"
+
+const goTestLog = `--- FAIL: TestAdd (0.00s)
+    main_test.go:27: got 3, want 4
+    --- FAIL: TestAdd/negative (0.00s)
+        main_test.go:31: got -1, want 1
+panic: runtime error: index out of range [3] with length 3 [recovered]
+
+goroutine 7 [running]:
+testing.tRunner.func1.2({0x5a2f40, 0xc000018150})
+	/usr/local/go/src/testing/testing.go:1545 +0x238
+sevki.org/x/pkg.(*parser).line(0xc00007e000, {0x5b1e1a, 0x3})
+	/home/ci/src/pkg/foo.go:88 +0x1b
+created by testing.(*T).Run in goroutine 1
+	/usr/local/go/src/testing/testing.go:1648 +0x3ad
+exit status 2
+FAIL	sevki.org/x/pkg	0.005s
+`
+
+func TestGoTest(t *testing.T) {
+	const msg = "runtime error: index out of range [3] with length 3 [recovered]"
+	checkSourceErrors(t, []SourceError{
+		{File: "main_test.go", Line: 27, Column: NoColumn, Message: "got 3, want 4"},
+		{File: "main_test.go", Line: 31, Column: NoColumn, Message: "got -1, want 1"},
+		{File: "/usr/local/go/src/testing/testing.go", Line: 1545, Column: NoColumn, Message: msg, Function: "testing.tRunner.func1.2", Kind: KindStackFrame, Severity: SeverityError},
+		{File: "/home/ci/src/pkg/foo.go", Line: 88, Column: NoColumn, Message: msg, Function: "sevki.org/x/pkg.(*parser).line", Kind: KindStackFrame, Severity: SeverityNote},
+		{File: "/usr/local/go/src/testing/testing.go", Line: 1648, Column: NoColumn, Message: msg, Function: "testing.(*T).Run", Kind: KindStackFrame, Severity: SeverityNote},
+	}, ScanSourceError(goTestLog))
+}
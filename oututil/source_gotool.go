@@ -0,0 +1,65 @@
+package oututil
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrIncomplete is returned, along with the errors, when a Go tool
+// stopped reporting them with "too many errors".
+var ErrIncomplete = errors.New("the tool reported too many errors")
+
+var (
+	// goPackageHeader matches the lines go build and go vet print
+	// before the diagnostics of a package,
+	//
+	//	# sevki.org/x/oututil
+	//	# sevki.org/x/oututil [sevki.org/x/oututil.test]
+	goPackageHeader = regexp.MustCompile(`^# ([^\s\["]+)(?: \[[^\]]+\])?$`)
+	// goTooManyErrors matches the line the go compiler and go/types
+	// end their diagnostics with after the first ten,
+	//
+	//	./main.go:12:2: too many errors
+	goTooManyErrors = regexp.MustCompile(`^(?:\S+:[0-9]+(?::[0-9]+)?: )?too many errors$`)
+)
+
+// goTool records the package headers and the too many errors line of
+// Go tools and reports whether line was one of them.
+func (s *sourceScanner) goTool(line string) bool {
+	if line == "" || (line[0] != '#' && !strings.HasSuffix(line, "too many errors")) {
+		return false
+	}
+	if m := goPackageHeader.FindStringSubmatch(line); m != nil {
+		s.goPackage = m[1]
+		return true
+	}
+	if goTooManyErrors.MatchString(line) {
+		s.incomplete = true
+		return true
+	}
+	return false
+}
+
+// ParseGofmtList parses the output of gofmt -l and goimports -l, the
+// names of the files that aren't formatted, as errors without a line.
+func ParseGofmtList(r io.Reader) ([]SourceError, error) {
+	var errs []SourceError
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		file := strings.TrimSpace(sc.Text())
+		if file == "" {
+			continue
+		}
+		errs = append(errs, SourceError{
+			File:     file,
+			Column:   NoColumn,
+			Message:  "file not formatted",
+			Severity: SeverityError,
+			Tool:     "gofmt",
+		})
+	}
+	return errs, sc.Err()
+}
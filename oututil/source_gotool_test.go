@@ -0,0 +1,63 @@
+package oututil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goBuildLog = `# sevki.org/x/oututil
+./a.go:3:2: undefined: foo
+./a.go:4:2: undefined: bar
+./a.go:5:2: too many errors
+# sevki.org/x/reconcile [sevki.org/x/reconcile.test]
+./b_test.go:7:9: cannot use x (variable of type int) as string value in argument to f
+`
+
+func TestGoPackages(t *testing.T) {
+	for _, opts := range [][]Option{nil, {Parallel()}} {
+		errs, err := ParseReader(strings.NewReader(goBuildLog), opts...)
+		if err != ErrIncomplete {
+			t.Logf("got %v, expected ErrIncomplete", err)
+			t.Fail()
+		}
+		var got []string
+		for _, e := range errs {
+			got = append(got, e.File+" "+e.Package)
+		}
+		want := []string{
+			"./a.go sevki.org/x/oututil",
+			"./a.go sevki.org/x/oututil",
+			"./b_test.go sevki.org/x/reconcile",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("got %q, expected %q", got, want)
+			t.Fail()
+		}
+	}
+	errs, err := ParseReader(strings.NewReader("# 1 \"lex.l\"\nmain.c:2:1: error: x\n"))
+	if err != nil || len(errs) != 1 || errs[0].Package != "" {
+		t.Logf("got %+v, %v for a line that isn't a package header", errs, err)
+		t.Fail()
+	}
+	if _, err := ParseReader(strings.NewReader(goBuildLog), MaxErrors(1)); err != ErrTruncated {
+		t.Logf("got %v, expected MaxErrors to take precedence", err)
+		t.Fail()
+	}
+}
+
+func TestParseGofmtList(t *testing.T) {
+	errs, err := ParseGofmtList(strings.NewReader("a.go\n\ncmd/main.go\n"))
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	want := []SourceError{
+		{File: "a.go", Column: NoColumn, Message: "file not formatted", Severity: SeverityError, Tool: "gofmt"},
+		{File: "cmd/main.go", Column: NoColumn, Message: "file not formatted", Severity: SeverityError, Tool: "gofmt"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Logf("got %+v, expected %+v", errs, want)
+		t.Fail()
+	}
+}
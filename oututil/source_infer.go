@@ -0,0 +1,74 @@
+package oututil
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SeverityRule gives the diagnostics whose message has Keyword, a word
+// matched case insensitively, the Severity.
+type SeverityRule struct {
+	Keyword  string
+	Severity Severity
+}
+
+// DefaultSeverityRules are the rules InferSeverity uses without any.
+var DefaultSeverityRules = []SeverityRule{
+	{"fatal", SeverityError},
+	{"deprecated", SeverityWarning},
+	{"unused", SeverityWarning},
+}
+
+// InferSeverity guesses the severity of diagnostics the tool didn't
+// print one for from the keywords of their message, the first rule
+// that matches wins and the SourceError is marked SeverityInferred.
+// Without rules it uses DefaultSeverityRules; to extend them append to
+// a copy.
+func InferSeverity(rules ...SeverityRule) Option {
+	if len(rules) == 0 {
+		rules = DefaultSeverityRules
+	}
+	return func(o *options) { o.severityRules = rules }
+}
+
+// inferSeverity applies rules to e if it has no severity.
+func inferSeverity(e SourceError, rules []SeverityRule) SourceError {
+	if e.Severity != SeverityUnknown || e.Kind != KindDiagnostic {
+		return e
+	}
+	message := strings.ToLower(e.Message)
+	for _, r := range rules {
+		if hasWord(message, strings.ToLower(r.Keyword)) {
+			e.Severity = r.Severity
+			e.SeverityInferred = true
+			break
+		}
+	}
+	return e
+}
+
+// hasWord reports whether s has word with no letters or digits around
+// it.
+func hasWord(s, word string) bool {
+	if word == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
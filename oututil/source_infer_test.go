@@ -0,0 +1,56 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInferSeverity(t *testing.T) {
+	log := `a.go:1:2: strings.Title is deprecated
+a.go:3:4: warning: x is Deprecated
+a.go:5:6: func f is unused
+a.go:7:8: undeprecated is not a word we know
+a.go:9:10: something went wrong
+a.go:11:12: TODO left in code`
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []Severity
+		inferred []bool
+	}{
+		{
+			name:     "off",
+			expected: []Severity{SeverityUnknown, SeverityWarning, SeverityUnknown, SeverityUnknown, SeverityUnknown, SeverityUnknown},
+			inferred: []bool{false, false, false, false, false, false},
+		},
+		{
+			name:     "defaults",
+			opts:     []Option{InferSeverity()},
+			expected: []Severity{SeverityWarning, SeverityWarning, SeverityWarning, SeverityUnknown, SeverityUnknown, SeverityUnknown},
+			inferred: []bool{true, false, true, false, false, false},
+		},
+		{
+			name:     "extended",
+			opts:     []Option{InferSeverity(append([]SeverityRule{{"todo", SeverityNote}, {"wrong", SeverityError}}, DefaultSeverityRules...)...)},
+			expected: []Severity{SeverityWarning, SeverityWarning, SeverityWarning, SeverityUnknown, SeverityError, SeverityNote},
+			inferred: []bool{true, false, true, false, true, true},
+		},
+	}
+	for _, test := range tests {
+		errs, err := ParseReader(strings.NewReader(log), test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != len(test.expected) {
+			t.Logf("%s: was expecting %d errors got %d instead", test.name, len(test.expected), len(errs))
+			t.Fail()
+			continue
+		}
+		for i, e := range errs {
+			if e.Severity != test.expected[i] || e.SeverityInferred != test.inferred[i] {
+				t.Logf("%s: %q: was expecting %v (inferred %v) got %v (%v) instead", test.name, e.Message, test.expected[i], test.inferred[i], e.Severity, e.SeverityInferred)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build go1.23
+// +build go1.23
+
+package oututil
+
+import (
+	"io"
+	"iter"
+)
+
+// Errors returns an iterator over the source errors in r, parsed as
+// they are read like Scan does. Errors reading r, ErrTruncated and
+// ErrIncomplete are yielded last with a zero SourceError. Breaking out
+// of the loop stops reading r.
+func Errors(r io.Reader, opts ...Option) iter.Seq2[SourceError, error] {
+	return func(yield func(SourceError, error) bool) {
+		stopped := false
+		err := Scan(r, func(e SourceError) bool {
+			stopped = !yield(e, nil)
+			return !stopped
+		}, opts...)
+		if err != nil && !stopped {
+			yield(SourceError{}, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//go:build go1.23
+// +build go1.23
+
+package oututil
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// countingReader counts the reads made on it.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestErrors(t *testing.T) {
+	var files []string
+	for e, err := range Errors(strings.NewReader("a.go:1:2: x\nnoise\nb.go:3: y\n")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, e.File)
+	}
+	if strings.Join(files, ",") != "a.go,b.go" {
+		t.Logf("was expecting a.go and b.go got %q instead", files)
+		t.Fail()
+	}
+
+	// every line is 4KB and the scanner reads at most 4KB at first, so
+	// breaking after the first error has to stop well before the end.
+	line := "a.go:1:2: " + strings.Repeat("x", 4086) + "\n"
+	r := &countingReader{r: strings.NewReader(strings.Repeat(line, 1000))}
+	for range Errors(r) {
+		break
+	}
+	if r.reads > 10 {
+		t.Logf("was expecting reading to stop after the first error, made %d reads", r.reads)
+		t.Fail()
+	}
+
+	var last error
+	n := 0
+	for _, err := range Errors(strings.NewReader("a.go:1: x\nb.go:2: y\nc.go:3: z\n"), MaxErrors(2)) {
+		n++
+		last = err
+	}
+	if n != 3 || !errors.Is(last, ErrTruncated) {
+		t.Logf("was expecting 2 errors and ErrTruncated got %d and %v instead", n, last)
+		t.Fail()
+	}
+}
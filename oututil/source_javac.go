@@ -0,0 +1,20 @@
+package oututil
+
+func init() {
+	registerBuiltinBlock("javac",
+		// src/Main.java:10: error: cannot find symbol
+		BlockLine{RoleHeader, `^` + javaFile + `:(?P<line>[0-9]+): (?P<severity>error|warning): (?P<message>.*)$`},
+		//   symbol:   method foo()
+		//   location: class Main
+		BlockLine{RoleNote, `^\s+(?P<message>(?:symbol|location|required|found|reason): .*)$`},
+		//         foo();
+		//         ^
+		BlockLine{RoleContext, `^\s+\S`},
+		// 2 errors
+		BlockLine{RoleTerminator, `^[0-9]+ (?:error|warning)s?$`},
+	)
+}
+
+// javaFile matches the name of a Java source file, optionally prefixed
+// by a Windows drive letter.
+const javaFile = `(?P<file>(?:[A-Za-z]:)?[^\s:()"]*\.java)`
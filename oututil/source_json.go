@@ -0,0 +1,379 @@
+package oututil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scanJSON calls fn with every JSON object or array in r that starts at
+// the beginning of a line, lines that aren't part of one are skipped.
+// Values can span multiple lines, like the indented output of go vet.
+// Lines starting with a bracket that isn't followed by JSON, like
+// "[ERROR] ...", and values with a string left open at the end of a
+// line, which JSON doesn't allow, aren't values.
+func scanJSON(r io.Reader, fn func([]byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	var (
+		buf   []byte
+		depth int
+		str   bool
+		esc   bool
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if depth == 0 {
+			line = bytes.TrimPrefix(line, []byte("\xef\xbb\xbf"))
+			if !startsJSON(line) {
+				continue
+			}
+			buf, str, esc = buf[:0], false, false
+		}
+		for _, c := range line {
+			switch {
+			case esc:
+				esc = false
+			case str:
+				switch c {
+				case '\\':
+					esc = true
+				case '"':
+					str = false
+				}
+			case c == '"':
+				str = true
+			case c == '{' || c == '[':
+				depth++
+			case c == '}' || c == ']':
+				depth--
+			}
+		}
+		if str {
+			depth, str, esc = 0, false, false
+			continue
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+		if depth <= 0 {
+			depth = 0
+			fn(buf)
+		}
+	}
+	return scanner.Err()
+}
+
+// startsJSON reports whether line starts with an object or an array,
+// a bracket followed by the start of a value, of a key or by the end of
+// the line.
+func startsJSON(line []byte) bool {
+	if len(line) == 0 || line[0] != '{' && line[0] != '[' {
+		return false
+	}
+	rest := bytes.TrimLeft(line[1:], " \t")
+	if len(rest) == 0 {
+		return true
+	}
+	if line[0] == '{' {
+		return rest[0] == '"' || rest[0] == '}'
+	}
+	switch c := rest[0]; {
+	case strings.IndexByte(`{["]`, c) >= 0:
+		return true
+	case c == '-' || c >= '0' && c <= '9':
+		n := bytes.IndexFunc(rest, func(r rune) bool { return !strings.ContainsRune("0123456789.eE+-", r) })
+		return n < 0 || strings.IndexByte(", \t]", rest[n]) >= 0
+	}
+	return bytes.HasPrefix(rest, []byte("true")) || bytes.HasPrefix(rest, []byte("false")) || bytes.HasPrefix(rest, []byte("null"))
+}
+
+// gccDiagnostic is a diagnostic of gcc's -fdiagnostics-format=json.
+type gccDiagnostic struct {
+	Kind      string          `json:"kind"`
+	Message   string          `json:"message"`
+	Option    string          `json:"option"`
+	Locations []gccLocation   `json:"locations"`
+	Children  []gccDiagnostic `json:"children"`
+}
+
+type gccLocation struct {
+	Caret  *gccPosition `json:"caret"`
+	Finish *gccPosition `json:"finish"`
+}
+
+type gccPosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// ParseGCCJSON parses the output of gcc -fdiagnostics-format=json.
+// Child diagnostics become the Related errors of their parent, lines
+// that aren't JSON are skipped.
+func ParseGCCJSON(r io.Reader) ([]SourceError, error) {
+	var errs []SourceError
+	err := scanJSON(r, func(b []byte) {
+		var diags []gccDiagnostic
+		if json.Unmarshal(b, &diags) != nil {
+			return
+		}
+		for _, d := range diags {
+			if e, ok := d.sourceError(); ok {
+				errs = append(errs, e)
+			}
+		}
+	})
+	return errs, err
+}
+
+func (d gccDiagnostic) sourceError() (SourceError, bool) {
+	if len(d.Locations) == 0 || d.Locations[0].Caret == nil {
+		return SourceError{}, false
+	}
+	loc := d.Locations[0]
+	e := SourceError{
+		File:    loc.Caret.File,
+		Line:    loc.Caret.Line,
+		Column:  loc.Caret.Column,
+		Message: d.Message,
+		Code:    d.Option,
+		Tool:    "gcc",
+	}
+	e.Severity, _ = parseSeverity(d.Kind + ":")
+	if e.Column < 1 {
+		e.Column = NoColumn
+	}
+	if f := loc.Finish; f != nil && (f.Line != e.Line || f.Column != e.Column) {
+		// finish is the last column of the range, EndColumn the one
+		// after it
+		e.EndLine, e.EndColumn = f.Line, f.Column+1
+	}
+	for _, child := range d.Children {
+		if c, ok := child.sourceError(); ok {
+			e.Related = append(e.Related, c)
+		}
+	}
+	return e, true
+}
+
+// rustcDiagnostic is a diagnostic of rustc's --error-format=json.
+type rustcDiagnostic struct {
+	Type     string                 `json:"$message_type"`
+	Message  string                 `json:"message"`
+	Level    string                 `json:"level"`
+	Code     *struct{ Code string } `json:"code"`
+	Spans    []rustcSpan            `json:"spans"`
+	Children []rustcDiagnostic      `json:"children"`
+}
+
+type rustcSpan struct {
+	File        string `json:"file_name"`
+	LineStart   int    `json:"line_start"`
+	LineEnd     int    `json:"line_end"`
+	ColumnStart int    `json:"column_start"`
+	ColumnEnd   int    `json:"column_end"`
+	Primary     bool   `json:"is_primary"`
+	Label       string `json:"label"`
+	Text        []struct {
+		Text string `json:"text"`
+	} `json:"text"`
+	Replacement   *string `json:"suggested_replacement"`
+	Applicability string  `json:"suggestion_applicability"`
+}
+
+// fix returns the suggestion of span if rustc says it can be applied
+// mechanically. rustc counts columns in characters, they are converted
+// to bytes with the text of the span.
+func (span rustcSpan) fix() (Fix, bool) {
+	if span.Replacement == nil || span.Applicability != "MachineApplicable" {
+		return Fix{}, false
+	}
+	f := Fix{
+		File:        span.File,
+		StartLine:   span.LineStart,
+		StartCol:    span.ColumnStart,
+		EndLine:     span.LineEnd,
+		EndCol:      span.ColumnEnd,
+		Replacement: *span.Replacement,
+	}
+	if n := len(span.Text); n > 0 {
+		f.StartCol = byteColumn(span.Text[0].Text, f.StartCol)
+		f.EndCol = byteColumn(span.Text[n-1].Text, f.EndCol)
+	}
+	return f, true
+}
+
+// byteColumn converts the character column col of line to bytes.
+func byteColumn(line string, col int) int {
+	for i := range line {
+		if col--; col == 0 {
+			return i + 1
+		}
+	}
+	return len(line) + col
+}
+
+// ParseRustcJSON parses the output of rustc --error-format=json and
+// cargo's --message-format=json. Like the text output, diagnostics
+// without a location, such as "aborting due to previous error", are
+// dropped, secondary spans and child diagnostics become Related notes.
+// The machine applicable suggestions of a diagnostic and its children
+// are its Fixes.
+func ParseRustcJSON(r io.Reader) ([]SourceError, error) {
+	var errs []SourceError
+	err := scanJSON(r, func(b []byte) {
+		// cargo wraps the compiler's diagnostics
+		var cargo struct {
+			Reason  string          `json:"reason"`
+			Message json.RawMessage `json:"message"`
+		}
+		if json.Unmarshal(b, &cargo) != nil {
+			return
+		}
+		if cargo.Reason != "" {
+			if cargo.Reason != "compiler-message" {
+				return
+			}
+			b = cargo.Message
+		}
+		var d rustcDiagnostic
+		if json.Unmarshal(b, &d) != nil || (d.Type != "" && d.Type != "diagnostic") {
+			return
+		}
+		if e, ok := d.sourceError(nil); ok {
+			errs = append(errs, e)
+		}
+	})
+	return errs, err
+}
+
+func (d rustcDiagnostic) sourceError(parent *SourceError) (SourceError, bool) {
+	e := SourceError{Message: d.Message, Tool: "rustc", Column: NoColumn}
+	e.Severity, _ = parseSeverity(d.Level + ":")
+	if d.Code != nil {
+		e.Code = d.Code.Code
+	}
+	var primary *rustcSpan
+	for i := range d.Spans {
+		if d.Spans[i].Primary {
+			primary = &d.Spans[i]
+			break
+		}
+	}
+	switch {
+	case primary != nil:
+		e.File, e.Line, e.Column = primary.File, primary.LineStart, primary.ColumnStart
+		e.EndLine, e.EndColumn = primary.LineEnd, primary.ColumnEnd
+	case parent != nil:
+		// notes without a span, "= note: ..." in the text output
+		e.File, e.Line, e.Column = parent.File, parent.Line, parent.Column
+	default:
+		return SourceError{}, false
+	}
+	for _, span := range d.Spans {
+		if f, ok := span.fix(); ok {
+			e.Fixes = append(e.Fixes, f)
+		}
+		if span.Primary || span.Label == "" {
+			continue
+		}
+		e.Related = append(e.Related, SourceError{
+			File:      span.File,
+			Line:      span.LineStart,
+			Column:    span.ColumnStart,
+			EndLine:   span.LineEnd,
+			EndColumn: span.ColumnEnd,
+			Message:   span.Label,
+			Severity:  SeverityNote,
+			Tool:      "rustc",
+		})
+	}
+	for _, child := range d.Children {
+		if c, ok := child.sourceError(&e); ok {
+			e.Fixes = append(e.Fixes, c.Fixes...)
+			c.Fixes = nil
+			e.Related = append(e.Related, c)
+		}
+	}
+	return e, true
+}
+
+// vetDiagnostic is a diagnostic of go vet -json.
+type vetDiagnostic struct {
+	Posn    string `json:"posn"`
+	End     string `json:"end"`
+	Message string `json:"message"`
+	Related []struct {
+		Posn    string `json:"posn"`
+		Message string `json:"message"`
+	} `json:"related"`
+}
+
+// vetPosn matches the positions of go vet -json, file:line:col.
+var vetPosn = regexp.MustCompile(`^(?P<file>.+?):(?P<line>[0-9]+)(?::(?P<col>[0-9]+))?$`)
+
+// ParseGoVetJSON parses the output of go vet -json. The analyzer that
+// reported a diagnostic is its Code, the "# package" lines in between
+// the JSON objects are skipped.
+func ParseGoVetJSON(r io.Reader) ([]SourceError, error) {
+	var errs []SourceError
+	err := scanJSON(r, func(b []byte) {
+		var pkgs map[string]map[string]json.RawMessage
+		if json.Unmarshal(b, &pkgs) != nil {
+			return
+		}
+		packages := make([]string, 0, len(pkgs))
+		for pkg := range pkgs {
+			packages = append(packages, pkg)
+		}
+		sort.Strings(packages)
+		for _, pkg := range packages {
+			analyzers := pkgs[pkg]
+			names := make([]string, 0, len(analyzers))
+			for name := range analyzers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, analyzer := range names {
+				var diags []vetDiagnostic
+				if json.Unmarshal(analyzers[analyzer], &diags) != nil {
+					continue
+				}
+				for _, d := range diags {
+					e := vetSourceError(d.Posn, d.Message)
+					e.Code = analyzer
+					e.Severity = SeverityError
+					if end := vetSourceError(d.End, ""); end.Line > 0 && (end.Line != e.Line || end.Column != e.Column) {
+						e.EndLine, e.EndColumn = end.Line, end.Column
+					}
+					for _, related := range d.Related {
+						note := vetSourceError(related.Posn, related.Message)
+						note.Severity = SeverityNote
+						e.Related = append(e.Related, note)
+					}
+					errs = append(errs, e)
+				}
+			}
+		}
+	})
+	return errs, err
+}
+
+func vetSourceError(posn, message string) SourceError {
+	e := SourceError{Column: NoColumn, Message: message, Tool: "vet"}
+	m := vetPosn.FindStringSubmatch(strings.TrimSpace(posn))
+	if m == nil {
+		return e
+	}
+	e.File = m[1]
+	e.Line, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		e.Column, _ = strconv.Atoi(m[3])
+	}
+	return e
+}
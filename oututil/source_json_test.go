@@ -0,0 +1,100 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+const gccJSONLog = `make: Entering directory '/src'
+[ERROR] unmatched [ in "main.c
+[1/2] Building main.o
+[{"kind": "warning", "message": "unused variable 'x'", "option": "-Wunused-variable", "children": [], "column-origin": 1, "locations": [{"caret": {"file": "main.c", "line": 3, "display-column": 9, "byte-column": 9, "column": 9}, "finish": {"file": "main.c", "line": 3, "display-column": 9, "byte-column": 9, "column": 9}}], "escape-source": false}, {"kind": "error", "message": "conflicting types for 'f'", "children": [{"kind": "note", "message": "previous declaration of 'f' with type 'int(void)'", "locations": [{"caret": {"file": "f.h", "line": 1, "column": 5}, "finish": {"file": "f.h", "line": 1, "column": 5}}]}], "locations": [{"caret": {"file": "main.c", "line": 7, "column": 6}, "finish": {"file": "main.c", "line": 7, "column": 6}}, {"caret": {"file": "main.c", "line": 7, "column": 8}, "finish": {"file": "main.c", "line": 7, "column": 20}}]}]
+[{"kind": "error", "message": "invalid operands to binary +", "locations": [{"caret": {"file": "main.c", "line": 9, "column": 5}, "finish": {"file": "main.c", "line": 9, "column": 11}}]}]
+make: *** [Makefile:2: main.o] Error 1
+`
+
+func TestParseGCCJSON(t *testing.T) {
+	errs, err := ParseGCCJSON(strings.NewReader(gccJSONLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "main.c", Line: 3, Column: 9, Message: "unused variable 'x'", Code: "-Wunused-variable", Severity: SeverityWarning},
+		{File: "main.c", Line: 7, Column: 6, Message: "conflicting types for 'f'", Severity: SeverityError},
+		// the finish is the last column of the range
+		{File: "main.c", Line: 9, Column: 5, EndLine: 9, EndColumn: 12, Message: "invalid operands to binary +", Severity: SeverityError},
+	}, errs)
+	if len(errs) == 3 {
+		checkSourceErrors(t, []SourceError{
+			{File: "f.h", Line: 1, Column: 5, Message: "previous declaration of 'f' with type 'int(void)'", Severity: SeverityNote},
+		}, errs[1].Related)
+	}
+}
+
+const rustcJSONLog = `   Compiling demo v0.1.0 (/src/demo)
+{"$message_type":"diagnostic","message":"mismatched types","code":{"code":"E0308","explanation":"..."},"level":"error","spans":[{"file_name":"src/main.rs","byte_start":60,"byte_end":63,"line_start":4,"line_end":4,"column_start":18,"column_end":21,"is_primary":true,"text":[],"label":"expected ` + "`i32`" + `, found ` + "`&str`" + `","suggested_replacement":null,"expansion":null},{"file_name":"src/main.rs","byte_start":54,"byte_end":57,"line_start":4,"line_end":4,"column_start":12,"column_end":15,"is_primary":false,"text":[],"label":"expected due to this","suggested_replacement":null,"expansion":null}],"children":[{"message":"expected type ` + "`i32`" + `","code":null,"level":"note","spans":[],"children":[],"rendered":null}],"rendered":"error[E0308]: mismatched types\n"}
+{"$message_type":"diagnostic","message":"aborting due to 1 previous error","code":null,"level":"error","spans":[],"children":[],"rendered":"error: aborting due to 1 previous error\n"}
+{"reason":"compiler-message","package_id":"demo 0.1.0","message":{"message":"unused variable: ` + "`x`" + `","code":{"code":"unused_variables","explanation":null},"level":"warning","spans":[{"file_name":"src/lib.rs","line_start":2,"line_end":2,"column_start":9,"column_end":10,"is_primary":true,"label":null}],"children":[]}}
+{"reason":"build-finished","success":false}
+`
+
+func TestParseRustcJSON(t *testing.T) {
+	errs, err := ParseRustcJSON(strings.NewReader(rustcJSONLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, EndLine: 4, EndColumn: 21, Message: "mismatched types", Code: "E0308", Severity: SeverityError},
+		{File: "src/lib.rs", Line: 2, Column: 9, EndLine: 2, EndColumn: 10, Message: "unused variable: `x`", Code: "unused_variables", Severity: SeverityWarning},
+	}, errs)
+	if len(errs) == 2 {
+		checkSourceErrors(t, []SourceError{
+			{File: "src/main.rs", Line: 4, Column: 12, EndLine: 4, EndColumn: 15, Message: "expected due to this", Severity: SeverityNote},
+			{File: "src/main.rs", Line: 4, Column: 18, Message: "expected type `i32`", Severity: SeverityNote},
+		}, errs[0].Related)
+	}
+}
+
+const goVetJSONLog = `# sevki.org/x/pkg
+{
+	"sevki.org/x/pkg": {
+		"printf": [
+			{
+				"posn": "/src/pkg/a.go:12:2",
+				"message": "fmt.Sprintf format %d has arg s of wrong type string"
+			}
+		],
+		"copylocks": [
+			{
+				"posn": "/src/pkg/b.go:4:9",
+				"end": "/src/pkg/b.go:4:15",
+				"message": "call of f copies lock value: sync.Mutex",
+				"related": [
+					{
+						"posn": "/src/pkg/b.go:2:6",
+						"message": "lock declared here {"
+					}
+				]
+			}
+		]
+	}
+}
+# sevki.org/x/other
+{}
+`
+
+func TestParseGoVetJSON(t *testing.T) {
+	errs, err := ParseGoVetJSON(strings.NewReader(goVetJSONLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "/src/pkg/b.go", Line: 4, Column: 9, EndLine: 4, EndColumn: 15, Message: "call of f copies lock value: sync.Mutex", Code: "copylocks", Severity: SeverityError},
+		{File: "/src/pkg/a.go", Line: 12, Column: 2, Message: "fmt.Sprintf format %d has arg s of wrong type string", Code: "printf", Severity: SeverityError},
+	}, errs)
+	if len(errs) == 2 {
+		checkSourceErrors(t, []SourceError{
+			{File: "/src/pkg/b.go", Line: 2, Column: 6, Message: "lock declared here {", Severity: SeverityNote},
+		}, errs[0].Related)
+	}
+}
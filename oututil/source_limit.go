@@ -0,0 +1,125 @@
+package oututil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// defaultMaxLineLength is the MaxLineLength of logs that don't set one.
+const defaultMaxLineLength = maxLineSize
+
+// MaxLineLength sets the number of bytes of a line that are parsed, 64
+// MiB by default. The expressions of the formats are matched in time
+// linear in the length of a line, but every format is tried at every
+// position of it, so logs from untrusted sources want much less. The rest
+// of longer lines is skipped without being buffered and the errors
+// parsed from them have LineTruncated set. A n of 0 or less disables
+// the limit, lines longer than 64 MiB are then an error.
+func MaxLineLength(n int) Option {
+	return func(o *options) { o.maxLineLength = n }
+}
+
+// MaxInputSize stops parsing after n bytes of input, ParseReader and
+// Scan return ErrInputTooLarge if there were more.
+func MaxInputSize(n int64) Option {
+	return func(o *options) { o.maxInputSize = n }
+}
+
+// ErrInputTooLarge is returned, along with the errors in the first
+// bytes, when the input is larger than MaxInputSize allows.
+var ErrInputTooLarge = errors.New("input is too large")
+
+// limitInput returns r cut at the MaxInputSize of o.
+func limitInput(r io.Reader, o *options) io.Reader {
+	if o.maxInputSize <= 0 {
+		return r
+	}
+	return &inputLimiter{r: r, left: o.maxInputSize}
+}
+
+// inputLimiter reads up to left bytes of r and fails with
+// ErrInputTooLarge if r has more.
+type inputLimiter struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *inputLimiter) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		var b [1]byte
+		for {
+			n, err := l.r.Read(b[:])
+			if n > 0 {
+				return 0, ErrInputTooLarge
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// newLineScanner returns a scanner of the lines of r and the splitter
+// that keeps track of their sizes.
+func newLineScanner(r io.Reader, o *options) (*bufio.Scanner, *lineSplitter) {
+	scanner := bufio.NewScanner(r)
+	size := maxLineSize
+	if o.maxLineLength > 0 {
+		// room for the line ending and the start of the next line
+		size = o.maxLineLength + 4096
+	}
+	scanner.Buffer(make([]byte, 4096), size)
+	lines := &lineSplitter{max: o.maxLineLength}
+	scanner.Split(lines.split)
+	return scanner, lines
+}
+
+// tooLong reports whether the line data starts with is longer than max.
+func (l *lineSplitter) tooLong(data []byte) bool {
+	if l.max <= 0 {
+		return false
+	}
+	if l.cut != nil {
+		return true
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		i = len(data)
+	}
+	if i > 0 && data[i-1] == '\r' {
+		i--
+	}
+	return i > l.max
+}
+
+// splitLong returns the first max bytes of a line that is too long once
+// the rest of it was skipped.
+func (l *lineSplitter) splitLong(data []byte, atEOF bool) (int, []byte, error) {
+	if l.cut == nil {
+		n := l.max
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		l.cut = append([]byte(nil), data[:n]...)
+		l.skipped = 0
+	}
+	advance := len(data)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		advance = i + 1
+	} else if !atEOF {
+		l.skipped += advance
+		return advance, nil, nil
+	}
+	token := l.cut
+	l.size, l.truncated, l.cut = l.skipped+advance, true, nil
+	return advance, token, nil
+}
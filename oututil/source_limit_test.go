@@ -0,0 +1,82 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxLineLength(t *testing.T) {
+	long := "a.go:1:2: " + strings.Repeat("é", 100) + "\r\n"
+	log := "x.go:1: before\n" + long + "b.go:3: short\n" + strings.Repeat("y", 500)
+	errs, err := ParseReader(strings.NewReader(log), MaxLineLength(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("was expecting 3 errors got %d instead", len(errs))
+	}
+	cut := errs[1]
+	if !cut.LineTruncated || errs[0].LineTruncated || errs[2].LineTruncated {
+		t.Logf("only the long line should be truncated, got %v %v %v", errs[0].LineTruncated, cut.LineTruncated, errs[2].LineTruncated)
+		t.Fail()
+	}
+	if len(cut.Raw) > 64 || !strings.HasPrefix(long, cut.Raw) {
+		t.Logf("got %q, expected the start of the line up to a rune", cut.Raw)
+		t.Fail()
+	}
+	// the lines after the cut one are where they are in the log
+	if b := errs[2]; b.LogLine != 3 || !strings.HasPrefix(log[b.LogOffset:], "b.go:3:") {
+		t.Logf("got line %d at offset %d", b.LogLine, b.LogOffset)
+		t.Fail()
+	}
+}
+
+func TestMaxLineLengthUnbuffered(t *testing.T) {
+	// lines much longer than the scanner's buffer aren't buffered
+	log := "a.go:1: " + strings.Repeat("x", 256<<10) + "\nb.go:2: short\n"
+	errs, err := ParseReader(strings.NewReader(log), MaxLineLength(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || errs[1].File != "b.go" || int(errs[1].LogOffset) != len(log)-len("b.go:2: short\n") {
+		t.Logf("got %v", errs)
+		t.Fail()
+	}
+}
+
+func TestMaxInputSize(t *testing.T) {
+	log := "a.go:1: one\nb.go:2: two\nc.go:3: three\n"
+	errs, err := ParseReader(strings.NewReader(log), MaxInputSize(int64(len("a.go:1: one\nb.go:2: two\n"))))
+	if err != ErrInputTooLarge {
+		t.Logf("got %v, expected %v", err, ErrInputTooLarge)
+		t.Fail()
+	}
+	if len(errs) != 2 {
+		t.Logf("was expecting the errors of the first 2 lines got %v", errs)
+		t.Fail()
+	}
+	if _, err := ParseReader(strings.NewReader(log), MaxInputSize(int64(len(log)))); err != nil {
+		t.Logf("a log of MaxInputSize failed with %v", err)
+		t.Fail()
+	}
+	if errs := ScanSourceError(log, MaxInputSize(12)); len(errs) != 1 {
+		t.Logf("got %v", errs)
+		t.Fail()
+	}
+}
+
+func BenchmarkParsePathological(b *testing.B) {
+	lines := []string{
+		strings.Repeat("a:", 1<<15),
+		strings.Repeat("a.c:1:", 1<<14),
+		strings.Repeat("a b.c ", 1<<14) + ":1:",
+		strings.Repeat("a.c(", 1<<14),
+		// the lines of a diagnostic that go on and on
+		"a.c:1:2: error: x" + strings.Repeat("\nfix-it:\"a.c\":{1:2-1:3}:\"y\"", 1<<12),
+	}
+	log := strings.Join(lines, "\n") + "\n"
+	b.SetBytes(int64(len(log)))
+	for i := 0; i < b.N; i++ {
+		ParseReader(strings.NewReader(log), MaxLineLength(4096), FixIts())
+	}
+}
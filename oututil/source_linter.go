@@ -0,0 +1,61 @@
+package oututil
+
+import "regexp"
+
+// linterSuffix matches the parenthesized check name golangci-lint and
+// staticcheck append to gcc style diagnostics,
+//
+//	ineffectual assignment to err (ineffassign)
+//	should use a simple channel send/receive instead of select (S1000)
+var linterSuffix = regexp.MustCompile(` \(([a-z][a-z0-9]+|[A-Z]{1,3}[0-9]{3,4})\)$`)
+
+// linters are the linters golangci-lint names in its suffixes. Messages
+// end with words in parentheses often enough that the lowercase names
+// need to be known ones to be taken for a code.
+var linters = map[string]bool{
+	"asciicheck": true, "bidichk": true, "bodyclose": true, "contextcheck": true,
+	"cyclop": true, "deadcode": true, "depguard": true, "dogsled": true,
+	"dupl": true, "durationcheck": true, "errcheck": true, "errchkjson": true,
+	"errname": true, "errorlint": true, "exhaustive": true, "exportloopref": true,
+	"forbidigo": true, "forcetypeassert": true, "funlen": true, "gci": true,
+	"gochecknoglobals": true, "gochecknoinits": true, "gocognit": true, "goconst": true,
+	"gocritic": true, "gocyclo": true, "godot": true, "godox": true,
+	"goerr113": true, "gofmt": true, "gofumpt": true, "goheader": true,
+	"goimports": true, "golint": true, "gomnd": true, "gomoddirectives": true,
+	"gomodguard": true, "goprintffuncname": true, "gosec": true, "gosimple": true,
+	"govet": true, "importas": true, "ineffassign": true, "ireturn": true,
+	"lll": true, "makezero": true, "misspell": true, "nakedret": true,
+	"nestif": true, "nilerr": true, "nilnil": true, "nlreturn": true,
+	"noctx": true, "nolintlint": true, "paralleltest": true, "prealloc": true,
+	"predeclared": true, "revive": true, "rowserrcheck": true, "sqlclosecheck": true,
+	"staticcheck": true, "structcheck": true, "stylecheck": true, "tagliatelle": true,
+	"tenv": true, "testpackage": true, "thelper": true, "tparallel": true,
+	"typecheck": true, "unconvert": true, "unparam": true, "unused": true,
+	"varcheck": true, "varnamelen": true, "wastedassign": true, "whitespace": true,
+	"wrapcheck": true, "wsl": true,
+}
+
+// KeepLinterSuffix leaves the check names golangci-lint and staticcheck
+// append to messages in the Message instead of moving them to Code, and
+// the warning options of gcc and clang, like [-Wunused-variable], too.
+func KeepLinterSuffix() Option {
+	return func(o *options) { o.keepLinterSuffix = true }
+}
+
+// trimLinterSuffix moves the check name at the end of the message of e
+// to its Code.
+func trimLinterSuffix(e SourceError) SourceError {
+	if e.Code != "" {
+		return e
+	}
+	m := linterSuffix.FindStringSubmatchIndex(e.Message)
+	if m == nil {
+		return e
+	}
+	code := e.Message[m[2]:m[3]]
+	if code[0] >= 'a' && code[0] <= 'z' && !linters[code] {
+		return e
+	}
+	e.Code, e.Message = code, e.Message[:m[0]]
+	return e
+}
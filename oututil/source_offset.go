@@ -0,0 +1,49 @@
+package oututil
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// FillLineColumn returns a copy of errs with the Line and Column of the
+// errors that only have an Offset, like the ones of validators that
+// report "offset 1234", computed from their files in fsys, and the
+// Offset of the ones that have a line computed from it. Columns count
+// bytes and the carriage returns of CRLF files end their line. Files
+// are read once, errors in files that can't be read, with positions
+// past the end of their file or with neither a line nor an offset
+// greater than 0 are left as they are.
+func FillLineColumn(errs []SourceError, fsys fs.FS) []SourceError {
+	files := make(map[string]*sourceFile)
+	filled := make([]SourceError, len(errs))
+	for i, e := range errs {
+		filled[i] = fillLineColumn(e, fsys, files)
+	}
+	return filled
+}
+
+func fillLineColumn(e SourceError, fsys fs.FS, files map[string]*sourceFile) SourceError {
+	if e.File == "" || e.IsVirtual() {
+		return e
+	}
+	f := openSourceFile(fsys, strings.TrimPrefix(slashPath(e.File), "./"), files)
+	if f == nil {
+		return e
+	}
+	if e.Line < 1 {
+		if e.Offset < 1 {
+			// like the errors of gofmt -l, not at the start of the file
+			return e
+		}
+		if line, column, ok := f.position(e.Offset); ok {
+			e.Line, e.Column = line, column
+		}
+		return e
+	}
+	if e.Offset == 0 {
+		if offset, ok := f.offset(e.Line, e.Column); ok {
+			e.Offset = offset
+		}
+	}
+	return e
+}
@@ -0,0 +1,56 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFillLineColumn(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lf.yaml":   {Data: []byte("a: 1\nb: [\nc: 3\n")},
+		"crlf.yaml": {Data: []byte("a: 1\r\nb: [\r\nc: 3\r\n")},
+	}
+	tests := []struct {
+		err, want SourceError
+	}{
+		{SourceError{File: "lf.yaml", Offset: 8}, SourceError{File: "lf.yaml", Line: 2, Column: 4, Offset: 8}},
+		{SourceError{File: "crlf.yaml", Offset: 9}, SourceError{File: "crlf.yaml", Line: 2, Column: 4, Offset: 9}},
+		// the carriage return is the end of the line
+		{SourceError{File: "crlf.yaml", Offset: 4}, SourceError{File: "crlf.yaml", Line: 1, Column: 5, Offset: 4}},
+		{SourceError{File: "./crlf.yaml", Offset: 12}, SourceError{File: "./crlf.yaml", Line: 3, Column: 1, Offset: 12}},
+		{SourceError{File: "lf.yaml", Line: 3, Column: 4}, SourceError{File: "lf.yaml", Line: 3, Column: 4, Offset: 13}},
+		{SourceError{File: "crlf.yaml", Line: 3, Column: 4}, SourceError{File: "crlf.yaml", Line: 3, Column: 4, Offset: 15}},
+		{SourceError{File: "crlf.yaml", Line: 2, Column: NoColumn}, SourceError{File: "crlf.yaml", Line: 2, Column: NoColumn, Offset: 6}},
+		{SourceError{File: "lf.yaml", Offset: 100}, SourceError{File: "lf.yaml", Offset: 100}},
+		{SourceError{File: "lf.yaml", Line: 9, Column: 1}, SourceError{File: "lf.yaml", Line: 9, Column: 1}},
+		{SourceError{File: "missing.yaml", Offset: 3}, SourceError{File: "missing.yaml", Offset: 3}},
+	}
+	for _, test := range tests {
+		got := FillLineColumn([]SourceError{test.err}, fsys)[0]
+		if got.Line != test.want.Line || got.Column != test.want.Column || got.Offset != test.want.Offset {
+			t.Logf("%+v: was expecting %d:%d at %d got %d:%d at %d instead", test.err, test.want.Line, test.want.Column, test.want.Offset, got.Line, got.Column, got.Offset)
+			t.Fail()
+		}
+	}
+
+	fsys["main.go"] = &fstest.MapFile{Data: []byte("package main\n")}
+	errs, _ := ParseGofmtList(strings.NewReader("main.go\n"))
+	if got := FillLineColumn(errs, fsys); len(got) != 1 || got[0].Line != 0 || got[0].Column != NoColumn {
+		t.Logf("was expecting gofmt -l errors to be left without a position got %+v", got)
+		t.Fail()
+	}
+}
+
+func TestParseOffsets(t *testing.T) {
+	errs := ScanSourceError(`api/v1.proto: offset 1234: expected "}"
+config.json: at byte offset 17: invalid character 'x' looking for beginning of value`)
+	checkSourceErrors(t, []SourceError{
+		{File: "api/v1.proto", Column: NoColumn, Message: `expected "}"`},
+		{File: "config.json", Column: NoColumn, Message: "invalid character 'x' looking for beginning of value"},
+	}, errs)
+	if len(errs) == 2 && (errs[0].Offset != 1234 || errs[1].Offset != 17) {
+		t.Logf("was expecting offsets 1234 and 17 got %d and %d instead", errs[0].Offset, errs[1].Offset)
+		t.Fail()
+	}
+}
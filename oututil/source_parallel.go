@@ -0,0 +1,292 @@
+package oututil
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// chunkSize is the size of the chunks Parallel splits logs in.
+var chunkSize = 4 << 20
+
+// chunkOverlap is the number of lines of the chunks around it a chunk
+// is parsed with, so the diagnostics that span the boundary between two
+// chunks are parsed whole.
+const chunkOverlap = 32
+
+// Parallel makes ParseReader and Scan split the log in chunks of whole
+// lines parsed by GOMAXPROCS workers, the source errors are still
+// returned in the order they appear in the log. Every chunk is parsed
+// with the lines around it, diagnostics that span more lines than that
+// at the boundary of two chunks can be cut short. Parallel is ignored
+// by Detector.
+func Parallel() Option {
+	return func(o *options) { o.parallel = true }
+}
+
+// logChunk is a chunk of a log and the lines around it.
+type logChunk struct {
+	data []byte
+	// line and offset are the number and offset of the first line of
+	// data, the errors of the lines in [start, end) are kept.
+	line       int
+	offset     int64
+	start, end int
+	// dirs are the directories make entered before start, goPackage
+	// and target the Go package and build target of the line before it.
+	dirs              []string
+	goPackage, target string
+	result            chan chunkResult
+}
+
+type chunkResult struct {
+	errs []SourceError
+	err  error
+	// incomplete is set when a Go tool in the chunk stopped at too
+	// many errors.
+	incomplete bool
+}
+
+func scanParallel(r io.Reader, yield func(SourceError) bool, o *options) error {
+	workers := runtime.GOMAXPROCS(0)
+	chunks := make(chan *logChunk, workers)
+	done, read := make(chan struct{}), make(chan struct{})
+	defer func() {
+		// don't return while r is still being read
+		close(done)
+		<-read
+	}()
+	var readErr error
+	go func() {
+		defer close(read)
+		defer close(chunks)
+		readErr = splitChunks(r, o, workers, chunks, done)
+	}()
+
+	s := newSourceScanner(yield, o)
+	for c := range chunks {
+		result := <-c.result
+		for _, e := range result.errs {
+			s.send(e)
+		}
+		s.incomplete = s.incomplete || result.incomplete
+		if result.err != nil {
+			return result.err
+		}
+		if s.stopped {
+			return s.err()
+		}
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return s.err()
+}
+
+// splitChunks reads r in chunks and parses them with at most workers at
+// a time, sending them to chunks in order.
+func splitChunks(r io.Reader, o *options, workers int, chunks chan<- *logChunk, done <-chan struct{}) error {
+	sem := make(chan struct{}, workers)
+	worker := *o
+	// the limit and duplicates are taken care of once the chunks are
+	// put back in order
+	worker.maxErrors, worker.keepDuplicates = 0, true
+
+	var (
+		prev, cur         []byte
+		line              = 1
+		offset            int64
+		dirs              []string
+		goPackage, target string
+		br                = bufio.NewReaderSize(r, chunkSize)
+		err               error
+	)
+	dispatch := func(next []byte) bool {
+		warmup := tail(prev, chunkOverlap)
+		lookahead := head(next, chunkOverlap)
+		data := make([]byte, 0, len(warmup)+len(cur)+len(lookahead))
+		data = append(append(append(data, warmup...), cur...), lookahead...)
+		lines := countLines(cur)
+		c := &logChunk{
+			data:      data,
+			line:      line - countLines(warmup),
+			offset:    offset - int64(len(warmup)),
+			start:     line,
+			end:       line + lines,
+			dirs:      append([]string(nil), dirs...),
+			goPackage: goPackage,
+			target:    target,
+			result:    make(chan chunkResult, 1),
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-done:
+			return false
+		}
+		go func() {
+			errs, incomplete, err := parseChunk(c, &worker)
+			c.result <- chunkResult{errs, err, incomplete}
+			<-sem
+		}()
+		select {
+		case chunks <- c:
+		case <-done:
+			return false
+		}
+		dirs = trackDirectories(dirs, cur, o)
+		build := cur
+		if line == 1 {
+			build = bytes.TrimPrefix(build, []byte(byteOrderMark))
+		}
+		goPackage, target = trackBuild(goPackage, target, build, o)
+		line += lines
+		offset += int64(len(cur))
+		return true
+	}
+	for {
+		var next []byte
+		next, err = readChunk(br)
+		if cur != nil && !dispatch(next) {
+			return nil
+		}
+		if len(next) == 0 {
+			break
+		}
+		prev, cur = cur, next
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// readChunk reads about chunkSize bytes of whole lines from r.
+func readChunk(r *bufio.Reader) ([]byte, error) {
+	chunk := make([]byte, 0, chunkSize+4096)
+	for len(chunk) < chunkSize {
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			chunk = append(chunk, line...)
+			continue
+		}
+		chunk = append(chunk, line...)
+		if err != nil {
+			return chunk, err
+		}
+	}
+	return chunk, nil
+}
+
+func parseChunk(c *logChunk, o *options) ([]SourceError, bool, error) {
+	var errs []SourceError
+	s := newSourceScanner(func(e SourceError) bool {
+		if e.LogLine >= c.start && e.LogLine < c.end {
+			errs = append(errs, e)
+		}
+		return true
+	}, o)
+	s.logLine, s.offset = c.line-1, c.offset
+	s.dirs, s.dirsFrom = c.dirs, c.start
+	// the lines before start only set them again to what they are at
+	// start, unlike directory lines they don't nest
+	s.goPackage, s.target = c.goPackage, c.target
+	scanner, lines := newLineScanner(bytes.NewReader(c.data), o)
+	for scanner.Scan() {
+		s.next(scanner.Text(), lines.size, lines.truncated)
+	}
+	s.flush()
+	return errs, s.incomplete, scanner.Err()
+}
+
+// trackDirectories returns dirs updated by make's directory lines in b,
+// read like the scanner reads them, without their colors and build
+// prefixes.
+func trackDirectories(dirs []string, b []byte, o *options) []string {
+	for {
+		i := bytes.Index(b, []byte(" directory "))
+		if i < 0 {
+			return dirs
+		}
+		start := bytes.LastIndexByte(b[:i], '\n') + 1
+		end := bytes.IndexByte(b[i:], '\n')
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += i
+		}
+		line := string(bytes.TrimRight(b[start:end], "\r"))
+		if !o.keepANSI {
+			line = stripANSI(line)
+		}
+		line, _ = stripBuildPrefix(ninjaProgress.ReplaceAllLiteralString(line, ""))
+		dirs, _ = changeDirectory(dirs, line)
+		if end == len(b) {
+			return dirs
+		}
+		b = b[end+1:]
+	}
+}
+
+// trackBuild returns the Go package and build target of the last line
+// of b, starting from goPackage and target, read like the scanner reads
+// the package headers and build steps.
+func trackBuild(goPackage, target string, b []byte, o *options) (string, string) {
+	s := sourceScanner{goPackage: goPackage, target: target}
+	for len(b) > 0 {
+		end := bytes.IndexByte(b, '\n')
+		if end < 0 {
+			end = len(b)
+		}
+		// the lines that can be neither start with one of these, or
+		// with the escape of a color
+		if l := b[:end]; len(l) > 0 && strings.IndexByte("[EWIDFAB#\x1b", l[0]) >= 0 {
+			line := strings.TrimRight(string(l), "\r")
+			if !o.keepANSI {
+				line = stripANSI(line)
+			}
+			if line, failed := s.buildStep(line); !failed {
+				line, _ = stripBuildPrefix(line)
+				s.goTool(line)
+			}
+		}
+		if end == len(b) {
+			break
+		}
+		b = b[end+1:]
+	}
+	return s.goPackage, s.target
+}
+
+// countLines returns the number of lines in b.
+func countLines(b []byte) int {
+	n := bytes.Count(b, []byte{'\n'})
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// head returns the first n lines of b.
+func head(b []byte, n int) []byte {
+	end := 0
+	for ; n > 0 && end < len(b); n-- {
+		i := bytes.IndexByte(b[end:], '\n')
+		if i < 0 {
+			return b
+		}
+		end += i + 1
+	}
+	return b[:end]
+}
+
+// tail returns the last n lines of b, b ends with a new line.
+func tail(b []byte, n int) []byte {
+	start := len(b)
+	for ; n > 0 && start > 0; n-- {
+		i := bytes.LastIndexByte(b[:start-1], '\n')
+		start = i + 1
+	}
+	return b[start:]
+}
@@ -0,0 +1,126 @@
+package oututil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+// parallelLog returns a log of more than a few chunks mixing single and
+// multi-line diagnostics, with colored and prefixed directory lines
+// spanning chunks.
+func parallelLog() string {
+	var b strings.Builder
+	for i := 0; b.Len() < 3*chunkSize+chunkSize/2; i++ {
+		switch i % 5 {
+		case 0:
+			fmt.Fprintf(&b, "make[1]: Entering directory '/src/lib%d'\n", i)
+			fmt.Fprintf(&b, "lib%d.c:%d:5: error: 'x' undeclared\n", i, i)
+			fmt.Fprintf(&b, "make[1]: Leaving directory '/src/lib%d'\n", i)
+		case 1:
+			fmt.Fprintf(&b, "error[E0308]: mismatched types\n --> src/main%d.rs:4:18\n  |\n4 |     let x: i32 = \"a\";\n\n", i)
+		case 2:
+			fmt.Fprintf(&b, "Traceback (most recent call last):\n  File \"app%d.py\", line 3, in <module>\n    main()\n  File \"lib.py\", line %d, in main\n    raise ValueError(\"bad\")\nValueError: bad\n", i, i)
+		case 3:
+			switch i % 20 {
+			case 3:
+				fmt.Fprintf(&b, "\x1b[1mmake: Entering directory '/src/gen%d'\x1b[0m\n", i)
+			case 18:
+				fmt.Fprintf(&b, "[INFO] make: Leaving directory '/src/gen%d'\n", i-15)
+			}
+			b.WriteString(strings.Repeat("some unrelated output\n", i%40))
+		case 4:
+			fmt.Fprintf(&b, "pkg/foo_%d.go:12:3: undefined: foo\r\n", i)
+		}
+	}
+	return b.String()
+}
+
+// smallChunks makes Parallel split logs in small chunks for the
+// duration of a test.
+func smallChunks(t *testing.T) {
+	size := chunkSize
+	chunkSize = 16 << 10
+	t.Cleanup(func() { chunkSize = size })
+}
+
+func TestParallel(t *testing.T) {
+	smallChunks(t)
+	log := parallelLog()
+	for _, opts := range [][]Option{nil, {Snippets(), FoldNotes()}} {
+		want, err := ParseReader(strings.NewReader(log), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseReader(strings.NewReader(log), append(opts, Parallel())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("was expecting %d errors got %d instead", len(want), len(got))
+		}
+		for i := range want {
+			if diff, equal := messagediff.PrettyDiff(want[i], got[i]); !equal {
+				t.Fatalf("error %d differs from the sequential one: %s", i, diff)
+			}
+		}
+	}
+}
+
+func TestParallelBuildState(t *testing.T) {
+	size := chunkSize
+	chunkSize = 256
+	t.Cleanup(func() { chunkSize = size })
+	var b strings.Builder
+	b.WriteString("# example.com/pkg\n")
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&b, "./a.go:%d:2: undefined: x\n", i)
+	}
+	b.WriteString("FAILED: src/CMakeFiles/app.dir/main.cc.o\n")
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&b, "src/main.cc:%d:5: error: 'x' was not declared\n", i)
+	}
+	want, err := ParseReader(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseReader(strings.NewReader(b.String()), Parallel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || len(want) != 120 {
+		t.Fatalf("was expecting 120 errors got %d and %d sequentially", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Package != want[i].Package || got[i].Target != want[i].Target {
+			t.Logf("was expecting error %d to be in %q of %q got %q of %q", i, want[i].Package, want[i].Target, got[i].Package, got[i].Target)
+			t.Fail()
+		}
+	}
+	if want[59].Package != "example.com/pkg" || want[119].Target != "src/CMakeFiles/app.dir/main.cc.o" {
+		t.Logf("was expecting the package and target of the log got %+v and %+v", want[59], want[119])
+		t.Fail()
+	}
+}
+
+func TestParallelMaxErrors(t *testing.T) {
+	smallChunks(t)
+	errs, err := ParseReader(strings.NewReader(parallelLog()), Parallel(), MaxErrors(10))
+	if err != ErrTruncated || len(errs) != 10 {
+		t.Logf("was expecting 10 errors and ErrTruncated got %d and %v instead", len(errs), err)
+		t.Fail()
+	}
+}
+
+func BenchmarkParseReaderParallel(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := &repeatReader{line: benchLine, n: benchLogSize / int64(len(benchLine))}
+		n := 0
+		if err := Scan(r, func(SourceError) bool { n++; return true }, Parallel()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
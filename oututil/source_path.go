@@ -0,0 +1,90 @@
+package oututil
+
+import (
+	"path"
+	"strings"
+)
+
+type pathOptions struct {
+	root string
+	dirs map[string]string
+}
+
+// PathOption configures ResolvePaths.
+type PathOption func(*pathOptions)
+
+// WorkspaceRoot makes ResolvePaths return the paths under root relative
+// to it.
+func WorkspaceRoot(root string) PathOption {
+	return func(o *pathOptions) { o.root = slashPath(root) }
+}
+
+// DirBases maps the directories the tools ran in, the Dir of a
+// SourceError, to the directories their relative paths are resolved
+// against. It is for logs of builds that ran somewhere else, like a
+// container, Dirs that aren't in bases are joined with the base passed
+// to ResolvePaths.
+func DirBases(bases map[string]string) PathOption {
+	return func(o *pathOptions) { o.dirs = bases }
+}
+
+// ResolvePaths returns a copy of errs with cleaned, slash separated
+// paths. Relative paths are joined with the Dir of their error and
+// base, absolute ones are left as they are unless they are under the
+// WorkspaceRoot, virtual ones like <stdin> and URLs aren't touched.
+func ResolvePaths(errs []SourceError, base string, opts ...PathOption) []SourceError {
+	var o pathOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	resolved := make([]SourceError, len(errs))
+	for i, e := range errs {
+		resolved[i] = o.resolve(e, base)
+	}
+	return resolved
+}
+
+func (o *pathOptions) resolve(e SourceError, base string) SourceError {
+	dir := slashPath(e.Dir)
+	if b, ok := o.dirs[e.Dir]; ok {
+		dir = slashPath(b)
+	} else if !isAbsPath(dir) {
+		dir = path.Join(slashPath(base), dir)
+	}
+	if e.File != "" && !e.IsVirtual() && e.Scheme() == "" {
+		file := slashPath(e.File)
+		if !isAbsPath(file) {
+			file = path.Join(dir, file)
+		}
+		e.File = o.rel(path.Clean(file))
+	}
+	if len(e.Related) > 0 {
+		related := make([]SourceError, len(e.Related))
+		for i, r := range e.Related {
+			if r.Dir == "" {
+				r.Dir = e.Dir
+			}
+			related[i] = o.resolve(r, base)
+		}
+		e.Related = related
+	}
+	return e
+}
+
+// rel makes file relative to the workspace root.
+func (o *pathOptions) rel(file string) string {
+	switch {
+	case o.root == "":
+		return file
+	case file == o.root:
+		return "."
+	case strings.HasPrefix(file, strings.TrimSuffix(o.root, "/")+"/"):
+		return file[len(strings.TrimSuffix(o.root, "/"))+1:]
+	}
+	return file
+}
+
+// isAbsPath reports whether p is an absolute Unix or Windows path.
+func isAbsPath(p string) bool {
+	return strings.HasPrefix(p, "/") || len(p) >= 3 && p[1] == ':' && p[2] == '/' && isLetter(p[0])
+}
@@ -0,0 +1,88 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolvePaths(t *testing.T) {
+	tests := []struct {
+		err  SourceError
+		base string
+		opts []PathOption
+		want string
+	}{
+		{err: SourceError{File: "./pkg/../a.go"}, base: "/src", want: "/src/a.go"},
+		{err: SourceError{File: "/usr/include/stdio.h"}, base: "/src", want: "/usr/include/stdio.h"},
+		{err: SourceError{File: "<stdin>"}, base: "/src", want: "<stdin>"},
+		{err: SourceError{File: `src\app\main.cpp`}, base: `C:\build`, want: "C:/build/src/app/main.cpp"},
+		{err: SourceError{File: `D:\lib\x.h`}, base: `C:\build`, want: "D:/lib/x.h"},
+		{err: SourceError{File: "a.c", Dir: "/src/lib"}, base: "/src", want: "/src/lib/a.c"},
+		{err: SourceError{File: "a.c", Dir: "lib"}, base: "/src", want: "/src/lib/a.c"},
+		{err: SourceError{File: "../a.c"}, base: "/src/lib", opts: []PathOption{WorkspaceRoot("/src/")}, want: "a.c"},
+		{err: SourceError{File: "/srcs/a.c"}, opts: []PathOption{WorkspaceRoot("/src")}, want: "/srcs/a.c"},
+		{err: SourceError{File: "a.c", Dir: "/build/lib"}, base: "/src", opts: []PathOption{DirBases(map[string]string{"/build/lib": "/home/me/lib"})}, want: "/home/me/lib/a.c"},
+	}
+	for _, test := range tests {
+		got := ResolvePaths([]SourceError{test.err}, test.base, test.opts...)[0].File
+		if got != test.want {
+			t.Logf("%+v: was expecting %q got %q instead", test.err, test.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestResolvePathsMake(t *testing.T) {
+	const log = `make: Entering directory '/src'
+make -C lib
+make[1]: Entering directory '/src/lib'
+lib.c:3:5: error: 'x' undeclared
+make[1]: Leaving directory '/src/lib'
+main.c:9:1: warning: control reaches end of non-void function
+make: Leaving directory '/src'
+`
+	errs, err := ParseReader(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs = ResolvePaths(errs, "/", WorkspaceRoot("/src"))
+	if len(errs) != 2 {
+		t.Fatalf("was expecting 2 errors got %d instead", len(errs))
+	}
+	for i, want := range []string{"lib/lib.c", "main.c"} {
+		if errs[i].File != want {
+			t.Logf("was expecting %q got %q instead", want, errs[i].File)
+			t.Fail()
+		}
+	}
+}
+
+func TestMakeDirectories(t *testing.T) {
+	const log = "make: Entering directory `/src'\n" +
+		"top.c:1:1: error: top\n" +
+		"make[1]: Entering directory '/src/lib'\n" +
+		"make[2]: Entering directory '/src/lib/foo'\n" +
+		"foo.c:2:1: error: foo\n" +
+		"make[2]: Leaving directory '/src/lib/foo'\n" +
+		"lib.c:3:1: error: lib\n" +
+		"make[3]: Leaving directory '/nowhere'\n" +
+		"lib.c:4:1: error: still lib\n" +
+		"make[1]: Leaving directory '/src'\n" +
+		"make: Leaving directory '/src'\n" +
+		"gmake[1]: Leaving directory '/src'\n" +
+		"out.c:5:1: error: outside\n"
+	errs, err := ParseReader(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/src", "/src/lib/foo", "/src/lib", "/src/lib", ""}
+	if len(errs) != len(want) {
+		t.Fatalf("was expecting %d errors got %d instead", len(want), len(errs))
+	}
+	for i, e := range errs {
+		if e.Dir != want[i] {
+			t.Logf("%s: was expecting dir %q got %q instead", e.Message, want[i], e.Dir)
+			t.Fail()
+		}
+	}
+}
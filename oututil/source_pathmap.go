@@ -0,0 +1,128 @@
+package oututil
+
+import (
+	"sort"
+	"strings"
+)
+
+// PathMapping rewrites the paths under From to be under To, like the
+// paths of a container under /workspace to the ones of a checkout. An
+// empty To makes the paths relative to From.
+type PathMapping struct {
+	From, To string
+}
+
+type mapOptions struct {
+	fold bool
+	drop bool
+}
+
+// MapOption configures MapPaths.
+type MapOption func(*mapOptions)
+
+// FoldCase matches the From of mappings ignoring case, for logs of
+// Windows builds.
+func FoldCase() MapOption {
+	return func(o *mapOptions) { o.fold = true }
+}
+
+// DropUnmapped drops the errors with a path no mapping matches, like
+// the ones in the headers of a toolchain. Errors without a file,
+// virtual ones like <stdin> and URLs are kept.
+func DropUnmapped() MapOption {
+	return func(o *mapOptions) { o.drop = true }
+}
+
+// MapPaths returns a copy of errs with their paths, and the paths of
+// their Related, rewritten by the mapping with the longest From they
+// are under. Paths are matched slash separated and by whole elements,
+// /workspace doesn't match /workspaces/a.go.
+func MapPaths(errs []SourceError, mappings []PathMapping, opts ...MapOption) []SourceError {
+	o := outputOptions{paths: newPathMapper(mappings, opts)}
+	return o.mapPaths(errs)
+}
+
+type pathMapper struct {
+	mappings []PathMapping
+	mapOptions
+}
+
+func newPathMapper(mappings []PathMapping, opts []MapOption) *pathMapper {
+	m := &pathMapper{}
+	for _, opt := range opts {
+		opt(&m.mapOptions)
+	}
+	for _, pm := range mappings {
+		m.mappings = append(m.mappings, PathMapping{slashPath(pm.From), slashPath(pm.To)})
+	}
+	sort.SliceStable(m.mappings, func(i, j int) bool {
+		return len(m.mappings[i].From) > len(m.mappings[j].From)
+	})
+	return m
+}
+
+// mapError maps the paths of e, it returns false if e is to be dropped.
+func (m *pathMapper) mapError(e SourceError) (SourceError, bool) {
+	if e.File != "" && !e.IsVirtual() && e.Scheme() == "" {
+		file, ok := m.mapPath(e.File)
+		if !ok && m.drop {
+			return e, false
+		}
+		e.File = file
+	}
+	if len(e.Related) > 0 {
+		related := make([]SourceError, 0, len(e.Related))
+		for _, r := range e.Related {
+			if r, ok := m.mapError(r); ok {
+				related = append(related, r)
+			}
+		}
+		e.Related = related
+	}
+	return e, true
+}
+
+// mapPath returns p rewritten by the first mapping it is under, or p
+// and false if it is under none.
+func (m *pathMapper) mapPath(p string) (string, bool) {
+	p = slashPath(p)
+	for _, pm := range m.mappings {
+		rest, ok := m.under(p, pm.From)
+		if !ok {
+			continue
+		}
+		switch {
+		case pm.To == "" || pm.To == ".":
+			if rest == "" {
+				return ".", true
+			}
+			return rest, true
+		case rest == "":
+			return pm.To, true
+		}
+		return strings.TrimSuffix(pm.To, "/") + "/" + rest, true
+	}
+	return p, false
+}
+
+// under returns the rest of p after the elements of dir, if p is dir or
+// under it.
+func (m *pathMapper) under(p, dir string) (string, bool) {
+	if len(p) < len(dir) {
+		return "", false
+	}
+	if prefix := p[:len(dir)]; prefix != dir && !(m.fold && strings.EqualFold(prefix, dir)) {
+		return "", false
+	}
+	rest := p[len(dir):]
+	switch {
+	case rest == "":
+		return "", true
+	case strings.HasSuffix(dir, "/"):
+		// the root, / or C:/
+		return rest, true
+	case rest[0] == '/':
+		return rest[1:], true
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+package oututil
+
+import "testing"
+
+func TestMapPaths(t *testing.T) {
+	mappings := []PathMapping{
+		{From: "/workspace", To: ""},
+		{From: "/workspace/vendor/lib", To: "third_party/lib"},
+		{From: `C:\agent\_work\1\s`, To: "."},
+		{From: "/opt/toolchain", To: "/usr/local/toolchain"},
+	}
+	tests := []struct {
+		file string
+		opts []MapOption
+		want string
+	}{
+		{file: "/workspace/src/a.go", want: "src/a.go"},
+		{file: "/workspace", want: "."},
+		{file: "/workspaces/a.go", want: "/workspaces/a.go"},
+		{file: "/workspace/vendor/lib/x.h", want: "third_party/lib/x.h"},
+		{file: "/workspace/vendor/libs/x.h", want: "vendor/libs/x.h"},
+		{file: "/opt/toolchain/include/stdio.h", want: "/usr/local/toolchain/include/stdio.h"},
+		{file: `C:\agent\_work\1\s\src\App.cs`, want: "src/App.cs"},
+		{file: `c:\Agent\_work\1\s\src\App.cs`, want: "c:/Agent/_work/1/s/src/App.cs"},
+		{file: `c:\Agent\_work\1\s\src\App.cs`, opts: []MapOption{FoldCase()}, want: "src/App.cs"},
+		{file: "<stdin>", want: "<stdin>"},
+	}
+	for _, test := range tests {
+		got := MapPaths([]SourceError{{File: test.file}}, mappings, test.opts...)
+		if len(got) != 1 || got[0].File != test.want {
+			t.Logf("%s: was expecting %q got %v instead", test.file, test.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestMapPathsDropUnmapped(t *testing.T) {
+	errs := []SourceError{
+		{File: "/workspace/a.c", Line: 1, Related: []SourceError{
+			{File: "/usr/include/stdio.h", Line: 2},
+			{File: "/workspace/a.h", Line: 3},
+		}},
+		{File: "/usr/include/stdlib.h", Line: 4},
+		{Message: "1 error generated"},
+		{File: "<built-in>"},
+	}
+	got := MapPaths(errs, []PathMapping{{From: "/workspace/"}}, DropUnmapped())
+	if len(got) != 3 || got[0].File != "a.c" || got[1].Message != "1 error generated" || got[2].File != "<built-in>" {
+		t.Fatalf("got %+v", got)
+	}
+	if r := got[0].Related; len(r) != 1 || r[0].File != "a.h" {
+		t.Logf("was expecting the related a.h got %+v", r)
+		t.Fail()
+	}
+	if errs[0].File != "/workspace/a.c" || len(errs[0].Related) != 2 {
+		t.Logf("the errors changed to %+v", errs[0])
+		t.Fail()
+	}
+}
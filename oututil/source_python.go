@@ -0,0 +1,76 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// pythonFrame matches a traceback frame and the location line of a
+	// SyntaxError,
+	//
+	//	  File "app/models.py", line 42, in save
+	//	  File "x.py", line 3
+	pythonFrame = regexp.MustCompile(`^\s*File "(?P<file>[^"]+)", line (?P<line>[0-9]+)(?:, in (?P<function>.+))?$`)
+	// pythonException matches the exception line that ends a traceback,
+	//
+	//	ValueError: bad value
+	//	KeyboardInterrupt
+	pythonException = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*(?:: .*)?$`)
+)
+
+// pythonParser turns Python tracebacks into source errors. Every frame
+// becomes a SourceError carrying the exception as its message; the
+// innermost frame, where the exception was raised, is an error and
+// the frames leading to it are notes.
+type pythonParser struct {
+	innermost bool
+	frames    []SourceError
+}
+
+func (p *pythonParser) parse(line string, emit func(SourceError)) bool {
+	if m := pythonFrame.FindStringSubmatch(line); m != nil {
+		e := newSourceError(m[1], m[2], "", "")
+		e.Function = m[3]
+		e.Kind = KindStackFrame
+		p.frames = append(p.frames, e)
+		return true
+	}
+	if len(p.frames) == 0 {
+		return false
+	}
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		// the source line and carets of the frame above.
+		return true
+	}
+	if pythonException.MatchString(line) {
+		p.finish(line, emit)
+		return true
+	}
+	p.finish("", emit)
+	return false
+}
+
+func (p *pythonParser) flush(emit func(SourceError)) { p.finish("", emit) }
+
+// finish emits the frames of the current traceback with exception as
+// their message.
+func (p *pythonParser) finish(exception string, emit func(SourceError)) {
+	frames := p.frames
+	p.frames = nil
+	if len(frames) == 0 {
+		return
+	}
+	if p.innermost {
+		frames = frames[len(frames)-1:]
+	}
+	for i, e := range frames {
+		e.Message = strings.TrimSpace(exception)
+		e.Severity = SeverityNote
+		if i == len(frames)-1 {
+			e.Severity = SeverityError
+		}
+		e.Tool = "python"
+		emit(e)
+	}
+}
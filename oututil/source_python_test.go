@@ -0,0 +1,71 @@
+package oututil
+
+import "testing"
+
+const pythonTraceback = `Traceback (most recent call last):
+  File "app/main.py", line 12, in <module>
+    main()
+  File "app/models.py", line 42, in save
+    raise ValueError("bad value")
+ValueError: bad value
+
+During handling of the above exception, another exception occurred:
+
+Traceback (most recent call last):
+  File "app/main.py", line 14, in <module>
+    log.error(err)
+KeyError: 'err'
+`
+
+const pythonSyntaxError = `  File "x.py", line 3
+    print("a"
+         ^
+SyntaxError: '(' was never closed
+`
+
+func TestPythonTraceback(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    string
+		opts   []Option
+		errors []SourceError
+	}{
+		{
+			name: "frames",
+			log:  pythonTraceback,
+			errors: []SourceError{
+				{File: "app/main.py", Line: 12, Column: NoColumn, Function: "<module>", Kind: KindStackFrame, Message: "ValueError: bad value", Severity: SeverityNote},
+				{File: "app/models.py", Line: 42, Column: NoColumn, Function: "save", Kind: KindStackFrame, Message: "ValueError: bad value", Severity: SeverityError},
+				{File: "app/main.py", Line: 14, Column: NoColumn, Function: "<module>", Kind: KindStackFrame, Message: "KeyError: 'err'", Severity: SeverityError},
+			},
+		},
+		{
+			name: "innermost",
+			log:  pythonTraceback,
+			opts: []Option{TracebackInnermost()},
+			errors: []SourceError{
+				{File: "app/models.py", Line: 42, Column: NoColumn, Function: "save", Kind: KindStackFrame, Message: "ValueError: bad value", Severity: SeverityError},
+				{File: "app/main.py", Line: 14, Column: NoColumn, Function: "<module>", Kind: KindStackFrame, Message: "KeyError: 'err'", Severity: SeverityError},
+			},
+		},
+		{
+			name: "syntax error",
+			log:  pythonSyntaxError,
+			errors: []SourceError{
+				{File: "x.py", Line: 3, Kind: KindStackFrame, Column: NoColumn, Message: "SyntaxError: '(' was never closed", Severity: SeverityError},
+			},
+		},
+		{
+			name: "truncated",
+			log:  "Traceback (most recent call last):\n  File \"a.py\", line 1, in f\n    f()\n",
+			errors: []SourceError{
+				{File: "a.py", Line: 1, Column: NoColumn, Function: "f", Kind: KindStackFrame, Severity: SeverityError},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkSourceErrors(t, test.errors, ScanSourceError(test.log, test.opts...))
+		})
+	}
+}
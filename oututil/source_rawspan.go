@@ -0,0 +1,143 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ByteRange is the bytes of a line from Start up to End, it is empty
+// when End isn't after Start.
+type ByteRange struct {
+	Start, End int
+}
+
+// IsEmpty reports whether r has no bytes.
+func (r ByteRange) IsEmpty() bool { return r.End <= r.Start }
+
+// Spans are where the parts of a diagnostic are in the line of its Raw
+// that has its location, so viewers can highlight them in the log.
+// Ranges are empty when the part isn't on that line, like the message
+// of a rustc diagnostic, or isn't in it verbatim, like text with ANSI
+// escapes in the middle.
+type Spans struct {
+	// Line is the index of the line in the lines of Raw, starting at 0
+	Line int
+	// File is the file name, Position the line and column numbers and
+	// Message the message without the severity and code
+	File, Position, Message ByteRange
+}
+
+// IsEmpty reports whether s has none of the parts.
+func (s Spans) IsEmpty() bool {
+	return s.File.IsEmpty() && s.Position.IsEmpty() && s.Message.IsEmpty()
+}
+
+// positionGroups are the captures that are part of the position of a
+// diagnostic.
+var positionGroups = map[string]bool{
+	"line": true, "col": true, "endline": true, "endcol": true,
+	"rangeline": true, "rangecol": true, "offset": true,
+}
+
+// spansOf returns the spans in line of the captures of re in m, the
+// submatch indexes of line. message is the message parsed from it.
+func spansOf(re *regexp.Regexp, line string, m []int, message string) Spans {
+	var s Spans
+	seen := make(map[string]bool)
+	for i, name := range re.SubexpNames() {
+		start, end := m[2*i], m[2*i+1]
+		if name == "" || start < 0 || end <= start || seen[name] {
+			continue
+		}
+		// the first alternative that matched wins, like in fromSubmatch
+		seen[name] = true
+		r := ByteRange{start, end}
+		switch {
+		case name == "file":
+			s.File = r
+		case name == "message":
+			if i := strings.Index(line[start:end], message); message != "" && i >= 0 {
+				r = ByteRange{start + i, start + i + len(message)}
+			}
+			s.Message = r
+		case positionGroups[name]:
+			if s.Position.IsEmpty() {
+				s.Position = r
+				continue
+			}
+			if r.Start < s.Position.Start {
+				s.Position.Start = r.Start
+			}
+			if r.End > s.Position.End {
+				s.Position.End = r.End
+			}
+		}
+	}
+	return s
+}
+
+// unmask moves spans of the masked line maskPath made to line, whose
+// file is file.
+func unmask(s Spans, line, masked, file string) Spans {
+	at := strings.Index(masked, maskedPath)
+	if at < 0 {
+		return Spans{}
+	}
+	shift := len(line) - len(masked)
+	move := func(r ByteRange) ByteRange {
+		if r.IsEmpty() || r.Start < at+len(maskedPath) {
+			return ByteRange{}
+		}
+		return ByteRange{r.Start + shift, r.End + shift}
+	}
+	i := strings.Index(line, file)
+	if i < 0 {
+		return Spans{}
+	}
+	return Spans{
+		File:     ByteRange{i, i + len(file)},
+		Position: move(s.Position),
+		Message:  move(s.Message),
+	}
+}
+
+// locate moves spans of the parsed line, which is raw once the build
+// prefixes, escapes and other diagnostics were taken out of it, to raw.
+// Parts that aren't in raw verbatim are dropped.
+func locate(s Spans, parsed, raw string) Spans {
+	if base := strings.Index(raw, parsed); base >= 0 {
+		for _, r := range []*ByteRange{&s.File, &s.Position, &s.Message} {
+			if !r.IsEmpty() {
+				r.Start += base
+				r.End += base
+			}
+		}
+		return s
+	}
+	// the parts are in raw in the order they are in parsed
+	from := 0
+	for _, r := range s.ordered() {
+		if r.IsEmpty() {
+			continue
+		}
+		i := strings.Index(raw[from:], parsed[r.Start:r.End])
+		if i < 0 {
+			*r = ByteRange{}
+			continue
+		}
+		*r = ByteRange{from + i, from + i + r.End - r.Start}
+		from = r.End
+	}
+	return s
+}
+
+// ordered returns the parts of s in the order they are in the line.
+func (s *Spans) ordered() []*ByteRange {
+	parts := []*ByteRange{&s.File, &s.Position, &s.Message}
+	for i := 1; i < len(parts); i++ {
+		for j := i; j > 0 && parts[j].Start < parts[j-1].Start; j-- {
+			parts[j], parts[j-1] = parts[j-1], parts[j]
+		}
+	}
+	return parts
+}
@@ -0,0 +1,122 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawSpans(t *testing.T) {
+	tests := []struct {
+		name, log               string
+		line                    int
+		file, position, message string
+	}{
+		{
+			name:     "gcc",
+			log:      "main.c:3:5: error: expected ';' before '}' token\n",
+			file:     "main.c",
+			position: "3:5",
+			message:  "expected ';' before '}' token",
+		},
+		{
+			name:     "msvc",
+			log:      `C:\src\app.cpp(10,7): error C2065: 'x': undeclared identifier [C:\src\app.vcxproj]` + "\n",
+			file:     `C:\src\app.cpp`,
+			position: "10,7",
+			message:  "'x': undeclared identifier",
+		},
+		{
+			name:     "build prefix",
+			log:      "[2/9] Building CXX object a.o\nFAILED: a.o\n[ERROR] lib/a.cc:12:1: error: unknown type name 'foo'\n",
+			file:     "lib/a.cc",
+			position: "12:1",
+			message:  "unknown type name 'foo'",
+		},
+		{
+			name:     "quoted",
+			log:      `"C:\Program Files\app\main.c"(3): error C2143: syntax error` + "\n",
+			file:     `C:\Program Files\app\main.c`,
+			position: "3",
+			message:  "syntax error",
+		},
+		{
+			name:     "ansi",
+			log:      "\x1b[1mmain.c:3:5: \x1b[0m\x1b[31merror: \x1b[0mexpected ';'\n",
+			file:     "main.c",
+			position: "3:5",
+			message:  "expected ';'",
+		},
+		{
+			name:     "rustc",
+			log:      "error[E0308]: mismatched types\n --> src/main.rs:4:18\n  |\n",
+			line:     1,
+			file:     "src/main.rs",
+			position: "4:18",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs, err := ParseReader(strings.NewReader(test.log))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors, expected 1", len(errs))
+			}
+			e := errs[0]
+			if e.Spans.Line != test.line {
+				t.Logf("got line %d, expected %d", e.Spans.Line, test.line)
+				t.Fail()
+			}
+			lines := strings.Split(e.Raw, "\n")
+			if e.Spans.Line >= len(lines) {
+				t.Fatalf("line %d isn't in %q", e.Spans.Line, e.Raw)
+			}
+			raw := lines[e.Spans.Line]
+			text := func(r ByteRange) string {
+				if r.IsEmpty() {
+					return ""
+				}
+				return raw[r.Start:r.End]
+			}
+			for _, part := range []struct{ name, got, want string }{
+				{"file", text(e.Spans.File), test.file},
+				{"position", text(e.Spans.Position), test.position},
+				{"message", text(e.Spans.Message), test.message},
+			} {
+				if part.got != part.want {
+					t.Logf("%s: got %q, expected %q", part.name, part.got, part.want)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestRawSpansInterleaved(t *testing.T) {
+	log := "a.h:3:1: error: unknown type name 'foo'b.h:7:2: warning: unused variable 'x'\n"
+	errs, err := ParseReader(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, expected 2", len(errs))
+	}
+	for _, e := range errs {
+		if file := e.Raw[e.Spans.File.Start:e.Spans.File.End]; file != e.File {
+			t.Logf("got file %q, expected %q", file, e.File)
+			t.Fail()
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	parsed := "a.c:1:2: msg"
+	s := Spans{File: ByteRange{0, 3}, Position: ByteRange{4, 7}, Message: ByteRange{9, 12}}
+	// escapes split the position
+	got := locate(s, parsed, "\x1b[1ma.c:1\x1b[0m:2: msg")
+	if !got.Position.IsEmpty() || got.File != (ByteRange{4, 7}) || got.Message != (ByteRange{17, 20}) {
+		t.Logf("got %+v", got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,74 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultReportSamples is the number of unmatched lines a ParseReport
+// keeps unless WithReport says otherwise.
+const defaultReportSamples = 20
+
+// maxSampleLength is the number of bytes of an unmatched line that are
+// kept.
+const maxSampleLength = 256
+
+// ParseReport is what the parser made of a log, to find out why lines
+// that look like diagnostics weren't parsed, see WithReport.
+type ParseReport struct {
+	// Lines is the number of lines read.
+	Lines int `json:"lines"`
+	// Matched counts the diagnostics each format or multi-line parser
+	// matched, duplicates included, by their Tool.
+	Matched map[string]int `json:"matched"`
+	// Errors is the number of source errors returned.
+	Errors int `json:"errors"`
+	// Unmatched is the number of lines no format matched that mention
+	// an error or a warning, Samples the first of them.
+	Unmatched int             `json:"unmatched"`
+	Samples   []UnmatchedLine `json:"samples,omitempty"`
+	// Reencoded is the number of lines that weren't valid UTF-8 and
+	// were decoded, see WithDecoder.
+	Reencoded int `json:"reencoded,omitempty"`
+	max       int
+}
+
+// UnmatchedLine is a line of the log no format matched, without its
+// ANSI escapes and cut at 256 bytes.
+type UnmatchedLine struct {
+	// Line is the number of the line, starting at 1.
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// WithReport fills r while the log is parsed, it keeps up to samples
+// unmatched lines, 20 if samples is 0 and none if it is negative. The
+// samples are the first lines of the log that qualify so they are the
+// same for the same log. r is only complete once parsing returned;
+// Parallel is ignored with it.
+func WithReport(r *ParseReport, samples int) Option {
+	return func(o *options) {
+		if samples == 0 {
+			samples = defaultReportSamples
+		}
+		*r = ParseReport{Matched: make(map[string]int), max: samples}
+		o.report = r
+	}
+}
+
+// highSignal matches the lines of a log worth a look when no format
+// matched them.
+var highSignal = regexp.MustCompile(`(?i)\b(?:error|warning)s?\b`)
+
+// unmatched records line, the number n of the log, if it is high
+// signal.
+func (r *ParseReport) unmatched(n int, line string) {
+	line = strings.TrimSpace(stripANSI(line))
+	if !highSignal.MatchString(line) {
+		return
+	}
+	r.Unmatched++
+	if len(r.Samples) < r.max {
+		r.Samples = append(r.Samples, UnmatchedLine{Line: n, Text: truncate(line, maxSampleLength)})
+	}
+}
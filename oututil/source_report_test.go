@@ -0,0 +1,64 @@
+package oututil
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const unparsedLog = `[1/3] Building CXX object main.o
+main.c:3:5: error: 'x' undeclared
+main.c:3:5: error: 'x' undeclared
+ld.lld: error: undefined symbol: frobnicate
+Linking failed with 2 errors
+checking for warnings... none
+` + "\x1b[31mFATAL ERROR\x1b[0m: out of memory\n"
+
+func TestParseReport(t *testing.T) {
+	var report ParseReport
+	errs, err := ParseReader(strings.NewReader(unparsedLog), WithReport(&report, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ParseReport{
+		Lines:     7,
+		Matched:   map[string]int{"gcc": 2},
+		Errors:    len(errs),
+		Unmatched: 4,
+		Samples: []UnmatchedLine{
+			{Line: 4, Text: "ld.lld: error: undefined symbol: frobnicate"},
+			{Line: 5, Text: "Linking failed with 2 errors"},
+		},
+		max: 2,
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Logf("was expecting\n%+v\ngot\n%+v", want, report)
+		t.Fail()
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantJSON = `{"lines":7,"matched":{"gcc":2},"errors":1,"unmatched":4,"samples":[{"line":4,"text":"ld.lld: error: undefined symbol: frobnicate"},{"line":5,"text":"Linking failed with 2 errors"}]}`
+	if string(b) != wantJSON {
+		t.Logf("was expecting %s got %s", wantJSON, b)
+		t.Fail()
+	}
+
+	var again ParseReport
+	ParseReader(strings.NewReader(unparsedLog), WithReport(&again, 2), Parallel())
+	if !reflect.DeepEqual(again, report) {
+		t.Logf("the report of a second parse is\n%+v", again)
+		t.Fail()
+	}
+}
+
+func TestParseReportSampleLength(t *testing.T) {
+	var report ParseReport
+	ParseReader(strings.NewReader("error "+strings.Repeat("x", 1000)), WithReport(&report, 0))
+	if len(report.Samples) != 1 || len(report.Samples[0].Text) > maxSampleLength || report.max != defaultReportSamples {
+		t.Logf("got %+v", report)
+		t.Fail()
+	}
+}
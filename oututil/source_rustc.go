@@ -0,0 +1,25 @@
+package oututil
+
+func init() {
+	registerBuiltinBlock("rustc",
+		// the summaries rustc and cargo print, which look like headers,
+		//
+		// error: aborting due to previous error; 1 warning emitted
+		// warning: `demo` (bin "demo") generated 1 warning
+		// error: could not compile `demo` due to 2 previous errors
+		BlockLine{RoleTerminator, `^(?:error|warning): (?:aborting due to |could not compile |[0-9]+ warnings? emitted$|.* generated [0-9]+ warnings?)`},
+		// error[E0308]: mismatched types
+		// warning: unused variable: `x`
+		BlockLine{RoleHeader, `^(?P<severity>error|warning)(?:\[(?P<code>[A-Z]+[0-9]+)\])?: (?P<message>.*)$`},
+		//  --> src/main.rs:4:9
+		//  ::: src/lib.rs:10:5
+		BlockLine{RoleLocation, `^\s*(?:-->|:::) (?P<file>.+):(?P<line>[0-9]+):(?P<col>[0-9]+)$`},
+		//   = note: expected type `i32`
+		BlockLine{RoleNote, `^\s*= (?:note|help): (?P<message>.*)$`},
+		//   |
+		// 4 |     let x: i32 = "a";
+		//   |            ^^^ expected `i32`, found `&str`
+		// ...
+		BlockLine{RoleContext, `^\s*(?:[0-9]+\s*)?\||^\s*\.\.\.$`},
+	)
+}
@@ -0,0 +1,764 @@
+package oututil
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Option configures how logs are parsed.
+type Option func(*options)
+
+type options struct {
+	tracebackInnermost bool
+	caretColumns       bool
+	keepANSI           bool
+	snippets           bool
+	foldNotes          bool
+	keepLinterSuffix   bool
+	xcode              bool
+	fixIts             bool
+	keepDuplicates     bool
+	maxErrors          int
+	severityRules      []SeverityRule
+	parallel           bool
+	flushTimeout       time.Duration
+	maxLineLength      int
+	maxInputSize       int64
+	// duplicate is called with the index of the first of the
+	// diagnostics that are dropped as duplicates.
+	duplicate func(first int)
+	// formats replace the registered formats and the multi-line
+	// parsers when set.
+	formats []Format
+	// detector counts the matches of every format when set.
+	detector *Detector
+	report   *ParseReport
+	// minConfidence and fsys are MinConfidence and WithSourceFS.
+	minConfidence float64
+	fsys          fs.FS
+	decoder       Decoder
+}
+
+func withFormats(formats []Format) Option {
+	return func(o *options) { o.formats = formats }
+}
+
+// TracebackInnermost makes Python tracebacks produce a single
+// SourceError for the innermost frame instead of one per frame.
+func TracebackInnermost() Option {
+	return func(o *options) { o.tracebackInnermost = true }
+}
+
+// CaretColumns derives the Column of diagnostics that only report a
+// line, like javac's, from the caret line printed under the source.
+func CaretColumns() Option {
+	return func(o *options) { o.caretColumns = true }
+}
+
+// Snippets attaches the lines that follow a diagnostic up to the next
+// blank or location bearing line, like the source excerpt and caret
+// lines compilers print, to its Snippet.
+func Snippets() Option {
+	return func(o *options) { o.snippets = true }
+}
+
+// FoldNotes folds the notes that immediately follow a diagnostic, and
+// the include chain that precedes it, into its Related errors instead
+// of returning them as errors of their own.
+func FoldNotes() Option {
+	return func(o *options) { o.foldNotes = true }
+}
+
+// holds reports whether diagnostics need to wait for the lines that
+// follow them.
+func (o *options) holds() bool {
+	return o.caretColumns || o.snippets || o.foldNotes || o.xcode || o.fixIts
+}
+
+// KeepANSI disables stripping ANSI escape sequences from lines before
+// they are parsed.
+func KeepANSI() Option {
+	return func(o *options) { o.keepANSI = true }
+}
+
+// MaxErrors stops parsing after n source errors, ParseReader and Scan
+// return ErrTruncated if there were more.
+func MaxErrors(n int) Option {
+	return func(o *options) { o.maxErrors = n }
+}
+
+// ErrTruncated is returned, along with the first errors, when there were
+// more source errors than MaxErrors allows.
+var ErrTruncated = errors.New("too many source errors")
+
+func newOptions(opts []Option) *options {
+	o := &options{maxLineLength: defaultMaxLineLength, minConfidence: DefaultMinConfidence}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// maxLineSize is the longest line the scanner will grow its buffer to.
+const maxLineSize = 64 << 20
+
+// ParseReader reads r line by line and returns the source errors in it.
+// Diagnostics that are repeated are returned once with the number of
+// times they were in Occurrences, see KeepDuplicates.
+func ParseReader(r io.Reader, opts ...Option) ([]SourceError, error) {
+	var errors []SourceError
+	count := func(o *options) {
+		o.duplicate = func(first int) { errors[first].Occurrences++ }
+	}
+	err := Scan(r, func(e SourceError) bool {
+		errors = append(errors, e)
+		return true
+	}, append(opts[:len(opts):len(opts)], count)...)
+	return errors, err
+}
+
+// KeepDuplicates disables dropping the diagnostics that have the same
+// file, position, code and message as one before them, like the ones
+// parallel builds print for every file including a header. Scan yields
+// every SourceError once with Occurrences set to 1 since it can't count
+// the ones it hasn't read yet.
+func KeepDuplicates() Option {
+	return func(o *options) { o.keepDuplicates = true }
+}
+
+// Scan reads r line by line and calls yield for every source error it
+// finds. Scanning stops early if yield returns false.
+func Scan(r io.Reader, yield func(SourceError) bool, opts ...Option) error {
+	o := newOptions(opts)
+	r = limitInput(r, o)
+	if o.parallel && o.detector == nil && o.report == nil {
+		return scanParallel(r, yield, o)
+	}
+	scanner, lines := newLineScanner(r, o)
+	s := newSourceScanner(yield, o)
+	for scanner.Scan() {
+		if !s.next(scanner.Text(), lines.size, lines.truncated) {
+			return s.err()
+		}
+	}
+	s.flush()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return s.err()
+}
+
+// lineSplitter splits lines like bufio.ScanLines and records the size
+// of the last one with its line ending. Lines longer than max are cut,
+// see MaxLineLength.
+type lineSplitter struct {
+	size int
+	max  int
+	// truncated is set when the last line was cut, cut is the start of
+	// the line being skipped and skipped the bytes of it skipped so far.
+	truncated bool
+	cut       []byte
+	skipped   int
+}
+
+func (l *lineSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) > 0 && l.tooLong(data) {
+		return l.splitLong(data, atEOF)
+	}
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token != nil {
+		l.size, l.truncated = advance, false
+	}
+	return advance, token, err
+}
+
+// blockParser is a stateful parser for diagnostics that span several
+// lines of a log.
+type blockParser interface {
+	// parse is called with every line of the log and reports whether
+	// the line was consumed.
+	parse(line string, emit func(SourceError)) bool
+	// flush is called at the end of the log.
+	flush(emit func(SourceError))
+}
+
+// sourceScanner feeds lines to the block parsers and falls back to the
+// single line patterns for lines none of them consumed.
+type sourceScanner struct {
+	blocks []blockParser
+	// emitBlock is emit, kept to not allocate a method value for every
+	// line.
+	emitBlock func(SourceError)
+	formats   []Format
+	opts      *options
+	yield     func(SourceError) bool
+	// pending is the last SourceError, held back until the lines that
+	// follow it can no longer change it, pendingRaw the lines added to
+	// its Raw since.
+	pending    *SourceError
+	pendingRaw []string
+	// included is the include chain reported before a diagnostic.
+	included []SourceError
+	// dirs are the directories make entered, the directory lines before
+	// dirsFrom are ignored.
+	dirs     []string
+	dirsFrom int
+	// target is the build target the lines come from, see buildStep.
+	target string
+	// goPackage is the package of the last "# package" header and
+	// incomplete is set when a Go tool stopped at too many errors.
+	goPackage  string
+	incomplete bool
+	// ldArch is the architecture of the undefined symbols being listed,
+	// see Xcode.
+	ldArch string
+	// seen are the diagnostics sent by the order they were sent in, see
+	// KeepDuplicates.
+	seen map[dedupeKey]int
+	// logLine and logOffset are the number and offset of the line being
+	// parsed, offset the offset of the next one.
+	logLine           int
+	logOffset, offset int64
+	// raw are the lines of the diagnostic being parsed, starting at
+	// rawLine and rawOffset, emitted is set when one of them produced
+	// a SourceError.
+	raw       []string
+	rawLine   int
+	rawOffset int64
+	emitted   bool
+	// parsed is the part of the last raw line the parsers were given,
+	// the Spans they make are in it.
+	parsed string
+	// lineTruncated is set when the last line was cut, see
+	// MaxLineLength.
+	lineTruncated bool
+	// sent is the number of errors yielded, truncated is set when
+	// scanning stopped at MaxErrors.
+	sent      int
+	truncated bool
+	stopped   bool
+	// files are the files of the WithSourceFS that were looked up.
+	files map[string]bool
+}
+
+func newSourceScanner(yield func(SourceError) bool, o *options) *sourceScanner {
+	s := &sourceScanner{
+		opts:  o,
+		yield: yield,
+	}
+	s.emitBlock = s.emit
+	if o.formats != nil {
+		s.formats = o.formats
+		return s
+	}
+	s.formats = RegisteredFormats()
+	for _, f := range RegisteredBlockFormats() {
+		s.blocks = append(s.blocks, &blockScanner{format: f, notes: o.foldNotes, ended: s.emitEnded})
+	}
+	s.blocks = append(s.blocks,
+		&pythonParser{innermost: o.tracebackInnermost},
+		&goPanicParser{},
+		&eslintParser{},
+	)
+	return s
+}
+
+// match tries the formats against line in order.
+func (s *sourceScanner) match(line string) (SourceError, bool) {
+	if file, masked, ok := maskPath(line); ok {
+		if e, ok := s.matchFormats(masked); ok {
+			e.File = file
+			e.Spans = unmask(e.Spans, line, masked, file)
+			return e, true
+		}
+	}
+	return s.matchFormats(line)
+}
+
+func (s *sourceScanner) matchFormats(line string) (SourceError, bool) {
+	if s.opts.detector != nil {
+		return s.opts.detector.match(s.formats, line)
+	}
+	for _, f := range s.formats {
+		if e, ok := f.Match(line); ok {
+			return e, true
+		}
+	}
+	return SourceError{}, false
+}
+
+// pathSegment matches a character of a path or a parenthesized part of
+// one without spaces, like the (x86) of C:\Program Files (x86).
+const pathSegment = `(?:[^\s:()"']|\([^\s():"']*\))`
+
+var (
+	// quotedPath matches a quoted file name followed by a position at
+	// the start of a line,
+	//
+	//	"C:\Program Files\app\main.c"(3): error C2065: ...
+	quotedPath = regexp.MustCompile(`^\s*(?:"([^"]+)"|'([^']+)')[:(][0-9]`)
+	// virtualPath matches the names tools give input that doesn't come
+	// from a file, see IsVirtual,
+	//
+	//	<stdin>:5:2: error: ...
+	//	-:5:2: error: ...
+	virtualPath = regexp.MustCompile(`^\s*(<[[:alpha:]][[:alpha:] -]*>|-)[:(][0-9]`)
+	// spacedPath matches a file name with spaces followed by a position
+	// at the start of a line, like the ones in Xcode logs,
+	//
+	//	/Users/me/My App/main.swift:3:5: error: ...
+	//
+	// Only file names starting the line and with a directory can have
+	// spaces, the words before one would be taken for part of it
+	// otherwise, like in "Error in app.c:3:4: ...".
+	spacedPath = regexp.MustCompile(`^((?:[A-Za-z]:)?` + pathSegment + `+(?: ` + pathSegment + `+)* ` +
+		pathSegment + `*[[:alnum:]]\.[[:alnum:]]+)[:(][0-9]`)
+)
+
+// maskedPath replaces the file names the formats can't match.
+const maskedPath = "path.masked"
+
+// maskPath returns the quoted, virtual, spaced or URL file name line
+// starts with and line with it replaced by a name the formats match.
+func maskPath(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" {
+		return "", "", false
+	}
+	if strings.Contains(line, "://") {
+		if m := urlPath.FindStringSubmatchIndex(line); m != nil {
+			return line[m[2]:m[3]], line[:m[2]] + maskedPath + line[m[3]:], true
+		}
+	}
+	switch trimmed[0] {
+	case '"', '\'':
+		if m := quotedPath.FindStringSubmatchIndex(line); m != nil {
+			file := m[2:4]
+			if file[0] < 0 {
+				file = m[4:6]
+			}
+			return line[file[0]:file[1]], line[:file[0]-1] + maskedPath + line[file[1]+1:], true
+		}
+	case '<', '-':
+		if m := virtualPath.FindStringSubmatchIndex(line); m != nil {
+			return line[m[2]:m[3]], line[:m[2]] + maskedPath + line[m[3]:], true
+		}
+	}
+	start := 0
+	if len(line) > 2 && line[1] == ':' && isLetter(line[0]) {
+		start = 2
+	}
+	if space := strings.IndexByte(line, ' '); space > start && space < start+strings.IndexAny(line[start:], ":(") {
+		if m := spacedPath.FindStringSubmatchIndex(line); m != nil && strings.ContainsAny(line[:m[3]], `/\`) {
+			return line[:m[3]], maskedPath + line[m[3]:], true
+		}
+	}
+	return "", "", false
+}
+
+func (s *sourceScanner) emit(e SourceError) {
+	e.Message = cleanMessage(e.Message, s.opts.keepANSI)
+	if s.opts.severityRules != nil {
+		e = inferSeverity(e, s.opts.severityRules)
+	}
+	if e.Raw == "" {
+		e.Raw = strings.Join(s.raw, "\n")
+		e.LogLine, e.LogOffset = s.rawLine, s.rawOffset
+		if !e.Spans.IsEmpty() && len(s.raw) > 0 {
+			e.Spans = locate(e.Spans, s.parsed, s.raw[len(s.raw)-1])
+			e.Spans.Line = len(s.raw) - 1
+		}
+	}
+	if e.Confidence == 0 {
+		// the multi-line parsers only match diagnostics
+		e.Confidence = 1
+	}
+	s.emitted = true
+	if s.opts.report != nil {
+		s.opts.report.Matched[e.Tool]++
+	}
+	if s.lineTruncated {
+		e.LineTruncated = true
+	}
+	if e.Dir == "" && len(s.dirs) > 0 {
+		e.Dir = s.dirs[len(s.dirs)-1]
+	}
+	if e.Target == "" {
+		e.Target = s.target
+	}
+	if e.Package == "" {
+		e.Package = s.goPackage
+	}
+	if s.opts.foldNotes {
+		if e.Severity == SeverityNote && e.Kind == KindDiagnostic && s.pending != nil {
+			s.pending.Related = append(s.pending.Related, e)
+			return
+		}
+		if len(s.included) > 0 {
+			e.Related = append(s.included, e.Related...)
+			s.included = nil
+		}
+	}
+	s.release()
+	if s.opts.holds() {
+		s.pending = &e
+		return
+	}
+	s.send(e)
+}
+
+// appendRaw adds line to the Raw of the pending SourceError. The lines
+// are joined once it's released, joining them as they come takes time
+// quadratic in their number.
+func (s *sourceScanner) appendRaw(line string) {
+	s.pendingRaw = append(s.pendingRaw, line)
+}
+
+// release sends the pending SourceError.
+func (s *sourceScanner) release() {
+	if s.pending != nil {
+		e := *s.pending
+		if len(s.pendingRaw) > 0 {
+			e.Raw += "\n" + strings.Join(s.pendingRaw, "\n")
+		}
+		s.pending, s.pendingRaw = nil, s.pendingRaw[:0]
+		s.send(e)
+	}
+}
+
+func (s *sourceScanner) send(e SourceError) {
+	if s.stopped {
+		return
+	}
+	if s.opts.xcode && e.Tool == "gcc" && strings.HasSuffix(e.File, ".swift") {
+		e.Tool = "swift"
+	}
+	e.Occurrences = 1
+	// notes and stack frames repeat for every diagnostic and goroutine
+	// they belong to
+	if !s.opts.keepDuplicates && e.Kind == KindDiagnostic && e.Severity != SeverityNote {
+		k := keyOf(e)
+		if i, ok := s.seen[k]; ok {
+			if s.opts.duplicate != nil {
+				s.opts.duplicate(i)
+			}
+			return
+		}
+		if s.seen == nil {
+			s.seen = make(map[dedupeKey]int)
+		}
+		s.seen[k] = s.sent
+	}
+	if s.opts.maxErrors > 0 && s.sent >= s.opts.maxErrors {
+		s.truncated, s.stopped = true, true
+		return
+	}
+	s.sent++
+	if s.opts.report != nil {
+		s.opts.report.Errors++
+	}
+	if !s.yield(e) {
+		s.stopped = true
+	}
+}
+
+func (s *sourceScanner) err() error {
+	if s.truncated {
+		return ErrTruncated
+	}
+	if s.incomplete {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// next processes the next line of the log, size is its size with its
+// line ending and truncated is set when it was cut by MaxLineLength.
+// The byte order mark of the log and the carriage returns
+// left by Windows line endings are dropped, and lines that aren't UTF-8
+// decoded, see WithDecoder.
+func (s *sourceScanner) next(line string, size int, truncated bool) bool {
+	s.lineTruncated = truncated
+	s.logLine++
+	s.logOffset = s.offset
+	s.offset += int64(size)
+	if s.logLine == 1 {
+		line = strings.TrimPrefix(line, byteOrderMark)
+	}
+	return s.line(s.decode(strings.TrimRight(line, "\r")))
+}
+
+const byteOrderMark = "\ufeff"
+
+// cleanMessage drops the control characters but tabs from a message,
+// the escapes of ANSI sequences are kept with KeepANSI.
+func cleanMessage(m string, keepANSI bool) string {
+	clean := func(r rune) rune {
+		if r == '\t' || keepANSI && r == '\x1b' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}
+	if strings.IndexFunc(m, func(r rune) bool { return clean(r) < 0 }) < 0 {
+		return m
+	}
+	return strings.Map(clean, m)
+}
+
+// line processes a single line and reports whether scanning should
+// continue.
+func (s *sourceScanner) line(line string) bool {
+	if len(s.raw) == 0 {
+		s.rawLine, s.rawOffset = s.logLine, s.logOffset
+	}
+	s.raw = append(s.raw, line)
+	s.emitted = false
+	consumed := s.parse(line)
+	if r := s.opts.report; r != nil {
+		r.Lines++
+		if !consumed && !s.emitted {
+			r.unmatched(s.logLine, line)
+		}
+	}
+	if !consumed || s.emitted {
+		s.raw = s.raw[:0]
+	}
+	return !s.stopped
+}
+
+// parse parses line and reports whether a block parser consumed it.
+func (s *sourceScanner) parse(line string) bool {
+	if !s.opts.keepANSI {
+		line = stripANSI(line)
+	}
+	line, ok := s.buildStep(line)
+	if ok {
+		return false
+	}
+	line, severity := stripBuildPrefix(line)
+	if s.directory(line) || s.includeChain(line) || s.goTool(line) {
+		return false
+	}
+	if s.opts.xcode {
+		if ok, header := s.undefinedSymbol(line); ok {
+			return header
+		}
+	}
+	s.parsed = line
+	for _, b := range s.blocks {
+		if b.parse(line, s.emitBlock) {
+			return true
+		}
+	}
+	if parts := splitInterleaved(line); parts != nil {
+		for _, part := range parts {
+			s.matchLine(part, severity)
+		}
+		return false
+	}
+	s.matchLine(line, severity)
+	return false
+}
+
+// matchLine emits the diagnostic on line or adds line to the pending
+// one. severity is the one implied by the build prefix of the line.
+func (s *sourceScanner) matchLine(line string, severity Severity) {
+	s.parsed = line
+	e, ok := s.match(line)
+	if !ok {
+		s.continuation(line)
+		return
+	}
+	if e.Severity == SeverityUnknown {
+		e.Severity = severity
+	}
+	if !s.opts.keepLinterSuffix {
+		e = trimLinterSuffix(e)
+	}
+	if e.Confidence = s.confidence(e); e.Confidence < s.opts.minConfidence {
+		s.continuation(line)
+		return
+	}
+	s.emit(markWarningAsError(markWarningFlags(e, s.opts.keepLinterSuffix)))
+}
+
+// interleaved matches the start of gcc style diagnostics with a
+// severity, to find the lines where parallel builds wrote two of them,
+//
+//	a.h:3:1: error: unknown type name 'foo'b.h:7:2: warning: unused variable 'x'
+//
+// The file names are stricter than filePattern's so the brackets and
+// quotes that end a message aren't taken for the start of the next one.
+var interleaved = regexp.MustCompile(`(?:[A-Za-z]:)?[^\s:()"'\[\]]*[[:alnum:]]\.[[:alnum:]]+:[0-9]+:[0-9]+: (?:fatal error|error|warning|note):`)
+
+// splitInterleaved splits line before every diagnostic in it if there
+// is more than one, it returns nil otherwise.
+func splitInterleaved(line string) []string {
+	if strings.Count(line, "error:")+strings.Count(line, "warning:")+strings.Count(line, "note:") < 2 {
+		return nil
+	}
+	matches := interleaved.FindAllStringIndex(line, -1)
+	if len(matches) < 2 {
+		return nil
+	}
+	parts := make([]string, 0, len(matches))
+	start := 0
+	for _, m := range matches[1:] {
+		parts = append(parts, strings.TrimRight(line[start:m[0]], " "))
+		start = m[0]
+	}
+	return append(parts, line[start:])
+}
+
+// makeDirectory matches the lines make prints when it runs in another
+// directory,
+//
+//	make[1]: Entering directory '/src/lib'
+//	make[1]: Leaving directory '/src/lib'
+var makeDirectory = regexp.MustCompile("^[[:alnum:]_.-]*make(?:\\[[0-9]+\\])?: (Entering|Leaving) directory [`'](.*)'$")
+
+// directory reports whether line is one of make's directory lines and
+// keeps track of the directory the errors that follow come from.
+func (s *sourceScanner) directory(line string) bool {
+	dirs, ok := changeDirectory(s.dirs, line)
+	if ok && s.logLine >= s.dirsFrom {
+		s.dirs = dirs
+	}
+	return ok
+}
+
+// changeDirectory returns dirs with the directory line enters pushed or
+// the one it leaves popped, it reports whether line is one of make's
+// directory lines.
+func changeDirectory(dirs []string, line string) ([]string, bool) {
+	m := makeDirectory.FindStringSubmatch(line)
+	if m == nil {
+		return dirs, false
+	}
+	if m[1] == "Entering" {
+		return append(dirs, m[2]), true
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == m[2] {
+			return dirs[:i], true
+		}
+	}
+	return dirs, true
+}
+
+// includeChain matches the "In file included from" lines gcc and clang
+// print before diagnostics in headers,
+//
+//	In file included from /usr/include/stdio.h:27,
+//	                 from main.c:1:
+var includeChain = regexp.MustCompile(`^(?:In file included|\s+) from (?P<file>.+?):(?P<line>[0-9]+)(?::(?P<col>[0-9]+))?[,:]$`)
+
+// includeChain reports whether line is part of an include chain.
+func (s *sourceScanner) includeChain(line string) bool {
+	m := includeChain.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	e := fromSubmatch(includeChain, m)
+	e.Severity = SeverityNote
+	e.Message = "included from here"
+	e.Tool = "gcc"
+	if !s.opts.foldNotes {
+		s.emit(e)
+		return true
+	}
+	s.release()
+	s.included = append(s.included, e)
+	return true
+}
+
+// continuation is called with the lines that follow a diagnostic but
+// don't carry a location of their own.
+func (s *sourceScanner) continuation(line string) {
+	s.included = nil
+	if s.pending == nil {
+		return
+	}
+	if strings.TrimSpace(line) == "" {
+		s.release()
+		return
+	}
+	if s.opts.fixIts {
+		if f, ok := parseFixIt(line); ok {
+			s.pending.Fixes = append(s.pending.Fixes, f)
+			s.appendRaw(s.raw[len(s.raw)-1])
+			return
+		}
+	}
+	if s.opts.caretColumns && s.pending.Column == NoColumn {
+		if i := strings.IndexByte(line, '^'); i >= 0 && strings.TrimSpace(line[:i]) == "" {
+			s.pending.Column = i + 1
+		}
+	}
+	if s.opts.snippets {
+		s.pending.Snippet = append(s.pending.Snippet, line)
+		s.appendRaw(s.raw[len(s.raw)-1])
+	}
+}
+
+// emitEnded emits e, the diagnostic of a block the line being parsed
+// ended, with the lines before it but the blank ones it ended with. The
+// line is then parsed as if it started the raw lines.
+func (s *sourceScanner) emitEnded(e SourceError) {
+	n := len(s.raw) - 1
+	end := n
+	for end > 0 && strings.TrimSpace(s.raw[end-1]) == "" {
+		end--
+	}
+	if end > 0 {
+		e.Raw = strings.Join(s.raw[:end], "\n")
+		e.LogLine, e.LogOffset = s.rawLine, s.rawOffset
+	}
+	emitted := s.emitted
+	s.emit(e)
+	s.emitted = emitted
+	if n > 0 {
+		s.raw = append(s.raw[:0], s.raw[n])
+		s.rawLine, s.rawOffset = s.logLine, s.logOffset
+	}
+}
+
+func (s *sourceScanner) flush() {
+	for _, b := range s.blocks {
+		b.flush(s.emit)
+	}
+	s.release()
+}
+
+// buildPrefixes are the prefixes build tools put in front of the
+// output of the compilers they run, with the severity they imply.
+var buildPrefixes = []struct {
+	prefix   string
+	severity Severity
+}{
+	{"[ERROR] ", SeverityError},
+	{"[WARNING] ", SeverityWarning},
+	{"[WARN] ", SeverityWarning},
+	{"[INFO] ", SeverityNote},
+	// bazel
+	{"ERROR: ", SeverityError},
+	{"WARNING: ", SeverityWarning},
+	{"INFO: ", SeverityNote},
+}
+
+// stripBuildPrefix removes a maven or bazel style prefix from line and
+// returns the severity it implies.
+func stripBuildPrefix(line string) (string, Severity) {
+	for _, p := range buildPrefixes {
+		if strings.HasPrefix(line, p.prefix) {
+			return line[len(p.prefix):], p.severity
+		}
+	}
+	return line, SeverityUnknown
+}
@@ -0,0 +1,65 @@
+package oututil
+
+import "sort"
+
+// less orders source errors by file, position, code and message.
+func less(a, b SourceError) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if pa, pb := a.Position(), b.Position(); pa.Before(pb) || pb.Before(pa) {
+		return pa.Before(pb)
+	}
+	if a.Code != b.Code {
+		return a.Code < b.Code
+	}
+	return a.Message < b.Message
+}
+
+// Sort sorts errs by file, line and column. Errors without a column
+// come before the ones on the same line that have one; ties are broken
+// by code and message and the sort is stable.
+func Sort(errs []SourceError) {
+	sort.SliceStable(errs, func(i, j int) bool { return less(errs[i], errs[j]) })
+}
+
+// dedupeKey is what makes two source errors the same.
+type dedupeKey struct {
+	file    string
+	pos     SourcePosition
+	code    string
+	message string
+}
+
+func keyOf(e SourceError) dedupeKey {
+	return dedupeKey{e.File, e.Position(), e.Code, e.Message}
+}
+
+// Dedupe returns errs without the errors that have the same file, line,
+// column, code and message as an error before them.
+func Dedupe(errs []SourceError) []SourceError {
+	seen := make(map[dedupeKey]bool, len(errs))
+	deduped := make([]SourceError, 0, len(errs))
+	for _, e := range errs {
+		k := keyOf(e)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		deduped = append(deduped, e)
+	}
+	return deduped
+}
+
+// GroupByFile groups errs by their file, the errors of every file are
+// sorted like Sort does.
+func GroupByFile(errs []SourceError) map[string][]SourceError {
+	groups := make(map[string][]SourceError)
+	for _, e := range errs {
+		groups[e.File] = append(groups[e.File], e)
+	}
+	for _, group := range groups {
+		Sort(group)
+	}
+	return groups
+}
@@ -0,0 +1,55 @@
+package oututil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	errs := []SourceError{
+		{File: "b.go", Line: 1, Column: 1, Message: "b"},
+		{File: "a.go", Line: 10, Column: 1, Message: "a10"},
+		{File: "a.go", Line: 2, Column: 5, Message: "a2:5"},
+		{File: "a.go", Line: 2, Column: NoColumn, Message: "a2"},
+		{File: "a.go", Line: 2, Column: 5, Code: "A", Message: "a2:5 A"},
+	}
+	Sort(errs)
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Message)
+	}
+	want := []string{"a2", "a2:5", "a2:5 A", "a10", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Logf("was expecting %q got %q instead", want, got)
+		t.Fail()
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	header := SourceError{File: "x.h", Line: 3, Column: 1, Severity: SeverityError, Message: "unknown type name 'foo'"}
+	other := header
+	other.Code = "E1"
+	errs := Dedupe([]SourceError{header, other, header, {File: "a.c", Line: 1, Column: 1}, header})
+	if len(errs) != 3 {
+		t.Fatalf("was expecting 3 errors got %d instead", len(errs))
+	}
+	if errs[0].Code != "" || errs[1].Code != "E1" || errs[2].File != "a.c" {
+		t.Logf("dedupe didn't keep the first occurrences in order %v", errs)
+		t.Fail()
+	}
+}
+
+func TestGroupByFile(t *testing.T) {
+	groups := GroupByFile([]SourceError{
+		{File: "b.go", Line: 3},
+		{File: "a.go", Line: 2},
+		{File: "b.go", Line: 1},
+	})
+	if len(groups) != 2 || len(groups["a.go"]) != 1 || len(groups["b.go"]) != 2 {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+	if groups["b.go"][0].Line != 1 {
+		t.Log("groups should be sorted by position")
+		t.Fail()
+	}
+}
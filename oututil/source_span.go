@@ -0,0 +1,144 @@
+package oututil
+
+import "math"
+
+// SourcePosition is a position in a file. Line and Column start at 1,
+// a Column below 1, like NoColumn, means the tool only reported the
+// line.
+type SourcePosition struct {
+	Line, Column int
+}
+
+// HasColumn reports whether p has a column.
+func (p SourcePosition) HasColumn() bool { return p.Column > 0 }
+
+// col returns the column of p, missing ones are before the first.
+func (p SourcePosition) col() int {
+	if p.Column < 1 {
+		return 0
+	}
+	return p.Column
+}
+
+// Before reports whether p comes before q. A position without a column
+// comes before the ones on its line that have one and isn't before
+// another one without a column.
+func (p SourcePosition) Before(q SourcePosition) bool {
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.col() < q.col()
+}
+
+// SourceSpan is a range of a file, its End is exclusive. A span with a
+// zero End is the character at its Start, or its line if Start doesn't
+// have a column. An End without a column is the end of its line. A span
+// whose End is its Start is empty, like the range of an insertion.
+type SourceSpan struct {
+	Start, End SourcePosition
+}
+
+// endOfLine is the column of the bounds of spans that run to the end
+// of their line.
+const endOfLine = math.MaxInt32
+
+// bounds returns the first position of s and the one after it with the
+// columns spelled out.
+func (s SourceSpan) bounds() (lo, hi SourcePosition) {
+	lo = SourcePosition{s.Start.Line, s.Start.col()}
+	switch {
+	case s.End.Line == 0 && s.Start.HasColumn():
+		hi = SourcePosition{s.Start.Line, s.Start.Column + 1}
+	case s.End.Line == 0:
+		hi = SourcePosition{s.Start.Line, endOfLine}
+	case s.End.HasColumn():
+		hi = s.End
+	default:
+		hi = SourcePosition{s.End.Line, endOfLine}
+	}
+	if hi.Before(lo) {
+		hi = lo
+	}
+	return lo, hi
+}
+
+// Contains reports whether all of t is in s, empty spans are in the
+// spans they are at or in.
+func (s SourceSpan) Contains(t SourceSpan) bool {
+	slo, shi := s.bounds()
+	tlo, thi := t.bounds()
+	return !tlo.Before(slo) && !shi.Before(thi)
+}
+
+// Overlaps reports whether s and t have a character in common. An
+// empty span overlaps the spans it is strictly inside of and the ones
+// that start where it does.
+func (s SourceSpan) Overlaps(t SourceSpan) bool {
+	slo, shi := s.bounds()
+	tlo, thi := t.bounds()
+	if slo == shi || tlo == thi {
+		return slo == tlo || slo.Before(thi) && tlo.Before(shi)
+	}
+	return slo.Before(thi) && tlo.Before(shi)
+}
+
+// Union returns the smallest span that contains s and t. Its Start has
+// no column if the start of one of them doesn't and its End has none
+// if one of them runs to the end of the line.
+func (s SourceSpan) Union(t SourceSpan) SourceSpan {
+	slo, shi := s.bounds()
+	tlo, thi := t.bounds()
+	lo, hi := slo, shi
+	if tlo.Before(lo) {
+		lo = tlo
+	}
+	if hi.Before(thi) {
+		hi = thi
+	}
+	if lo.Column == 0 {
+		lo.Column = NoColumn
+	}
+	if hi.Column == endOfLine {
+		hi.Column = NoColumn
+	}
+	return SourceSpan{lo, hi}
+}
+
+// Position returns the Line and Column of e.
+func (e SourceError) Position() SourcePosition {
+	return SourcePosition{e.Line, e.Column}
+}
+
+// Span returns the range of e, its End is zero when the tool only
+// reported a position.
+func (e SourceError) Span() SourceSpan {
+	s := SourceSpan{Start: e.Position()}
+	if e.EndLine == 0 && e.EndColumn == 0 {
+		return s
+	}
+	s.End = SourcePosition{e.EndLine, e.EndColumn}
+	if s.End.Line == 0 {
+		s.End.Line = e.Line
+	}
+	if e.EndColumn == 0 {
+		s.End.Column = NoColumn
+	}
+	return s
+}
+
+// SetSpan sets the position and the range of e to s.
+func (e *SourceError) SetSpan(s SourceSpan) {
+	e.Line, e.Column = s.Start.Line, s.Start.Column
+	e.EndLine, e.EndColumn = s.End.Line, s.End.Column
+	if !s.End.HasColumn() {
+		e.EndColumn = 0
+	}
+}
+
+// Span returns the range f replaces.
+func (f Fix) Span() SourceSpan {
+	return SourceSpan{
+		Start: SourcePosition{f.StartLine, f.StartCol},
+		End:   SourcePosition{f.EndLine, f.EndCol},
+	}
+}
@@ -0,0 +1,167 @@
+package oututil
+
+import "testing"
+
+func pos(line, col int) SourcePosition { return SourcePosition{line, col} }
+
+func span(l1, c1, l2, c2 int) SourceSpan { return SourceSpan{pos(l1, c1), pos(l2, c2)} }
+
+func TestPositionBefore(t *testing.T) {
+	tests := []struct {
+		p, q SourcePosition
+		want bool
+	}{
+		{pos(1, 5), pos(2, 1), true},
+		{pos(2, 1), pos(1, 5), false},
+		{pos(3, 2), pos(3, 7), true},
+		{pos(3, NoColumn), pos(3, 1), true},
+		{pos(3, 1), pos(3, NoColumn), false},
+		{pos(3, NoColumn), pos(3, 0), false},
+		{pos(3, 4), pos(3, 4), false},
+	}
+	for _, test := range tests {
+		if got := test.p.Before(test.q); got != test.want {
+			t.Logf("%v.Before(%v) = %v, expected %v", test.p, test.q, got, test.want)
+			t.Fail()
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	line := SourceSpan{Start: pos(3, NoColumn)}
+	point := SourceSpan{Start: pos(3, 5)}
+	tests := []struct {
+		name               string
+		s, t               SourceSpan
+		contains, overlaps bool
+		union              SourceSpan
+	}{
+		{
+			name:     "nested",
+			s:        span(3, 1, 3, 10),
+			t:        span(3, 2, 3, 4),
+			contains: true,
+			overlaps: true,
+			union:    span(3, 1, 3, 10),
+		},
+		{
+			name:     "crossing",
+			s:        span(3, 1, 3, 5),
+			t:        span(3, 4, 4, 2),
+			overlaps: true,
+			union:    span(3, 1, 4, 2),
+		},
+		{
+			name:  "adjacent",
+			s:     span(3, 1, 3, 5),
+			t:     span(3, 5, 3, 8),
+			union: span(3, 1, 3, 8),
+		},
+		{
+			name:     "line contains point",
+			s:        line,
+			t:        point,
+			contains: true,
+			overlaps: true,
+			union:    span(3, NoColumn, 3, NoColumn),
+		},
+		{
+			name:     "point doesn't contain line",
+			s:        point,
+			t:        line,
+			overlaps: true,
+			union:    span(3, NoColumn, 3, NoColumn),
+		},
+		{
+			name:     "point",
+			s:        span(3, 1, 3, 10),
+			t:        point,
+			contains: true,
+			overlaps: true,
+			union:    span(3, 1, 3, 10),
+		},
+		{
+			name:     "insertion inside",
+			s:        span(3, 1, 3, 10),
+			t:        span(3, 4, 3, 4),
+			contains: true,
+			overlaps: true,
+			union:    span(3, 1, 3, 10),
+		},
+		{
+			name:     "insertion at the end",
+			s:        span(3, 1, 3, 10),
+			t:        span(3, 10, 3, 10),
+			contains: true,
+			union:    span(3, 1, 3, 10),
+		},
+		{
+			name:     "insertion at the start",
+			s:        span(3, 1, 3, 10),
+			t:        span(3, 1, 3, 1),
+			contains: true,
+			overlaps: true,
+			union:    span(3, 1, 3, 10),
+		},
+		{
+			name:     "end of line",
+			s:        span(2, 4, 3, NoColumn),
+			t:        span(3, 1, 3, 80),
+			contains: true,
+			overlaps: true,
+			union:    span(2, 4, 3, NoColumn),
+		},
+		{
+			name:  "apart",
+			s:     span(1, 1, 1, 3),
+			t:     span(5, 1, 5, 3),
+			union: span(1, 1, 5, 3),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.s.Contains(test.t); got != test.contains {
+				t.Logf("Contains = %v, expected %v", got, test.contains)
+				t.Fail()
+			}
+			if got := test.s.Overlaps(test.t); got != test.overlaps {
+				t.Logf("Overlaps = %v, expected %v", got, test.overlaps)
+				t.Fail()
+			}
+			if got := test.t.Overlaps(test.s); got != test.overlaps {
+				t.Logf("reversed Overlaps = %v, expected %v", got, test.overlaps)
+				t.Fail()
+			}
+			if got := test.s.Union(test.t); got != test.union {
+				t.Logf("Union = %v, expected %v", got, test.union)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSourceErrorSpan(t *testing.T) {
+	tests := []struct {
+		e    SourceError
+		want SourceSpan
+	}{
+		{SourceError{Line: 3, Column: NoColumn}, SourceSpan{Start: pos(3, NoColumn)}},
+		{SourceError{Line: 3, Column: 2}, SourceSpan{Start: pos(3, 2)}},
+		{SourceError{Line: 3, Column: 2, EndLine: 4, EndColumn: 7}, span(3, 2, 4, 7)},
+		{SourceError{Line: 3, Column: 2, EndColumn: 7}, span(3, 2, 3, 7)},
+		{SourceError{Line: 3, Column: 2, EndLine: 5}, span(3, 2, 5, NoColumn)},
+	}
+	for _, test := range tests {
+		got := test.e.Span()
+		if got != test.want {
+			t.Logf("%+v: got %v, expected %v", test.e, got, test.want)
+			t.Fail()
+		}
+		var e SourceError
+		e.SetSpan(got)
+		if e.Span() != got {
+			t.Logf("%+v: SetSpan(%v) round trips to %v", test.e, got, e.Span())
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package oututil
+
+import (
+	"bufio"
+	"errors"
+)
+
+// Parser parses a log written to it in chunks of any size, like the
+// output of a build copied to it as it comes. A line is only parsed once
+// its line ending was written, so a diagnostic whose line is cut
+// between two writes, in the middle of its file name or column, is the
+// same as if it was written at once. Close parses the line that didn't
+// end.
+type Parser struct {
+	s     *sourceScanner
+	lines lineSplitter
+	// buf holds the start of the line that didn't end yet.
+	buf     []byte
+	written int64
+	done    bool
+	err     error
+}
+
+// ErrParserClosed is returned by the writes to a Parser after Close.
+var ErrParserClosed = errors.New("parser is closed")
+
+// NewParser returns a Parser that calls yield for every source error
+// parsed from what is written to it, it stops parsing if yield returns
+// false. Parallel doesn't apply to it.
+func NewParser(yield func(SourceError) bool, opts ...Option) *Parser {
+	o := newOptions(opts)
+	return &Parser{
+		s:     newSourceScanner(yield, o),
+		lines: lineSplitter{max: o.maxLineLength},
+	}
+}
+
+// Write parses the lines that end in b and holds the rest of b until
+// the next line ending is written. It returns ErrInputTooLarge once
+// more than MaxInputSize bytes were written and the error of Scan if
+// parsing stopped.
+func (p *Parser) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n := len(b)
+	if max := p.s.opts.maxInputSize; max > 0 && p.written+int64(n) > max {
+		b = b[:max-p.written]
+		p.err = ErrInputTooLarge
+	}
+	p.written += int64(len(b))
+	if p.done {
+		return n, p.err
+	}
+	p.buf = append(p.buf, b...)
+	if err := p.parse(false); err != nil && p.err == nil {
+		p.err = err
+	}
+	if p.err != nil {
+		return len(b), p.err
+	}
+	return n, nil
+}
+
+// parse parses the lines in buf, the line that didn't end too at the
+// end of the log.
+func (p *Parser) parse(atEOF bool) error {
+	start := 0
+	for start < len(p.buf) && !p.done {
+		advance, token, _ := p.lines.split(p.buf[start:], atEOF)
+		if advance == 0 {
+			break
+		}
+		start += advance
+		if token != nil && !p.s.next(string(token), p.lines.size, p.lines.truncated) {
+			p.done = true
+		}
+	}
+	p.buf = append(p.buf[:0], p.buf[start:]...)
+	if cut := p.lines.cut; atEOF && cut != nil && !p.done {
+		// the log ended in a line that was being skipped
+		p.lines.cut = nil
+		if !p.s.next(string(cut), p.lines.skipped, true) {
+			p.done = true
+		}
+	}
+	if p.lines.max <= 0 && len(p.buf) > maxLineSize {
+		return bufio.ErrTooLong
+	}
+	if p.done {
+		return p.s.err()
+	}
+	return nil
+}
+
+// Flush sends the diagnostic held back for the lines that may follow it,
+// like Watch does when its reader is idle for the FlushTimeout. The line
+// that didn't end isn't parsed.
+func (p *Parser) Flush() {
+	if !p.done {
+		p.s.release()
+	}
+}
+
+// Close parses the line that didn't end, sends the diagnostics that are
+// still pending and returns the error of Scan, if any. Writes after it
+// fail with ErrParserClosed.
+func (p *Parser) Close() error {
+	if p.err == ErrParserClosed {
+		return nil
+	}
+	err := p.err
+	if !p.done {
+		if perr := p.parse(true); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	if !p.done {
+		p.s.flush()
+		if serr := p.s.err(); err == nil {
+			err = serr
+		}
+	}
+	p.done, p.err = true, ErrParserClosed
+	return err
+}
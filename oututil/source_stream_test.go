@@ -0,0 +1,159 @@
+package oututil
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+const streamedLog = `src/parser/lexer.c:128:17: error: 'tok' undeclared (first use in this function)
+  128 |         return tok;
+      |                ^~~
+error[E0308]: mismatched types
+ --> src/main.rs:4:18
+  |
+4 |     let x: i32 = "a";
+  |                  ^^^ expected ` + "`i32`" + `, found ` + "`&str`" + `
+src/util.c:40:9: warning: unused variable 'n' [-Wunused-variable]
+`
+
+// parseChunks writes log to a Parser in chunks that end at cuts.
+func parseChunks(t *testing.T, log string, cuts []int, opts ...Option) []SourceError {
+	t.Helper()
+	var errs []SourceError
+	p := NewParser(func(e SourceError) bool {
+		errs = append(errs, e)
+		return true
+	}, opts...)
+	from := 0
+	for _, cut := range append(cuts, len(log)) {
+		if _, err := io.WriteString(p, log[from:cut]); err != nil {
+			t.Fatal(err)
+		}
+		from = cut
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return errs
+}
+
+func TestParserChunks(t *testing.T) {
+	want, err := ParseReader(strings.NewReader(streamedLog), Snippets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 3 {
+		t.Fatalf("was expecting 3 errors got %d", len(want))
+	}
+	for _, cut := range []string{"src/par", "lexer.c:12", "c:128:1", "src/main.", "main.rs:4:1", "util.c:40:", "\n  128"} {
+		i := strings.Index(streamedLog, cut) + len(cut)/2
+		if got := parseChunks(t, streamedLog, []int{i}, Snippets()); !reflect.DeepEqual(got, want) {
+			t.Logf("cut at %d, %q: got\n%+v\nwas expecting\n%+v", i, streamedLog[:i], got, want)
+			t.Fail()
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		var cuts []int
+		for i := 0; i < len(streamedLog); i += 1 + r.Intn(16) {
+			cuts = append(cuts, i)
+		}
+		if got := parseChunks(t, streamedLog, cuts, Snippets()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("cut at %v: got\n%+v\nwas expecting\n%+v", cuts, got, want)
+		}
+	}
+
+	got, err := ParseReader(iotest.OneByteReader(strings.NewReader(streamedLog)), Snippets())
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Logf("reading a byte at a time got %v\n%+v", err, got)
+		t.Fail()
+	}
+}
+
+func TestParserUnterminated(t *testing.T) {
+	var errs []SourceError
+	p := NewParser(func(e SourceError) bool {
+		errs = append(errs, e)
+		return true
+	})
+	io.WriteString(p, "main.c:3:5: error: 'x' undeclared\nmain.c:9:1")
+	p.Flush()
+	if len(errs) != 1 || errs[0].Line != 3 {
+		t.Fatalf("was expecting Flush to send the error on line 3 got %+v", errs)
+	}
+	io.WriteString(p, "2: warning: no return")
+	if len(errs) != 1 {
+		t.Fatalf("the line that didn't end was parsed: %+v", errs)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || errs[1].Line != 9 || errs[1].Column != 12 || errs[1].Message != "no return" {
+		t.Fatalf("was expecting Close to parse the last line got %+v", errs)
+	}
+	if _, err := io.WriteString(p, "\n"); err != ErrParserClosed {
+		t.Logf("was expecting ErrParserClosed got %v", err)
+		t.Fail()
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	var errs []SourceError
+	p := NewParser(func(e SourceError) bool {
+		errs = append(errs, e)
+		return true
+	}, MaxLineLength(32))
+	io.WriteString(p, "main.c:3:5: error: "+strings.Repeat("x", 20))
+	io.WriteString(p, strings.Repeat("y", 100))
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || !errs[0].LineTruncated || errs[0].Message != strings.Repeat("x", 13) {
+		t.Logf("was expecting a truncated error got %+v", errs)
+		t.Fail()
+	}
+
+	p = NewParser(func(SourceError) bool { return true }, MaxInputSize(8))
+	if n, err := io.WriteString(p, "main.c:3:5: error: x\n"); err != ErrInputTooLarge || n != 8 {
+		t.Logf("was expecting 8 bytes and ErrInputTooLarge got %d, %v", n, err)
+		t.Fail()
+	}
+	if err := p.Close(); err != ErrInputTooLarge {
+		t.Logf("was expecting Close to return ErrInputTooLarge got %v", err)
+		t.Fail()
+	}
+}
+
+func TestWatchPartialLine(t *testing.T) {
+	r, w := io.Pipe()
+	ch := make(chan SourceError, 4)
+	errc := make(chan error, 1)
+	go func() { errc <- Watch(context.Background(), r, ch, FlushTimeout(time.Millisecond)) }()
+
+	io.WriteString(w, "src/lexer.c:12")
+	select {
+	case e := <-ch:
+		t.Fatalf("the line that didn't end was parsed: %+v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+	io.WriteString(w, "8:17: error: 'tok' undeclared\n")
+	w.Close()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	var got []SourceError
+	for e := range ch {
+		got = append(got, e)
+	}
+	if len(got) != 1 || got[0].File != "src/lexer.c" || got[0].Line != 128 || got[0].Column != 17 {
+		t.Logf("got %+v", got)
+		t.Fail()
+	}
+}
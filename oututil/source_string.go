@@ -0,0 +1,78 @@
+package oututil
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bracketedCode matches the codes that can be written as error[CODE].
+var bracketedCode = regexp.MustCompile(`^[A-Za-z]+[0-9]+$`)
+
+// Error returns e in the canonical {file}:{line}:{col}: {message} form
+// compilers use, leaving out the column if there isn't one and adding
+// the severity and code when they are known,
+//
+//	main.rs:4:18: error[E0308]: mismatched types
+//	app.js:1:10: error: 'foo' is defined but never used [no-unused-vars]
+//
+// Parsing the result yields an equivalent SourceError as long as e has
+// a file and line.
+func (e SourceError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(e.Column))
+		}
+	}
+	b.WriteString(": ")
+	code := e.Code
+	if e.Severity != SeverityUnknown {
+		b.WriteString(e.Severity.String())
+		if bracketedCode.MatchString(code) {
+			b.WriteString("[" + code + "]")
+			code = ""
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	if code != "" {
+		b.WriteString(" [" + code + "]")
+	}
+	return b.String()
+}
+
+// Format implements fmt.Formatter. %s and %v print the canonical form
+// returned by Error, %q quotes it and %+v also prints the related
+// errors, indented, on the lines that follow.
+func (e SourceError) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(f, "%q", e.Error())
+	case 'v':
+		io.WriteString(f, e.Error())
+		if f.Flag('+') {
+			for _, r := range e.Related {
+				fmt.Fprintf(f, "\n\t%v", r)
+			}
+		}
+	default:
+		io.WriteString(f, e.Error())
+	}
+}
+
+// Rel returns a copy of e with File relative to base. File is left as
+// it is if it can't be made relative to base.
+func (e SourceError) Rel(base string) SourceError {
+	if rel, err := filepath.Rel(base, e.File); err == nil && !strings.HasPrefix(rel, "..") {
+		e.File = rel
+	}
+	return e
+}
@@ -0,0 +1,73 @@
+package oututil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSourceErrorString(t *testing.T) {
+	tests := []struct {
+		e    SourceError
+		want string
+	}{
+		{SourceError{File: "a.go", Line: 1, Column: 2, Message: "missing return"}, "a.go:1:2: missing return"},
+		{SourceError{File: "a.go", Line: 1, Column: NoColumn, Message: "missing return"}, "a.go:1: missing return"},
+		{SourceError{File: "a.go", Line: 1, Column: 0, Message: "missing return"}, "a.go:1: missing return"},
+		{SourceError{File: "main.rs", Line: 4, Column: 18, Severity: SeverityError, Code: "E0308", Message: "mismatched types"}, "main.rs:4:18: error[E0308]: mismatched types"},
+		{SourceError{File: "app.js", Line: 1, Column: 10, Severity: SeverityError, Code: "no-unused-vars", Message: "'foo' is defined but never used"}, "app.js:1:10: error: 'foo' is defined but never used [no-unused-vars]"},
+		{SourceError{File: "x.py", Line: 10, Column: 80, Code: "E501", Message: "line too long"}, "x.py:10:80: line too long [E501]"},
+		{SourceError{File: "main.obj", Column: NoColumn, Severity: SeverityError, Code: "LNK2019", Message: "unresolved external symbol"}, "main.obj: error[LNK2019]: unresolved external symbol"},
+	}
+	for _, test := range tests {
+		if got := test.e.Error(); got != test.want {
+			t.Logf("was expecting %q got %q instead", test.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestSourceErrorRoundTrip(t *testing.T) {
+	for _, test := range sourceTests {
+		for _, e := range test.errors {
+			if e.Line == 0 || e.Project != "" {
+				continue
+			}
+			t.Run(e.Error(), func(t *testing.T) {
+				e.EndLine, e.EndColumn = 0, 0
+				checkSourceErrors(t, []SourceError{e}, ScanSourceError(e.Error()))
+			})
+		}
+	}
+}
+
+func TestSourceErrorFormat(t *testing.T) {
+	e := SourceError{
+		File:     "/src/app/main.cpp",
+		Line:     12,
+		Column:   3,
+		Severity: SeverityError,
+		Message:  "no matching function for call to 'f'",
+		Related: []SourceError{
+			{File: "/src/app/main.cpp", Line: 3, Column: 6, Severity: SeverityNote, Message: "candidate function not viable"},
+		},
+	}
+	if got, want := fmt.Sprintf("%+v", e), "/src/app/main.cpp:12:3: error: no matching function for call to 'f'\n\t/src/app/main.cpp:3:6: note: candidate function not viable"; got != want {
+		t.Logf("was expecting %q got %q instead", want, got)
+		t.Fail()
+	}
+	if got, want := e.Rel("/src").Error(), "app/main.cpp:12:3: error: no matching function for call to 'f'"; got != want {
+		t.Logf("was expecting %q got %q instead", want, got)
+		t.Fail()
+	}
+	if got := e.Rel("/other").File; got != e.File {
+		t.Logf("file outside of base was changed to %q", got)
+		t.Fail()
+	}
+	var err error = e
+	var se SourceError
+	if !errors.As(err, &se) {
+		t.Log("SourceError should be usable as an error")
+		t.Fail()
+	}
+}
@@ -0,0 +1,54 @@
+package oututil
+
+// Summary counts source errors by severity, file and code.
+type Summary struct {
+	Total      int            `json:"total"`
+	Severities map[string]int `json:"severities"`
+	Files      map[string]int `json:"files"`
+	Codes      map[string]int `json:"codes,omitempty"`
+	// Suppressed is the number of errors CapPerFile dropped, they
+	// aren't in the other counts.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Summarize counts errs, errors without a code aren't counted in Codes.
+func Summarize(errs []SourceError) Summary {
+	s := Summary{
+		Total:      len(errs),
+		Severities: make(map[string]int),
+		Files:      make(map[string]int),
+		Codes:      make(map[string]int),
+	}
+	for _, e := range errs {
+		s.Severities[e.Severity.String()]++
+		s.Files[e.File]++
+		if e.Code != "" {
+			s.Codes[e.Code]++
+		}
+		s.Suppressed += e.Suppressed
+	}
+	return s
+}
+
+// WorstSeverity returns the most severe severity in s, errors without a
+// severity count as errors.
+func (s Summary) WorstSeverity() Severity {
+	if s.Severities[SeverityUnknown.String()] > 0 {
+		return SeverityError
+	}
+	for sev := SeverityError; sev > SeverityUnknown; sev-- {
+		if s.Severities[sev.String()] > 0 {
+			return sev
+		}
+	}
+	return SeverityUnknown
+}
+
+// ExitCode returns 1 if s has errors and 0 if it only has warnings and
+// notes.
+func (s Summary) ExitCode() int {
+	if s.WorstSeverity() == SeverityError {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package oututil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		errs  []SourceError
+		worst Severity
+		exit  int
+	}{
+		{nil, SeverityUnknown, 0},
+		{[]SourceError{{Severity: SeverityNote}, {Severity: SeverityWarning}}, SeverityWarning, 0},
+		{[]SourceError{{Severity: SeverityWarning}, {Severity: SeverityError}}, SeverityError, 1},
+		{[]SourceError{{Severity: SeverityUnknown}}, SeverityError, 1},
+	}
+	for _, test := range tests {
+		s := Summarize(test.errs)
+		if worst := s.WorstSeverity(); worst != test.worst {
+			t.Logf("%v: was expecting %s got %s instead", test.errs, test.worst, worst)
+			t.Fail()
+		}
+		if exit := s.ExitCode(); exit != test.exit {
+			t.Logf("%v: was expecting exit code %d got %d instead", test.errs, test.exit, exit)
+			t.Fail()
+		}
+	}
+}
+
+func TestSummarizeJSON(t *testing.T) {
+	b, err := json.Marshal(Summarize([]SourceError{
+		{File: "a.rs", Severity: SeverityError, Code: "E0308"},
+		{File: "a.rs", Severity: SeverityWarning},
+		{File: "b.rs", Severity: SeverityError, Code: "E0308"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"total":3,"severities":{"error":2,"warning":1},"files":{"a.rs":2,"b.rs":1},"codes":{"E0308":2}}`
+	if string(b) != want {
+		t.Logf("was expecting\n%s\ngot\n%s", want, b)
+		t.Fail()
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	const log = "a.go:1: one\na.go:2: two\na.go:3: three\n"
+	errs, err := ParseReader(strings.NewReader(log), MaxErrors(2))
+	if err != ErrTruncated {
+		t.Logf("was expecting ErrTruncated got %v instead", err)
+		t.Fail()
+	}
+	if len(errs) != 2 {
+		t.Logf("was expecting 2 errors got %d instead", len(errs))
+		t.Fail()
+	}
+	errs, err = ParseReader(strings.NewReader(log), MaxErrors(3))
+	if err != nil || len(errs) != 3 {
+		t.Logf("was expecting 3 errors and no error got %d and %v instead", len(errs), err)
+		t.Fail()
+	}
+}
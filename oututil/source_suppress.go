@@ -0,0 +1,99 @@
+package oututil
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// SuppressionRules configures the in-source comments FilterSuppressed
+// honours.
+type SuppressionRules struct {
+	// Directives are the words that suppress diagnostics when they
+	// start a comment, matched case insensitively. They suppress every
+	// diagnostic on their line or, if they are followed by a list like
+	// "nolint:errcheck,gosec" or "NOLINT(bugprone-*)", the ones with the
+	// listed codes.
+	Directives []string
+	// NextLine makes directives on a line of their own apply to the
+	// line that follows them.
+	NextLine bool
+}
+
+// DefaultSuppressions are the suppression comments of golangci-lint,
+// flake8 and clang-tidy,
+//
+//	x, _ := f() //nolint:errcheck
+//	import os  # noqa: F401
+//	int x; /* NOLINT */
+var DefaultSuppressions = SuppressionRules{Directives: []string{"nolint", "noqa"}}
+
+// compile returns the expression matching the directives of r.
+func (r SuppressionRules) compile() *regexp.Regexp {
+	quoted := make([]string, len(r.Directives))
+	for i, d := range r.Directives {
+		quoted[i] = regexp.QuoteMeta(d)
+	}
+	return regexp.MustCompile(`(?i)(?://|#|/\*|--|;)\s*(?:` + strings.Join(quoted, "|") + `)\b(?:\s*[:(]\s*([A-Za-z0-9_.*, -]*))?`)
+}
+
+// FilterSuppressed reads the line of every error from fsys and returns
+// the errors that aren't suppressed by a comment on it and the ones that
+// are. Errors in files that can't be read are kept.
+func FilterSuppressed(errs []SourceError, fsys fs.FS, rules SuppressionRules) (kept, suppressed []SourceError) {
+	if len(rules.Directives) == 0 {
+		return errs, nil
+	}
+	re := rules.compile()
+	files := make(map[string]*sourceFile)
+	for _, e := range errs {
+		if isSuppressed(e, fsys, re, rules.NextLine, files) {
+			suppressed = append(suppressed, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	return kept, suppressed
+}
+
+func isSuppressed(e SourceError, fsys fs.FS, re *regexp.Regexp, nextLine bool, files map[string]*sourceFile) bool {
+	if e.IsVirtual() || e.Line < 1 {
+		return false
+	}
+	f := openSourceFile(fsys, strings.TrimPrefix(slashPath(e.File), "./"), files)
+	if f == nil {
+		return false
+	}
+	if line, ok := f.line(e.Line); ok && suppresses(re, line, e.Code) {
+		return true
+	}
+	if !nextLine {
+		return false
+	}
+	above, ok := f.line(e.Line - 1)
+	if !ok {
+		return false
+	}
+	trimmed := strings.TrimSpace(above)
+	if m := re.FindStringIndex(trimmed); m == nil || m[0] != 0 {
+		// the directive belongs to the code on its line
+		return false
+	}
+	return suppresses(re, trimmed, e.Code)
+}
+
+// suppresses reports whether line has a directive suppressing code.
+func suppresses(re *regexp.Regexp, line, code string) bool {
+	for _, m := range re.FindAllStringSubmatch(line, -1) {
+		list := strings.TrimSpace(m[1])
+		if list == "" {
+			return true
+		}
+		for _, rule := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if matchCode(rule, code) || strings.EqualFold(rule, code) {
+				return true
+			}
+		}
+	}
+	return false
+}
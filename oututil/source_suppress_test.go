@@ -0,0 +1,75 @@
+package oututil
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFilterSuppressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go": {Data: []byte(`package main
+
+func main() {
+	x, _ := f() //nolint:errcheck,gosec // f never fails
+	y := g() //nolint
+	z := h() // nolint:unused
+	//nolint:ineffassign
+	w := 1
+}
+`)},
+		"app.py": {Data: []byte("import os  # noqa: F401\nimport sys  # NOQA\nimport re\n")},
+		"a.cpp":  {Data: []byte("int x; /* NOLINT(bugprone-*) */\nint y;\n")},
+	}
+	errs := []SourceError{
+		{File: "main.go", Line: 4, Code: "errcheck", Message: "suppressed by list"},
+		{File: "main.go", Line: 4, Code: "unused", Message: "not in the list"},
+		{File: "main.go", Line: 5, Code: "errcheck", Message: "suppressed by bare nolint"},
+		{File: "main.go", Line: 6, Code: "errcheck", Message: "other rule"},
+		{File: "main.go", Line: 8, Code: "ineffassign", Message: "next line"},
+		{File: "app.py", Line: 1, Code: "F401", Message: "noqa with code"},
+		{File: "app.py", Line: 2, Code: "F401", Message: "bare NOQA"},
+		{File: "app.py", Line: 3, Code: "F401", Message: "no comment"},
+		{File: "a.cpp", Line: 1, Code: "bugprone-narrowing", Message: "glob"},
+		{File: "a.cpp", Line: 2, Code: "bugprone-narrowing", Message: "no comment"},
+		{File: "missing.go", Line: 1, Code: "errcheck", Message: "missing file"},
+	}
+	tests := []struct {
+		rules      SuppressionRules
+		suppressed []string
+	}{
+		{
+			rules:      DefaultSuppressions,
+			suppressed: []string{"suppressed by list", "suppressed by bare nolint", "noqa with code", "bare NOQA", "glob"},
+		},
+		{
+			rules:      SuppressionRules{Directives: DefaultSuppressions.Directives, NextLine: true},
+			suppressed: []string{"suppressed by list", "suppressed by bare nolint", "next line", "noqa with code", "bare NOQA", "glob"},
+		},
+		{
+			rules: SuppressionRules{},
+		},
+	}
+	for _, test := range tests {
+		kept, suppressed := FilterSuppressed(errs, fsys, test.rules)
+		if len(kept)+len(suppressed) != len(errs) {
+			t.Logf("%v: lost errors, %d kept and %d suppressed", test.rules, len(kept), len(suppressed))
+			t.Fail()
+		}
+		var got []string
+		for _, e := range suppressed {
+			got = append(got, e.Message)
+		}
+		if len(got) != len(test.suppressed) {
+			t.Logf("%v: was expecting %q to be suppressed got %q instead", test.rules, test.suppressed, got)
+			t.Fail()
+			continue
+		}
+		for i := range got {
+			if got[i] != test.suppressed[i] {
+				t.Logf("%v: was expecting %q to be suppressed got %q instead", test.rules, test.suppressed, got)
+				t.Fail()
+				break
+			}
+		}
+	}
+}
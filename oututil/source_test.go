@@ -1,6 +1,8 @@
 package oututil
 
 import (
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -14,10 +16,10 @@ var sourceTests = []struct {
 		"a.go:123:6: expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 		[]SourceError{
 			{
-				"a.go",
-				123,
-				6,
-				"expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
+				File:    "a.go",
+				Line:    123,
+				Column:  6,
+				Message: "expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 			},
 		},
 	},
@@ -26,10 +28,10 @@ var sourceTests = []struct {
 		"a.go:123: expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 		[]SourceError{
 			{
-				"a.go",
-				123,
-				-1,
-				"expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
+				File:    "a.go",
+				Line:    123,
+				Column:  -1,
+				Message: "expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 			},
 		},
 	},
@@ -39,48 +41,1003 @@ var sourceTests = []struct {
     b.go:123:1122: expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)`,
 		[]SourceError{
 			{
-				"a.go",
-				123,
-				12,
-				"expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
+				File:    "a.go",
+				Line:    123,
+				Column:  12,
+				Message: "expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 			},
 			{
-				"b.go",
-				123,
-				1122,
-				"expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
+				File:    "b.go",
+				Line:    123,
+				Column:  1122,
+				Message: "expected '(', found 'IDENT' decodeLoggedInUserRequest (and 2 more errors)",
 			},
 		},
 	},
+	{
+		"gcc severities",
+		`main.c:3:5: error: 'x' undeclared (first use in this function)
+main.c:4:1: warning: control reaches end of non-void function
+main.c:1:10: fatal error: foo.h: No such file or directory
+main.c:3:5: note: each undeclared identifier is reported only once`,
+		[]SourceError{
+			{
+				File:     "main.c",
+				Line:     3,
+				Column:   5,
+				Message:  "'x' undeclared (first use in this function)",
+				Severity: SeverityError,
+			},
+			{
+				File:     "main.c",
+				Line:     4,
+				Column:   1,
+				Message:  "control reaches end of non-void function",
+				Severity: SeverityWarning,
+			},
+			{
+				File:     "main.c",
+				Line:     1,
+				Column:   10,
+				Message:  "foo.h: No such file or directory",
+				Severity: SeverityError,
+			},
+			{
+				File:     "main.c",
+				Line:     3,
+				Column:   5,
+				Message:  "each undeclared identifier is reported only once",
+				Severity: SeverityNote,
+			},
+		},
+	},
+	{
+		"severity without colon",
+		"a.go:1:2: WARNING unused variable",
+		[]SourceError{
+			{
+				File:     "a.go",
+				Line:     1,
+				Column:   2,
+				Message:  "unused variable",
+				Severity: SeverityWarning,
+			},
+		},
+	},
+	{
+		"severity lookalike",
+		"a.go:1:2: errors.New is not wrapped",
+		[]SourceError{
+			{
+				File:    "a.go",
+				Line:    1,
+				Column:  2,
+				Message: "errors.New is not wrapped",
+			},
+		},
+	},
+	{
+		"empty message",
+		"a.go:123:",
+		[]SourceError{
+			{
+				File:   "a.go",
+				Line:   123,
+				Column: NoColumn,
+			},
+		},
+	},
+	{
+		"empty message with column",
+		"a.go:123:4:",
+		[]SourceError{
+			{
+				File:   "a.go",
+				Line:   123,
+				Column: 4,
+			},
+		},
+	},
+	{
+		"numeric message no column",
+		"a.go:12: 34 is not a valid index",
+		[]SourceError{
+			{
+				File:    "a.go",
+				Line:    12,
+				Column:  NoColumn,
+				Message: "34 is not a valid index",
+			},
+		},
+	},
+	{
+		"column range",
+		"file.c:10:5-15: error: expected expression",
+		[]SourceError{
+			{
+				File:      "file.c",
+				Line:      10,
+				Column:    5,
+				EndLine:   10,
+				EndColumn: 15,
+				Message:   "expected expression",
+				Severity:  SeverityError,
+			},
+		},
+	},
+	{
+		"line and column range",
+		"file.c:10:5-12:3: warning: unterminated comment",
+		[]SourceError{
+			{
+				File:      "file.c",
+				Line:      10,
+				Column:    5,
+				EndLine:   12,
+				EndColumn: 3,
+				Message:   "unterminated comment",
+				Severity:  SeverityWarning,
+			},
+		},
+	},
+	{
+		"clang source range info",
+		"file.c:10:5:{10:5-10:8}{11:1-11:4}: error: invalid operands to binary expression",
+		[]SourceError{
+			{
+				File:      "file.c",
+				Line:      10,
+				Column:    5,
+				EndLine:   10,
+				EndColumn: 8,
+				Message:   "invalid operands to binary expression",
+				Severity:  SeverityError,
+			},
+		},
+	},
+	{
+		"parenthesized position",
+		"file.ts(10,5): error: Cannot find name 'foo'.",
+		[]SourceError{
+			{
+				File:     "file.ts",
+				Line:     10,
+				Column:   5,
+				Message:  "Cannot find name 'foo'.",
+				Severity: SeverityError,
+			},
+		},
+	},
+	{
+		"parenthesized ranges",
+		`file.ts(10,5-10,12): error: one
+file.ts(10,5,11,2): error: two
+file.ts(10,5-9): error: three`,
+		[]SourceError{
+			{
+				File:      "file.ts",
+				Line:      10,
+				Column:    5,
+				EndLine:   10,
+				EndColumn: 12,
+				Message:   "one",
+				Severity:  SeverityError,
+			},
+			{
+				File:      "file.ts",
+				Line:      10,
+				Column:    5,
+				EndLine:   11,
+				EndColumn: 2,
+				Message:   "two",
+				Severity:  SeverityError,
+			},
+			{
+				File:      "file.ts",
+				Line:      10,
+				Column:    5,
+				EndLine:   10,
+				EndColumn: 9,
+				Message:   "three",
+				Severity:  SeverityError,
+			},
+		},
+	},
+	{
+		"msvc compile error",
+		`src\main.cpp(23,5): error C2065: 'foo': undeclared identifier [C:\src\app\app.vcxproj]
+C:\src\util.h(7): warning C4244: conversion from 'double' to 'int', possible loss of data`,
+		[]SourceError{
+			{
+				File:     `src\main.cpp`,
+				Line:     23,
+				Column:   5,
+				Message:  "'foo': undeclared identifier",
+				Severity: SeverityError,
+				Code:     "C2065",
+				Project:  `C:\src\app\app.vcxproj`,
+			},
+			{
+				File:     `C:\src\util.h`,
+				Line:     7,
+				Column:   NoColumn,
+				Message:  "conversion from 'double' to 'int', possible loss of data",
+				Severity: SeverityWarning,
+				Code:     "C4244",
+			},
+		},
+	},
+	{
+		"msvc link error",
+		`main.obj : error LNK2019: unresolved external symbol "void __cdecl foo(void)" (?foo@@YAXXZ) referenced in function main [C:\src\app\app.vcxproj]
+LINK : fatal error LNK1120: 1 unresolved externals`,
+		[]SourceError{
+			{
+				File:     "main.obj",
+				Column:   NoColumn,
+				Message:  `unresolved external symbol "void __cdecl foo(void)" (?foo@@YAXXZ) referenced in function main`,
+				Severity: SeverityError,
+				Code:     "LNK2019",
+				Project:  `C:\src\app\app.vcxproj`,
+			},
+			{
+				File:     "LINK",
+				Column:   NoColumn,
+				Message:  "1 unresolved externals",
+				Severity: SeverityError,
+				Code:     "LNK1120",
+			},
+		},
+	},
+	{
+		"dotnet build",
+		`  Determining projects to restore...
+/src/app/Program.cs(12,9): error CS1002: ; expected [/src/app/app.csproj]
+/src/app/Program.cs(20,13,20,37): warning CA2000: Call System.IDisposable.Dispose on object created by 'new StreamReader(path)' [/src/app/app.csproj]
+/src/app/Program.cs(21,5): error CS0168: The variable 'ex' is declared but never used [/src/app/app.csproj]
+
+Build FAILED.
+
+    1 Warning(s)
+    2 Error(s)
+
+Time Elapsed 00:00:01.23`,
+		[]SourceError{
+			{
+				File:     "/src/app/Program.cs",
+				Line:     12,
+				Column:   9,
+				Message:  "; expected",
+				Severity: SeverityError,
+				Code:     "CS1002",
+				Project:  "/src/app/app.csproj",
+			},
+			{
+				File:      "/src/app/Program.cs",
+				Line:      20,
+				Column:    13,
+				EndLine:   20,
+				EndColumn: 37,
+				Message:   "Call System.IDisposable.Dispose on object created by 'new StreamReader(path)'",
+				Severity:  SeverityWarning,
+				Code:      "CA2000",
+				Project:   "/src/app/app.csproj",
+			},
+			{
+				File:     "/src/app/Program.cs",
+				Line:     21,
+				Column:   5,
+				Message:  "The variable 'ex' is declared but never used",
+				Severity: SeverityError,
+				Code:     "CS0168",
+				Project:  "/src/app/app.csproj",
+			},
+		},
+	},
+	{
+		"directories",
+		"pkg/foo/bar.go:3:1: missing return",
+		[]SourceError{
+			{
+				File:    "pkg/foo/bar.go",
+				Line:    3,
+				Column:  1,
+				Message: "missing return",
+			},
+		},
+	},
+	{
+		"rustc",
+		`   Compiling demo v0.1.0 (/src/demo)
+warning: unused variable: ` + "`y`" + `
+ --> src/main.rs:2:9
+  |
+2 |     let y = 1;
+  |         ^ help: if this is intentional, prefix it with an underscore: ` + "`_y`" + `
+  |
+  = note: ` + "`#[warn(unused_variables)]`" + ` on by default
+
+error[E0308]: mismatched types
+ --> src/main.rs:4:18
+  |
+4 |     let x: i32 = "a";
+  |            ---   ^^^ expected ` + "`i32`" + `, found ` + "`&str`" + `
+  |            |
+  |            expected due to this
+ ::: src/lib.rs:10:5
+  |
+10 |     fn f() {}
+  |     --------- other
+
+error: aborting due to previous error; 1 warning emitted
+
+For more information about this error, try ` + "`rustc --explain E0308`" + `.`,
+		[]SourceError{
+			{
+				File:     "src/main.rs",
+				Line:     2,
+				Column:   9,
+				Message:  "unused variable: `y`",
+				Severity: SeverityWarning,
+			},
+			{
+				File:     "src/main.rs",
+				Line:     4,
+				Column:   18,
+				Message:  "mismatched types",
+				Severity: SeverityError,
+				Code:     "E0308",
+			},
+		},
+	},
+	{
+		"xcode paths with spaces",
+		`CompileSwift normal arm64 /Users/ci/My App/Sources/View Model.swift (in target 'My App' from project 'My App')
+/Users/ci/My App/Sources/View Model.swift:12:9: error: cannot find 'foo' in scope
+        foo()
+        ^~~
+/Users/ci/My App/Sources/App.swift:3:1: warning: result of call to 'run()' is unused
+My Documents/app.c:3:1: error: expected ';' before '}' token`,
+		[]SourceError{
+			{
+				File:     "/Users/ci/My App/Sources/View Model.swift",
+				Line:     12,
+				Column:   9,
+				Message:  "cannot find 'foo' in scope",
+				Severity: SeverityError,
+			},
+			{
+				File:     "/Users/ci/My App/Sources/App.swift",
+				Line:     3,
+				Column:   1,
+				Message:  "result of call to 'run()' is unused",
+				Severity: SeverityWarning,
+			},
+			{
+				File:     "My Documents/app.c",
+				Line:     3,
+				Column:   1,
+				Message:  "expected ';' before '}' token",
+				Severity: SeverityError,
+			},
+		},
+	},
+	{
+		"msvc quoted paths",
+		`  "C:\Program Files\Microsoft Visual Studio\include\xutility"(4507): warning C4244: '=': conversion from 'int' to 'char' [C:\src\app.vcxproj]
+C:\Program Files (x86)\Windows Kits\10\Include\um\winnt.h(12): error C2146: syntax error: missing ';'
+'My Documents/a.c':7:2: error: unknown type name 'foo'`,
+		[]SourceError{
+			{
+				File:     `C:\Program Files\Microsoft Visual Studio\include\xutility`,
+				Line:     4507,
+				Column:   NoColumn,
+				Message:  "'=': conversion from 'int' to 'char'",
+				Severity: SeverityWarning,
+				Code:     "C4244",
+				Project:  `C:\src\app.vcxproj`,
+			},
+			{
+				File:     `C:\Program Files (x86)\Windows Kits\10\Include\um\winnt.h`,
+				Line:     12,
+				Column:   NoColumn,
+				Message:  "syntax error: missing ';'",
+				Severity: SeverityError,
+				Code:     "C2146",
+			},
+			{
+				File:     "My Documents/a.c",
+				Line:     7,
+				Column:   2,
+				Message:  "unknown type name 'foo'",
+				Severity: SeverityError,
+			},
+		},
+	},
+	{
+		"prose before a path",
+		`Error in app.c:3:4: bad thing`,
+		[]SourceError{
+			{File: "app.c", Line: 3, Column: 4, Message: "bad thing"},
+		},
+	},
+	{
+		"virtual files",
+		`<stdin>:5:2: error: expected expression
+-:7:1: warning: unused variable 'x'
+<command-line>:0:0: warning: "DEBUG" redefined
+<scratch space>:1:1: note: expanded from here
+In file included from <built-in>:1:`,
+		[]SourceError{
+			{File: "<stdin>", Line: 5, Column: 2, Message: "expected expression", Severity: SeverityError},
+			{File: "-", Line: 7, Column: 1, Message: "unused variable 'x'", Severity: SeverityWarning},
+			{File: "<command-line>", Line: 0, Column: 0, Message: "\"DEBUG\" redefined", Severity: SeverityWarning},
+			{File: "<scratch space>", Line: 1, Column: 1, Message: "expanded from here", Severity: SeverityNote},
+			{File: "<built-in>", Line: 1, Column: NoColumn, Message: "included from here", Severity: SeverityNote},
+		},
+	},
+	{
+		"linter rule suffixes",
+		`script.sh:3:10: warning: Double quote to prevent globbing and word splitting. [SC2086]
+script.sh:7:1: error: Couldn't parse this function. Fix to allow more checks. [SC1073]
+main.go:10:2: ineffectual assignment to err (ineffassign)
+main.go:14:1: exported function Run should have comment or be unexported (golint)
+main.go:20:9: Error return value of ` + "`f.Close`" + ` is not checked (errcheck)
+main.go:22:2: should use a simple channel send/receive instead of select with a single case (S1000)
+main.go:30:6: func unused is unused (U1000)
+main.go:40:1: missing return (and 2 more errors)
+main.go:41:1: undefined: foo (typo)`,
+		[]SourceError{
+			{File: "script.sh", Line: 3, Column: 10, Message: "Double quote to prevent globbing and word splitting.", Code: "SC2086", Severity: SeverityWarning},
+			{File: "script.sh", Line: 7, Column: 1, Message: "Couldn't parse this function. Fix to allow more checks.", Code: "SC1073", Severity: SeverityError},
+			{File: "main.go", Line: 10, Column: 2, Message: "ineffectual assignment to err", Code: "ineffassign"},
+			{File: "main.go", Line: 14, Column: 1, Message: "exported function Run should have comment or be unexported", Code: "golint"},
+			{File: "main.go", Line: 20, Column: 9, Message: "Error return value of `f.Close` is not checked", Code: "errcheck"},
+			{File: "main.go", Line: 22, Column: 2, Message: "should use a simple channel send/receive instead of select with a single case", Code: "S1000"},
+			{File: "main.go", Line: 30, Column: 6, Message: "func unused is unused", Code: "U1000"},
+			{File: "main.go", Line: 40, Column: 1, Message: "missing return (and 2 more errors)"},
+			{File: "main.go", Line: 41, Column: 1, Message: "undefined: foo (typo)"},
+		},
+	},
 }
 
 func TestParse(t *testing.T) {
 	for _, test := range sourceTests {
 		t.Run(test.name, func(t *testing.T) {
-			errs := ScanSourceError(test.message)
-			if len(errs) != len(test.errors) {
-				t.Logf("was expecting %d errors got %d instead", len(test.errors), len(errs))
-				t.Fail()
-			}
-			for i, actual := range errs {
-				expected := test.errors[i]
-				if actual.File != expected.File {
-					t.Logf("was expecting file %q got %q instead", expected.File, actual.File)
-					t.Fail()
-				}
-				if actual.Line != expected.Line {
-					t.Logf("was expecting line %d got %d instead", expected.Line, actual.Line)
-					t.Fail()
-				}
-				if actual.Column != expected.Column {
-					t.Logf("was expecting column %d got %d instead", expected.Column, actual.Column)
-					t.Fail()
-				}
-				if actual.Message != expected.Message {
-					t.Logf("was expecting message %q got %q instead", expected.Message, actual.Message)
-					t.Fail()
-				}
+			checkSourceErrors(t, test.errors, ScanSourceError(test.message))
+		})
+	}
+}
+
+// checkSourceErrors compares the parsed fields of actual against
+// expected.
+func checkSourceErrors(t *testing.T, expected, actual []SourceError) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Logf("was expecting %d errors got %d instead", len(expected), len(actual))
+		t.Fail()
+	}
+	for i, actual := range actual {
+		if i >= len(expected) {
+			t.Logf("unexpected error %+v", actual)
+			continue
+		}
+		expected := expected[i]
+		if actual.File != expected.File {
+			t.Logf("was expecting file %q got %q instead", expected.File, actual.File)
+			t.Fail()
+		}
+		if actual.Line != expected.Line {
+			t.Logf("was expecting line %d got %d instead", expected.Line, actual.Line)
+			t.Fail()
+		}
+		if actual.Column != expected.Column {
+			t.Logf("was expecting column %d got %d instead", expected.Column, actual.Column)
+			t.Fail()
+		}
+		if actual.Message != expected.Message {
+			t.Logf("was expecting message %q got %q instead", expected.Message, actual.Message)
+			t.Fail()
+		}
+		if actual.EndLine != expected.EndLine || actual.EndColumn != expected.EndColumn {
+			t.Logf("was expecting range end %d:%d got %d:%d instead", expected.EndLine, expected.EndColumn, actual.EndLine, actual.EndColumn)
+			t.Fail()
+		}
+		if actual.Code != expected.Code {
+			t.Logf("was expecting code %q got %q instead", expected.Code, actual.Code)
+			t.Fail()
+		}
+		if actual.Project != expected.Project {
+			t.Logf("was expecting project %q got %q instead", expected.Project, actual.Project)
+			t.Fail()
+		}
+		if actual.Function != expected.Function {
+			t.Logf("was expecting function %q got %q instead", expected.Function, actual.Function)
+			t.Fail()
+		}
+		if actual.Kind != expected.Kind {
+			t.Logf("was expecting kind %s got %s instead", expected.Kind, actual.Kind)
+			t.Fail()
+		}
+		if actual.Severity != expected.Severity {
+			t.Logf("was expecting severity %s got %s instead", expected.Severity, actual.Severity)
+			t.Fail()
+		}
+	}
+}
+
+func TestIsVirtual(t *testing.T) {
+	for file, virtual := range map[string]bool{
+		"<stdin>":         true,
+		"-":               true,
+		"<built-in>":      true,
+		"main.c":          false,
+		"<stdin>.c":       false,
+		"src/<stdin>/a.c": false,
+	} {
+		if got := (SourceError{File: file}).IsVirtual(); got != virtual {
+			t.Logf("%q: was expecting IsVirtual to be %v", file, virtual)
+			t.Fail()
+		}
+	}
+}
+
+func TestKeepLinterSuffix(t *testing.T) {
+	errs, err := ParseReader(strings.NewReader("main.go:10:2: ineffectual assignment to err (ineffassign)\n"), KeepLinterSuffix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "main.go", Line: 10, Column: 2, Message: "ineffectual assignment to err (ineffassign)"},
+	}, errs)
+}
+
+func TestRawLines(t *testing.T) {
+	const log = "   Compiling demo\r\n" +
+		"error[E0308]: mismatched types\n" +
+		" --> src/main.rs:4:18\n" +
+		"\x1b[1mmain.c:3:5:\x1b[0m error: 'x' undeclared\n" +
+		"    3 |   x = 1;\n"
+	errs, err := ParseReader(strings.NewReader(log), Snippets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		raw    string
+		line   int
+		offset int64
+	}{
+		{"error[E0308]: mismatched types\n --> src/main.rs:4:18", 2, 19},
+		{"\x1b[1mmain.c:3:5:\x1b[0m error: 'x' undeclared\n    3 |   x = 1;", 4, 72},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("was expecting %d errors got %d instead", len(want), len(errs))
+	}
+	for i, e := range errs {
+		if e.Raw != want[i].raw || e.LogLine != want[i].line || e.LogOffset != want[i].offset {
+			t.Logf("was expecting %q at line %d offset %d got %q at line %d offset %d instead", want[i].raw, want[i].line, want[i].offset, e.Raw, e.LogLine, e.LogOffset)
+			t.Fail()
+		}
+		if !strings.HasPrefix(log[e.LogOffset:], strings.SplitN(e.Raw, "\n", 2)[0]) {
+			t.Logf("offset %d doesn't point at %q", e.LogOffset, e.Raw)
+			t.Fail()
+		}
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	expected := []SourceError{
+		{File: "a.go", Line: 1, Column: 2, Message: "x declared and not used", Severity: SeverityError},
+		{File: "b.go", Line: 3, Column: NoColumn, Message: "missing return"},
+		{File: "c.go", Line: 5, Column: 6, Message: "bell\trung", Severity: SeverityWarning},
+		{File: "d.go", Line: 7, Column: 8, Message: "last line"},
+	}
+	tests := []struct {
+		name, log string
+	}{
+		{"crlf", "a.go:1:2: error: x declared and not used\r\nb.go:3: missing return\r\nc.go:5:6: warning: bell\t\arung\r\nd.go:7:8: last line\r\n"},
+		{"bom", "\ufeffa.go:1:2: error: x declared and not used\r\nb.go:3: missing return\r\nc.go:5:6: warning: bell\t\arung\r\nd.go:7:8: last line"},
+		{"mixed", "\ufeffa.go:1:2: error: x declared and not used\nb.go:3: missing return\r\r\nc.go:5:6: warning: bell\t\arung\n\r\nd.go:7:8: last line\r"},
+	}
+	for _, test := range tests {
+		for _, opts := range [][]Option{nil, {Parallel()}} {
+			errs, err := ParseReader(strings.NewReader(test.log), opts...)
+			if err != nil {
+				t.Fatal(err)
 			}
+			t.Logf("%s: %d options", test.name, len(opts))
+			checkSourceErrors(t, expected, errs)
+		}
+	}
+}
+
+func TestWarningAsError(t *testing.T) {
+	errs := ScanSourceError(`Program.cs(21,5): error CS0168: The variable 'ex' is declared but never used [app.csproj]
+Program.cs(12,9): error CS1002: ; expected [app.csproj]
+Program.cs(20,13): warning CA2000: Call Dispose [app.csproj]
+main.cpp(7): error C4244: conversion from 'double' to 'int', possible loss of data`)
+	want := []bool{true, false, false, true}
+	if len(errs) != len(want) {
+		t.Fatalf("was expecting %d errors got %d instead", len(want), len(errs))
+	}
+	for i, e := range errs {
+		if e.WarningAsError != want[i] {
+			t.Logf("%v: was expecting WarningAsError to be %v", e, want[i])
+			t.Fail()
+		}
+	}
+}
+
+// parallelBuild is the log of a make -j8 build where every file
+// includes a broken header and two compilers wrote to the terminal at
+// the same time.
+const parallelBuild = `gcc -c -o obj/a.o src/a.c
+gcc -c -o obj/b.o src/b.c
+gcc -c -o obj/c.o src/c.c
+In file included from src/a.c:1:
+include/util.h:12:5: error: unknown type name 'size_tt'
+   12 |     size_tt len;
+      |     ^~~~~~~
+In file included from src/b.c:3:
+include/util.h:12:5: error: unknown type name 'size_tt'
+src/b.c:40:9: warning: unused variable 'n' [-Wunused-variable]src/c.c:7:1: error: expected ';' before '}' token
+In file included from src/c.c:2:
+include/util.h:12:5: error: unknown type name 'size_tt'
+src/c.c:9:3: note: each undeclared identifier is reported only once
+src/a.c:9:3: note: each undeclared identifier is reported only once
+make: *** [Makefile:12: obj/a.o] Error 1
+`
+
+func TestInterleaved(t *testing.T) {
+	errs, err := ParseReader(strings.NewReader(parallelBuild))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SourceError{
+		{File: "src/a.c", Line: 1, Column: NoColumn, Severity: SeverityNote, Message: "included from here"},
+		{File: "include/util.h", Line: 12, Column: 5, Severity: SeverityError, Message: "unknown type name 'size_tt'"},
+		{File: "src/b.c", Line: 3, Column: NoColumn, Severity: SeverityNote, Message: "included from here"},
+		{File: "src/b.c", Line: 40, Column: 9, Severity: SeverityWarning, Code: "-Wunused-variable", Message: "unused variable 'n'"},
+		{File: "src/c.c", Line: 7, Column: 1, Severity: SeverityError, Message: "expected ';' before '}' token"},
+		{File: "src/c.c", Line: 2, Column: NoColumn, Severity: SeverityNote, Message: "included from here"},
+		{File: "src/c.c", Line: 9, Column: 3, Severity: SeverityNote, Message: "each undeclared identifier is reported only once"},
+		{File: "src/a.c", Line: 9, Column: 3, Severity: SeverityNote, Message: "each undeclared identifier is reported only once"},
+	}
+	checkSourceErrors(t, expected, errs)
+	if len(errs) == len(expected) && errs[1].Occurrences != 3 {
+		t.Logf("was expecting the header error 3 times got %d instead", errs[1].Occurrences)
+		t.Fail()
+	}
+	if len(errs) == len(expected) && errs[3].Raw != errs[4].Raw {
+		t.Logf("was expecting the interleaved diagnostics to have the same raw line got %q and %q", errs[3].Raw, errs[4].Raw)
+		t.Fail()
+	}
+
+	var streamed []SourceError
+	Scan(strings.NewReader(parallelBuild), func(e SourceError) bool {
+		streamed = append(streamed, e)
+		return true
+	})
+	checkSourceErrors(t, expected, streamed)
+
+	all, _ := ParseReader(strings.NewReader(parallelBuild), KeepDuplicates())
+	if len(all) != len(expected)+2 {
+		t.Logf("was expecting %d errors with KeepDuplicates got %d", len(expected)+2, len(all))
+		t.Fail()
+	}
+	par, _ := ParseReader(strings.NewReader(parallelBuild), Parallel())
+	checkSourceErrors(t, expected, par)
+}
+
+func TestParseReaderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	errs, err := ParseReader(strings.NewReader("a.go:1:2: " + long + "\nb.go:3: short\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("was expecting 2 errors got %d instead", len(errs))
+	}
+	if errs[0].Message != long {
+		t.Logf("long message was truncated to %d bytes", len(errs[0].Message))
+		t.Fail()
+	}
+}
+
+func TestScanStop(t *testing.T) {
+	n := 0
+	err := Scan(strings.NewReader("a.go:1: one\na.go:2: two\na.go:3: three\n"), func(SourceError) bool {
+		n++
+		return n < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Logf("was expecting scan to stop after 2 errors, got %d", n)
+		t.Fail()
+	}
+}
+
+// repeatReader yields the same log line over and over without holding
+// the whole log in memory.
+type repeatReader struct {
+	line []byte
+	n    int64
+	off  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	written := 0
+	for written < len(p) && r.n > 0 {
+		c := copy(p[written:], r.line[r.off:])
+		written += c
+		r.off += c
+		if r.off == len(r.line) {
+			r.off = 0
+			r.n--
+		}
+	}
+	return written, nil
+}
+
+const benchLogSize = 200 << 20
+
+var benchLine = []byte("pkg/foo/bar.go:123:45: error: expected '(', found 'IDENT' decodeLoggedInUserRequest\nsome unrelated log output that doesn't match anything\n")
+
+func BenchmarkParseReader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := &repeatReader{line: benchLine, n: benchLogSize / int64(len(benchLine))}
+		n := 0
+		if err := Scan(r, func(SourceError) bool { n++; return true }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanSourceError(b *testing.B) {
+	b.ReportAllocs()
+	log := strings.Repeat(string(benchLine), benchLogSize/len(benchLine))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ScanSourceError(log)
+	}
+}
+
+const javacLog = `src/Main.java:10: error: cannot find symbol
+        foo();
+        ^
+  symbol:   method foo()
+  location: class Main
+src/Main.java:12: warning: [deprecation] Date(String) in Date has been deprecated
+	Date d = new Date("x");
+	         ^
+2 errors`
+
+const mavenLog = `[INFO] --- maven-compiler-plugin:3.8.1:compile (default-compile) @ app ---
+[ERROR] /home/ci/app/src/main/java/App.java:[10,5] cannot find symbol
+[ERROR] src/Main.java:10: error: cannot find symbol
+[WARNING] /home/ci/app/src/main/java/App.java:[3,8] unused import
+[INFO] BUILD FAILURE`
+
+const gradleLog = `> Task :compileJava FAILED
+/home/ci/app/src/main/java/Main.java:10: error: cannot find symbol
+        foo();
+        ^
+  symbol:   method foo()
+  location: class Main
+1 error
+
+FAILURE: Build failed with an exception.`
+
+func TestJavac(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    string
+		opts   []Option
+		errors []SourceError
+	}{
+		{
+			name: "javac",
+			log:  javacLog,
+			errors: []SourceError{
+				{File: "src/Main.java", Line: 10, Column: NoColumn, Message: "cannot find symbol", Severity: SeverityError},
+				{File: "src/Main.java", Line: 12, Column: NoColumn, Message: "[deprecation] Date(String) in Date has been deprecated", Severity: SeverityWarning},
+			},
+		},
+		{
+			name: "javac carets",
+			log:  javacLog,
+			opts: []Option{CaretColumns()},
+			errors: []SourceError{
+				{File: "src/Main.java", Line: 10, Column: 9, Message: "cannot find symbol", Severity: SeverityError},
+				{File: "src/Main.java", Line: 12, Column: 11, Message: "[deprecation] Date(String) in Date has been deprecated", Severity: SeverityWarning},
+			},
+		},
+		{
+			name: "maven",
+			log:  mavenLog,
+			errors: []SourceError{
+				{File: "/home/ci/app/src/main/java/App.java", Line: 10, Column: 5, Message: "cannot find symbol", Severity: SeverityError},
+				{File: "src/Main.java", Line: 10, Column: NoColumn, Message: "cannot find symbol", Severity: SeverityError},
+				{File: "/home/ci/app/src/main/java/App.java", Line: 3, Column: 8, Message: "unused import", Severity: SeverityWarning},
+			},
+		},
+		{
+			name: "gradle",
+			log:  gradleLog,
+			opts: []Option{CaretColumns()},
+			errors: []SourceError{
+				{File: "/home/ci/app/src/main/java/Main.java", Line: 10, Column: 9, Message: "cannot find symbol", Severity: SeverityError},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkSourceErrors(t, test.errors, ScanSourceError(test.log, test.opts...))
 		})
 	}
 }
+
+const tscLog = `src/app.ts(10,5): error TS2304: Cannot find name 'foo'.
+src/util/date.ts(3,1): warning TS6133: 'x' is declared but its value is never read.
+src/app.ts:12:7 - error TS2322: Type 'string' is not assignable to type 'number'.
+
+12   const n: number = "a";
+         ~
+
+Found 3 errors.`
+
+const eslintLog = `
+/home/ci/web/src/app.js
+   1:10  error    'foo' is defined but never used  no-unused-vars
+   2:1   warning  Unexpected console statement     no-console
+  10:3   error    Parsing error: Unexpected token
+
+/home/ci/web/src/lib/util.js
+  4:15  error  Missing semicolon  @typescript-eslint/semi
+
+✖ 4 problems (3 errors, 1 warning)
+`
+
+func TestFrontend(t *testing.T) {
+	t.Run("tsc", func(t *testing.T) {
+		checkSourceErrors(t, []SourceError{
+			{File: "src/app.ts", Line: 10, Column: 5, Message: "Cannot find name 'foo'.", Severity: SeverityError, Code: "TS2304"},
+			{File: "src/util/date.ts", Line: 3, Column: 1, Message: "'x' is declared but its value is never read.", Severity: SeverityWarning, Code: "TS6133"},
+			{File: "src/app.ts", Line: 12, Column: 7, Message: "Type 'string' is not assignable to type 'number'.", Severity: SeverityError, Code: "TS2322"},
+		}, ScanSourceError(tscLog))
+	})
+	t.Run("eslint", func(t *testing.T) {
+		checkSourceErrors(t, []SourceError{
+			{File: "/home/ci/web/src/app.js", Line: 1, Column: 10, Message: "'foo' is defined but never used", Severity: SeverityError, Code: "no-unused-vars"},
+			{File: "/home/ci/web/src/app.js", Line: 2, Column: 1, Message: "Unexpected console statement", Severity: SeverityWarning, Code: "no-console"},
+			{File: "/home/ci/web/src/app.js", Line: 10, Column: 3, Message: "Parsing error: Unexpected token", Severity: SeverityError},
+			{File: "/home/ci/web/src/lib/util.js", Line: 4, Column: 15, Message: "Missing semicolon", Severity: SeverityError, Code: "@typescript-eslint/semi"},
+		}, ScanSourceError(eslintLog))
+	})
+}
+
+func TestSnippets(t *testing.T) {
+	log := `main.c:3:5: error: use of undeclared identifier 'x'
+    x = 1;
+    ^
+main.c:4:12: warning: format specifies type 'int' but the argument has type 'char *' [-Wformat]
+    printf("%d", s);
+            ~~   ^
+            %s
+
+1 warning and 1 error generated.
+error[E0308]: mismatched types
+ --> src/main.rs:4:18
+  |
+4 |     let x: i32 = "a";
+  |                  ^^^ expected ` + "`i32`" + `
+  = note: expected type ` + "`i32`" + `
+`
+	want := [][]string{
+		{"    x = 1;", "    ^"},
+		{`    printf("%d", s);`, "            ~~   ^", "            %s"},
+		{"  |", `4 |     let x: i32 = "a";`, "  |                  ^^^ expected `i32`", "  = note: expected type `i32`"},
+	}
+	errs := ScanSourceError(log, Snippets())
+	if len(errs) != len(want) {
+		t.Fatalf("was expecting %d errors got %d instead", len(want), len(errs))
+	}
+	for i, e := range errs {
+		if strings.Join(e.Snippet, "\n") != strings.Join(want[i], "\n") {
+			t.Logf("was expecting snippet %q got %q instead", want[i], e.Snippet)
+			t.Fail()
+		}
+	}
+	if errs := ScanSourceError(log); errs[0].Snippet != nil {
+		t.Log("snippets should only be captured when asked for")
+		t.Fail()
+	}
+}
+
+const relatedLog = `In file included from /usr/include/stdio.h:27,
+                 from main.c:1:
+/usr/include/features.h:10:2: error: #error unsupported
+main.cpp:12:3: error: no matching function for call to 'f'
+    f(1, 2);
+    ^
+main.cpp:3:6: note: candidate function not viable: requires 1 argument, but 2 were provided
+void f(int);
+     ^
+
+./a.go:5:6: x redeclared in this block
+	./a.go:3:6: other declaration of x
+`
+
+func TestFoldNotes(t *testing.T) {
+	errs := ScanSourceError(relatedLog, FoldNotes())
+	checkSourceErrors(t, []SourceError{
+		{File: "/usr/include/features.h", Line: 10, Column: 2, Message: "#error unsupported", Severity: SeverityError},
+		{File: "main.cpp", Line: 12, Column: 3, Message: "no matching function for call to 'f'", Severity: SeverityError},
+		{File: "./a.go", Line: 5, Column: 6, Message: "x redeclared in this block"},
+	}, errs)
+	if len(errs) != 3 {
+		return
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "/usr/include/stdio.h", Line: 27, Column: NoColumn, Message: "included from here", Severity: SeverityNote},
+		{File: "main.c", Line: 1, Column: NoColumn, Message: "included from here", Severity: SeverityNote},
+	}, errs[0].Related)
+	checkSourceErrors(t, []SourceError{
+		{File: "main.cpp", Line: 3, Column: 6, Message: "candidate function not viable: requires 1 argument, but 2 were provided", Severity: SeverityNote},
+	}, errs[1].Related)
+	checkSourceErrors(t, []SourceError{
+		{File: "./a.go", Line: 3, Column: 6, Message: "other declaration of x", Severity: SeverityNote},
+	}, errs[2].Related)
+
+	t.Run("flat", func(t *testing.T) {
+		checkSourceErrors(t, []SourceError{
+			{File: "/usr/include/stdio.h", Line: 27, Column: NoColumn, Message: "included from here", Severity: SeverityNote},
+			{File: "main.c", Line: 1, Column: NoColumn, Message: "included from here", Severity: SeverityNote},
+			{File: "/usr/include/features.h", Line: 10, Column: 2, Message: "#error unsupported", Severity: SeverityError},
+			{File: "main.cpp", Line: 12, Column: 3, Message: "no matching function for call to 'f'", Severity: SeverityError},
+			{File: "main.cpp", Line: 3, Column: 6, Message: "candidate function not viable: requires 1 argument, but 2 were provided", Severity: SeverityNote},
+			{File: "./a.go", Line: 5, Column: 6, Message: "x redeclared in this block"},
+			{File: "./a.go", Line: 3, Column: 6, Message: "other declaration of x", Severity: SeverityNote},
+		}, ScanSourceError(relatedLog))
+	})
+
+	t.Run("rustc", func(t *testing.T) {
+		errs := ScanSourceError("error[E0308]: mismatched types\n --> src/main.rs:4:18\n  |\n  = note: expected type `i32`\n  = help: try `1`\n", FoldNotes())
+		if len(errs) != 1 {
+			t.Fatalf("was expecting 1 error got %d instead", len(errs))
+		}
+		checkSourceErrors(t, []SourceError{
+			{File: "src/main.rs", Line: 4, Column: 18, Message: "expected type `i32`", Severity: SeverityNote},
+			{File: "src/main.rs", Line: 4, Column: 18, Message: "try `1`", Severity: SeverityNote},
+		}, errs[0].Related)
+	})
+}
+
+func TestCodes(t *testing.T) {
+	log := `src/main.rs:4:18: error[E0308]: mismatched types
+Program.cs(12,9): error CS1002: ; expected
+app.cs:3:1: warning CS0168: The variable 'e' is declared but never used
+src/app.ts(10,5): error TS2304: Cannot find name 'foo'.
+app/models.py:10:80: E501 line too long (82 > 79 characters)
+app/models.py:1:1: F401 'os' imported but unused
+script.sh:3:10: warning: Double quote to prevent globbing. [SC2086]
+a.go:1:2: call has possible formatting directive %d [printf]
+a.go:1:2: E12 is not a code`
+	checkSourceErrors(t, []SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, Message: "mismatched types", Severity: SeverityError, Code: "E0308"},
+		{File: "Program.cs", Line: 12, Column: 9, Message: "; expected", Severity: SeverityError, Code: "CS1002"},
+		{File: "app.cs", Line: 3, Column: 1, Message: "The variable 'e' is declared but never used", Severity: SeverityWarning, Code: "CS0168"},
+		{File: "src/app.ts", Line: 10, Column: 5, Message: "Cannot find name 'foo'.", Severity: SeverityError, Code: "TS2304"},
+		{File: "app/models.py", Line: 10, Column: 80, Message: "line too long (82 > 79 characters)", Code: "E501"},
+		{File: "app/models.py", Line: 1, Column: 1, Message: "'os' imported but unused", Code: "F401"},
+		{File: "script.sh", Line: 3, Column: 10, Message: "Double quote to prevent globbing.", Severity: SeverityWarning, Code: "SC2086"},
+		{File: "a.go", Line: 1, Column: 2, Message: "call has possible formatting directive %d [printf]"},
+		{File: "a.go", Line: 1, Column: 2, Message: "E12 is not a code"},
+	}, ScanSourceError(log))
+}
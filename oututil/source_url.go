@@ -0,0 +1,38 @@
+package oututil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// urlScheme matches the scheme of a URL. Schemes have two letters
+	// at least so drive letters aren't taken for one.
+	urlScheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+)://`)
+	// urlPath matches a URL followed by a position at the start of a
+	// line,
+	//
+	//	https://raw.githubusercontent.com/me/app/main/main.go:10:2: ...
+	urlPath = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9+.-]+://[^\s"'()]*[[:alnum:]]\.[[:alnum:]]+)[:(][0-9]`)
+)
+
+// Scheme returns the scheme of the File of e if it is a URL, like https
+// or file, and "" otherwise.
+func (e SourceError) Scheme() string { return schemeOf(e.File) }
+
+// IsRemote reports whether the File of e is a URL of something that
+// isn't a local file, which formatters can link to but can't open.
+func (e SourceError) IsRemote() bool {
+	s := e.Scheme()
+	return s != "" && !strings.EqualFold(s, "file")
+}
+
+func schemeOf(p string) string {
+	if !strings.Contains(p, "://") {
+		return ""
+	}
+	if m := urlScheme.FindStringSubmatch(p); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package oututil
+
+import "testing"
+
+func TestURLPaths(t *testing.T) {
+	tests := []struct {
+		line        string
+		file        string
+		lineNo, col int
+		scheme      string
+		remote      bool
+	}{
+		{
+			line: "https://raw.githubusercontent.com/me/app/main/main.go:10:2: undefined: x",
+			file: "https://raw.githubusercontent.com/me/app/main/main.go", lineNo: 10, col: 2, scheme: "https", remote: true,
+		},
+		{
+			line: "http://localhost:8080/src/app.ts(3,4): error TS2304: Cannot find name 'x'.",
+			file: "http://localhost:8080/src/app.ts", lineNo: 3, col: 4, scheme: "http", remote: true,
+		},
+		{
+			line: "file:///home/me/a.go:7:1: error: x",
+			file: "file:///home/me/a.go", lineNo: 7, col: 1, scheme: "file",
+		},
+		{
+			line: "example.com/mod/pkg/file.go:10:2: undefined: x",
+			file: "example.com/mod/pkg/file.go", lineNo: 10, col: 2,
+		},
+		{
+			line: "bazel-out/k8-fastbuild/bin/app/gen.go:5:9: undefined: y",
+			file: "bazel-out/k8-fastbuild/bin/app/gen.go", lineNo: 5, col: 9,
+		},
+		{
+			line: `C:\src\main.c(3): error C2065: 'x': undeclared identifier`,
+			file: `C:\src\main.c`, lineNo: 3, col: NoColumn,
+		},
+	}
+	for _, test := range tests {
+		errs := ScanSourceError(test.line)
+		if len(errs) != 1 {
+			t.Logf("%s: was expecting 1 error got %v", test.line, errs)
+			t.Fail()
+			continue
+		}
+		e := errs[0]
+		if e.File != test.file || e.Line != test.lineNo || e.Column != test.col || e.Scheme() != test.scheme || e.IsRemote() != test.remote {
+			t.Logf("%s: got %q %d:%d scheme=%q remote=%v", test.line, e.File, e.Line, e.Column, e.Scheme(), e.IsRemote())
+			t.Fail()
+		}
+		if s := e.Spans.File; test.line[s.Start:s.End] != test.file {
+			t.Logf("%s: the file span is %q", test.line, test.line[s.Start:s.End])
+			t.Fail()
+		}
+	}
+}
+
+func TestURLPathsUntouched(t *testing.T) {
+	const u = "https://example.com/src//a.go"
+	e := SourceError{File: u, Dir: "/build", Line: 1}
+	if got := ResolvePaths([]SourceError{e}, "/src", WorkspaceRoot("/src"))[0].File; got != u {
+		t.Logf("ResolvePaths changed the URL to %q", got)
+		t.Fail()
+	}
+	if got := MapPaths([]SourceError{e}, []PathMapping{{From: "/src"}}, DropUnmapped()); len(got) != 1 || got[0].File != u {
+		t.Logf("MapPaths changed the URL to %v", got)
+		t.Fail()
+	}
+	if got := fileURI(u); got != u {
+		t.Logf("fileURI changed the URL to %q", got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,99 @@
+package oututil
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// defaultFlushTimeout is how long Watch waits for the lines that follow
+// a diagnostic before sending it.
+const defaultFlushTimeout = 250 * time.Millisecond
+
+// FlushTimeout sets how long Watch holds a diagnostic that is waiting
+// for more lines, for CaretColumns, Snippets or FoldNotes, when r stops
+// producing output.
+func FlushTimeout(d time.Duration) Option {
+	return func(o *options) { o.flushTimeout = d }
+}
+
+// Watch reads r as it grows, like a pipe from a running build, and
+// sends every source error to ch as soon as the lines it spans have been
+// read. It returns when r is exhausted or ctx is cancelled. Sends to ch
+// block until ctx is done, the errors still pending then are only sent
+// if ch is ready for them, so a reader that stops reading ch has to
+// cancel ctx. Watch doesn't close ch. A line is parsed once its line
+// ending was read, or r is exhausted, so the end of a line a writer
+// hasn't flushed yet isn't mistaken for the whole of it.
+//
+// A Read blocked on r can't be interrupted, close r to stop watching a
+// reader that never returns.
+func Watch(ctx context.Context, r io.Reader, ch chan<- SourceError, opts ...Option) error {
+	o := newOptions(opts)
+	if o.flushTimeout <= 0 {
+		o.flushTimeout = defaultFlushTimeout
+	}
+	s := newSourceScanner(func(e SourceError) bool {
+		select {
+		case ch <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, o)
+
+	type line struct {
+		text      string
+		size      int
+		truncated bool
+	}
+	lines := make(chan line)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		scanner, split := newLineScanner(limitInput(r, o), o)
+		for scanner.Scan() {
+			select {
+			case lines <- line{scanner.Text(), split.size, split.truncated}:
+			case <-done:
+				return
+			}
+		}
+		errc <- scanner.Err()
+		close(lines)
+	}()
+
+	idle := time.NewTimer(o.flushTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush()
+			return ctx.Err()
+		case l, ok := <-lines:
+			if !ok {
+				s.flush()
+				if err := <-errc; err != nil {
+					return err
+				}
+				return s.err()
+			}
+			if !s.next(l.text, l.size, l.truncated) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return s.err()
+			}
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(o.flushTimeout)
+		case <-idle.C:
+			s.release()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package oututil
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	r, w := io.Pipe()
+	ch := make(chan SourceError)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Watch(ctx, r, ch, Snippets(), FlushTimeout(10*time.Millisecond))
+		close(ch)
+	}()
+
+	io.WriteString(w, "main.c:3:5: error: 'x' undeclared\n    3 |   x = 1;\n")
+	select {
+	case e := <-ch:
+		if e.Line != 3 || len(e.Snippet) != 1 {
+			t.Logf("unexpected error %+v", e)
+			t.Fail()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending error wasn't flushed while the log was idle")
+	}
+
+	io.WriteString(w, "main.c:9:1: warning: no return\n")
+	cancel()
+	var got []SourceError
+	for e := range ch {
+		got = append(got, e)
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Logf("was expecting context.Canceled got %v instead", err)
+		t.Fail()
+	}
+	if len(got) > 1 || len(got) == 1 && got[0].Line != 9 {
+		t.Logf("unexpected errors after cancelling %+v", got)
+		t.Fail()
+	}
+	w.Close()
+}
+
+func TestWatchEOF(t *testing.T) {
+	r, w := io.Pipe()
+	ch := make(chan SourceError, 10)
+	go func() {
+		io.WriteString(w, "error[E0308]: mismatched types\n --> src/main.rs:4:18\n")
+		w.Close()
+	}()
+	if err := Watch(context.Background(), r, ch, FoldNotes()); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	var got []SourceError
+	for e := range ch {
+		got = append(got, e)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "src/main.rs", Line: 4, Column: 18, Message: "mismatched types", Code: "E0308", Severity: SeverityError},
+	}, got)
+}
+
+func TestWatchUnread(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- Watch(ctx, r, make(chan SourceError)) }()
+	io.WriteString(w, "main.c:3:5: error: 'x' undeclared\nmain.c:4:5: error: 'y' undeclared\n")
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Logf("was expecting context.Canceled got %v instead", err)
+			t.Fail()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch blocked sending to a channel nobody reads")
+	}
+}
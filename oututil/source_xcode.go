@@ -0,0 +1,65 @@
+package oututil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Xcode parses the output of xcodebuild: Swift's notes and fix-its are
+// folded into the diagnostics they belong to, as with FoldNotes and
+// Snippets, and the undefined symbols ld64 reports become an error
+// each.
+func Xcode() Option {
+	return func(o *options) { o.xcode, o.foldNotes, o.snippets = true, true, true }
+}
+
+var (
+	// undefinedSymbols matches the lines ld64 starts its list of
+	// undefined symbols with and the ones that follow,
+	//
+	//	Undefined symbols for architecture arm64:
+	//	  "_foo", referenced from:
+	//	      _main in main.o
+	undefinedSymbols = regexp.MustCompile(`^Undefined symbols for architecture (\S+):$`)
+	undefinedSymbol  = regexp.MustCompile(`^\s+"(.+)", referenced from:$`)
+	referencedFrom   = regexp.MustCompile(`^\s+(\S.*?)(?: in \S+)?$`)
+)
+
+// undefinedSymbol reports whether line is part of ld64's list of
+// undefined symbols and whether it belongs to the Raw lines of the
+// error that follows. The errors for the symbols are held as pending so
+// the lines that follow can add what referenced them.
+func (s *sourceScanner) undefinedSymbol(line string) (ok, header bool) {
+	if m := undefinedSymbols.FindStringSubmatch(line); m != nil {
+		s.release()
+		s.ldArch = m[1]
+		return true, true
+	}
+	if s.ldArch == "" {
+		return false, false
+	}
+	if m := undefinedSymbol.FindStringSubmatch(line); m != nil {
+		s.emit(SourceError{
+			Column:   NoColumn,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("undefined symbol %s for architecture %s", m[1], s.ldArch),
+			Tool:     "ld",
+		})
+		return true, false
+	}
+	if m := referencedFrom.FindStringSubmatch(line); m != nil && s.pending != nil && s.pending.Tool == "ld" {
+		s.pending.Related = append(s.pending.Related, SourceError{
+			Column:   NoColumn,
+			Severity: SeverityNote,
+			Message:  "referenced from " + strings.TrimSpace(line),
+			Function: m[1],
+			Tool:     "ld",
+		})
+		s.appendRaw(line)
+		return true, false
+	}
+	s.ldArch = ""
+	s.release()
+	return false, false
+}
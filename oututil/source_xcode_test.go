@@ -0,0 +1,71 @@
+package oututil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXcode(t *testing.T) {
+	const log = `CompileSwift normal arm64 /src/App/main.swift
+/src/App/main.swift:10:15: error: cannot find 'foo' in scope
+        let x = foo()
+                ^~~
+/src/App/main.swift:3:6: note: did you mean 'food'?
+func food() {}
+     ^
+/src/App/Model.swift:4:12: warning: variable 'y' was never mutated; consider changing to 'let' constant
+    var y = 1
+    ~~~ ^
+    let
+
+Ld /build/App normal arm64
+Undefined symbols for architecture arm64:
+  "_bar", referenced from:
+      _main in main.o
+      _helper in util.o
+  "App.Thing.run() -> ()", referenced from:
+      implicit entry/start for main executable
+ld: symbol(s) not found for architecture arm64
+clang: error: linker command failed with exit code 1 (use -v to see invocation)
+
+The following build commands failed:
+/src/App/main.swift:10:15: error: cannot find 'foo' in scope
+`
+	errs, err := ParseReader(strings.NewReader(log), Xcode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "/src/App/main.swift", Line: 10, Column: 15, Severity: SeverityError, Message: "cannot find 'foo' in scope"},
+		{File: "/src/App/Model.swift", Line: 4, Column: 12, Severity: SeverityWarning, Message: "variable 'y' was never mutated; consider changing to 'let' constant"},
+		{Column: NoColumn, Severity: SeverityError, Message: "undefined symbol _bar for architecture arm64"},
+		{Column: NoColumn, Severity: SeverityError, Message: "undefined symbol App.Thing.run() -> () for architecture arm64"},
+	}, errs)
+	if len(errs) != 4 {
+		t.FailNow()
+	}
+	if len(errs[0].Related) != 1 || errs[0].Related[0].Message != "did you mean 'food'?" || errs[0].Tool != "swift" {
+		t.Logf("was expecting the note to be folded into %+v", errs[0])
+		t.Fail()
+	}
+	if fixit := errs[1].Snippet; len(fixit) != 3 || strings.TrimSpace(fixit[2]) != "let" {
+		t.Logf("was expecting the fix-it in the snippet got %q instead", fixit)
+		t.Fail()
+	}
+	var refs []string
+	for _, r := range errs[2].Related {
+		refs = append(refs, r.Function)
+	}
+	if strings.Join(refs, ",") != "_main,_helper" {
+		t.Logf("was expecting _bar to be referenced from _main and _helper got %q instead", refs)
+		t.Fail()
+	}
+	if want := "Undefined symbols for architecture arm64:\n  \"_bar\", referenced from:\n      _main in main.o\n      _helper in util.o"; errs[2].Raw != want || errs[2].LogLine != 14 {
+		t.Logf("was expecting %q at line 14 got %q at %d instead", want, errs[2].Raw, errs[2].LogLine)
+		t.Fail()
+	}
+	if len(errs[3].Related) != 1 || errs[3].Related[0].Message != "referenced from implicit entry/start for main executable" {
+		t.Logf("was expecting a single reference got %+v instead", errs[3].Related)
+		t.Fail()
+	}
+}
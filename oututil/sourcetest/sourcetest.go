@@ -0,0 +1,180 @@
+// Package sourcetest has a corpus of the logs of well-known tools and
+// the source errors they should parse to, and a harness to run parsers
+// against them.
+package sourcetest // import "sevki.org/x/oututil/sourcetest"
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+
+	"sevki.org/x/oututil"
+)
+
+//go:embed corpus
+var corpus embed.FS
+
+// Case is a log and the source errors it should parse to.
+type Case struct {
+	Name string
+	Log  string
+	Want []oututil.SourceError
+}
+
+// Golden is the part of a source error the expected JSON of a case
+// has. Severities and kinds are named like their String methods do.
+type Golden struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Project   string `json:"project,omitempty"`
+	Function  string `json:"function,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Message   string `json:"message"`
+}
+
+var severities = map[string]oututil.Severity{
+	"":        oututil.SeverityUnknown,
+	"note":    oututil.SeverityNote,
+	"warning": oututil.SeverityWarning,
+	"error":   oututil.SeverityError,
+}
+
+// SourceError returns the source error g stands for.
+func (g Golden) SourceError() (oututil.SourceError, error) {
+	severity, ok := severities[g.Severity]
+	if !ok {
+		return oututil.SourceError{}, fmt.Errorf("unknown severity %q", g.Severity)
+	}
+	e := oututil.SourceError{
+		File:      g.File,
+		Line:      g.Line,
+		Column:    g.Column,
+		EndLine:   g.EndLine,
+		EndColumn: g.EndColumn,
+		Severity:  severity,
+		Code:      g.Code,
+		Project:   g.Project,
+		Function:  g.Function,
+		Message:   g.Message,
+	}
+	switch g.Kind {
+	case "", "diagnostic":
+	case "frame":
+		e.Kind = oututil.KindStackFrame
+	default:
+		return oututil.SourceError{}, fmt.Errorf("unknown kind %q", g.Kind)
+	}
+	return e, nil
+}
+
+// GoldenOf returns the Golden of e.
+func GoldenOf(e oututil.SourceError) Golden {
+	g := Golden{
+		File:      e.File,
+		Line:      e.Line,
+		Column:    e.Column,
+		EndLine:   e.EndLine,
+		EndColumn: e.EndColumn,
+		Code:      e.Code,
+		Project:   e.Project,
+		Function:  e.Function,
+		Message:   e.Message,
+	}
+	if e.Severity != oututil.SeverityUnknown {
+		g.Severity = e.Severity.String()
+	}
+	if e.Kind == oututil.KindStackFrame {
+		g.Kind = e.Kind.String()
+	}
+	return g
+}
+
+// LoadCases reads the cases in the root of fsys, a name.log file with
+// the log of a case and a name.json file with the list of Goldens it
+// should parse to.
+func LoadCases(fsys fs.FS) ([]Case, error) {
+	logs, err := fs.Glob(fsys, "*.log")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(logs)
+	cases := make([]Case, 0, len(logs))
+	for _, name := range logs {
+		log, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSuffix(name, ".log")
+		data, err := fs.ReadFile(fsys, name+".json")
+		if err != nil {
+			return nil, err
+		}
+		var goldens []Golden
+		if err := json.Unmarshal(data, &goldens); err != nil {
+			return nil, fmt.Errorf("%s.json: %v", name, err)
+		}
+		c := Case{Name: name, Log: string(log)}
+		for _, g := range goldens {
+			e, err := g.SourceError()
+			if err != nil {
+				return nil, fmt.Errorf("%s.json: %v", name, err)
+			}
+			c.Want = append(c.Want, e)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Corpus returns the cases of the corpus in the order of their names:
+// gcc, clang, rustc, tsc, eslint, msvc, javac, go and python logs.
+func Corpus() []Case {
+	sub, err := fs.Sub(corpus, "corpus")
+	if err != nil {
+		panic(err)
+	}
+	cases, err := LoadCases(sub)
+	if err != nil {
+		panic(err)
+	}
+	return cases
+}
+
+// RunConformance runs parser on the logs of cases, or of the Corpus if
+// there are none, in subtests of t named after them and fails the ones
+// whose source errors aren't the ones they want.
+func RunConformance(t *testing.T, parser func(string) []oututil.SourceError, cases ...Case) {
+	t.Helper()
+	if len(cases) == 0 {
+		cases = Corpus()
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got := parser(c.Log)
+			if len(got) != len(c.Want) {
+				t.Logf("got %d source errors, expected %d", len(got), len(c.Want))
+				t.Fail()
+			}
+			for i, e := range got {
+				if i >= len(c.Want) {
+					t.Logf("unexpected source error %+v", GoldenOf(e))
+					continue
+				}
+				if g, w := GoldenOf(e), GoldenOf(c.Want[i]); g != w {
+					t.Logf("source error %d is %+v, expected %+v", i, g, w)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
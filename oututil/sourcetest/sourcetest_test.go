@@ -0,0 +1,60 @@
+package sourcetest
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"sevki.org/x/oututil"
+)
+
+func TestCorpus(t *testing.T) {
+	var names []string
+	for _, c := range Corpus() {
+		names = append(names, c.Name)
+		if c.Log == "" || len(c.Want) == 0 {
+			t.Logf("%s has no log or no source errors", c.Name)
+			t.Fail()
+		}
+	}
+	want := []string{"clang", "eslint", "gcc", "go", "javac", "msvc", "python", "rustc", "tsc"}
+	if !reflect.DeepEqual(names, want) {
+		t.Logf("got cases %q, expected %q", names, want)
+		t.Fail()
+	}
+}
+
+func TestLoadCases(t *testing.T) {
+	fsys := fstest.MapFS{
+		"custom.log":  {Data: []byte("x.q@3: boom\n")},
+		"custom.json": {Data: []byte(`[{"file": "x.q", "line": 3, "column": -1, "severity": "error", "message": "boom"}]`)},
+		"bad.log":     {Data: []byte("")},
+		"bad.json":    {Data: []byte(`[{"severity": "fatal"}]`)},
+	}
+	if _, err := LoadCases(fsys); err == nil || err.Error() != `bad.json: unknown severity "fatal"` {
+		t.Logf("got %v, expected the unknown severity", err)
+		t.Fail()
+	}
+	delete(fsys, "bad.log")
+	cases, err := LoadCases(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Case{{
+		Name: "custom",
+		Log:  "x.q@3: boom\n",
+		Want: []oututil.SourceError{{File: "x.q", Line: 3, Column: oututil.NoColumn, Severity: oututil.SeverityError, Message: "boom"}},
+	}}
+	if !reflect.DeepEqual(cases, want) {
+		t.Logf("got %+v, expected %+v", cases, want)
+		t.Fail()
+	}
+	format := oututil.MustFormat(`^(?P<file>[^@]+)@(?P<line>[0-9]+): (?P<message>.*)$`)
+	RunConformance(t, func(log string) []oututil.SourceError {
+		errs := oututil.ParseWith([]oututil.Format{format}, log)
+		for i := range errs {
+			errs[i].Severity = oututil.SeverityError
+		}
+		return errs
+	}, cases...)
+}
@@ -0,0 +1,72 @@
+package oututil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type tapOptions struct {
+	suppressed []SourceError
+}
+
+// TAPOption configures WriteTAP.
+type TAPOption func(*tapOptions)
+
+// TAPSuppressed adds the errors that were suppressed or filtered out as
+// skipped test points.
+func TAPSuppressed(errs []SourceError) TAPOption {
+	return func(o *tapOptions) { o.suppressed = errs }
+}
+
+// WriteTAP writes errs as a TAP 13 stream with a test point per error.
+// Errors and errors without a severity are "not ok", warnings and notes
+// are "ok", suppressed errors are "ok" and skipped. Every test point has
+// a YAML block with the position and message of its error, its strings
+// are double quoted so messages can't end the block. Without errors the
+// plan is 1..0.
+func WriteTAP(w io.Writer, errs []SourceError, opts ...TAPOption) error {
+	var o tapOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "TAP version 13\n1..%d\n", len(errs)+len(o.suppressed))
+	n := 0
+	for _, e := range errs {
+		n++
+		status := "ok"
+		if e.Severity == SeverityError || e.Severity == SeverityUnknown {
+			status = "not ok"
+		}
+		writeTAPPoint(b, status, n, e, "")
+	}
+	for _, e := range o.suppressed {
+		n++
+		writeTAPPoint(b, "ok", n, e, " # SKIP suppressed")
+	}
+	return b.Flush()
+}
+
+func writeTAPPoint(w io.Writer, status string, n int, e SourceError, directive string) {
+	description := e.Error()
+	if i := strings.IndexByte(description, '\n'); i >= 0 {
+		description = description[:i]
+	}
+	// a # starts a directive
+	description = strings.ReplaceAll(description, "#", `\#`)
+	fmt.Fprintf(w, "%s %d - %s%s\n  ---\n", status, n, description, directive)
+	fmt.Fprintf(w, "  message: %s\n", strconv.Quote(e.Message))
+	fmt.Fprintf(w, "  severity: %s\n", e.Severity)
+	fmt.Fprintf(w, "  file: %s\n", strconv.Quote(e.File))
+	fmt.Fprintf(w, "  line: %d\n", e.Line)
+	if e.Column != NoColumn {
+		fmt.Fprintf(w, "  column: %d\n", e.Column)
+	}
+	if e.Code != "" {
+		fmt.Fprintf(w, "  code: %s\n", strconv.Quote(e.Code))
+	}
+	fmt.Fprint(w, "  ...\n")
+}
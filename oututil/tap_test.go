@@ -0,0 +1,91 @@
+package oututil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTAP(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []SourceError
+		opts []TAPOption
+		want string
+	}{
+		{
+			name: "empty",
+			want: "TAP version 13\n1..0\n",
+		},
+		{
+			name: "errors",
+			errs: []SourceError{
+				{File: "a.go", Line: 4, Column: 1, Severity: SeverityError, Code: "E1", Message: "missing return"},
+				{File: "a.go", Line: 9, Column: NoColumn, Message: "---\n...\nissue #12"},
+				{File: "b.go", Line: 3, Column: 2, Severity: SeverityWarning, Message: "x declared and not used"},
+			},
+			opts: []TAPOption{TAPSuppressed([]SourceError{
+				{File: "c.py", Line: 1, Column: 1, Severity: SeverityError, Code: "F401", Message: "os imported but unused"},
+			})},
+			want: `TAP version 13
+1..4
+not ok 1 - a.go:4:1: error[E1]: missing return
+  ---
+  message: "missing return"
+  severity: error
+  file: "a.go"
+  line: 4
+  column: 1
+  code: "E1"
+  ...
+not ok 2 - a.go:9: ---
+  ---
+  message: "---\n...\nissue #12"
+  severity: unknown
+  file: "a.go"
+  line: 9
+  ...
+ok 3 - b.go:3:2: warning: x declared and not used
+  ---
+  message: "x declared and not used"
+  severity: warning
+  file: "b.go"
+  line: 3
+  column: 2
+  ...
+ok 4 - c.py:1:1: error[F401]: os imported but unused # SKIP suppressed
+  ---
+  message: "os imported but unused"
+  severity: error
+  file: "c.py"
+  line: 1
+  column: 1
+  code: "F401"
+  ...
+`,
+		},
+		{
+			name: "directive in message",
+			errs: []SourceError{{File: "a.go", Line: 1, Column: NoColumn, Severity: SeverityNote, Message: "see # TODO"}},
+			want: `TAP version 13
+1..1
+ok 1 - a.go:1: note: see \# TODO
+  ---
+  message: "see # TODO"
+  severity: note
+  file: "a.go"
+  line: 1
+  ...
+`,
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteTAP(&buf, test.errs, test.opts...); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.want {
+			t.Logf("%s: was expecting:\n%s\ngot:\n%s", test.name, test.want, buf.String())
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package oututil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Templates are the built-in templates of NewFormatter by name.
+var Templates = map[string]string{
+	// gnu is the {file}:{line}:{col}: {severity}: {message} form of
+	// the GNU coding standards
+	"gnu": `{{.File}}:{{.Line}}{{if gt .Column 0}}:{{.Column}}{{end}}: {{with .Severity}}{{.}}: {{end}}{{.Message}}`,
+	// short is the base name of the file, the line and the message
+	// truncated to 80 characters
+	"short": `{{base .File}}:{{.Line}}: {{truncate 80 .Message}}`,
+	// json-lines is a JSON object per error
+	"json-lines": `{"file":{{json .File}},"line":{{.Line}},"column":{{.Column}},"severity":{{json .Severity.String}},` +
+		`"code":{{json .Code}},"message":{{json .Message}}}`,
+}
+
+// templateFuncs are the functions templates can use besides the
+// builtin ones of text/template.
+var templateFuncs = template.FuncMap{
+	// rel returns the path relative to base, see SourceError.Rel
+	"rel":   func(file, base string) string { return SourceError{File: file}.Rel(base).File },
+	"base":  path.Base,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// truncate shortens s to n characters ending with an ellipsis
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n && n > 0 {
+			return string(r[:n-1]) + "…"
+		}
+		return s
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Formatter formats source errors with a text/template, compiled once
+// so it can be reused.
+type Formatter struct {
+	tmpl    *template.Template
+	newline bool
+}
+
+// NewFormatter compiles tmpl, the name of one of the Templates or the
+// text of a template executed for every SourceError. Templates can use
+// the fields and methods of SourceError and the functions rel, base,
+// upper, lower, truncate and json,
+//
+//	{{upper .Severity.String}} {{rel .File "/src"}}:{{.Line}} {{truncate 60 .Message}}
+func NewFormatter(tmpl string) (*Formatter, error) {
+	text, ok := Templates[tmpl]
+	if !ok {
+		text = tmpl
+	}
+	t, err := template.New("error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Formatter{tmpl: t, newline: !strings.HasSuffix(text, "\n")}, nil
+}
+
+// Format writes every error in errs to w with the template of f, each
+// one on its own line unless the template ends with a newline itself.
+func (f *Formatter) Format(w io.Writer, errs []SourceError) error {
+	var buf bytes.Buffer
+	for _, e := range errs {
+		buf.Reset()
+		if err := f.tmpl.Execute(&buf, e); err != nil {
+			return err
+		}
+		if f.newline {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatErrors writes errs to w with tmpl, see NewFormatter.
+func FormatErrors(w io.Writer, errs []SourceError, tmpl string) error {
+	f, err := NewFormatter(tmpl)
+	if err != nil {
+		return err
+	}
+	return f.Format(w, errs)
+}
@@ -0,0 +1,61 @@
+package oututil
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFormatErrors(t *testing.T) {
+	errs := []SourceError{
+		{File: "/src/app/main.go", Line: 4, Column: 2, Severity: SeverityError, Code: "E1", Message: `x "declared" and not used`},
+		{File: "/src/app/lib/util.go", Line: 9, Column: NoColumn, Message: "a message that is long enough to be truncated by the short template, which keeps eighty"},
+	}
+	tests := []struct {
+		tmpl, want string
+	}{
+		{"gnu", "/src/app/main.go:4:2: error: x \"declared\" and not used\n/src/app/lib/util.go:9: a message that is long enough to be truncated by the short template, which keeps eighty\n"},
+		{"short", "main.go:4: x \"declared\" and not used\nutil.go:9: a message that is long enough to be truncated by the short template, which keep…\n"},
+		{"json-lines", `{"file":"/src/app/main.go","line":4,"column":2,"severity":"error","code":"E1","message":"x \"declared\" and not used"}` + "\n" +
+			`{"file":"/src/app/lib/util.go","line":9,"column":-1,"severity":"unknown","code":"","message":"a message that is long enough to be truncated by the short template, which keeps eighty"}` + "\n"},
+		{`{{upper .Severity.String}} {{rel .File "/src/app"}}:{{.Line}} {{truncate 10 .Message}}`, "ERROR main.go:4 x \"declar…\nUNKNOWN lib/util.go:9 a message…\n"},
+		{"{{.File}}\n\n", "/src/app/main.go\n\n/src/app/lib/util.go\n\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := FormatErrors(&buf, errs, test.tmpl); err != nil {
+			t.Logf("%s: %v", test.tmpl, err)
+			t.Fail()
+			continue
+		}
+		if buf.String() != test.want {
+			t.Logf("%s: was expecting:\n%s\ngot:\n%s", test.tmpl, test.want, buf.String())
+			t.Fail()
+		}
+	}
+	if err := FormatErrors(&bytes.Buffer{}, errs, "{{.File"); err == nil {
+		t.Log("was expecting an error for an invalid template")
+		t.Fail()
+	}
+	if err := FormatErrors(&bytes.Buffer{}, errs, "{{.NoSuchField}}"); err == nil {
+		t.Log("was expecting an error for a missing field")
+		t.Fail()
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	f, err := NewFormatter("{{.File}}#{{.Line}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []SourceError{{File: "a.go", Line: 1}, {File: "b.go", Line: 2}} {
+		var buf bytes.Buffer
+		if err := f.Format(&buf, []SourceError{e}); err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("%s#%d\n", e.File, e.Line); buf.String() != want {
+			t.Logf("was expecting %q got %q instead", want, buf.String())
+			t.Fail()
+		}
+	}
+}
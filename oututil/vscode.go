@@ -0,0 +1,197 @@
+package oututil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// matcherFields maps the captures of a Format to the properties of a
+// VS Code problem matcher pattern.
+var matcherFields = map[string]string{
+	"file":     "file",
+	"line":     "line",
+	"col":      "column",
+	"endline":  "endLine",
+	"endcol":   "endColumn",
+	"severity": "severity",
+	"code":     "code",
+	"message":  "message",
+}
+
+// posixClasses are the ASCII classes of Go's syntax JavaScript doesn't
+// have, as the ranges they stand for in a bracket expression.
+var posixClasses = map[string]string{
+	"alnum":  `0-9A-Za-z`,
+	"alpha":  `A-Za-z`,
+	"ascii":  `\x00-\x7F`,
+	"blank":  `\t `,
+	"cntrl":  `\x00-\x1F\x7F`,
+	"digit":  `0-9`,
+	"graph":  `!-~`,
+	"lower":  `a-z`,
+	"print":  ` -~`,
+	"punct":  `!-/:-@[-` + "`" + `{-~`,
+	"space":  `\t\n\v\f\r `,
+	"upper":  `A-Z`,
+	"word":   `0-9A-Za-z_`,
+	"xdigit": `0-9A-Fa-f`,
+}
+
+// toJSRegexp rewrites a Go expression in the JavaScript syntax VS Code
+// uses. Named captures become plain ones, which keeps the numbering of
+// the groups the same as in Go; syntax JavaScript has no equivalent for
+// is an error.
+func toJSRegexp(pattern string) (string, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			switch next := pattern[i+1]; next {
+			case 'A':
+				b.WriteByte('^')
+			case 'z':
+				b.WriteByte('$')
+			case 'p', 'P', 'Q', 'C':
+				return "", fmt.Errorf("%s: \\%c has no equivalent in a problem matcher", pattern, next)
+			default:
+				b.WriteString(pattern[i : i+2])
+			}
+			i++
+			continue
+		case inClass && strings.HasPrefix(pattern[i:], "[:"):
+			end := strings.Index(pattern[i:], ":]")
+			if end < 0 {
+				break
+			}
+			name := pattern[i+2 : i+end]
+			ranges, ok := posixClasses[name]
+			if !ok {
+				return "", fmt.Errorf("%s: unknown character class [:%s:]", pattern, name)
+			}
+			b.WriteString(ranges)
+			i += end + 1
+			continue
+		case inClass && c == ']':
+			inClass = false
+		case !inClass && c == '[':
+			inClass = true
+			b.WriteByte(c)
+			// a ] right after the opening bracket is a literal
+			if strings.HasPrefix(pattern[i+1:], "^]") {
+				b.WriteString(`^\]`)
+				i += 2
+			} else if strings.HasPrefix(pattern[i+1:], "]") {
+				b.WriteString(`\]`)
+				i++
+			}
+			continue
+		case !inClass && strings.HasPrefix(pattern[i:], "(?P<"):
+			i = strings.IndexByte(pattern[i:], '>') + i
+			b.WriteByte('(')
+			continue
+		case !inClass && strings.HasPrefix(pattern[i:], "(?") && !strings.HasPrefix(pattern[i:], "(?:"):
+			return "", fmt.Errorf("%s: flags have no equivalent in a problem matcher", pattern)
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+type problemMatcher struct {
+	Owner        string                 `json:"owner"`
+	Source       string                 `json:"source,omitempty"`
+	FileLocation []string               `json:"fileLocation"`
+	Pattern      map[string]interface{} `json:"pattern"`
+}
+
+// ToProblemMatcher returns a VS Code problem matcher for f owned by
+// owner. Its pattern is f's expression in JavaScript syntax with the
+// indexes of its captures; f must capture a message and every capture
+// once, which rules out expressions with alternatives capturing the
+// same name.
+func (f Format) ToProblemMatcher(owner string) ([]byte, error) {
+	if f.re == nil {
+		return nil, fmt.Errorf("format doesn't have an expression")
+	}
+	re, err := toJSRegexp(f.re.String())
+	if err != nil {
+		return nil, err
+	}
+	pattern := map[string]interface{}{"regexp": re}
+	for i, name := range f.re.SubexpNames() {
+		field, ok := matcherFields[name]
+		if !ok {
+			continue
+		}
+		if _, dup := pattern[field]; dup {
+			return nil, fmt.Errorf("%s: %s is captured more than once", f.re, name)
+		}
+		pattern[field] = i
+	}
+	if _, ok := pattern["message"]; !ok {
+		return nil, fmt.Errorf("%s: problem matchers need a message", f.re)
+	}
+	return json.MarshalIndent(problemMatcher{
+		Owner:        owner,
+		Source:       f.name,
+		FileLocation: []string{"autoDetect", "${workspaceFolder}"},
+		Pattern:      pattern,
+	}, "", "  ")
+}
+
+// ProblemsJSON is the Format of the lines WriteProblemsJSON writes, its
+// ToProblemMatcher is the matcher for them. The strings it captures are
+// still JSON escaped.
+var ProblemsJSON = MustFormat(`^\{"file":"(?P<file>(?:[^"\\]|\\.)*)","line":(?P<line>[0-9]+)` +
+	`(?:,"column":(?P<col>[0-9]+))?(?:,"endLine":(?P<endline>[0-9]+))?(?:,"endColumn":(?P<endcol>[0-9]+))?` +
+	`(?:,"severity":"(?P<severity>[a-z]+)")?(?:,"code":"(?P<code>(?:[^"\\]|\\.)*)")?` +
+	`,"message":"(?P<message>(?:[^"\\]|\\.)*)"\}$`)
+
+type problem struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
+
+// WriteProblemsJSON writes errs to w as JSON objects, one per line, in
+// a fixed shape the ProblemsJSON problem matcher reads. Notes have the
+// info severity VS Code knows them as.
+func WriteProblemsJSON(w io.Writer, errs []SourceError) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, e := range errs {
+		p := problem{
+			File:    e.File,
+			Line:    e.Line,
+			Code:    e.Code,
+			Message: e.Message,
+		}
+		if e.Column != NoColumn {
+			p.Column = e.Column
+		}
+		if e.EndColumn > 0 {
+			p.EndLine, p.EndColumn = e.EndLine, e.EndColumn
+		}
+		switch e.Severity {
+		case SeverityError:
+			p.Severity = "error"
+		case SeverityWarning:
+			p.Severity = "warning"
+		case SeverityNote:
+			p.Severity = "info"
+		}
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
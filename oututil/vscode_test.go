@@ -0,0 +1,114 @@
+package oututil
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestToJSRegexp(t *testing.T) {
+	tests := []struct {
+		pattern, js string
+		err         bool
+	}{
+		{pattern: `(?P<file>\S+):(?P<line>[0-9]+)`, js: `(\S+):([0-9]+)`},
+		{pattern: `\A(?:x|y)[[:alnum:]_]\z`, js: `^(?:x|y)[0-9A-Za-z_]$`},
+		{pattern: `[]a](?P<m>.*)`, js: `[\]a](.*)`},
+		{pattern: `[^]a]`, js: `[^\]a]`},
+		{pattern: `\(\[(?P<x>a)`, js: `\(\[(a)`},
+		{pattern: `(?i)error`, err: true},
+		{pattern: `\pL+`, err: true},
+	}
+	for _, test := range tests {
+		js, err := toJSRegexp(test.pattern)
+		if (err != nil) != test.err || js != test.js {
+			t.Logf("%s: was expecting %q (error %v) got %q (%v) instead", test.pattern, test.js, test.err, js, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestToProblemMatcher(t *testing.T) {
+	for _, f := range RegisteredFormats() {
+		if f.Name() == "tsc" {
+			// tsc captures its positions in two alternatives
+			if _, err := f.ToProblemMatcher("tsc"); err == nil {
+				t.Log("tsc: was expecting an error for captures used twice")
+				t.Fail()
+			}
+			continue
+		}
+		b, err := f.ToProblemMatcher("x")
+		if err != nil {
+			t.Logf("%s: %v", f.Name(), err)
+			t.Fail()
+			continue
+		}
+		var m struct {
+			Owner   string
+			Source  string
+			Pattern map[string]interface{}
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		// the group numbers of the JavaScript expression and Go's
+		// have to agree, and the only syntax Go and JavaScript read
+		// differently is rewritten so Go can check them.
+		re, err := regexp.Compile(m.Pattern["regexp"].(string))
+		if err != nil {
+			t.Logf("%s: %v", f.Name(), err)
+			t.Fail()
+			continue
+		}
+		if re.NumSubexp() != f.re.NumSubexp() {
+			t.Logf("%s: was expecting %d groups got %d instead", f.Name(), f.re.NumSubexp(), re.NumSubexp())
+			t.Fail()
+		}
+		names := f.re.SubexpNames()
+		for name, field := range matcherFields {
+			i, ok := m.Pattern[field].(float64)
+			if !ok {
+				continue
+			}
+			if names[int(i)] != name {
+				t.Logf("%s: %s is group %v, was expecting %s", f.Name(), field, i, names[int(i)])
+				t.Fail()
+			}
+		}
+		if m.Owner != "x" || m.Source != f.Name() {
+			t.Logf("%s: was expecting owner x got %s, %s", f.Name(), m.Owner, m.Source)
+			t.Fail()
+		}
+	}
+	if _, err := MustFormat(`(?P<file>\S+):(?P<line>[0-9]+)`).ToProblemMatcher("x"); err == nil {
+		t.Log("was expecting an error for a format without a message")
+		t.Fail()
+	}
+}
+
+func TestWriteProblemsJSON(t *testing.T) {
+	errs := []SourceError{
+		{File: "a.go", Line: 3, Column: 7, Severity: SeverityError, Code: "SA4006", Message: "x <is> never used"},
+		{File: `dir\b.c`, Line: 9, Column: NoColumn, Severity: SeverityNote, Message: `"quoted"`},
+		{File: "c.rs", Line: 1, Column: 2, EndLine: 1, EndColumn: 5, Message: "span"},
+	}
+	var buf bytes.Buffer
+	if err := WriteProblemsJSON(&buf, errs); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"file":"a.go","line":3,"column":7,"severity":"error","code":"SA4006","message":"x <is> never used"}
+{"file":"dir\\b.c","line":9,"severity":"info","message":"\"quoted\""}
+{"file":"c.rs","line":1,"column":2,"endLine":1,"endColumn":5,"message":"span"}
+`
+	if buf.String() != expected {
+		t.Logf("was expecting:\n%s\ngot:\n%s", expected, buf.String())
+		t.Fail()
+	}
+	checkSourceErrors(t, []SourceError{
+		{File: "a.go", Line: 3, Column: 7, Severity: SeverityError, Code: "SA4006", Message: "x <is> never used"},
+		{File: `dir\\b.c`, Line: 9, Column: NoColumn, Severity: SeverityNote, Message: `\"quoted\"`},
+		{File: "c.rs", Line: 1, Column: 2, EndLine: 1, EndColumn: 5, Message: "span"},
+	}, ParseWith([]Format{ProblemsJSON}, buf.String()))
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import "time"
+
+// AdaptiveInterval makes a Runner pass more often while the states
+// drift and less often while they don't. Zero fields get their
+// defaults.
+type AdaptiveInterval struct {
+	// Multiplier is the factor the interval is shortened and lengthened
+	// by, 2 by default.
+	Multiplier float64
+	// Floor and Ceiling bound the interval, they are the Runner's
+	// Interval and 16 times it by default.
+	Floor, Ceiling time.Duration
+	// Smoothing is the weight of the latest pass in the moving average
+	// of drift, 0.5 by default.
+	Smoothing float64
+}
+
+// withDefaults returns a with the defaults for a Runner passing every
+// interval.
+func (a AdaptiveInterval) withDefaults(interval time.Duration) AdaptiveInterval {
+	if a.Multiplier <= 1 {
+		a.Multiplier = 2
+	}
+	if a.Floor <= 0 {
+		a.Floor = interval
+	}
+	if a.Ceiling <= 0 {
+		a.Ceiling = 16 * interval
+	}
+	if a.Ceiling < a.Floor {
+		a.Ceiling = a.Floor
+	}
+	if a.Smoothing <= 0 || a.Smoothing > 1 {
+		a.Smoothing = 0.5
+	}
+	return a
+}
+
+// next returns the interval after a pass that did or didn't change the
+// current state, and the moving average of drift that includes it. A
+// pass with changes shortens the interval; one without lengthens it
+// once the average falls below a quarter, which with the default
+// smoothing takes at least two passes in a row without changes.
+func (a AdaptiveInterval) next(interval time.Duration, drift float64, changed bool) (time.Duration, float64) {
+	sample := 0.0
+	if changed {
+		sample = 1
+	}
+	drift = a.Smoothing*sample + (1-a.Smoothing)*drift
+	switch {
+	case changed:
+		interval = time.Duration(float64(interval) / a.Multiplier)
+	case drift < 0.25:
+		interval = time.Duration(float64(interval) * a.Multiplier)
+	}
+	if interval < a.Floor {
+		interval = a.Floor
+	}
+	if interval > a.Ceiling {
+		interval = a.Ceiling
+	}
+	return interval, drift
+}
+
+// changed reports whether r changed the current state.
+func (r Result) changed() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Deleted) > 0
+}
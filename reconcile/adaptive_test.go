@@ -0,0 +1,111 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := AdaptiveInterval{}.withDefaults(time.Second)
+	tests := []struct {
+		changed  bool
+		interval time.Duration
+	}{
+		// quiet states back off up to the ceiling
+		{false, 2 * time.Second},
+		{false, 4 * time.Second},
+		{false, 8 * time.Second},
+		{false, 16 * time.Second},
+		{false, 16 * time.Second},
+		// drift shortens it right away, one quiet pass isn't enough to
+		// lengthen it again
+		{true, 8 * time.Second},
+		{false, 8 * time.Second},
+		{false, 16 * time.Second},
+		{true, 8 * time.Second},
+		{true, 4 * time.Second},
+		{true, 2 * time.Second},
+		{true, time.Second},
+		{true, time.Second},
+	}
+	interval, drift := time.Second, 0.0
+	for i, test := range tests {
+		interval, drift = a.next(interval, drift, test.changed)
+		if interval != test.interval {
+			t.Logf("pass %d: got %v, expected %v", i, interval, test.interval)
+			t.Fail()
+		}
+		if drift < 0 || drift > 1 {
+			t.Logf("pass %d: drift %v out of range", i, drift)
+			t.Fail()
+		}
+	}
+}
+
+func TestAdaptiveIntervalDefaults(t *testing.T) {
+	a := AdaptiveInterval{Ceiling: time.Millisecond}.withDefaults(time.Second)
+	if a.Multiplier != 2 || a.Floor != time.Second || a.Ceiling != time.Second || a.Smoothing != 0.5 {
+		t.Logf("got %+v", a)
+		t.Fail()
+	}
+}
+
+func TestRunnerAdaptive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	passes := 0
+	r := &Runner{
+		Current:  &MapState{},
+		Desired:  NewMapState(map[string]interface{}{"a": "1"}),
+		Interval: time.Millisecond,
+		Adaptive: &AdaptiveInterval{Ceiling: 4 * time.Millisecond},
+		OnResult: func(Result) {
+			passes++
+			if passes == 4 {
+				cancel()
+			}
+		},
+	}
+	if got := r.CurrentInterval(); got != time.Millisecond {
+		t.Logf("got %v before running, expected 1ms", got)
+		t.Fail()
+	}
+	r.Run(ctx)
+	// one pass added a, the three after it didn't change anything
+	if got := r.CurrentInterval(); got != 4*time.Millisecond {
+		t.Logf("got %v, expected 4ms", got)
+		t.Fail()
+	}
+	if d := r.Drift(); d <= 0 || d >= 0.5 {
+		t.Logf("got a drift of %v", d)
+		t.Fail()
+	}
+}
+
+func TestRunnerTrigger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan Result)
+	r := &Runner{
+		Current:  &MapState{},
+		Desired:  &MapState{},
+		Interval: time.Hour,
+		Adaptive: &AdaptiveInterval{},
+		OnResult: func(result Result) { results <- result },
+	}
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	<-results
+	r.Trigger()
+	select {
+	case <-results:
+	case <-time.After(10 * time.Second):
+		t.Log("Trigger didn't cause a pass")
+		t.Fail()
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Logf("got %v, expected %v", err, context.Canceled)
+		t.Fail()
+	}
+}
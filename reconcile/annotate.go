@@ -0,0 +1,57 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Annotated is implemented by desired values that carry metadata, like
+// the ticket of the change that made them, which is copied onto their
+// Update.
+//
+// Annotations shouldn't make values differ. Values that implement
+// Checksumed should leave them out of their Sum, others can implement
+//
+//	WithoutAnnotations() interface{}
+//
+// to return a copy of themselves without annotations that is compared
+// instead of them.
+type Annotated interface {
+	Annotations() map[string]string
+}
+
+// ErrMissingAnnotation is the reason for rejecting updates without an
+// annotation set with WithRequiredAnnotation.
+var ErrMissingAnnotation = errors.New("missing annotation")
+
+func annotations(v interface{}) map[string]string {
+	if a, ok := v.(Annotated); ok {
+		return a.Annotations()
+	}
+	return nil
+}
+
+func withoutAnnotations(v interface{}) interface{} {
+	if s, ok := v.(interface{ WithoutAnnotations() interface{} }); ok {
+		return s.WithoutAnnotations()
+	}
+	return v
+}
+
+// missingAnnotation returns an error for an addition or an update of u
+// that doesn't have one of the required annotations.
+func missingAnnotation(u update, required []string) error {
+	if u.state == old || u.state == touched {
+		return nil
+	}
+	for _, name := range required {
+		if _, ok := u.annotations[name]; !ok {
+			return fmt.Errorf("%s: %w %q", u.key, ErrMissingAnnotation, name)
+		}
+	}
+	return nil
+}
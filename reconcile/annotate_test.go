@@ -0,0 +1,63 @@
+package reconcile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type ticketed struct {
+	Value string
+	Meta  map[string]string
+}
+
+func (t ticketed) Annotations() map[string]string  { return t.Meta }
+func (t ticketed) WithoutAnnotations() interface{} { return ticketed{Value: t.Value} }
+
+func TestAnnotations(t *testing.T) {
+	current := NewMapState(map[string]interface{}{
+		"same":    ticketed{Value: "a", Meta: map[string]string{"ticket": "OPS-1"}},
+		"changed": ticketed{Value: "a"},
+		"gone":    ticketed{Value: "a"},
+	})
+	desired := NewMapState(map[string]interface{}{
+		"same":       ticketed{Value: "a", Meta: map[string]string{"ticket": "OPS-2"}},
+		"changed":    ticketed{Value: "b", Meta: map[string]string{"ticket": "OPS-3", "owner": "web"}},
+		"unticketed": ticketed{Value: "c"},
+	})
+	cs := NewChangeSet(current, desired)
+	want := []Update{
+		{Key: "changed", Action: "Update", Annotations: map[string]string{"ticket": "OPS-3", "owner": "web"}},
+		{Key: "unticketed", Action: "Add"},
+		{Key: "gone", Action: "Delete"},
+	}
+	got := cs.Updates()
+	for i := range got {
+		got[i].Reason = ""
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Logf("got %+v, expected %+v", got, want)
+		t.Fail()
+	}
+
+	var hooked []Update
+	r := cs.ApplyTo(current, WithRequiredAnnotation("ticket"), WithApplyHook(func(u Update) {
+		hooked = append(hooked, u)
+	}))
+	if !errors.Is(r.Rejected["unticketed"], ErrMissingAnnotation) || len(r.Rejected) != 1 {
+		t.Logf("got rejections %v, expected unticketed to be missing a ticket", r.Rejected)
+		t.Fail()
+	}
+	if !reflect.DeepEqual(r.Updated, []string{"changed"}) || !reflect.DeepEqual(r.Deleted, []string{"gone"}) {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+	if len(hooked) != 2 || hooked[0].Annotations["ticket"] != "OPS-3" || hooked[1].Key != "gone" {
+		t.Logf("hook got %+v", hooked)
+		t.Fail()
+	}
+	if current.Get("unticketed") != nil {
+		t.Log("rejected update was applied")
+		t.Fail()
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ChangeSet is the list of updates that turns a reference state into a
+// desired one. It can be applied to the reference state and to any
+// number of other states that are copies of it, which saves walking the
+// desired state once per target.
+type ChangeSet struct {
+	updates []update
+	// err is the panic planning recovered, see WithRecoverPanics.
+	err error
+}
+
+// NewChangeSet returns the ChangeSet that turns current into desired.
+// It doesn't check the states like Plan does.
+func NewChangeSet(current, desired State, opts ...Option) *ChangeSet {
+	updates, err := planRecovered(current, desired, newOptions(opts))
+	return &ChangeSet{updates: updates, err: err}
+}
+
+// Len returns the number of updates in c.
+func (c *ChangeSet) Len() int { return len(c.updates) }
+
+// Update is a change a ChangeSet makes to a key.
+type Update struct {
+	Key string `json:"key"`
+	// Action is Add, Delete, Update, PendingDelete or Touch.
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	// TextDiff is a unified diff of the current and desired values of
+	// the key, see WithValueDiff.
+	TextDiff string `json:"textDiff,omitempty"`
+	// Annotations are the annotations of the desired value, see
+	// Annotated.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Updates returns the updates in c in the order they are applied.
+func (c *ChangeSet) Updates() []Update {
+	updates := make([]Update, len(c.updates))
+	for i, u := range c.updates {
+		updates[i] = u.export()
+	}
+	return updates
+}
+
+// String formats c as a plan, see FormatUpdates.
+func (c *ChangeSet) String() string {
+	return FormatUpdates(c.Updates())
+}
+
+// FormatUpdates formats updates as a plan, a line per update followed
+// by its indented text diff.
+func FormatUpdates(updates []Update) string {
+	var b strings.Builder
+	for _, u := range updates {
+		fmt.Fprintf(&b, "%s %s: %s\n", u.Action, u.Key, u.Reason)
+		for _, line := range splitLines(u.TextDiff) {
+			fmt.Fprintf(&b, "\t%s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes c as a list of its updates.
+func (c *ChangeSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Updates())
+}
+
+// Result is the outcome of applying updates to a State.
+type Result struct {
+	Added, Updated, Deleted []string
+	// Diverged are the keys that weren't applied because the target
+	// didn't have the value the reference state had, see Verify.
+	Diverged []string
+	// Conflicts are the keys that weren't applied because they changed
+	// after they were compared, see WithCompareAndSwap.
+	Conflicts []string
+	// Pending are the keys whose deletion waits for WithDeleteGrace.
+	Pending []string
+	// Touched are the keys in sync that were rewritten, see WithTouch.
+	Touched []string
+	// Paused are the keys that weren't applied because they are
+	// paused, see WithPause.
+	Paused []string
+	// Rejected are the keys that weren't applied and why, like the ones
+	// missing a WithRequiredAnnotation.
+	Rejected map[string]error
+	// Panicked are the keys that weren't compared or applied because
+	// they panicked, and their panics, see WithRecoverPanics. A key
+	// whose hook panicked was applied.
+	Panicked map[string]*PanicError
+	// Err is the error flushing the state returned, see Flusher, or
+	// why Reconcile didn't run, like ErrSameState.
+	Err error
+	// DryRun is set when nothing was applied, see WithServerDryRun.
+	DryRun bool
+	// Statuses are the records the pass left in the WithStatusStore.
+	Statuses map[string]KeyStatus
+	// Remaining is the number of keys left after a Teardown.
+	Remaining int
+}
+
+// ExitCode returns 1 if r failed, because of its Err or because keys
+// were rejected, panicked, diverged or conflicted, and 0 otherwise. Paused and
+// pending keys aren't failures.
+func (r Result) ExitCode() int {
+	if r.Err != nil || len(r.Rejected)+len(r.Panicked)+len(r.Diverged)+len(r.Conflicts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (r *Result) reject(key string, err error) {
+	if r.Rejected == nil {
+		r.Rejected = make(map[string]error)
+	}
+	r.Rejected[key] = err
+}
+
+func (r *Result) record(u update) {
+	switch u.state {
+	case new:
+		r.Added = append(r.Added, u.key)
+	case old:
+		r.Deleted = append(r.Deleted, u.key)
+	case dirty:
+		r.Updated = append(r.Updated, u.key)
+	case touched:
+		r.Touched = append(r.Touched, u.key)
+	}
+}
+
+// ApplyTo applies the updates in c to s.
+func (c *ChangeSet) ApplyTo(s State, opts ...Option) Result {
+	if c.err != nil {
+		return Result{Err: c.err}
+	}
+	o := newOptions(opts)
+	r := fix(s, c.updates, o)
+	r.flushStatus(o)
+	return r
+}
@@ -0,0 +1,191 @@
+package reconcile
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func copyState(m map[string]interface{}) *testState {
+	ts := &testState{map[string]interface{}{}}
+	for k, v := range m {
+		ts.i[k] = v
+	}
+	return ts
+}
+
+func TestChangeSetApplyTo(t *testing.T) {
+	reference := map[string]interface{}{
+		"a": "old",
+		"b": "stale",
+		"c": "gone",
+	}
+	desired := &testState{map[string]interface{}{
+		"a": "old",
+		"b": "fresh",
+		"d": "new",
+	}}
+	cs := NewChangeSet(copyState(reference), desired)
+	if cs.Len() != 3 {
+		t.Logf("got %d updates, expected 3", cs.Len())
+		t.Fail()
+	}
+	diverged := copyState(reference)
+	diverged.i["b"] = "changed elsewhere"
+	diverged.i["d"] = "added elsewhere"
+	tests := []struct {
+		name   string
+		target *testState
+		opts   []Option
+		want   Result
+		state  map[string]interface{}
+	}{
+		{
+			name:   "copy",
+			target: copyState(reference),
+			want:   Result{Added: []string{"d"}, Updated: []string{"b"}, Deleted: []string{"c"}},
+			state:  desired.i,
+		},
+		{
+			name:   "verified copy",
+			target: copyState(reference),
+			opts:   []Option{Verify()},
+			want:   Result{Added: []string{"d"}, Updated: []string{"b"}, Deleted: []string{"c"}},
+			state:  desired.i,
+		},
+		{
+			name:   "diverged",
+			target: diverged,
+			opts:   []Option{Verify()},
+			want:   Result{Deleted: []string{"c"}, Diverged: []string{"b", "d"}},
+			state: map[string]interface{}{
+				"a": "old",
+				"b": "changed elsewhere",
+				"d": "added elsewhere",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := cs.ApplyTo(test.target, test.opts...)
+			sort.Strings(r.Diverged)
+			if !reflect.DeepEqual(r, test.want) {
+				t.Logf("got %+v, expected %+v", r, test.want)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(test.target.i, test.state) {
+				t.Logf("got state %v, expected %v", test.target.i, test.state)
+				t.Fail()
+			}
+		})
+	}
+}
+
+// casState is a testState that implements CASer.
+type casState struct {
+	*testState
+	calls int
+}
+
+func (s *casState) CAS(key string, old, new interface{}) error {
+	s.calls++
+	if cur := s.Get(key); cur != old {
+		return fmt.Errorf("%s is %v, expected %v", key, cur, old)
+	}
+	if new == nil {
+		s.Delete(key)
+	} else {
+		s.Add(key, new)
+	}
+	return nil
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	reference := map[string]interface{}{
+		"a": "1",
+		"b": "1",
+		"c": "1",
+	}
+	desired := &testState{map[string]interface{}{
+		"a": "2",
+		"b": "2",
+		"d": "2",
+	}}
+	want := Result{Updated: []string{"a"}, Conflicts: []string{"b", "c", "d"}}
+	wantState := map[string]interface{}{
+		"a": "2",
+		"b": "changed",
+		"c": "changed",
+		"d": "raced",
+	}
+	change := func(s *testState) {
+		s.i["b"] = "changed"
+		s.i["c"] = "changed"
+		s.i["d"] = "raced"
+	}
+	t.Run("get", func(t *testing.T) {
+		current := copyState(reference)
+		cs := NewChangeSet(current, desired)
+		change(current)
+		r := cs.ApplyTo(current, WithCompareAndSwap(true))
+		sort.Strings(r.Conflicts)
+		if !reflect.DeepEqual(r, want) {
+			t.Logf("got %+v, expected %+v", r, want)
+			t.Fail()
+		}
+		if !reflect.DeepEqual(current.i, wantState) {
+			t.Logf("got state %v, expected %v", current.i, wantState)
+			t.Fail()
+		}
+	})
+	t.Run("native", func(t *testing.T) {
+		current := &casState{testState: copyState(reference)}
+		cs := NewChangeSet(current, desired)
+		change(current.testState)
+		r := cs.ApplyTo(current, WithCompareAndSwap(true))
+		sort.Strings(r.Conflicts)
+		if !reflect.DeepEqual(r, want) {
+			t.Logf("got %+v, expected %+v", r, want)
+			t.Fail()
+		}
+		if current.calls != 4 {
+			t.Logf("CAS was called %d times, expected 4", current.calls)
+			t.Fail()
+		}
+		if !reflect.DeepEqual(current.i, wantState) {
+			t.Logf("got state %v, expected %v", current.i, wantState)
+			t.Fail()
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		current := copyState(reference)
+		cs := NewChangeSet(current, desired)
+		change(current)
+		r := cs.ApplyTo(current, WithCompareAndSwap(false))
+		if len(r.Conflicts) != 0 || !reflect.DeepEqual(current.i, desired.i) {
+			t.Logf("got %+v and state %v, expected the desired state", r, current.i)
+			t.Fail()
+		}
+	})
+}
+
+func TestResultExitCode(t *testing.T) {
+	tests := []struct {
+		r    Result
+		code int
+	}{
+		{Result{}, 0},
+		{Result{Added: []string{"a"}, Paused: []string{"b"}, Pending: []string{"c"}}, 0},
+		{Result{Err: fmt.Errorf("flush")}, 1},
+		{Result{Rejected: map[string]error{"a": fmt.Errorf("bad")}}, 1},
+		{Result{Diverged: []string{"a"}}, 1},
+		{Result{Conflicts: []string{"a"}}, 1},
+	}
+	for _, test := range tests {
+		if got := test.r.ExitCode(); got != test.code {
+			t.Logf("was expecting %d for %+v got %d", test.code, test.r, got)
+			t.Fail()
+		}
+	}
+}
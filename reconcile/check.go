@@ -0,0 +1,256 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ContractError is the error CheckState returns for a State that
+// doesn't behave the way Reconcile expects.
+type ContractError struct {
+	// Contract is the name of the contract that was violated, like
+	// "get after add".
+	Contract string
+	Detail   string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Contract, e.Detail)
+}
+
+type checkOptions struct {
+	concurrent bool
+	prefix     string
+}
+
+// CheckOption configures CheckState.
+type CheckOption func(*checkOptions)
+
+// Concurrent checks that s can be used from several goroutines at once,
+// it should only be set for states that are documented to be safe for
+// concurrent use.
+func Concurrent() CheckOption {
+	return func(o *checkOptions) { o.concurrent = true }
+}
+
+// CheckPrefix sets the prefix of the keys CheckState uses, it is
+// "reconcile-check/" by default.
+func CheckPrefix(prefix string) CheckOption {
+	return func(o *checkOptions) { o.prefix = prefix }
+}
+
+// CheckState exercises the Add, Update, Get, Delete and Walk methods of
+// s and returns a *ContractError for the first behaviour Reconcile
+// relies on that s doesn't have. It only touches keys with the
+// CheckPrefix and deletes them before returning, s can have other keys.
+func CheckState(s State, opts ...CheckOption) error {
+	o := checkOptions{prefix: "reconcile-check/"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := checker{s: s, prefix: o.prefix}
+	defer c.cleanup()
+	steps := []func() error{
+		c.missing,
+		c.add,
+		c.overwrite,
+		c.update,
+		c.delete,
+		c.walk,
+	}
+	if o.concurrent {
+		steps = append(steps, c.concurrent)
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	s      State
+	prefix string
+}
+
+func (c checker) key(name string) string { return c.prefix + name }
+
+// call runs f and turns a panic into a violation of contract.
+func (c checker) call(contract string, f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ContractError{contract, fmt.Sprintf("panicked: %v", r)}
+		}
+	}()
+	f()
+	return nil
+}
+
+// expect checks that key has the value want, nil meaning missing.
+func (c checker) expect(contract, key string, want interface{}) error {
+	var got interface{}
+	if err := c.call(contract, func() { got = c.s.Get(key) }); err != nil {
+		return err
+	}
+	switch {
+	case want == nil && got != nil:
+		return &ContractError{contract, fmt.Sprintf("Get(%q) = %v, expected nil", key, got)}
+	case want != nil && got == nil:
+		return &ContractError{contract, fmt.Sprintf("Get(%q) = nil, expected %v", key, want)}
+	case want != nil && compare(got, want) != nil:
+		return &ContractError{contract, fmt.Sprintf("Get(%q) = %v, expected %v", key, got, want)}
+	}
+	return nil
+}
+
+func (c checker) missing() error {
+	return c.expect("get missing key", c.key("missing"), nil)
+}
+
+func (c checker) add() error {
+	k := c.key("a")
+	if err := c.call("add", func() { c.s.Add(k, "1") }); err != nil {
+		return err
+	}
+	return c.expect("get after add", k, "1")
+}
+
+func (c checker) overwrite() error {
+	k := c.key("a")
+	if err := c.call("add existing key", func() { c.s.Add(k, "2") }); err != nil {
+		return err
+	}
+	return c.expect("add overwrites", k, "2")
+}
+
+func (c checker) update() error {
+	k := c.key("a")
+	if err := c.call("update", func() { c.s.Update(k, "3") }); err != nil {
+		return err
+	}
+	return c.expect("get after update", k, "3")
+}
+
+func (c checker) delete() error {
+	k := c.key("a")
+	if err := c.call("delete", func() { c.s.Delete(k) }); err != nil {
+		return err
+	}
+	if err := c.expect("get after delete", k, nil); err != nil {
+		return err
+	}
+	return c.call("delete missing key", func() { c.s.Delete(k) })
+}
+
+func (c checker) walk() error {
+	want := map[string]string{
+		c.key("w1"): "x",
+		c.key("w2"): "y",
+		c.key("w3"): "z",
+	}
+	for k, v := range want {
+		k, v := k, v
+		if err := c.call("add", func() { c.s.Add(k, v) }); err != nil {
+			return err
+		}
+	}
+	deleted := c.key("w4")
+	if err := c.call("add", func() { c.s.Add(deleted, "gone") }); err != nil {
+		return err
+	}
+	if err := c.call("delete", func() { c.s.Delete(deleted) }); err != nil {
+		return err
+	}
+	seen := make(map[string]int)
+	var bad error
+	err := c.call("walk", func() {
+		c.s.Walk(func(key string, v interface{}) {
+			seen[key]++
+			if w, ok := want[key]; ok && bad == nil && compare(v, w) != nil {
+				bad = &ContractError{"walk values", fmt.Sprintf("walked %q with %v, expected %v", key, v, w)}
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if bad != nil {
+		return bad
+	}
+	for k := range want {
+		switch seen[k] {
+		case 0:
+			return &ContractError{"walk completeness", fmt.Sprintf("Walk didn't visit %q", k)}
+		case 1:
+		default:
+			return &ContractError{"walk visits keys once", fmt.Sprintf("Walk visited %q %d times", k, seen[k])}
+		}
+	}
+	if seen[deleted] > 0 {
+		return &ContractError{"walk after delete", fmt.Sprintf("Walk visited deleted key %q", deleted)}
+	}
+	return nil
+}
+
+// concurrentKeys is the number of goroutines the concurrent check runs.
+const concurrentKeys = 16
+
+func (c checker) concurrent() error {
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentKeys+1)
+	for i := 0; i < concurrentKeys; i++ {
+		k := c.key(fmt.Sprintf("c%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.call("concurrent use", func() {
+				c.s.Add(k, "1")
+				c.s.Get(k)
+				c.s.Update(k, "2")
+				c.s.Walk(func(string, interface{}) {})
+			})
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- c.call("concurrent use", func() {
+			c.s.Walk(func(string, interface{}) {})
+		})
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for i := 0; i < concurrentKeys; i++ {
+		if err := c.expect("concurrent use", c.key(fmt.Sprintf("c%d", i)), "2"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanup deletes the keys the checks added, ignoring panics.
+func (c checker) cleanup() {
+	var keys []string
+	c.call("walk", func() {
+		c.s.Walk(func(key string, _ interface{}) {
+			if strings.HasPrefix(key, c.prefix) {
+				keys = append(keys, key)
+			}
+		})
+	})
+	for _, k := range keys {
+		k := k
+		c.call("delete", func() { c.s.Delete(k) })
+	}
+}
@@ -0,0 +1,137 @@
+package reconcile
+
+import (
+	"sync"
+	"testing"
+)
+
+type lockedState struct {
+	mu sync.Mutex
+	testState
+}
+
+func (s *lockedState) Add(key string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.testState.Add(key, v)
+}
+
+func (s *lockedState) Update(key string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.testState.Update(key, v)
+}
+
+func (s *lockedState) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.testState.Get(key)
+}
+
+func (s *lockedState) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.testState.Delete(key)
+}
+
+func (s *lockedState) Walk(f StateWalkFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.testState.Walk(f)
+}
+
+// staleAdd doesn't overwrite keys it already has.
+type staleAdd struct{ *testState }
+
+func (s staleAdd) Add(key string, v interface{}) {
+	if _, ok := s.i[key]; !ok {
+		s.i[key] = v
+	}
+}
+
+// softDelete keeps deleted keys around as tombstones.
+type softDelete struct{ *testState }
+
+func (s softDelete) Delete(key string) { s.i[key] = "deleted" }
+
+// partialWalk stops walking after the first key.
+type partialWalk struct{ *testState }
+
+func (s partialWalk) Walk(f StateWalkFunc) {
+	for k, v := range s.i {
+		f(k, v)
+		return
+	}
+}
+
+// ghostWalk walks keys it deleted.
+type ghostWalk struct {
+	*testState
+	deleted map[string]bool
+}
+
+func (s ghostWalk) Delete(key string) {
+	s.deleted[key] = true
+	delete(s.i, key)
+}
+
+func (s ghostWalk) Walk(f StateWalkFunc) {
+	s.testState.Walk(f)
+	for k := range s.deleted {
+		f(k, "gone")
+	}
+}
+
+// panicUpdate panics on Update.
+type panicUpdate struct{ *testState }
+
+func (s panicUpdate) Update(key string, v interface{}) { panic("not implemented") }
+
+func TestCheckState(t *testing.T) {
+	empty := func() *testState { return &testState{map[string]interface{}{}} }
+	tests := []struct {
+		name     string
+		s        State
+		opts     []CheckOption
+		contract string
+	}{
+		{name: "map", s: empty()},
+		{name: "locked", s: &lockedState{testState: *empty()}, opts: []CheckOption{Concurrent()}},
+		{name: "stale add", s: staleAdd{empty()}, contract: "add overwrites"},
+		{name: "soft delete", s: softDelete{empty()}, contract: "get after delete"},
+		{name: "partial walk", s: partialWalk{empty()}, contract: "walk completeness"},
+		{name: "ghost walk", s: ghostWalk{empty(), map[string]bool{}}, contract: "walk after delete"},
+		{name: "panic", s: panicUpdate{empty()}, contract: "update"},
+		{name: "prefix", s: staleAdd{empty()}, opts: []CheckOption{CheckPrefix("x/")}, contract: "add overwrites"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckState(test.s, test.opts...)
+			contract := ""
+			if err != nil {
+				ce, ok := err.(*ContractError)
+				if !ok {
+					t.Logf("got %T, expected a *ContractError", err)
+					t.FailNow()
+				}
+				contract = ce.Contract
+			}
+			if contract != test.contract {
+				t.Logf("got %v, expected a violation of %q", err, test.contract)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestCheckStateCleanup(t *testing.T) {
+	s := &testState{map[string]interface{}{"mine": "kept"}}
+	if err := CheckState(s); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	if len(s.i) != 1 || s.i["mine"] != "kept" {
+		t.Logf("got %v after the check, expected only the existing key", s.i)
+		t.Fail()
+	}
+}
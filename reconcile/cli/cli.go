@@ -0,0 +1,358 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cli is the main of the commands that reconcile a State with a
+// JSON document of its desired keys,
+//
+//	app plan [flags] desired.json
+//	app apply [flags] desired.json
+//	app detect [flags] desired.json
+//
+// plan prints the changes, apply makes them and detect exits with
+// ExitDrift if there are any. The document is a JSON object of the
+// keys and their values; -current compares it with another document
+// instead of the State, which is how two files are diffed.
+//
+// It is built on the options of reconcile alone, see run for how the
+// flags map to them.
+package cli // import "sevki.org/x/reconcile/cli"
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"sevki.org/x/reconcile"
+)
+
+// The exit codes of Run.
+const (
+	// ExitOK is the code of commands that succeeded, apply exits with
+	// the reconcile.Result ExitCode.
+	ExitOK = 0
+	// ExitFailure is the code of commands that failed.
+	ExitFailure = 1
+	// ExitUsage is the code of bad arguments, like the flag package's.
+	ExitUsage = 2
+	// ExitDrift is the code of detect when the State isn't the desired
+	// one.
+	ExitDrift = 3
+)
+
+// Run runs the subcommand args start with on the State newCurrent
+// returns and returns the exit code. Its output goes to os.Stdout and
+// os.Stderr.
+func Run(ctx context.Context, args []string, newCurrent func() (reconcile.State, error)) int {
+	return run(ctx, args, newCurrent, os.Stdout, os.Stderr)
+}
+
+type command struct {
+	name       string
+	prune      bool
+	filters    []string
+	maxDeletes int
+	output     string
+	current    string
+	hash       string
+	verbose    bool
+	cas        bool
+	dryRun     bool
+}
+
+func (c *command) flags(stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&c.prune, "prune", false, "delete the keys that aren't in the desired document")
+	fs.Func("filter", "only reconcile the keys matching the `glob`, repeatable", func(glob string) error {
+		if _, err := path.Match(glob, ""); err != nil {
+			return err
+		}
+		c.filters = append(c.filters, glob)
+		return nil
+	})
+	fs.IntVar(&c.maxDeletes, "max-deletes", -1, "refuse to apply plans deleting more than `n` keys, -1 for no limit")
+	fs.StringVar(&c.output, "o", "text", "output `format`, text or json")
+	fs.StringVar(&c.current, "current", "", "compare with the JSON document at `file` instead of the state")
+	fs.BoolVar(&c.verbose, "v", false, "log every update")
+	if c.name == "apply" {
+		fs.StringVar(&c.hash, "hash", "", "only apply the plan with the `hash` plan printed")
+		fs.BoolVar(&c.cas, "cas", false, "only apply keys that didn't change since they were compared")
+		fs.BoolVar(&c.dryRun, "dry-run", false, "validate the plan with the state without applying it")
+	}
+	return fs
+}
+
+// options returns the options the flags stand for.
+func (c *command) options() []reconcile.Option {
+	opts := []reconcile.Option{reconcile.WithValueDiff(func(v interface{}) ([]byte, error) {
+		return json.MarshalIndent(v, "", "  ")
+	})}
+	if c.verbose {
+		opts = append(opts, reconcile.Verbose())
+	}
+	if c.cas {
+		opts = append(opts, reconcile.WithCompareAndSwap(true))
+	}
+	if c.dryRun {
+		opts = append(opts, reconcile.WithServerDryRun(true))
+	}
+	if len(c.filters) > 0 {
+		opts = append(opts, reconcile.WithKeys(c.match))
+	}
+	return opts
+}
+
+func run(ctx context.Context, args []string, newCurrent func() (reconcile.State, error), stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: plan|apply|detect [flags] desired.json")
+		return ExitUsage
+	}
+	c := &command{name: args[0]}
+	switch c.name {
+	case "plan", "apply", "detect":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q, want plan, apply or detect\n", c.name)
+		return ExitUsage
+	}
+	fs := c.flags(stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 || c.output != "text" && c.output != "json" {
+		fs.Usage()
+		return ExitUsage
+	}
+	if c.current != "" && c.name == "apply" {
+		fmt.Fprintln(stderr, "apply: -current can't be applied to")
+		return ExitUsage
+	}
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	desired, err := readDocument(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	var current reconcile.State
+	if c.current != "" {
+		current, err = readDocument(c.current)
+	} else {
+		current, err = newCurrent()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	compared := c.scope(current, desired)
+	opts := c.options()
+	plan, err := reconcile.Plan(current, compared, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	updates := plan.Updates()
+
+	switch c.name {
+	case "plan":
+		if err := writePlan(stdout, c.output, plan); err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailure
+		}
+		return ExitOK
+	case "detect":
+		if err := writePlan(stdout, c.output, plan); err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailure
+		}
+		if len(updates) > 0 {
+			return ExitDrift
+		}
+		return ExitOK
+	}
+
+	if deletes := count(updates, "Delete"); c.maxDeletes >= 0 && deletes > c.maxDeletes {
+		fmt.Fprintf(stderr, "apply: the plan deletes %d keys, more than -max-deletes %d\n", deletes, c.maxDeletes)
+		return ExitFailure
+	}
+	// the keys left once ctx is done are paused instead of applied
+	opts = append(opts, reconcile.WithPause(func(string) bool { return ctx.Err() != nil }))
+	var result reconcile.Result
+	if c.hash != "" {
+		expected, err := hex.DecodeString(c.hash)
+		if err != nil {
+			fmt.Fprintf(stderr, "apply: -hash: %v\n", err)
+			return ExitUsage
+		}
+		if result, err = plan.Apply(current, expected, opts...); err != nil {
+			fmt.Fprintf(stderr, "apply: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		result = plan.ApplyTo(current, opts...)
+	}
+	if err := writeResult(stdout, c.output, result); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(stderr, "apply: %v\n", err)
+		return ExitFailure
+	}
+	return result.ExitCode()
+}
+
+// scope returns the desired state the flags compare, without -prune one
+// that keeps the keys it doesn't have.
+func (c *command) scope(current reconcile.State, desired *reconcile.MapState) reconcile.State {
+	if !c.prune {
+		current.Walk(func(key string, v interface{}) {
+			if desired.Get(key) == nil {
+				desired.Add(key, v)
+			}
+		})
+	}
+	return desired
+}
+
+// match reports whether key matches one of the -filter globs.
+func (c *command) match(key string) bool {
+	for _, f := range c.filters {
+		if ok, _ := path.Match(f, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readDocument reads the JSON object in the file at name.
+func readDocument(name string) (*reconcile.MapState, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc map[string]interface{}
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%s: %w", name, errors.New("document isn't a JSON object"))
+	}
+	return reconcile.NewMapState(doc), nil
+}
+
+func count(updates []reconcile.Update, action string) int {
+	n := 0
+	for _, u := range updates {
+		if u.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func writePlan(w io.Writer, format string, plan *reconcile.ChangeSet) error {
+	sum, err := plan.Hash()
+	if err != nil {
+		return err
+	}
+	hash := hex.EncodeToString(sum)
+	updates := plan.Updates()
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Updates []reconcile.Update `json:"updates"`
+			Hash    string             `json:"hash"`
+		}{updates, hash})
+	}
+	if len(updates) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n%d to add, %d to update, %d to delete\nplan hash: %s\n",
+		plan, count(updates, "Add"), count(updates, "Update"), count(updates, "Delete"), hash)
+	return err
+}
+
+// result is a reconcile.Result with its errors as strings.
+type result struct {
+	Added     []string          `json:"added,omitempty"`
+	Updated   []string          `json:"updated,omitempty"`
+	Deleted   []string          `json:"deleted,omitempty"`
+	Diverged  []string          `json:"diverged,omitempty"`
+	Conflicts []string          `json:"conflicts,omitempty"`
+	Paused    []string          `json:"paused,omitempty"`
+	Rejected  map[string]string `json:"rejected,omitempty"`
+	Err       string            `json:"error,omitempty"`
+	DryRun    bool              `json:"dryRun,omitempty"`
+}
+
+func writeResult(w io.Writer, format string, r reconcile.Result) error {
+	out := result{
+		Added:     r.Added,
+		Updated:   r.Updated,
+		Deleted:   r.Deleted,
+		Diverged:  r.Diverged,
+		Conflicts: r.Conflicts,
+		Paused:    r.Paused,
+		DryRun:    r.DryRun,
+	}
+	if r.Err != nil {
+		out.Err = r.Err.Error()
+	}
+	for k, err := range r.Rejected {
+		if out.Rejected == nil {
+			out.Rejected = make(map[string]string)
+		}
+		out.Rejected[k] = err.Error()
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	var b strings.Builder
+	for _, l := range []struct {
+		name string
+		keys []string
+	}{
+		{"added", out.Added}, {"updated", out.Updated}, {"deleted", out.Deleted},
+		{"diverged", out.Diverged}, {"conflicts", out.Conflicts}, {"paused", out.Paused},
+	} {
+		if len(l.keys) > 0 {
+			fmt.Fprintf(&b, "%s: %s\n", l.name, strings.Join(l.keys, ", "))
+		}
+	}
+	rejected := make([]string, 0, len(out.Rejected))
+	for k := range out.Rejected {
+		rejected = append(rejected, k)
+	}
+	sort.Strings(rejected)
+	for _, k := range rejected {
+		fmt.Fprintf(&b, "rejected: %s: %s\n", k, out.Rejected[k])
+	}
+	if out.Err != "" {
+		fmt.Fprintf(&b, "error: %s\n", out.Err)
+	}
+	if b.Len() == 0 {
+		b.WriteString("no changes\n")
+	}
+	if out.DryRun {
+		b.WriteString("dry run, nothing was applied\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sevki.org/x/reconcile"
+)
+
+func writeDocument(t *testing.T, doc string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "doc.json")
+	if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	desired := `{"a": "1", "b": "2", "web/x": "1"}`
+	tests := []struct {
+		name    string
+		args    []string
+		code    int
+		want    map[string]interface{}
+		stdout  string
+		stderr  string
+		failGet bool
+	}{
+		{
+			name:   "plan",
+			args:   []string{"plan"},
+			code:   ExitOK,
+			want:   map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stdout: "Update b: ",
+		},
+		{
+			name:   "detect drift",
+			args:   []string{"detect"},
+			code:   ExitDrift,
+			want:   map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stdout: "1 to add, 1 to update, 0 to delete",
+		},
+		{
+			name: "apply keeps keys without prune",
+			args: []string{"apply"},
+			code: ExitOK,
+			want: map[string]interface{}{"a": "1", "b": "2", "old": "1", "web/x": "1"},
+		},
+		{
+			name: "apply prune",
+			args: []string{"apply", "-prune"},
+			code: ExitOK,
+			want: map[string]interface{}{"a": "1", "b": "2", "web/x": "1"},
+		},
+		{
+			name:   "apply max deletes",
+			args:   []string{"apply", "-prune", "-max-deletes", "0"},
+			code:   ExitFailure,
+			want:   map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stderr: "deletes 1 keys",
+		},
+		{
+			name: "apply filter",
+			args: []string{"apply", "-prune", "-filter", "web/*", "-filter", "o*"},
+			code: ExitOK,
+			want: map[string]interface{}{"a": "1", "b": "1", "web/x": "1"},
+		},
+		{
+			name:   "apply wrong hash",
+			args:   []string{"apply", "-hash", "00"},
+			code:   ExitFailure,
+			want:   map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stderr: "apply: ",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"destroy"},
+			code:   ExitUsage,
+			want:   map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stderr: "unknown command",
+		},
+		{
+			name: "bad output",
+			args: []string{"plan", "-o", "yaml"},
+			code: ExitUsage,
+			want: map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+		},
+		{
+			name:    "state error",
+			args:    []string{"plan"},
+			code:    ExitFailure,
+			want:    map[string]interface{}{"a": "1", "b": "1", "old": "1"},
+			stderr:  "unreachable",
+			failGet: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := reconcile.NewMapState(map[string]interface{}{"a": "1", "b": "1", "old": "1"})
+			newCurrent := func() (reconcile.State, error) {
+				if test.failGet {
+					return nil, errors.New("unreachable")
+				}
+				return current, nil
+			}
+			var stdout, stderr bytes.Buffer
+			args := append(test.args, writeDocument(t, desired))
+			code := run(context.Background(), args, newCurrent, &stdout, &stderr)
+			if code != test.code {
+				t.Logf("was expecting exit code %d got %d: %s%s", test.code, code, stdout.String(), stderr.String())
+				t.Fail()
+			}
+			got := make(map[string]interface{})
+			current.Walk(func(key string, v interface{}) { got[key] = v })
+			if !reflect.DeepEqual(got, test.want) {
+				t.Logf("was expecting the state\n%v\ngot\n%v", test.want, got)
+				t.Fail()
+			}
+			if !strings.Contains(stdout.String(), test.stdout) || !strings.Contains(stderr.String(), test.stderr) {
+				t.Logf("was expecting %q and %q in the output got\n%s\n%s", test.stdout, test.stderr, stdout.String(), stderr.String())
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestRunApplyHash(t *testing.T) {
+	current := reconcile.NewMapState(map[string]interface{}{"a": "1"})
+	newCurrent := func() (reconcile.State, error) { return current, nil }
+	desired := writeDocument(t, `{"a": "2"}`)
+
+	var stdout bytes.Buffer
+	if code := run(context.Background(), []string{"plan", "-o", "json", desired}, newCurrent, &stdout, ioutil.Discard); code != ExitOK {
+		t.Fatalf("plan exited with %d", code)
+	}
+	var plan struct {
+		Updates []reconcile.Update
+		Hash    string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Updates) != 1 || plan.Updates[0].Key != "a" {
+		t.Logf("was expecting an update of a got %+v", plan.Updates)
+		t.Fail()
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), []string{"apply", "-o", "json", "-hash", plan.Hash, desired}, newCurrent, &stdout, ioutil.Discard); code != ExitOK {
+		t.Fatalf("apply exited with %d", code)
+	}
+	var result struct{ Updated []string }
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"a"}) || current.Get("a") != "2" {
+		t.Logf("was expecting a to be updated got %s", stdout.String())
+		t.Fail()
+	}
+}
+
+func TestRunDiffFiles(t *testing.T) {
+	newCurrent := func() (reconcile.State, error) {
+		t.Log("-current shouldn't read the state")
+		t.Fail()
+		return nil, nil
+	}
+	current := writeDocument(t, `{"a": 1, "b": 2}`)
+	desired := writeDocument(t, `{"a": 1, "b": 2}`)
+	var stdout bytes.Buffer
+	if code := run(context.Background(), []string{"detect", "-current", current, desired}, newCurrent, &stdout, ioutil.Discard); code != ExitOK {
+		t.Logf("was expecting no drift got %d: %s", code, stdout.String())
+		t.Fail()
+	}
+	if code := run(context.Background(), []string{"apply", "-current", current, desired}, newCurrent, ioutil.Discard, ioutil.Discard); code != ExitUsage {
+		t.Logf("was expecting apply -current to be a usage error got %d", code)
+		t.Fail()
+	}
+}
+
+// cancelState is a MapState that cancels its context on the first
+// change and counts the CAS calls.
+type cancelState struct {
+	*reconcile.MapState
+	cancel func()
+	cas    int
+}
+
+func (s *cancelState) Add(key string, v interface{}) {
+	s.cancel()
+	s.MapState.Add(key, v)
+}
+
+func (s *cancelState) Update(key string, v interface{}) {
+	s.cancel()
+	s.MapState.Update(key, v)
+}
+
+func (s *cancelState) CAS(key string, _, v interface{}) error {
+	s.cas++
+	s.MapState.Update(key, v)
+	return nil
+}
+
+func TestRunApplyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	current := &cancelState{MapState: reconcile.NewMapState(map[string]interface{}{"a": "1"}), cancel: cancel}
+	newCurrent := func() (reconcile.State, error) { return current, nil }
+	var stderr bytes.Buffer
+	code := run(ctx, []string{"apply", writeDocument(t, `{"a": "2", "b": "1", "c": "1"}`)}, newCurrent, ioutil.Discard, &stderr)
+	changed := len(current.Keys()) - 1
+	if current.Get("a") == "2" {
+		changed++
+	}
+	if code != ExitFailure || changed != 1 || !strings.Contains(stderr.String(), "context canceled") {
+		t.Logf("was expecting apply to stop after the first key got %d with %d keys changed: %s", code, changed, stderr.String())
+		t.Fail()
+	}
+}
+
+func TestRunFilterKeepsState(t *testing.T) {
+	current := &cancelState{MapState: reconcile.NewMapState(map[string]interface{}{"web/a": "1", "b": "1"}), cancel: func() {}}
+	newCurrent := func() (reconcile.State, error) { return current, nil }
+	code := run(context.Background(), []string{"apply", "-cas", "-filter", "web/*", writeDocument(t, `{"web/a": "2", "b": "2"}`)}, newCurrent, ioutil.Discard, ioutil.Discard)
+	if code != ExitOK || current.cas != 1 || current.Get("web/a") != "2" || current.Get("b") != "1" {
+		t.Logf("was expecting web/a to be swapped by the state got %d with %d swaps", code, current.cas)
+		t.Fail()
+	}
+}
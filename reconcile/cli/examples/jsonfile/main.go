@@ -0,0 +1,64 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// jsonfile reconciles a JSON document with another one, the target,
+// which is rewritten when the plan is applied
+//
+//	jsonfile plan desired.json
+//	jsonfile apply -prune desired.json
+//
+// The target is $JSONFILE_TARGET, state.json if it isn't set.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"sevki.org/x/reconcile"
+	"sevki.org/x/reconcile/cli"
+)
+
+// target is a MapState that writes itself to its file when it is
+// flushed.
+type target struct {
+	*reconcile.MapState
+	path string
+}
+
+func (t target) Flush() error {
+	m := make(map[string]interface{})
+	t.Walk(func(key string, v interface{}) { m[key] = v })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, append(data, '\n'), 0644)
+}
+
+func open(path string) (reconcile.State, error) {
+	m := make(map[string]interface{})
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+	}
+	return target{reconcile.NewMapState(m), path}, nil
+}
+
+func main() {
+	path := os.Getenv("JSONFILE_TARGET")
+	if path == "" {
+		path = "state.json"
+	}
+	os.Exit(cli.Run(context.Background(), os.Args[1:], func() (reconcile.State, error) {
+		return open(path)
+	}))
+}
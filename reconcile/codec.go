@@ -0,0 +1,154 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec turns the values of a State into bytes and back. Unmarshal
+// gets the name the type of the value was registered with, see
+// RegisterType and TypeName.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, typeHint string) (interface{}, error)
+}
+
+// ErrUnregisteredType is returned by codecs for values whose type
+// wasn't registered with RegisterType.
+var ErrUnregisteredType = errors.New("unregistered type")
+
+var (
+	typesMu sync.RWMutex
+	// factories maps the registered names to their factories.
+	factories = make(map[string]func() interface{})
+	// typeNames maps the registered types to their names.
+	typeNames = make(map[reflect.Type]string)
+)
+
+func init() {
+	RegisterType("string", func() interface{} { return "" })
+	RegisterType("bool", func() interface{} { return false })
+	RegisterType("int", func() interface{} { return 0 })
+	RegisterType("int64", func() interface{} { return int64(0) })
+	RegisterType("float64", func() interface{} { return float64(0) })
+	RegisterType("bytes", func() interface{} { return []byte(nil) })
+	RegisterType("map", func() interface{} { return map[string]interface{}(nil) })
+}
+
+// RegisterType registers the type of the values factory returns under
+// name. Values of a pointer type are decoded into what factory returns,
+// others into a copy of it. The builtin types are registered as string,
+// bool, int, int64, float64, bytes and map, for map[string]interface{}.
+// Registering a name or a type twice panics.
+func RegisterType(name string, factory func() interface{}) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	if factory == nil {
+		panic("reconcile: RegisterType factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("reconcile: RegisterType called twice for " + name)
+	}
+	t := reflect.TypeOf(factory())
+	if t == nil {
+		panic("reconcile: RegisterType factory for " + name + " returns nil")
+	}
+	if other, dup := typeNames[t]; dup {
+		panic(fmt.Sprintf("reconcile: %s is already registered as %s", t, other))
+	}
+	factories[name] = factory
+	typeNames[t] = name
+}
+
+// TypeName returns the name v's type was registered with, nil values
+// have an empty name.
+func TypeName(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	typesMu.RLock()
+	name, ok := typeNames[reflect.TypeOf(v)]
+	typesMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%T: %w", v, ErrUnregisteredType)
+	}
+	return name, nil
+}
+
+// decode calls f with a pointer to a new value of the type registered
+// as name and returns the value.
+func decode(name string, f func(ptr interface{}) error) (interface{}, error) {
+	typesMu.RLock()
+	factory, ok := factories[name]
+	typesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", name, ErrUnregisteredType)
+	}
+	v := factory()
+	if reflect.TypeOf(v).Kind() == reflect.Ptr {
+		return v, f(v)
+	}
+	ptr := reflect.New(reflect.TypeOf(v))
+	ptr.Elem().Set(reflect.ValueOf(v))
+	if err := f(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// JSON is a Codec that encodes values as JSON, nil values are null.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	if _, err := TypeName(v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, typeHint string) (interface{}, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	return decode(typeHint, func(ptr interface{}) error { return json.Unmarshal(data, ptr) })
+}
+
+// Gob is a Codec that encodes values with encoding/gob, nil values are
+// empty.
+var Gob Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if _, err := TypeName(v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, typeHint string) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return decode(typeHint, func(ptr interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(ptr)
+	})
+}
@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type codecValue struct {
+	Name  string
+	Ports []int
+}
+
+type codecPtr struct {
+	Owner string
+}
+
+type unregistered struct{ A int }
+
+func init() {
+	RegisterType("codecValue", func() interface{} { return codecValue{} })
+	RegisterType("codecPtr", func() interface{} { return &codecPtr{} })
+}
+
+func TestCodecs(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{
+		{"json", JSON},
+		{"gob", Gob},
+	}
+	values := []interface{}{
+		nil,
+		"hello",
+		42,
+		true,
+		[]byte("raw"),
+		codecValue{Name: "web", Ports: []int{80, 443}},
+		&codecPtr{Owner: "ops"},
+	}
+	for _, c := range codecs {
+		for _, v := range values {
+			hint, err := TypeName(v)
+			if err != nil {
+				t.Logf("%s: TypeName(%#v): %v", c.name, v, err)
+				t.Fail()
+				continue
+			}
+			data, err := c.codec.Marshal(v)
+			if err != nil {
+				t.Logf("%s: Marshal(%#v): %v", c.name, v, err)
+				t.Fail()
+				continue
+			}
+			got, err := c.codec.Unmarshal(data, hint)
+			if err != nil {
+				t.Logf("%s: Unmarshal(%q, %q): %v", c.name, data, hint, err)
+				t.Fail()
+				continue
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Logf("%s: got %#v, expected %#v", c.name, got, v)
+				t.Fail()
+			}
+		}
+		if _, err := c.codec.Marshal(unregistered{1}); !errors.Is(err, ErrUnregisteredType) {
+			t.Logf("%s: marshaling an unregistered type returned %v", c.name, err)
+			t.Fail()
+		}
+		data, _ := c.codec.Marshal("x")
+		if _, err := c.codec.Unmarshal(data, "nope"); !errors.Is(err, ErrUnregisteredType) {
+			t.Logf("%s: unmarshaling an unregistered type returned %v", c.name, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestRegisterTypeTwice(t *testing.T) {
+	for _, name := range []string{"codecValue", "other"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Logf("registering %s again didn't panic", name)
+					t.Fail()
+				}
+			}()
+			RegisterType(name, func() interface{} { return codecValue{} })
+		}()
+	}
+}
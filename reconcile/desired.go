@@ -0,0 +1,35 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+// CompareDesired returns the updates that switching the desired state
+// from a to b would make to a current state that is converged to a,
+// the keys of a that b doesn't have being deleted. It is the plan of
+// Plan(a, b) without a being treated as a live state: a is only walked
+// and read, and the options about the current state, WithDeleteGrace,
+// WithTouch and WithCurrentTransform, are ignored. Nil states are
+// empty. Format the updates with FormatUpdates like a ChangeSet.
+//
+// With WithRecoverPanics the keys whose comparison panicked are
+// Panicked updates, and a panic walking a or b the only update.
+func CompareDesired(a, b State, opts ...Option) []Update {
+	if isNil(a) {
+		a = &MapState{}
+	}
+	if isNil(b) {
+		b = &MapState{}
+	}
+	o := newOptions(opts)
+	o.deleteGrace, o.touch, o.currentTransform = 0, nil, nil
+	updates, err := planRecovered(a, b, o)
+	if p, ok := err.(*PanicError); ok {
+		updates = []update{{key: p.Key, state: panicked, why: p.Error(), panic: p}}
+	}
+	exported := make([]Update, len(updates))
+	for i, u := range updates {
+		exported[i] = u.export()
+	}
+	return exported
+}
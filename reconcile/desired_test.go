@@ -0,0 +1,77 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// readOnlyState is a MapState that fails the test it is given to if it
+// is changed.
+type readOnlyState struct {
+	*MapState
+	t *testing.T
+}
+
+func (s readOnlyState) Add(key string, _ interface{}) {
+	s.t.Logf("%s was added to a read only state", key)
+	s.t.Fail()
+}
+
+func (s readOnlyState) Update(key string, _ interface{}) {
+	s.t.Logf("%s was updated in a read only state", key)
+	s.t.Fail()
+}
+
+func (s readOnlyState) Delete(key string) {
+	s.t.Logf("%s was deleted from a read only state", key)
+	s.t.Fail()
+}
+
+func TestCompareDesired(t *testing.T) {
+	a := readOnlyState{NewMapState(map[string]interface{}{"x": "1", "y": "2", "z": "3"}), t}
+	b := readOnlyState{NewMapState(map[string]interface{}{"x": "1", "y": "20", "w": "4"}), t}
+	touch := WithTouch(func(string, interface{}) bool { return true })
+	updates := CompareDesired(a, b, WithDeleteGrace(time.Hour), touch, WithValueDiff(json.Marshal))
+	want := map[string]string{"y": "Update", "w": "Add", "z": "Delete"}
+	if len(updates) != len(want) {
+		t.Logf("was expecting %d updates got %+v", len(want), updates)
+		t.Fail()
+	}
+	for _, u := range updates {
+		if want[u.Key] != u.Action {
+			t.Logf("was expecting %s to %s got %s", u.Key, want[u.Key], u.Action)
+			t.Fail()
+		}
+		if u.Key == "y" && u.TextDiff == "" {
+			t.Logf("was expecting y to have a text diff")
+			t.Fail()
+		}
+	}
+	if got, plan := FormatUpdates(CompareDesired(a, b)), NewChangeSet(a, b).String(); got != plan {
+		t.Logf("was expecting the plan\n%s\ngot\n%s", plan, got)
+		t.Fail()
+	}
+	if updates := CompareDesired(nil, b); len(updates) != 3 {
+		t.Logf("was expecting b to be added to a nil state got %+v", updates)
+		t.Fail()
+	}
+}
+
+func TestCompareDesiredPanics(t *testing.T) {
+	desired := NewMapState(map[string]interface{}{"get": "2", "added": "1"})
+	tests := []struct {
+		panics map[string]string
+		want   string
+	}{
+		{map[string]string{"Get": "get"}, "Add added: current state doesn't have added doesn't exist\nPanicked get: get: panic: Get get\n"},
+		{map[string]string{"Walk": "*"}, "Panicked : panic: Walk *\n"},
+	}
+	for _, test := range tests {
+		a := &panicState{MapState: NewMapState(map[string]interface{}{"get": "1"}), panics: test.panics}
+		if got := FormatUpdates(CompareDesired(a, desired, WithRecoverPanics(true))); got != test.want {
+			t.Logf("was expecting the updates\n%s\ngot\n%s", test.want, got)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package reconcile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// admissionState is a MapState whose backend refuses values that
+// aren't strings.
+type admissionState struct {
+	MapState
+	flushes int
+}
+
+var errNotString = errors.New("value isn't a string")
+
+func (s *admissionState) admit(v interface{}) error {
+	if _, ok := v.(string); !ok {
+		return errNotString
+	}
+	return nil
+}
+
+func (s *admissionState) AddDryRun(key string, v interface{}) error    { return s.admit(v) }
+func (s *admissionState) UpdateDryRun(key string, v interface{}) error { return s.admit(v) }
+func (s *admissionState) DeleteDryRun(key string) error                { return nil }
+func (s *admissionState) Flush() error                                 { s.flushes++; return nil }
+
+func TestServerDryRun(t *testing.T) {
+	current := &admissionState{}
+	current.Add("a", "1")
+	current.Add("gone", "1")
+	desired := NewMapState(map[string]interface{}{
+		"a": 2,
+		"b": "2",
+	})
+	hooked := 0
+	r := Reconcile(current, desired, false, WithServerDryRun(true), WithApplyHook(func(Update) { hooked++ }))
+	want := Result{
+		Added:    []string{"b"},
+		Deleted:  []string{"gone"},
+		Rejected: map[string]error{"a": errNotString},
+		DryRun:   true,
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Logf("got %+v, expected %+v", r, want)
+		t.Fail()
+	}
+	if got := current.Keys(); !reflect.DeepEqual(got, []string{"a", "gone"}) || current.Get("a") != "1" {
+		t.Logf("dry run changed the state to %v", got)
+		t.Fail()
+	}
+	if hooked != 0 || current.flushes != 0 {
+		t.Logf("dry run called the hook %d times and flushed %d times", hooked, current.flushes)
+		t.Fail()
+	}
+
+	plain := NewMapState(map[string]interface{}{"a": "1"})
+	r = Reconcile(plain, desired, false, WithServerDryRun(true))
+	if len(r.Updated) != 1 || len(r.Added) != 1 || plain.Get("a") != "1" {
+		t.Logf("got %+v and %v for a state without dry run support", r, plain.Keys())
+		t.Fail()
+	}
+}
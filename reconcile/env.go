@@ -0,0 +1,201 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// StringMapState returns a State of the strings in m, it walks its keys
+// in sorted order. Values added to it that aren't strings are formatted
+// with fmt.Sprint.
+func StringMapState(m map[string]string) State {
+	s := make(stringMapState, len(m))
+	for k, v := range m {
+		s[k] = v
+	}
+	return s
+}
+
+type stringMapState map[string]string
+
+func (s stringMapState) Add(key string, v interface{}) {
+	if str, ok := v.(string); ok {
+		s[key] = str
+		return
+	}
+	s[key] = fmt.Sprint(v)
+}
+
+func (s stringMapState) Update(key string, v interface{}) { s.Add(key, v) }
+
+func (s stringMapState) Get(key string) interface{} {
+	if v, ok := s[key]; ok {
+		return v
+	}
+	return nil
+}
+
+func (s stringMapState) Delete(key string) { delete(s, key) }
+
+func (s stringMapState) Walk(f StateWalkFunc) {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f(k, s[k])
+	}
+}
+
+type envOptions struct {
+	stripPrefix bool
+	upper       bool
+	lower       bool
+}
+
+// EnvOption configures how FromEnv and FromDotEnv name their keys.
+type EnvOption func(*envOptions)
+
+// StripPrefix removes the prefix from the keys of FromEnv.
+func StripPrefix() EnvOption {
+	return func(o *envOptions) { o.stripPrefix = true }
+}
+
+// UpperKeys upper cases keys.
+func UpperKeys() EnvOption {
+	return func(o *envOptions) { o.upper, o.lower = true, false }
+}
+
+// LowerKeys lower cases keys.
+func LowerKeys() EnvOption {
+	return func(o *envOptions) { o.upper, o.lower = false, true }
+}
+
+func (o envOptions) key(k string) string {
+	switch {
+	case o.upper:
+		return strings.ToUpper(k)
+	case o.lower:
+		return strings.ToLower(k)
+	}
+	return k
+}
+
+// canonicalValue trims the trailing whitespace loaders don't keep.
+func canonicalValue(v string) string { return strings.TrimRight(v, " \t\r") }
+
+// FromEnv returns a StringMapState of the environment variables that
+// start with prefix.
+func FromEnv(prefix string, opts ...EnvOption) State {
+	var o envOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := make(stringMapState)
+	for _, kv := range os.Environ() {
+		k, v, ok := cut(kv, "=")
+		if !ok || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if o.stripPrefix {
+			k = strings.TrimPrefix(k, prefix)
+		}
+		s[o.key(k)] = canonicalValue(v)
+	}
+	return s
+}
+
+// FromDotEnv returns a StringMapState of the variables of a .env file.
+// It reads KEY=value lines, optionally starting with export, and skips
+// blank lines and comments. Values are unquoted, single quoted values
+// are taken literally and double quoted ones have their \n, \t, \" and
+// \\ escapes replaced; unquoted values end at a " #" comment. Trailing
+// whitespace is dropped so quoting doesn't change a value.
+func FromDotEnv(r io.Reader, opts ...EnvOption) (State, error) {
+	var o envOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := make(stringMapState)
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing =", n)
+		}
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, fmt.Errorf("line %d: missing key", n)
+		}
+		v, err := dotEnvValue(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		s[o.key(k)] = canonicalValue(v)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func dotEnvValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	switch q := v[0]; q {
+	case '\'', '"':
+		end := closingQuote(v, q)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %c quote", q)
+		}
+		if rest := strings.TrimSpace(v[end+1:]); rest != "" && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		if q == '\'' {
+			return v[1:end], nil
+		}
+		return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(v[1:end]), nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return v, nil
+}
+
+// closingQuote returns the index of the quote that closes the one v
+// starts with, skipping escaped ones in double quotes.
+func closingQuote(v string, q byte) int {
+	for i := 1; i < len(v); i++ {
+		switch {
+		case q == '"' && v[i] == '\\':
+			i++
+		case v[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
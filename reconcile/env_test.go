@@ -0,0 +1,110 @@
+package reconcile
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringMapStateContract(t *testing.T) {
+	if err := CheckState(StringMapState(nil)); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestFromDotEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts []EnvOption
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "values",
+			in: `# settings
+export HOST=example.com
+PORT = 8080 # the port
+
+EMPTY=
+SINGLE='a "raw" \n value'
+DOUBLE="two\nlines \"quoted\""
+SPACES=trailing   
+QUOTED="trailing   "
+`,
+			want: map[string]string{
+				"HOST":   "example.com",
+				"PORT":   "8080",
+				"EMPTY":  "",
+				"SINGLE": `a "raw" \n value`,
+				"DOUBLE": "two\nlines \"quoted\"",
+				"SPACES": "trailing",
+				"QUOTED": "trailing",
+			},
+		},
+		{
+			name: "lower",
+			in:   "Api_Key=x\n",
+			opts: []EnvOption{LowerKeys()},
+			want: map[string]string{"api_key": "x"},
+		},
+		{
+			name: "missing equals",
+			in:   "A=1\nB\n",
+			err:  "line 2: missing =",
+		},
+		{
+			name: "unterminated",
+			in:   `A="open`,
+			err:  "line 1: unterminated \" quote",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := FromDotEnv(strings.NewReader(test.in), test.opts...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Logf("got error %v, expected %q", err, test.err)
+					t.Fail()
+				}
+				return
+			}
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if got := map[string]string(s.(stringMapState)); !reflect.DeepEqual(got, test.want) {
+				t.Logf("got %q, expected %q", got, test.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for k, v := range map[string]string{
+		"RECONCILE_TEST_HOST": "example.com ",
+		"RECONCILE_TEST_PORT": "8080",
+		"OTHER_RECONCILE":     "x",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	env := FromEnv("RECONCILE_TEST_", StripPrefix(), LowerKeys())
+	want := map[string]string{"host": "example.com", "port": "8080"}
+	if got := map[string]string(env.(stringMapState)); !reflect.DeepEqual(got, want) {
+		t.Logf("got %q, expected %q", got, want)
+		t.Fail()
+	}
+	dotenv, err := FromDotEnv(strings.NewReader("HOST=\"example.com\"\nPORT='8080'\n"), LowerKeys())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if cs := NewChangeSet(env, dotenv); cs.Len() != 0 {
+		t.Logf("got drift between the same values:\n%s", cs)
+		t.Fail()
+	}
+}
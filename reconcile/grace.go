@@ -0,0 +1,89 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DeleteCandidates remembers when keys were first planned for deletion,
+// see WithDeleteGrace.
+type DeleteCandidates interface {
+	// MarkDeleteCandidate returns when key became a candidate, making
+	// it one as of now if it isn't.
+	MarkDeleteCandidate(key string, now time.Time) time.Time
+	// ClearDeleteCandidates forgets the candidates that aren't in keep,
+	// the keys that are back in the desired state or were deleted.
+	ClearDeleteCandidates(keep map[string]bool)
+}
+
+// NewDeleteCandidates returns an in memory DeleteCandidates that is
+// safe for concurrent use.
+func NewDeleteCandidates() DeleteCandidates {
+	return &memoryCandidates{since: make(map[string]time.Time)}
+}
+
+type memoryCandidates struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+}
+
+func (c *memoryCandidates) MarkDeleteCandidate(key string, now time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.since[key]; ok {
+		return t
+	}
+	c.since[key] = now
+	return now
+}
+
+func (c *memoryCandidates) ClearDeleteCandidates(keep map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.since {
+		if !keep[k] {
+			delete(c.since, k)
+		}
+	}
+}
+
+func candidatesFor(current State, o options) DeleteCandidates {
+	if o.candidates != nil {
+		return o.candidates
+	}
+	if c, ok := current.(DeleteCandidates); ok {
+		return c
+	}
+	return nil
+}
+
+// graceDeletes turns the deletions of keys that haven't been candidates
+// for the grace period into pending ones.
+func graceDeletes(updates []update, c DeleteCandidates, o options) []update {
+	now := o.now()
+	keep := make(map[string]bool)
+	for i, u := range updates {
+		if u.state != old {
+			continue
+		}
+		keep[u.key] = true
+		since := now
+		if c != nil {
+			since = c.MarkDeleteCandidate(u.key, now)
+		}
+		if remaining := o.deleteGrace - now.Sub(since); remaining > 0 {
+			updates[i].state = pending
+			updates[i].why = fmt.Sprintf("pending delete (%ds remaining)", int(math.Ceil(remaining.Seconds())))
+		}
+	}
+	if c != nil {
+		c.ClearDeleteCandidates(keep)
+	}
+	return updates
+}
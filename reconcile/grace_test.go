@@ -0,0 +1,104 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func at(t time.Time) Option {
+	return func(o *options) { o.now = func() time.Time { return t } }
+}
+
+func TestDeleteGrace(t *testing.T) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := NewMapState(map[string]interface{}{
+		"gone":  "1",
+		"flaky": "1",
+		"kept":  "1",
+	})
+	candidates := NewDeleteCandidates()
+	passes := []struct {
+		at      time.Duration
+		desired map[string]interface{}
+		reasons map[string]string
+		deleted []string
+	}{
+		{
+			at:      0,
+			desired: map[string]interface{}{"kept": "1"},
+			reasons: map[string]string{
+				"flaky": "pending delete (60s remaining)",
+				"gone":  "pending delete (60s remaining)",
+			},
+		},
+		{
+			at:      30 * time.Second,
+			desired: map[string]interface{}{"kept": "1", "flaky": "1"},
+			reasons: map[string]string{"gone": "pending delete (30s remaining)"},
+		},
+		{
+			at:      40 * time.Second,
+			desired: map[string]interface{}{"kept": "1"},
+			reasons: map[string]string{
+				"flaky": "pending delete (60s remaining)",
+				"gone":  "pending delete (20s remaining)",
+			},
+		},
+		{
+			at:      60 * time.Second,
+			desired: map[string]interface{}{"kept": "1"},
+			reasons: map[string]string{"flaky": "pending delete (40s remaining)"},
+			deleted: []string{"gone"},
+		},
+	}
+	for _, pass := range passes {
+		opts := []Option{WithDeleteGrace(time.Minute), WithDeleteCandidates(candidates), at(t0.Add(pass.at))}
+		desired := NewMapState(pass.desired)
+		reasons := make(map[string]string)
+		for _, u := range NewChangeSet(current, desired, opts...).Updates() {
+			if u.Action != "PendingDelete" {
+				continue
+			}
+			reasons[u.Key] = u.Reason
+		}
+		if !reflect.DeepEqual(reasons, pass.reasons) {
+			t.Logf("%s: got %v, expected %v", pass.at, reasons, pass.reasons)
+			t.Fail()
+		}
+		r := Reconcile(current, desired, false, opts...)
+		if !reflect.DeepEqual(r.Deleted, pass.deleted) {
+			t.Logf("%s: deleted %v, expected %v", pass.at, r.Deleted, pass.deleted)
+			t.Fail()
+		}
+	}
+	if got, want := current.Keys(), []string{"flaky", "kept"}; !reflect.DeepEqual(got, want) {
+		t.Logf("got keys %v, expected %v", got, want)
+		t.Fail()
+	}
+}
+
+func TestDeleteGraceWithoutStore(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"gone": "1"})
+	r := Reconcile(current, &MapState{}, false, WithDeleteGrace(time.Nanosecond))
+	if len(r.Deleted) != 0 || !reflect.DeepEqual(r.Pending, []string{"gone"}) {
+		t.Logf("got %+v, expected the delete to be pending", r)
+		t.Fail()
+	}
+}
+
+func TestRunnerDeleteGrace(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"gone": "1"})
+	r := &Runner{
+		Current: current,
+		Desired: &MapState{},
+		Options: []Option{WithDeleteGrace(time.Nanosecond)},
+	}
+	r.pass()
+	time.Sleep(time.Millisecond)
+	r.pass()
+	if current.Len() != 0 {
+		t.Logf("got keys %v, expected the runner to delete after the grace", current.Keys())
+		t.Fail()
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrNilState is returned when the current or the desired state is
+	// nil.
+	ErrNilState = errors.New("nil state")
+	// ErrSameState is returned when the current and the desired state
+	// are the same state, see Identifier.
+	ErrSameState = errors.New("current and desired are the same state")
+)
+
+// Identifier is implemented by states that can tell whether two of them
+// are the same, like wrappers of the same backend. States with the same
+// Identity are the same state.
+type Identifier interface {
+	Identity() string
+}
+
+// Plan returns the ChangeSet that turns current into desired, or an
+// error if they can't be reconciled, like ErrNilState and ErrSameState,
+// or the PanicError of WithRecoverPanics if planning panicked.
+func Plan(current, desired State, opts ...Option) (*ChangeSet, error) {
+	if err := checkStates(current, desired); err != nil {
+		return nil, err
+	}
+	c := NewChangeSet(current, desired, opts...)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c, nil
+}
+
+// checkStates returns an error for states that can't be reconciled.
+func checkStates(current, desired State) error {
+	if isNil(current) {
+		return fmt.Errorf("current state: %w", ErrNilState)
+	}
+	if isNil(desired) {
+		return fmt.Errorf("desired state: %w", ErrNilState)
+	}
+	if sameState(current, desired) {
+		return fmt.Errorf("%T: %w", current, ErrSameState)
+	}
+	return nil
+}
+
+func isNil(s State) bool {
+	if s == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(s); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// sameState reports whether a and b have the same Identity or point to
+// the same value. Other values aren't compared, they can hold ones that
+// can't be, like a struct wrapping a map.
+func sameState(a, b State) bool {
+	ia, aok := a.(Identifier)
+	ib, bok := b.(Identifier)
+	if aok && bok {
+		return ia.Identity() == ib.Identity()
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+	return false
+}
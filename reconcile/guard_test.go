@@ -0,0 +1,74 @@
+package reconcile
+
+import (
+	"errors"
+	"testing"
+)
+
+// wrappedState is a State that forwards to another one, two wrappers of
+// the same state are different values.
+type wrappedState struct {
+	State
+	name string
+}
+
+func (s *wrappedState) Identity() string { return s.name }
+
+// valueWrapper is a comparable struct holding a State that may not be.
+type valueWrapper struct{ State }
+
+func TestCheckStates(t *testing.T) {
+	shared := &MapState{}
+	var nilMap *MapState
+	env := StringMapState(map[string]string{"a": "1"})
+	tests := []struct {
+		name             string
+		current, desired State
+		err              error
+	}{
+		{name: "different", current: &MapState{}, desired: &MapState{}},
+		{name: "nil current", desired: shared, err: ErrNilState},
+		{name: "nil desired", current: shared, err: ErrNilState},
+		{name: "typed nil", current: nilMap, desired: shared, err: ErrNilState},
+		{name: "same pointer", current: shared, desired: shared, err: ErrSameState},
+		{name: "same map", current: env, desired: env, err: ErrSameState},
+		{name: "copied map", current: env, desired: StringMapState(map[string]string{"a": "1"})},
+		{name: "wrapped maps", current: valueWrapper{env}, desired: valueWrapper{StringMapState(map[string]string{"b": "2"})}},
+		{
+			name:    "same identity",
+			current: &wrappedState{State: shared, name: "db"},
+			desired: &wrappedState{State: shared, name: "db"},
+			err:     ErrSameState,
+		},
+		{
+			name:    "different identity",
+			current: &wrappedState{State: shared, name: "db"},
+			desired: &wrappedState{State: &MapState{}, name: "cache"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Plan(test.current, test.desired)
+			if !errors.Is(err, test.err) || (err == nil) != (test.err == nil) {
+				t.Logf("Plan: got %v, expected %v", err, test.err)
+				t.Fail()
+			}
+			if test.err == nil {
+				return
+			}
+			if r := Reconcile(test.current, test.desired, false); !errors.Is(r.Err, test.err) {
+				t.Logf("Reconcile: got %v, expected %v", r.Err, test.err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestReconcileSameStateIsUntouched(t *testing.T) {
+	s := NewMapState(map[string]interface{}{"a": "1"})
+	r := Reconcile(s, s, false)
+	if len(r.Added)+len(r.Updated)+len(r.Deleted) != 0 || s.Get("a") != "1" {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrPlanMismatch is returned by Apply when the ChangeSet isn't the one
+// that was approved.
+var ErrPlanMismatch = errors.New("plan doesn't match the approved one")
+
+// planHashVersion is the first line of the text Hash hashes, it changes
+// with the format of the text.
+const planHashVersion = "reconcile plan v1\n"
+
+// Hash returns a SHA-256 of c that is the same for the same updates in
+// any process. It covers the action, key and values of every update, in
+// key order, with the values encoded as JSON canonicalized to sorted
+// keys and shortest number formatting along with the name of their type
+// in the RegisterType registry. Reasons and text diffs aren't hashed.
+// Plans with values that can't be encoded as JSON can't be hashed.
+func (c *ChangeSet) Hash() ([]byte, error) {
+	updates := append([]update(nil), c.updates...)
+	sort.SliceStable(updates, func(i, j int) bool { return updates[i].key < updates[j].key })
+	h := sha256.New()
+	h.Write([]byte(planHashVersion))
+	for _, u := range updates {
+		fmt.Fprintf(h, "%s %s\n", u.state, quote(u.key))
+		if u.state != new {
+			was, err := canonicalJSON(u.was)
+			if err != nil {
+				return nil, fmt.Errorf("hashing the current value of %s: %w", u.key, err)
+			}
+			fmt.Fprintf(h, "was %s\n", was)
+		}
+		if u.state != old && u.state != pending {
+			now, err := canonicalJSON(u.v)
+			if err != nil {
+				return nil, fmt.Errorf("hashing the desired value of %s: %w", u.key, err)
+			}
+			fmt.Fprintf(h, "now %s\n", now)
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// Apply applies c to s like ApplyTo if c's Hash is expected and
+// returns ErrPlanMismatch, or the error of Hash, without applying
+// anything otherwise.
+func (c *ChangeSet) Apply(s State, expected []byte, opts ...Option) (Result, error) {
+	hash, err := c.Hash()
+	if err != nil {
+		return Result{}, err
+	}
+	if !bytes.Equal(hash, expected) {
+		return Result{}, ErrPlanMismatch
+	}
+	return c.ApplyTo(s, opts...), nil
+}
+
+// canonicalJSON returns the type and the canonical JSON of v.
+func canonicalJSON(v interface{}) (string, error) {
+	if v == nil {
+		return "null", nil
+	}
+	name, err := TypeName(v)
+	if err != nil {
+		name = fmt.Sprintf("%T", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	writeCanonical(&b, generic)
+	return quote(name) + " " + b.String(), nil
+}
+
+// writeCanonical writes v, decoded from JSON with numbers, as JSON with
+// sorted object keys and canonical numbers.
+func writeCanonical(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(quote(k))
+			b.WriteByte(':')
+			writeCanonical(b, v[k])
+		}
+		b.WriteByte('}')
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonical(b, e)
+		}
+		b.WriteByte(']')
+	case json.Number:
+		b.WriteString(canonicalNumber(string(v)))
+	case string:
+		b.WriteString(quote(v))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	default:
+		b.WriteString("null")
+	}
+}
+
+// canonicalNumber keeps integers as they are and formats other numbers
+// in the shortest exponent form that parses back to the same float64.
+func canonicalNumber(n string) string {
+	if !strings.ContainsAny(n, ".eE") {
+		return n
+	}
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return n
+	}
+	if f == float64(int64(f)) && f < 1e15 && f > -1e15 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'e', -1, 64)
+}
+
+// quote quotes s the way encoding/json does, which unlike strconv.Quote
+// doesn't depend on the Unicode version of Go.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
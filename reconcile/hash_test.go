@@ -0,0 +1,122 @@
+package reconcile
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// hexHash returns the hash of c in hex, failing the test if it can't be
+// hashed.
+func hexHash(t *testing.T, c *ChangeSet) string {
+	t.Helper()
+	hash, err := c.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(hash)
+}
+
+func hashFixture(desired map[string]interface{}) *ChangeSet {
+	current := NewMapState(map[string]interface{}{
+		"svc/web":  map[string]interface{}{"image": "web:1", "replicas": 2, "cpu": 0.5},
+		"svc/old":  "legacy",
+		"svc/same": "same",
+	})
+	return NewChangeSet(current, NewMapState(desired))
+}
+
+func TestPlanHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]interface{}
+		hash    string
+	}{
+		{
+			name: "empty",
+			desired: map[string]interface{}{
+				"svc/web":  map[string]interface{}{"image": "web:1", "replicas": 2, "cpu": 0.5},
+				"svc/old":  "legacy",
+				"svc/same": "same",
+			},
+			hash: "1d61a87dc249f113622571424145360cfd93951fc6a239d9c48a1c970de7e505",
+		},
+		{
+			name: "fixture",
+			desired: map[string]interface{}{
+				"svc/web":  map[string]interface{}{"replicas": 3, "image": "web:2", "cpu": 0.25, "ratio": 1e21},
+				"svc/new":  []interface{}{"a", 1.5, true, nil},
+				"svc/same": "same",
+				"svc/ünï":  "<&>",
+			},
+			hash: "2e69b4110277ca902ea6bdd9d94576601765fc36b6e3baed3c25405fc65915b3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hexHash(t, hashFixture(test.desired))
+			if got != test.hash {
+				t.Logf("got %s, expected %s", got, test.hash)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestPlanHashCanonical(t *testing.T) {
+	a := hashFixture(map[string]interface{}{
+		"svc/web": map[string]interface{}{"image": "web:2", "cpu": 0.25},
+	})
+	b := hashFixture(map[string]interface{}{
+		"svc/web": map[string]interface{}{"cpu": 2.5e-1, "image": "web:2"},
+	})
+	c := hashFixture(map[string]interface{}{
+		"svc/web": map[string]interface{}{"image": "web:3", "cpu": 0.25},
+	})
+	if hexHash(t, a) != hexHash(t, b) {
+		t.Log("the same plan has different hashes")
+		t.Fail()
+	}
+	if hexHash(t, a) == hexHash(t, c) {
+		t.Log("different plans have the same hash")
+		t.Fail()
+	}
+}
+
+func TestApplyApproved(t *testing.T) {
+	desired := NewMapState(map[string]interface{}{"a": "2"})
+	current := NewMapState(map[string]interface{}{"a": "1"})
+	approved, err := NewChangeSet(current, desired).Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current.Add("a", "changed")
+	if _, err := NewChangeSet(current, desired).Apply(current, approved); err != ErrPlanMismatch {
+		t.Logf("got %v, expected ErrPlanMismatch", err)
+		t.Fail()
+	}
+	if current.Get("a") != "changed" {
+		t.Log("a plan that wasn't approved was applied")
+		t.Fail()
+	}
+
+	current.Add("a", "1")
+	r, err := NewChangeSet(current, desired).Apply(current, approved)
+	if err != nil || len(r.Updated) != 1 || current.Get("a") != "2" {
+		t.Logf("got %+v, %v applying the approved plan", r, err)
+		t.Fail()
+	}
+}
+
+func TestPlanHashUnencodable(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"a": "1"})
+	plan := NewChangeSet(current, NewMapState(map[string]interface{}{"a": func() {}}))
+	if _, err := plan.Hash(); err == nil {
+		t.Log("was expecting a func value not to be hashed")
+		t.Fail()
+	}
+	if _, err := plan.Apply(current, nil); err == nil || current.Get("a") != "1" {
+		t.Logf("got %v, expected the plan not to be applied", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package httpstate is a reconcile.State over a REST API that keeps
+// its resources under a collection,
+//
+//	GET    {base}          the keys of the resources as a JSON array
+//	GET    {base}/{key}    the resource, encoded with the codec
+//	PUT    {base}/{key}    creates or replaces the resource
+//	DELETE {base}/{key}    deletes the resource
+//
+// Lists are paginated with the rel="next" link of the Link header.
+// Resources with an ETag are replaced and deleted with If-Match so the
+// changes of others since they were compared aren't overwritten.
+package httpstate // import "sevki.org/x/reconcile/httpstate"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"sevki.org/x/reconcile"
+)
+
+// ErrNoETag is the error of compare and swaps of resources the server
+// didn't send an ETag for.
+var ErrNoETag = errors.New("resource has no etag")
+
+// StatusError is the error of requests the server answered with a
+// status that isn't a success.
+type StatusError struct {
+	Method, URL string
+	StatusCode  int
+	// Body is the start of the body of the response.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Temporary reports whether the request can be retried, it is for
+// statuses like 429 Too Many Requests and 503 Service Unavailable.
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// State is a reconcile.State whose values are the resources of a REST
+// API. The State interface has no errors, the errors of the requests
+// are kept and Flush, which Reconcile calls after every pass, returns
+// them; a resource that couldn't be read is missing.
+type State struct {
+	base     string
+	client   *http.Client
+	codec    reconcile.Codec
+	typeName string
+	ctx      context.Context
+
+	mu    sync.Mutex
+	etags map[string]string
+	errs  []error
+}
+
+var (
+	_ reconcile.State   = (*State)(nil)
+	_ reconcile.CASer   = (*State)(nil)
+	_ reconcile.Flusher = (*State)(nil)
+)
+
+// Option configures New.
+type Option func(*State)
+
+// ValueType sets the name the type of the resources was registered with,
+// see reconcile.RegisterType. It is map by default, for JSON objects.
+func ValueType(name string) Option {
+	return func(s *State) { s.typeName = name }
+}
+
+// WithContext sets the context of the requests.
+func WithContext(ctx context.Context) Option {
+	return func(s *State) { s.ctx = ctx }
+}
+
+// New returns the State of the collection at base, requested with
+// client, http.DefaultClient if it is nil, and encoded with codec.
+func New(base string, client *http.Client, codec reconcile.Codec, opts ...Option) (*State, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s: base isn't an absolute url", base)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	s := &State{
+		base:     strings.TrimSuffix(u.String(), "/"),
+		client:   client,
+		codec:    codec,
+		typeName: "map",
+		ctx:      context.Background(),
+		etags:    make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Add creates the resource at key.
+func (s *State) Add(key string, v interface{}) { s.fail(s.put(key, v, nil)) }
+
+// Update replaces the resource at key, if its ETag is still the one it
+// had when it was read.
+func (s *State) Update(key string, v interface{}) {
+	s.fail(s.put(key, v, s.ifMatch(key)))
+}
+
+// Get returns the resource at key, nil if there isn't one.
+func (s *State) Get(key string) interface{} {
+	v, err := s.get(key)
+	s.fail(err)
+	return v
+}
+
+// Delete deletes the resource at key, resources that don't exist are
+// deleted.
+func (s *State) Delete(key string) { s.fail(s.delete(key, s.ifMatch(key))) }
+
+// Walk calls f for the resources of every page of the collection. A
+// next page that was already listed ends the walk with an error.
+func (s *State) Walk(f reconcile.StateWalkFunc) {
+	next := s.base
+	listed := make(map[string]bool)
+	for next != "" {
+		if listed[next] {
+			s.fail(fmt.Errorf("%s: next page was already listed", next))
+			return
+		}
+		listed[next] = true
+		var keys []string
+		var err error
+		keys, next, err = s.list(next)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		for _, k := range keys {
+			v, err := s.get(k)
+			if err != nil {
+				s.fail(err)
+				continue
+			}
+			if v != nil {
+				f(k, v)
+			}
+		}
+	}
+}
+
+// CAS replaces, creates or deletes the resource at key if it is still
+// the one that was read. Creations fail if there is a resource, the
+// others if its ETag changed or it never had one, see ErrNoETag.
+func (s *State) CAS(key string, old, new interface{}) error {
+	header := http.Header{}
+	if old == nil {
+		header.Set("If-None-Match", "*")
+	} else {
+		h := s.ifMatch(key)
+		if h == nil {
+			return fmt.Errorf("%s: %w", key, ErrNoETag)
+		}
+		header = h
+	}
+	if new == nil {
+		return s.delete(key, header)
+	}
+	return s.put(key, new, header)
+}
+
+// Flush returns the errors of the requests since it was last called,
+// the first one if there were more, and ends the pass: the ETags of
+// the resources are the ones of their first read in a pass, the one
+// their value was compared with.
+func (s *State) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := s.errs
+	s.errs = nil
+	s.etags = make(map[string]string)
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return fmt.Errorf("%w (and %d more errors)", errs[0], len(errs)-1)
+}
+
+func (s *State) fail(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errs = append(s.errs, err)
+	s.mu.Unlock()
+}
+
+func (s *State) ifMatch(key string) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	etag, ok := s.etags[key]
+	if !ok {
+		return nil
+	}
+	return http.Header{"If-Match": {etag}}
+}
+
+// readETag keeps the etag of a read of key unless it was read in the
+// pass already.
+func (s *State) readETag(key, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.etags[key]; !ok && etag != "" {
+		s.etags[key] = etag
+	}
+}
+
+// setETag sets the etag of key after it was written.
+func (s *State) setETag(key, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if etag == "" {
+		delete(s.etags, key)
+		return
+	}
+	s.etags[key] = etag
+}
+
+// stale forgets the etag of key if err is a failed precondition, so
+// the next read gets the current one.
+func (s *State) stale(key string, err error) {
+	var serr *StatusError
+	if errors.As(err, &serr) && serr.StatusCode == http.StatusPreconditionFailed {
+		s.setETag(key, "")
+	}
+}
+
+func (s *State) url(key string) string { return s.base + "/" + url.PathEscape(key) }
+
+// do sends a request and returns the response of the ones that
+// succeeded or were a status in ok.
+func (s *State) do(method, u string, body []byte, header http.Header, ok ...int) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(s.ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", s.contentType())
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, &StatusError{Method: method, URL: u, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+}
+
+func (s *State) contentType() string {
+	if s.codec == reconcile.JSON {
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
+
+func (s *State) get(key string) (interface{}, error) {
+	resp, err := s.do(http.MethodGet, s.url(key), nil, nil, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.codec.Unmarshal(data, s.typeName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	s.readETag(key, resp.Header.Get("ETag"))
+	return v, nil
+}
+
+func (s *State) put(key string, v interface{}, header http.Header) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	resp, err := s.do(http.MethodPut, s.url(key), data, header)
+	if err != nil {
+		s.stale(key, err)
+		return err
+	}
+	resp.Body.Close()
+	s.setETag(key, resp.Header.Get("ETag"))
+	return nil
+}
+
+func (s *State) delete(key string, header http.Header) error {
+	resp, err := s.do(http.MethodDelete, s.url(key), nil, header, http.StatusNotFound)
+	if err != nil {
+		s.stale(key, err)
+		return err
+	}
+	resp.Body.Close()
+	s.setETag(key, "")
+	return nil
+}
+
+// nextLink matches the rel="next" link of a Link header.
+var nextLink = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// list returns the keys of the page at u and the url of the next one.
+func (s *State) list(u string) (keys []string, next string, err error) {
+	resp, err := s.do(http.MethodGet, u, nil, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", u, err)
+	}
+	if m := nextLink.FindStringSubmatch(strings.Join(resp.Header.Values("Link"), ",")); m != nil {
+		ref, err := resp.Request.URL.Parse(m[1])
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: next page: %w", u, err)
+		}
+		next = ref.String()
+	}
+	return keys, next, nil
+}
@@ -0,0 +1,262 @@
+package httpstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"sevki.org/x/reconcile"
+)
+
+type resource struct {
+	body    []byte
+	version int
+}
+
+// server is a REST API of resources with ETags and pages of two keys.
+type server struct {
+	mu        sync.Mutex
+	resources map[string]*resource
+	version   int
+	// served, if set, is called after a resource was served.
+	served func(key string)
+	// status, if set, is the status of every request.
+	status int
+}
+
+func newServer(values map[string]interface{}) *server {
+	s := &server{resources: make(map[string]*resource)}
+	for k, v := range values {
+		b, _ := json.Marshal(v)
+		s.put(k, b)
+	}
+	return s
+}
+
+func (s *server) put(key string, body []byte) *resource {
+	s.version++
+	r := &resource{body: body, version: s.version}
+	s.resources[key] = r
+	return r
+}
+
+func (s *server) values() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]interface{})
+	for k, r := range s.resources {
+		var v interface{}
+		json.Unmarshal(r.body, &v)
+		values[k] = v
+	}
+	return values
+}
+
+func etag(r *resource) string { return `"` + strconv.Itoa(r.version) + `"` }
+
+func (s *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	if s.status != 0 {
+		s.mu.Unlock()
+		http.Error(w, "try again later", s.status)
+		return
+	}
+	key := strings.TrimPrefix(req.URL.Path, "/resources/")
+	if req.URL.Path == "/resources" {
+		defer s.mu.Unlock()
+		s.list(w, req)
+		return
+	}
+	r, exists := s.resources[key]
+	if m := req.Header.Get("If-Match"); m != "" && (!exists || m != etag(r)) ||
+		req.Header.Get("If-None-Match") == "*" && exists {
+		s.mu.Unlock()
+		http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		if !exists {
+			s.mu.Unlock()
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("ETag", etag(r))
+		w.Write(r.body)
+		served := s.served
+		s.mu.Unlock()
+		if served != nil {
+			served(key)
+		}
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(req.Body)
+		r := s.put(key, body)
+		s.mu.Unlock()
+		w.Header().Set("ETag", etag(r))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(s.resources, key)
+		s.mu.Unlock()
+		if !exists {
+			http.NotFound(w, req)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *server) list(w http.ResponseWriter, req *http.Request) {
+	var keys []string
+	for k := range s.resources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	if end := 2 * (page + 1); end < len(keys) {
+		w.Header().Set("Link", fmt.Sprintf(`</resources?page=%d>; rel="next"`, page+1))
+		keys = keys[2*page : end]
+	} else if 2*page < len(keys) {
+		keys = keys[2*page:]
+	} else {
+		keys = nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(append([]string{}, keys...))
+}
+
+func resources(names ...string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, n := range names {
+		k, v := n, "1"
+		if i := strings.IndexByte(n, '='); i >= 0 {
+			k, v = n[:i], n[i+1:]
+		}
+		values[k] = map[string]interface{}{"v": v}
+	}
+	return values
+}
+
+func newState(t *testing.T, s *server) *State {
+	t.Helper()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	state, err := New(ts.URL+"/resources", ts.Client(), reconcile.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestReconcile(t *testing.T) {
+	srv := newServer(resources("a", "b", "c=1", "d", "e"))
+	current := newState(t, srv)
+	want := resources("a", "c=2", "e", "f", "g/h")
+	r := reconcile.Reconcile(current, reconcile.NewMapState(want), false)
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	sort.Strings(r.Added)
+	sort.Strings(r.Deleted)
+	if !reflect.DeepEqual(r.Added, []string{"f", "g/h"}) || !reflect.DeepEqual(r.Updated, []string{"c"}) || !reflect.DeepEqual(r.Deleted, []string{"b", "d"}) {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+	if got := srv.values(); !reflect.DeepEqual(got, want) {
+		t.Logf("was expecting the server to have\n%v\ngot\n%v", want, got)
+		t.Fail()
+	}
+	r = reconcile.Reconcile(current, reconcile.NewMapState(want), false)
+	if r.Err != nil || len(r.Added)+len(r.Updated)+len(r.Deleted) != 0 {
+		t.Logf("was expecting the second pass to be a no op got %+v", r)
+		t.Fail()
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	srv := newServer(resources("a", "b"))
+	// someone else edits a right after it was read
+	srv.served = func(key string) {
+		if key != "a" {
+			return
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		srv.served = nil
+		srv.put("a", []byte(`{"v":"theirs"}`))
+	}
+	current := newState(t, srv)
+	r := reconcile.Reconcile(current, reconcile.NewMapState(resources("a=2", "b=2", "c")), false, reconcile.WithCompareAndSwap(true))
+	if !reflect.DeepEqual(r.Conflicts, []string{"a"}) || !reflect.DeepEqual(r.Updated, []string{"b"}) || !reflect.DeepEqual(r.Added, []string{"c"}) {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+	if got := srv.values()["a"]; !reflect.DeepEqual(got, map[string]interface{}{"v": "theirs"}) {
+		t.Logf("a was overwritten with %v", got)
+		t.Fail()
+	}
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	srv := newServer(resources("a"))
+	current := newState(t, srv)
+	current.Get("a")
+	srv.mu.Lock()
+	srv.put("a", []byte(`{"v":"theirs"}`))
+	srv.mu.Unlock()
+	current.Update("a", map[string]interface{}{"v": "2"})
+	var serr *StatusError
+	if err := current.Flush(); !errors.As(err, &serr) || serr.StatusCode != http.StatusPreconditionFailed {
+		t.Logf("was expecting the update to fail its precondition got %v", err)
+		t.Fail()
+	}
+}
+
+func TestDeleteMissing(t *testing.T) {
+	current := newState(t, newServer(nil))
+	current.Delete("a")
+	if err := current.Flush(); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	srv := newServer(resources("a"))
+	srv.status = http.StatusServiceUnavailable
+	current := newState(t, srv)
+	r := reconcile.Reconcile(current, reconcile.NewMapState(resources("a")), false)
+	var serr *StatusError
+	if !errors.As(r.Err, &serr) || serr.StatusCode != http.StatusServiceUnavailable || !serr.Temporary() {
+		t.Logf("was expecting a temporary 503 got %v", r.Err)
+		t.Fail()
+	}
+}
+
+func TestWalkLinkLoop(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Link", `</resources>; rel="next"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(ts.Close)
+	current, err := New(ts.URL+"/resources", ts.Client(), reconcile.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current.Walk(func(string, interface{}) {})
+	if err := current.Flush(); err == nil || requests != 1 {
+		t.Logf("was expecting the walk to stop at the page linking to itself got %d requests and %v", requests, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import "bytes"
+
+// KV is a key of a listing, see Lister.
+type KV struct {
+	Key string
+	// Value is the value of the key, nil if the listing only has its
+	// Sum.
+	Value interface{}
+	// Sum is the checksum of the value, computed like the ObservedSum of
+	// a KeyStatus: the Sum of Checksumed values or a SHA-256 of the value
+	// as canonical JSON. Keys with equal sums are in sync.
+	Sum []byte
+}
+
+// Lister is implemented by states that can list their keys faster than
+// they are walked, like stores that hold a lock while they are: the
+// listing is a snapshot taken at once, so the lock is released before
+// the keys are compared. States that implement it are compared through
+// List instead of Walk; the values of the keys listed with only a Sum
+// are read with Get when they need to be changed. If List fails the
+// state is walked.
+//
+// A listing trades consistency for the time locks are held: keys that
+// change after List returned are compared with the values they had,
+// where the Gets after it see the new ones, and the plan may overwrite
+// a change made in between, WithCompareAndSwap catches those. Listings
+// sorted by key, of both states, are merged without indexing either.
+type Lister interface {
+	List() ([]KV, error)
+}
+
+// diffListings is diffKeys for states of which at least one is a
+// Lister, the other one is walked into a listing.
+func diffListings(current, desired State, o options) ([]update, bool) {
+	_, currentLists := current.(Lister)
+	_, desiredLists := desired.(Lister)
+	if !currentLists && !desiredLists {
+		return nil, false
+	}
+	cur, des := listing(current), listing(desired)
+	keep := o.keep
+	var updates, deletes []update
+	join(des, cur, func(d, c *KV) {
+		if d != nil {
+			defer o.recoverKey(d.Key, &updates)
+		} else {
+			defer o.recoverKey(c.Key, &updates)
+		}
+		if d == nil {
+			if keep == nil || keep(c.Key) {
+				deletes = append(deletes, deleteKey(c.Key, valueOf(current, c)))
+			} else {
+				o.filteredOut(c.Key)
+			}
+			return
+		}
+		if keep != nil && !keep(d.Key) {
+			o.filteredOut(d.Key)
+			return
+		}
+		if c != nil && o.currentTransform == nil && sameSum(d, c) {
+			if o.touch == nil {
+				if o.observe != nil {
+					o.observe(d.Key, Decision{Kind: Clean, Method: "sum"})
+				}
+				if o.inSync != nil {
+					o.inSync(*c)
+				}
+				return
+			}
+			if u, ok := touchKey(d.Key, valueOf(current, c), "sum", o); ok {
+				updates = append(updates, u)
+			}
+			return
+		}
+		var currentValue interface{}
+		if c != nil {
+			currentValue = valueOf(current, c)
+		}
+		if u, ok := compareKey(d.Key, valueOf(desired, d), currentValue, o); ok {
+			updates = append(updates, u)
+		}
+	})
+	return append(updates, deletes...), true
+}
+
+// listing returns the listing of s, walking it if it isn't a Lister or
+// its List failed.
+func listing(s State) []KV {
+	if l, ok := s.(Lister); ok {
+		if kvs, err := l.List(); err == nil {
+			return kvs
+		}
+	}
+	var kvs []KV
+	s.Walk(func(key string, v interface{}) {
+		kvs = append(kvs, KV{Key: key, Value: v})
+	})
+	return kvs
+}
+
+// valueOf returns the value of kv, reading it from s if it was listed
+// with only a Sum.
+func valueOf(s State, kv *KV) interface{} {
+	if kv.Value != nil {
+		return kv.Value
+	}
+	return s.Get(kv.Key)
+}
+
+// sameSum reports whether d and c were listed with sums, or one of them
+// with a value whose sum can be computed, and they are equal. Keys
+// listed with values are compared like walked ones.
+func sameSum(d, c *KV) bool {
+	if d.Sum == nil && c.Sum == nil {
+		return false
+	}
+	ds, cs := d.Sum, c.Sum
+	if ds == nil {
+		ds = observedSum(d.Value)
+	}
+	if cs == nil {
+		cs = observedSum(c.Value)
+	}
+	return ds != nil && bytes.Equal(ds, cs)
+}
+
+// join calls f with the keys of desired in their order and the current
+// one with the same key, nil if there isn't one, then with nil and the
+// keys of current that aren't in desired in their order. Sorted
+// listings are merged, the others indexed.
+func join(desired, current []KV, f func(d, c *KV)) {
+	if sortedKeys(desired) && sortedKeys(current) {
+		var deletes []*KV
+		i, j := 0, 0
+		for i < len(desired) {
+			switch {
+			case j < len(current) && current[j].Key < desired[i].Key:
+				deletes = append(deletes, &current[j])
+				j++
+			case j < len(current) && current[j].Key == desired[i].Key:
+				f(&desired[i], &current[j])
+				i, j = i+1, j+1
+			default:
+				f(&desired[i], nil)
+				i++
+			}
+		}
+		for _, c := range deletes {
+			f(nil, c)
+		}
+		for ; j < len(current); j++ {
+			f(nil, &current[j])
+		}
+		return
+	}
+	byKey := make(map[string]*KV, len(current))
+	for i := range current {
+		byKey[current[i].Key] = &current[i]
+	}
+	listed := make(map[string]bool, len(desired))
+	for i := range desired {
+		listed[desired[i].Key] = true
+		f(&desired[i], byKey[desired[i].Key])
+	}
+	for i := range current {
+		if !listed[current[i].Key] {
+			f(nil, &current[i])
+		}
+	}
+}
+
+// sortedKeys reports whether the keys of kvs are sorted and unique.
+func sortedKeys(kvs []KV) bool {
+	for i := 1; i < len(kvs); i++ {
+		if kvs[i].Key <= kvs[i-1].Key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// listState is a MapState that lists its keys, with only their sums if
+// sums is set and in reverse order if reversed is.
+type listState struct {
+	*MapState
+	sums, reversed bool
+	err            error
+	walks, gets    int
+}
+
+func (s *listState) List() ([]KV, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	var kvs []KV
+	s.MapState.Walk(func(key string, v interface{}) {
+		kv := KV{Key: key, Value: v}
+		if s.sums {
+			kv = KV{Key: key, Sum: observedSum(v)}
+		}
+		kvs = append(kvs, kv)
+	})
+	if s.reversed {
+		for i, j := 0, len(kvs)-1; i < j; i, j = i+1, j-1 {
+			kvs[i], kvs[j] = kvs[j], kvs[i]
+		}
+	}
+	return kvs, nil
+}
+
+func (s *listState) Walk(f StateWalkFunc) {
+	s.walks++
+	s.MapState.Walk(f)
+}
+
+func (s *listState) Get(key string) interface{} {
+	s.gets++
+	return s.MapState.Get(key)
+}
+
+func randomMap(r *rand.Rand) map[string]interface{} {
+	m := make(map[string]interface{})
+	for i := 0; i < 20; i++ {
+		if r.Intn(3) > 0 {
+			m[fmt.Sprintf("k%02d", i)] = fmt.Sprint(r.Intn(3))
+		}
+	}
+	return m
+}
+
+func TestListerMatchesWalk(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		cm, dm := randomMap(r), randomMap(r)
+		want := NewChangeSet(NewMapState(cm), NewMapState(dm)).Updates()
+		for _, l := range []struct {
+			name             string
+			current, desired State
+			sorted           bool
+		}{
+			{"current", &listState{MapState: NewMapState(cm)}, NewMapState(dm), true},
+			{"both", &listState{MapState: NewMapState(cm)}, &listState{MapState: NewMapState(dm)}, true},
+			{"sums", &listState{MapState: NewMapState(cm), sums: true}, &listState{MapState: NewMapState(dm), sums: true}, true},
+			{"desired sums", NewMapState(cm), &listState{MapState: NewMapState(dm), sums: true}, true},
+			{"unsorted", &listState{MapState: NewMapState(cm), reversed: true}, NewMapState(dm), false},
+			{"failing", &listState{MapState: NewMapState(cm), err: errors.New("unavailable")}, NewMapState(dm), true},
+		} {
+			got := NewChangeSet(l.current, l.desired).Updates()
+			expected := want
+			if !l.sorted {
+				expected = byKey(want)
+				got = byKey(got)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Logf("%d %s: was expecting\n%v\ngot\n%v", i, l.name, expected, got)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func byKey(updates []Update) []Update {
+	sorted := append([]Update(nil), updates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	return sorted
+}
+
+func TestListerSnapshot(t *testing.T) {
+	current := &listState{MapState: NewMapState(map[string]interface{}{"a": "1", "b": "1", "c": "1"}), sums: true}
+	desired := NewMapState(map[string]interface{}{"a": "1", "b": "2"})
+	plan := NewChangeSet(current, desired)
+	if current.walks != 0 {
+		t.Logf("was expecting the listing to be used instead of Walk, walked %d times", current.walks)
+		t.Fail()
+	}
+	// b and c are read to be changed, a is in sync by its sum
+	if current.gets != 2 {
+		t.Logf("was expecting 2 gets got %d", current.gets)
+		t.Fail()
+	}
+	r := plan.ApplyTo(current)
+	if !reflect.DeepEqual(r.Updated, []string{"b"}) || !reflect.DeepEqual(r.Deleted, []string{"c"}) || current.MapState.Get("b") != "2" {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+}
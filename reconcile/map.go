@@ -0,0 +1,124 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// MapState is a State backed by a map that is safe for concurrent use.
+// It walks its keys in sorted order. The zero value is an empty state.
+type MapState struct {
+	mu sync.RWMutex
+	m  map[string]interface{}
+}
+
+var _ State = &MapState{}
+
+// NewMapState returns a MapState with a copy of m.
+func NewMapState(m map[string]interface{}) *MapState {
+	s := &MapState{m: make(map[string]interface{}, len(m))}
+	for k, v := range m {
+		s.m[k] = v
+	}
+	return s
+}
+
+// Add sets key to v.
+func (s *MapState) Add(key string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(map[string]interface{})
+	}
+	s.m[key] = v
+}
+
+// Update sets key to v.
+func (s *MapState) Update(key string, v interface{}) { s.Add(key, v) }
+
+// Get returns the value of key or nil.
+func (s *MapState) Get(key string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[key]
+}
+
+// Delete deletes key.
+func (s *MapState) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+}
+
+// Walk calls f for the keys of s in sorted order. It walks a snapshot
+// of s, so f can change it.
+func (s *MapState) Walk(f StateWalkFunc) {
+	s.mu.RLock()
+	keys := s.keys()
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = s.m[k]
+	}
+	s.mu.RUnlock()
+	for i, k := range keys {
+		f(k, values[i])
+	}
+}
+
+// Keys returns the keys of s in sorted order.
+func (s *MapState) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys()
+}
+
+func (s *MapState) keys() []string {
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of keys in s.
+func (s *MapState) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.m)
+}
+
+// Dump writes the keys and values of s to w, see Dump.
+func (s *MapState) Dump(w io.Writer, opts ...Option) error { return Dump(s, w, opts...) }
+
+// Dump writes the keys and values of s to w, one per line in sorted
+// order, passing the values through the WithRedactor function if there
+// is one.
+func Dump(s State, w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	type entry struct {
+		key string
+		v   interface{}
+	}
+	var entries []entry
+	s.Walk(func(key string, v interface{}) {
+		entries = append(entries, entry{key, v})
+	})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	for _, e := range entries {
+		v := e.v
+		if o.redact != nil {
+			v = o.redact(e.key, v)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %v\n", e.key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
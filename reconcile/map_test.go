@@ -0,0 +1,92 @@
+package reconcile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapStateContract(t *testing.T) {
+	if err := CheckState(&MapState{}, Concurrent()); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestMapState(t *testing.T) {
+	s := NewMapState(map[string]interface{}{
+		"b": 2,
+		"a": 1,
+		"c": 3,
+	})
+	s.Delete("c")
+	s.Add("d/password", "hunter2")
+	if got, want := s.Keys(), []string{"a", "b", "d/password"}; !reflect.DeepEqual(got, want) {
+		t.Logf("got keys %v, expected %v", got, want)
+		t.Fail()
+	}
+	if s.Len() != 3 {
+		t.Logf("got %d keys, expected 3", s.Len())
+		t.Fail()
+	}
+	var walked []string
+	s.Walk(func(key string, v interface{}) {
+		walked = append(walked, key)
+		s.Delete(key)
+	})
+	if !reflect.DeepEqual(walked, []string{"a", "b", "d/password"}) || s.Len() != 0 {
+		t.Logf("walked %v and left %d keys", walked, s.Len())
+		t.Fail()
+	}
+}
+
+func TestDump(t *testing.T) {
+	redact := WithRedactor(func(key string, v interface{}) interface{} {
+		if strings.HasSuffix(key, "password") {
+			return "REDACTED"
+		}
+		return v
+	})
+	values := map[string]interface{}{
+		"b":          2,
+		"a":          "one",
+		"d/password": "hunter2",
+	}
+	tests := []struct {
+		name string
+		s    State
+		opts []Option
+		want string
+	}{
+		{
+			name: "map",
+			s:    NewMapState(values),
+			want: "a: one\nb: 2\nd/password: hunter2\n",
+		},
+		{
+			name: "unsorted",
+			s:    copyState(values),
+			opts: []Option{redact},
+			want: "a: one\nb: 2\nd/password: REDACTED\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Dump(test.s, &b, test.opts...); err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+			if b.String() != test.want {
+				t.Logf("got %q, expected %q", b.String(), test.want)
+				t.Fail()
+			}
+		})
+	}
+	var b strings.Builder
+	NewMapState(values).Dump(&b, redact)
+	if !strings.Contains(b.String(), "REDACTED") {
+		t.Logf("MapState.Dump didn't redact %q", b.String())
+		t.Fail()
+	}
+}
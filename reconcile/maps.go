@@ -0,0 +1,129 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Maps returns the keys that would be added to, updated in and removed
+// from current to make it desired, each in order. Values are the same
+// if eq says so, reflect.DeepEqual if eq is nil. Nil maps are empty.
+func Maps[T any](current, desired map[string]T, eq func(a, b T) bool) (added, updated, removed []string) {
+	for _, u := range plan(goMap[T]{current, eq}, goMap[T]{desired, eq}, newOptions(nil)) {
+		switch u.state {
+		case new:
+			added = append(added, u.key)
+		case dirty:
+			updated = append(updated, u.key)
+		case old:
+			removed = append(removed, u.key)
+		}
+	}
+	return added, updated, removed
+}
+
+// SyncMaps reconciles current with desired in place like Reconcile,
+// comparing values with reflect.DeepEqual. A nil desired map is empty,
+// a nil current one can't be changed and is ErrNilState. The functions
+// of WithTransform, WithCurrentTransform and WithTouch are given values
+// of the maps, transforms that don't return a T are Rejected.
+func SyncMaps[T any](current, desired map[string]T, opts ...Option) Result {
+	if current == nil {
+		return Result{Err: fmt.Errorf("current map: %w", ErrNilState)}
+	}
+	opts = append(opts[:len(opts):len(opts)], unboxed[T]())
+	return Reconcile(goMap[T]{m: current}, goMap[T]{m: desired}, false, opts...)
+}
+
+// unboxed makes the functions of the options take and return the values
+// of a goMap instead of their boxes.
+func unboxed[T any]() Option {
+	return func(o *options) {
+		if f := o.transform; f != nil {
+			o.transform = func(key string, v interface{}) (interface{}, error) {
+				return rebox[T](f(key, v.(boxed[T]).v))
+			}
+		}
+		if f := o.currentTransform; f != nil {
+			o.currentTransform = func(key string, v interface{}) (interface{}, error) {
+				return rebox[T](f(key, v.(boxed[T]).v))
+			}
+		}
+		if f := o.touch; f != nil {
+			o.touch = func(key string, v interface{}) bool { return f(key, v.(boxed[T]).v) }
+		}
+	}
+}
+
+// rebox boxes the value a transform returned.
+func rebox[T any](v interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	t, ok := v.(T)
+	if !ok && (v != nil || reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Interface) {
+		return nil, fmt.Errorf("%T isn't a %v", v, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return boxed[T]{v: t}, nil
+}
+
+// goMap is a State of a Go map, its values are boxed so nil values
+// aren't taken for missing keys and eq is used to compare them.
+type goMap[T any] struct {
+	m  map[string]T
+	eq func(a, b T) bool
+}
+
+type boxed[T any] struct {
+	v  T
+	eq func(a, b T) bool
+}
+
+func (b boxed[T]) equal(other interface{}) bool {
+	o, ok := other.(boxed[T])
+	if !ok {
+		return false
+	}
+	if b.eq == nil {
+		return reflect.DeepEqual(b.v, o.v)
+	}
+	return b.eq(b.v, o.v)
+}
+
+// MarshalJSON encodes the value, for WithValueDiff and Hash.
+func (b boxed[T]) MarshalJSON() ([]byte, error) { return json.Marshal(b.v) }
+
+func (s goMap[T]) Add(key string, v interface{})    { s.m[key] = v.(boxed[T]).v }
+func (s goMap[T]) Update(key string, v interface{}) { s.m[key] = v.(boxed[T]).v }
+func (s goMap[T]) Delete(key string)                { delete(s.m, key) }
+
+func (s goMap[T]) Get(key string) interface{} {
+	v, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+	return boxed[T]{v, s.eq}
+}
+
+func (s goMap[T]) Walk(f StateWalkFunc) {
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f(k, boxed[T]{s.m[k], s.eq})
+	}
+}
+
+// Identity is the map, so a map isn't reconciled with itself.
+func (s goMap[T]) Identity() string { return fmt.Sprintf("%p", s.m) }
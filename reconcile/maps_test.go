@@ -0,0 +1,158 @@
+//go:build go1.21
+// +build go1.21
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaps(t *testing.T) {
+	type port struct{ n int }
+	one, two := &port{1}, &port{2}
+	tests := []struct {
+		name                    string
+		current, desired        map[string]*port
+		eq                      func(a, b *port) bool
+		added, updated, removed []string
+	}{
+		{
+			name:    "delta",
+			current: map[string]*port{"a": one, "b": one, "c": one},
+			desired: map[string]*port{"b": one, "c": two, "d": two},
+			added:   []string{"d"}, updated: []string{"c"}, removed: []string{"a"},
+		},
+		{
+			name:    "nil values",
+			current: map[string]*port{"a": nil, "b": one},
+			desired: map[string]*port{"a": nil, "b": nil, "c": nil},
+			added:   []string{"c"}, updated: []string{"b"},
+		},
+		{
+			name:    "deep equal",
+			current: map[string]*port{"a": {1}},
+			desired: map[string]*port{"a": {1}},
+		},
+		{
+			name:    "eq",
+			current: map[string]*port{"a": {1}},
+			desired: map[string]*port{"a": {1}},
+			eq:      func(a, b *port) bool { return a == b },
+			updated: []string{"a"},
+		},
+		{name: "nil maps"},
+		{
+			name:    "nil current",
+			desired: map[string]*port{"b": one, "a": one},
+			added:   []string{"a", "b"},
+		},
+		{
+			name:    "nil desired",
+			current: map[string]*port{"a": one},
+			removed: []string{"a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, updated, removed := Maps(test.current, test.desired, test.eq)
+			for _, got := range []struct {
+				what       string
+				keys, want []string
+			}{
+				{"added", added, test.added},
+				{"updated", updated, test.updated},
+				{"removed", removed, test.removed},
+			} {
+				if !reflect.DeepEqual(got.keys, got.want) {
+					t.Logf("%s %v, expected %v", got.what, got.keys, got.want)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestSyncMaps(t *testing.T) {
+	current := map[string]interface{}{"a": 1, "b": nil, "gone": "x"}
+	desired := map[string]interface{}{"a": 2, "b": nil, "c": nil}
+	r := SyncMaps(current, desired)
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if !reflect.DeepEqual(current, desired) {
+		t.Logf("got %v, expected %v", current, desired)
+		t.Fail()
+	}
+	if !reflect.DeepEqual(r.Added, []string{"c"}) || !reflect.DeepEqual(r.Updated, []string{"a"}) || !reflect.DeepEqual(r.Deleted, []string{"gone"}) {
+		t.Logf("got %+v", r)
+		t.Fail()
+	}
+}
+
+func TestSyncMapsOptions(t *testing.T) {
+	current := map[string]int{"mine/a": 1, "theirs/b": 1}
+	desired := map[string]int{"mine/c": 1, "theirs/d": 1}
+	// a hash of the keys starting with mine/ and one of the others
+	// that puts them in different shards
+	other := uint64(2)
+	for jumpHash(other, 2) == jumpHash(1, 2) {
+		other++
+	}
+	byPrefix := func(key string) uint64 {
+		if strings.HasPrefix(key, "mine/") {
+			return 1
+		}
+		return other
+	}
+	var applied []string
+	SyncMaps(current, desired, WithShard(jumpHash(1, 2), 2, byPrefix),
+		WithApplyHook(func(u Update) { applied = append(applied, u.Action+" "+u.Key) }))
+	want := map[string]int{"mine/c": 1, "theirs/b": 1}
+	if !reflect.DeepEqual(current, want) {
+		t.Logf("got %v after %v, expected %v", current, applied, want)
+		t.Fail()
+	}
+}
+
+func TestSyncMapsTransforms(t *testing.T) {
+	current := map[string]int{"a": 10, "b": 10, "c": 1}
+	desired := map[string]int{"a": 1, "b": 2, "c": 1, "d": 4}
+	var touched []string
+	r := SyncMaps(current, desired,
+		WithTransform(func(key string, v interface{}) (interface{}, error) {
+			if key == "d" {
+				return "four", nil
+			}
+			return v.(int) * 10, nil
+		}),
+		WithCurrentTransform(func(_ string, v interface{}) (interface{}, error) { return v.(int) / 10, nil }),
+		WithTouch(func(key string, v interface{}) bool {
+			touched = append(touched, fmt.Sprintf("%s=%v", key, v))
+			return false
+		}))
+	want := map[string]int{"a": 10, "b": 20, "c": 10}
+	if !reflect.DeepEqual(current, want) || !reflect.DeepEqual(r.Updated, []string{"b", "c"}) || r.Rejected["d"] == nil {
+		t.Logf("got %v and %+v, expected %v and d rejected", current, r, want)
+		t.Fail()
+	}
+	if !reflect.DeepEqual(touched, []string{"a=10"}) {
+		t.Logf("got %v touched, expected the values of the map", touched)
+		t.Fail()
+	}
+}
+
+func TestSyncMapsMisuse(t *testing.T) {
+	if r := SyncMaps(nil, map[string]int{"a": 1}); !errors.Is(r.Err, ErrNilState) {
+		t.Logf("got %v, expected %v", r.Err, ErrNilState)
+		t.Fail()
+	}
+	m := map[string]int{"a": 1}
+	if r := SyncMaps(m, m); !errors.Is(r.Err, ErrSameState) {
+		t.Logf("got %v, expected %v", r.Err, ErrSameState)
+		t.Fail()
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import "sync"
+
+// DecisionKind is what planning made of a key, see WithDiffObserver.
+type DecisionKind int
+
+const (
+	// Clean keys are in sync, the ones WithTouch touches too.
+	Clean DecisionKind = iota
+	// Dirty keys are updated.
+	Dirty
+	// MissingInCurrent keys are added.
+	MissingInCurrent
+	// MissingInDesired keys are deleted.
+	MissingInDesired
+	// PendingDelete keys wait for their WithDeleteGrace.
+	PendingDelete
+	// FilteredOut keys aren't compared, see WithShard.
+	FilteredOut
+	// Paused keys would be updated if they weren't paused, see
+	// WithPause.
+	Paused
+	// Panicked keys panicked while they were compared, see
+	// WithRecoverPanics.
+	Panicked
+)
+
+func (k DecisionKind) String() string {
+	switch k {
+	case Clean:
+		return "Clean"
+	case Dirty:
+		return "Dirty"
+	case MissingInCurrent:
+		return "MissingInCurrent"
+	case MissingInDesired:
+		return "MissingInDesired"
+	case PendingDelete:
+		return "PendingDelete"
+	case FilteredOut:
+		return "FilteredOut"
+	case Paused:
+		return "Paused"
+	case Panicked:
+		return "Panicked"
+	}
+	return ""
+}
+
+// Decision is what planning made of a key and why.
+type Decision struct {
+	Kind DecisionKind
+	// Method is how the values of clean and dirty keys were compared:
+	// string, equal, checksum, deep-equal, or sum for the sums of a
+	// Lister. It is empty for dirty keys whose current value couldn't
+	// be transformed, see WithCurrentTransform.
+	Method string
+	// Reason is the reason of the update of the key, empty for the keys
+	// that aren't updated.
+	Reason string
+}
+
+// WithDiffObserver calls observe with the decision planning made for
+// every key it considered, the ones WithShard filters out included.
+// Keys are observed as they are compared, so the clean keys while the
+// states are walked; observe must not change them.
+func WithDiffObserver(observe func(key string, d Decision)) Option {
+	return func(o *options) { o.observe = observe }
+}
+
+// observeUpdates observes the decisions of updates, the keys that are in
+// sync without a touch were observed when they were compared.
+func observeUpdates(updates []update, o options) {
+	for _, u := range updates {
+		d := Decision{Method: u.method, Reason: u.why}
+		switch {
+		case o.paused != nil && o.paused(u.key):
+			d.Kind = Paused
+		case u.state == new:
+			d.Kind = MissingInCurrent
+		case u.state == old:
+			d.Kind = MissingInDesired
+		case u.state == dirty:
+			d.Kind = Dirty
+		case u.state == pending:
+			d.Kind = PendingDelete
+		case u.state == touched:
+			d.Kind = Clean
+		case u.state == panicked:
+			d.Kind = Panicked
+		}
+		o.observe(u.key, d)
+	}
+}
+
+// DecisionCounter counts the decisions it observes, by their kind, it is
+// safe for concurrent use. Pass its Observe to WithDiffObserver.
+type DecisionCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Observe counts d.
+func (c *DecisionCounter) Observe(_ string, d Decision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[d.Kind.String()]++
+}
+
+// Counts returns the number of decisions of every kind observed, by the
+// names of the kinds.
+func (c *DecisionCounter) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int, len(c.counts))
+	for k, n := range c.counts {
+		counts[k] = n
+	}
+	return counts
+}
+
+// Reset forgets the decisions observed.
+func (c *DecisionCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+}
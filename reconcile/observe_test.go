@@ -0,0 +1,96 @@
+package reconcile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffObserver(t *testing.T) {
+	current := map[string]interface{}{
+		"clean": "1", "map": map[string]interface{}{"a": 1.0}, "dirty": "1", "gone": "1", "paused": "1",
+		"other/x": "1", "other/gone": "1",
+	}
+	desired := map[string]interface{}{
+		"clean": "1", "map": map[string]interface{}{"a": 1.0}, "dirty": "2", "added": "1", "paused": "2",
+		"other/x": "2", "other/y": "1",
+	}
+	notOther := func(o *options) { o.keep = func(key string) bool { return !strings.HasPrefix(key, "other/") } }
+	paused := WithPause(func(key string) bool { return key == "paused" })
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]Decision
+	}{
+		{
+			name: "decisions",
+			opts: []Option{notOther, paused},
+			want: map[string]Decision{
+				"clean":      {Kind: Clean, Method: "string"},
+				"map":        {Kind: Clean, Method: "deep-equal"},
+				"dirty":      {Kind: Dirty, Method: "string", Reason: "string mismatch"},
+				"added":      {Kind: MissingInCurrent, Reason: "current state doesn't have added doesn't exist"},
+				"gone":       {Kind: MissingInDesired, Reason: "currentValue is with key gone marked for deletion"},
+				"paused":     {Kind: Paused, Method: "string", Reason: "string mismatch"},
+				"other/x":    {Kind: FilteredOut},
+				"other/y":    {Kind: FilteredOut},
+				"other/gone": {Kind: FilteredOut},
+			},
+		},
+		{
+			name: "grace and touches",
+			opts: []Option{WithDeleteGrace(time.Minute), WithDeleteCandidates(NewDeleteCandidates()), at(t0),
+				WithTouch(func(key string, _ interface{}) bool { return key == "clean" })},
+			want: map[string]Decision{
+				"clean":      {Kind: Clean, Method: "string", Reason: "clean is in sync and needs a touch"},
+				"map":        {Kind: Clean, Method: "deep-equal"},
+				"dirty":      {Kind: Dirty, Method: "string", Reason: "string mismatch"},
+				"added":      {Kind: MissingInCurrent, Reason: "current state doesn't have added doesn't exist"},
+				"gone":       {Kind: PendingDelete, Reason: "pending delete (60s remaining)"},
+				"paused":     {Kind: Dirty, Method: "string", Reason: "string mismatch"},
+				"other/x":    {Kind: Dirty, Method: "string", Reason: "string mismatch"},
+				"other/y":    {Kind: MissingInCurrent, Reason: "current state doesn't have other/y doesn't exist"},
+				"other/gone": {Kind: PendingDelete, Reason: "pending delete (60s remaining)"},
+			},
+		},
+	}
+	for _, test := range tests {
+		for _, lists := range []bool{false, true} {
+			got := make(map[string]Decision)
+			var c DecisionCounter
+			observe := WithDiffObserver(func(key string, d Decision) {
+				if _, ok := got[key]; ok {
+					t.Logf("%s: %s was observed twice", test.name, key)
+					t.Fail()
+				}
+				got[key] = d
+				c.Observe(key, d)
+			})
+			var cur State = NewMapState(current)
+			if lists {
+				cur = &listState{MapState: NewMapState(current)}
+			}
+			NewChangeSet(cur, NewMapState(desired), append(test.opts, observe)...)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Logf("%s, listing %v: was expecting\n%v\ngot\n%v", test.name, lists, test.want, got)
+				t.Fail()
+			}
+			if n := c.Counts()[Clean.String()]; n != 2 {
+				t.Logf("%s: was expecting 2 clean keys counted got %v", test.name, c.Counts())
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestDiffObserverSums(t *testing.T) {
+	current := &listState{MapState: NewMapState(map[string]interface{}{"a": "1"}), sums: true}
+	var got Decision
+	NewChangeSet(current, NewMapState(map[string]interface{}{"a": "1"}), WithDiffObserver(func(_ string, d Decision) { got = d }))
+	if got != (Decision{Kind: Clean, Method: "sum"}) {
+		t.Logf("was expecting a to be clean by its sum got %+v", got)
+		t.Fail()
+	}
+}
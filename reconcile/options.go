@@ -0,0 +1,192 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"time"
+)
+
+type options struct {
+	verbose bool
+	verify  bool
+	cas     bool
+	redact  func(key string, v interface{}) interface{}
+	marshal func(v interface{}) ([]byte, error)
+	// diffLimit caps the size of the text diffs of WithValueDiff.
+	diffLimit int
+	required  []string
+	hook      func(Update)
+	// deleteGrace delays deletions, candidates remembers when they
+	// were planned and now is the time of the pass.
+	deleteGrace time.Duration
+	candidates  DeleteCandidates
+	now         func() time.Time
+	dryRun      bool
+	// keep filters the keys that are compared, see WithShard and
+	// WithKeys.
+	keep func(key string) bool
+	// transform turns desired values into the ones applied and
+	// currentTransform current values into the ones compared.
+	transform        func(key string, v interface{}) (interface{}, error)
+	currentTransform func(key string, v interface{}) (interface{}, error)
+	status           StatusStore
+	touch            func(key string, current interface{}) bool
+	paused           func(key string) bool
+	confirmTeardown  bool
+	observe          func(key string, d Decision)
+	recoverPanics    bool
+	// inSync is given the keys found in sync with their current values,
+	// or sums when they were listed with only one, see trackInSync.
+	inSync func(kv KV)
+}
+
+func newOptions(opts []Option) options {
+	o := options{diffLimit: 4096, now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Option configures Reconcile and ApplyTo.
+type Option func(*options)
+
+// Verbose logs every update as it is applied.
+func Verbose() Option {
+	return func(o *options) { o.verbose = true }
+}
+
+// Verify checks that the target has the value the reference state had
+// for a key before updating it and skips the keys where it doesn't.
+// Without it a ChangeSet applied to a target that diverged from the
+// reference adds, overwrites and deletes as if it hadn't; such targets
+// need their own ChangeSet.
+func Verify() Option {
+	return func(o *options) { o.verify = true }
+}
+
+// WithCompareAndSwap makes fix read every key again right before it is
+// changed and skip the ones whose value is no longer the one it was
+// compared with, they are reported as Conflicts. States that implement
+// CASer do the check themselves.
+func WithCompareAndSwap(enabled bool) Option {
+	return func(o *options) { o.cas = enabled }
+}
+
+// WithRedactor sets a function that replaces the values Dump writes,
+// like the ones of keys holding secrets.
+func WithRedactor(redact func(key string, v interface{}) interface{}) Option {
+	return func(o *options) { o.redact = redact }
+}
+
+// WithValueDiff sets a function that marshals values to text so the
+// updates of dirty keys carry a unified diff of their values. A value
+// that fails to marshal leaves the update without one.
+func WithValueDiff(marshal func(v interface{}) ([]byte, error)) Option {
+	return func(o *options) { o.marshal = marshal }
+}
+
+// WithValueDiffLimit caps the size of the diffs of WithValueDiff in
+// bytes, it is 4096 by default. Longer diffs are cut at a line.
+func WithValueDiffLimit(n int) Option {
+	return func(o *options) { o.diffLimit = n }
+}
+
+// WithRequiredAnnotation rejects the additions and updates of values
+// that don't have the annotation name, see Annotated.
+func WithRequiredAnnotation(name string) Option {
+	return func(o *options) { o.required = append(o.required, name) }
+}
+
+// WithApplyHook sets a function that is called with every update after
+// it is applied.
+func WithApplyHook(f func(Update)) Option {
+	return func(o *options) { o.hook = f }
+}
+
+// WithDeleteGrace delays the deletion of keys until they have been
+// missing from the desired state for d, the updates of the ones that
+// haven't are PendingDelete. When keys went missing is kept by the
+// WithDeleteCandidates store, by current if it implements
+// DeleteCandidates or by the Runner; without one nothing is deleted.
+func WithDeleteGrace(d time.Duration) Option {
+	return func(o *options) { o.deleteGrace = d }
+}
+
+// WithDeleteCandidates sets the store of WithDeleteGrace.
+func WithDeleteCandidates(c DeleteCandidates) Option {
+	return func(o *options) { o.candidates = c }
+}
+
+// WithServerDryRun makes fix validate the updates with the dry run
+// methods of states that implement DryRunnable and report the ones the
+// backend refuses as Rejected. Nothing is applied, flushed or passed to
+// the WithApplyHook; the updates of other states are only listed.
+func WithServerDryRun(enabled bool) Option {
+	return func(o *options) { o.dryRun = enabled }
+}
+
+// WithShard makes the plan only cover the keys of shard index of total,
+// the other keys are neither compared nor deleted. Keys are assigned to
+// shards by a jump consistent hash of hash(key), FNV-1a if hash is nil,
+// so changing total moves as few keys as possible.
+func WithShard(index, total int, hash func(key string) uint64) Option {
+	if total < 1 || index < 0 || index >= total {
+		panic(fmt.Sprintf("reconcile: shard %d of %d is out of range", index, total))
+	}
+	if hash == nil {
+		hash = fnv1a
+	}
+	return func(o *options) {
+		o.keep = func(key string) bool { return jumpHash(hash(key), total) == index }
+	}
+}
+
+// WithKeys makes the plan only cover the keys match matches, the other
+// keys are neither compared nor deleted. It filters the same keys as
+// WithShard, the last of the two is the one used.
+func WithKeys(match func(key string) bool) Option {
+	return func(o *options) { o.keep = match }
+}
+
+// WithTransform sets a function that turns the desired value of a key
+// into the value added or updated, like expanding the name of a secret
+// into the secret. It is called right before the change is made, the
+// plan and the comparisons keep the desired value; a key whose
+// transform fails is Rejected.
+func WithTransform(f func(key string, desired interface{}) (interface{}, error)) Option {
+	return func(o *options) { o.transform = f }
+}
+
+// WithCurrentTransform sets a function that turns the current value of
+// a key into the one compared with the desired value, so values applied
+// with WithTransform can be compared with what they were made from. A
+// key whose transform fails is updated.
+func WithCurrentTransform(f func(key string, current interface{}) (interface{}, error)) Option {
+	return func(o *options) { o.currentTransform = f }
+}
+
+// WithStatusStore records the outcome of every key of a pass in s, so
+// when keys were last in sync can be served from it. Dry runs aren't
+// recorded.
+func WithStatusStore(s StatusStore) Option {
+	return func(o *options) { o.status = s }
+}
+
+// WithTouch makes the keys in sync touch says need it be touched, like
+// resources whose lease has to be renewed: states that implement
+// Toucher are called, the others updated with the value they have.
+// Touches are the Touched of the Result, not its Updated.
+func WithTouch(touch func(key string, current interface{}) bool) Option {
+	return func(o *options) { o.touch = touch }
+}
+
+// WithPause makes fix skip the updates of the keys paused says are
+// paused, they are the Paused of the Result. The keys are still
+// compared so the plan shows how they drift, see Runner.Pause.
+func WithPause(paused func(key string) bool) Option {
+	return func(o *options) { o.paused = paused }
+}
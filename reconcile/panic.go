@@ -0,0 +1,92 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic of a State, a value or a function of an option
+// that WithRecoverPanics recovered.
+type PanicError struct {
+	// Key is the key the panic was about, empty for the panics of a
+	// state's Walk or Flush.
+	Key string
+	// Value is what was passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+	return fmt.Sprintf("%s: panic: %v", e.Key, e.Value)
+}
+
+// Unwrap returns the value of the panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithRecoverPanics recovers the panics of the states, of the values
+// they compare and of the functions given to the other options. A key
+// whose comparison or update panics is one of the Panicked of the
+// Result, the others are reconciled; a panic walking or flushing a
+// state is the Err of the Result, nothing is applied if it happens
+// while planning. Runners recover panics unless their Options say
+// otherwise, Reconcile, ApplyTo and Teardown don't.
+func WithRecoverPanics(enabled bool) Option {
+	return func(o *options) { o.recoverPanics = enabled }
+}
+
+// recovered passes the panic it recovers to f as a PanicError of key if
+// WithRecoverPanics is set, it must be deferred.
+func (o options) recovered(key string, f func(err *PanicError)) {
+	if !o.recoverPanics {
+		return
+	}
+	if v := recover(); v != nil {
+		f(&PanicError{Key: key, Value: v, Stack: debug.Stack()})
+	}
+}
+
+// recoverKey adds the panic comparing key it recovers to updates, it
+// must be deferred.
+func (o options) recoverKey(key string, updates *[]update) {
+	if !o.recoverPanics {
+		return
+	}
+	if v := recover(); v != nil {
+		err := &PanicError{Key: key, Value: v, Stack: debug.Stack()}
+		*updates = append(*updates, update{key: key, state: panicked, why: err.Error(), panic: err})
+	}
+}
+
+// planRecovered is plan, a panic while planning is its error.
+func planRecovered(current, desired State, o options) (updates []update, err error) {
+	defer o.recovered("", func(p *PanicError) { updates, err = nil, p })
+	return plan(current, desired, o), nil
+}
+
+// fixRecovered applies update like fix, a panic applying it makes its
+// key Panicked. A key whose hook panics was applied.
+func (r *Result) fixRecovered(current State, u update, o options, cas CASer, native bool) {
+	defer o.recovered(u.key, func(err *PanicError) {
+		r.panicked(err)
+		r.track(o, u.key, "Panicked", err, false, nil)
+	})
+	r.fixKey(current, u, o, cas, native)
+}
+
+func (r *Result) panicked(err *PanicError) {
+	if r.Panicked == nil {
+		r.Panicked = make(map[string]*PanicError)
+	}
+	r.Panicked[err.Key] = err
+}
@@ -0,0 +1,128 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// panicState is a MapState that panics on the calls of panics, by the
+// name of the method and the key, "*" for Walk and Flush.
+type panicState struct {
+	*MapState
+	panics map[string]string
+}
+
+func (s *panicState) panicOn(method, key string) {
+	if s.panics[method] == key {
+		panic(fmt.Sprintf("%s %s", method, key))
+	}
+}
+
+func (s *panicState) Get(key string) interface{} {
+	s.panicOn("Get", key)
+	return s.MapState.Get(key)
+}
+
+func (s *panicState) Update(key string, v interface{}) {
+	s.panicOn("Update", key)
+	s.MapState.Update(key, v)
+}
+
+func (s *panicState) Walk(f StateWalkFunc) {
+	s.panicOn("Walk", "*")
+	s.MapState.Walk(f)
+}
+
+func (s *panicState) Flush() error {
+	s.panicOn("Flush", "*")
+	return nil
+}
+
+// panicSum is a value whose Sum panics.
+type panicSum struct{}
+
+func (panicSum) Sum() []byte { panic(errNoSum) }
+
+func panickedKeys(r Result) string {
+	var keys []string
+	for k, err := range r.Panicked {
+		if err.Key != k || len(err.Stack) == 0 {
+			keys = append(keys, "bad:"+k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func TestRecoverPanics(t *testing.T) {
+	current := map[string]interface{}{"get": "1", "update": "1", "sum": panicSum{}}
+	desired := map[string]interface{}{"get": "2", "update": "2", "sum": panicSum{}, "hook": "1", "added": "1"}
+	hook := WithApplyHook(func(u Update) {
+		if u.Key == "hook" {
+			panic("hook")
+		}
+	})
+	tests := []struct {
+		name     string
+		panics   map[string]string
+		panicked string
+		added    []string
+		err      string
+	}{
+		{name: "keys", panics: map[string]string{"Get": "get", "Update": "update"},
+			panicked: "get,hook,sum,update", added: []string{"added", "hook"}},
+		{name: "walk", panics: map[string]string{"Walk": "*"}, err: "panic: Walk *"},
+		{name: "flush", panics: map[string]string{"Flush": "*"},
+			panicked: "hook,sum", added: []string{"added", "hook"}, err: "panic: Flush *"},
+	}
+	for _, test := range tests {
+		s := &panicState{MapState: NewMapState(current), panics: test.panics}
+		r := Reconcile(s, NewMapState(desired), false, hook, WithRecoverPanics(true))
+		sort.Strings(r.Added)
+		if got := panickedKeys(r); got != test.panicked || !reflect.DeepEqual(r.Added, test.added) {
+			t.Logf("%s: was expecting %q panicked and %v added got %q and %v", test.name, test.panicked, test.added, got, r.Added)
+			t.Fail()
+		}
+		var err *PanicError
+		if test.err == "" && r.Err != nil || test.err != "" && (!errors.As(r.Err, &err) || err.Error() != test.err) {
+			t.Logf("%s: was expecting the error %q got %v", test.name, test.err, r.Err)
+			t.Fail()
+		}
+		if len(r.Rejected) > 0 || r.ExitCode() != 1 {
+			t.Logf("%s: was expecting panics to fail without rejections got %+v", test.name, r)
+			t.Fail()
+		}
+	}
+	if err := (&PanicError{Key: "sum", Value: errNoSum}); !errors.Is(err, errNoSum) || err.Error() != "sum: panic: no sum" {
+		t.Logf("was expecting the panic to wrap its error got %v", err)
+		t.Fail()
+	}
+}
+
+var errNoSum = errors.New("no sum")
+
+func TestPanicsWithoutRecovery(t *testing.T) {
+	defer func() {
+		if v := recover(); v != "Update update" {
+			t.Logf("was expecting the panic of Update got %v", v)
+			t.Fail()
+		}
+	}()
+	s := &panicState{MapState: NewMapState(map[string]interface{}{"update": "1"}), panics: map[string]string{"Update": "update"}}
+	Reconcile(s, NewMapState(map[string]interface{}{"update": "2"}), false)
+}
+
+func TestRunnerRecoversPanics(t *testing.T) {
+	s := &panicState{MapState: NewMapState(map[string]interface{}{"update": "1"}), panics: map[string]string{"Update": "update"}}
+	r := &Runner{Current: s, Desired: NewMapState(map[string]interface{}{"update": "2", "added": "1"})}
+	if got := r.pass(); panickedKeys(got) != "update" || len(got.Added) != 1 {
+		t.Logf("was expecting update to panic and added to be added got %+v", got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Patch is a change to a key of a state like the Updates of a
+// ChangeSet, with the value it sets.
+type Patch struct {
+	Key string `json:"key"`
+	// Action is Add, Update or Delete.
+	Action string `json:"action"`
+	// Value is the value of additions and updates.
+	Value interface{} `json:"value,omitempty"`
+}
+
+var (
+	// ErrKeyExists is the error of patches adding a key that exists.
+	ErrKeyExists = errors.New("key exists")
+	// ErrKeyNotFound is the error of patches updating or deleting a key
+	// that doesn't exist.
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// PatchError is the error ApplyPatches returns for a patch that can't be
+// applied.
+type PatchError struct {
+	// Index is the index of the patch in the patches.
+	Index int
+	Patch Patch
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch %d: %s %s: %v", e.Index, e.Patch.Action, e.Patch.Key, e.Err)
+}
+
+func (e *PatchError) Unwrap() error { return e.Err }
+
+type patchOptions struct {
+	lenient bool
+}
+
+// PatchOption configures ApplyPatches.
+type PatchOption func(*patchOptions)
+
+// LenientPatches makes additions of keys that exist update them,
+// updates of keys that don't exist add them and deletions of keys that
+// don't exist do nothing.
+func LenientPatches() PatchOption {
+	return func(o *patchOptions) { o.lenient = true }
+}
+
+// ApplyPatches returns the state base becomes with patches applied in
+// order, base isn't changed. A patch that adds a key that exists or
+// updates or deletes one that doesn't is a *PatchError, unless the
+// patches are LenientPatches.
+func ApplyPatches(base State, patches []Patch, opts ...PatchOption) (State, error) {
+	var o patchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &MapState{}
+	base.Walk(func(key string, v interface{}) { s.Add(key, v) })
+	for i, p := range patches {
+		if err := applyPatch(s, p, o); err != nil {
+			return nil, &PatchError{Index: i, Patch: p, Err: err}
+		}
+	}
+	return s, nil
+}
+
+func applyPatch(s *MapState, p Patch, o patchOptions) error {
+	exists := s.Get(p.Key) != nil
+	switch p.Action {
+	case new.String(), dirty.String():
+		if p.Value == nil {
+			return errors.New("missing value")
+		}
+		if p.Action == new.String() && exists && !o.lenient {
+			return ErrKeyExists
+		}
+		if p.Action == dirty.String() && !exists && !o.lenient {
+			return ErrKeyNotFound
+		}
+		s.Add(p.Key, p.Value)
+	case old.String():
+		if !exists && !o.lenient {
+			return ErrKeyNotFound
+		}
+		s.Delete(p.Key)
+	default:
+		return fmt.Errorf("unknown action %q", p.Action)
+	}
+	return nil
+}
+
+// Patches returns the updates of c as patches, ApplyPatches of them to
+// the state c was planned from is the desired state. Pending deletions
+// aren't changes and are left out.
+func (c *ChangeSet) Patches() []Patch {
+	var patches []Patch
+	for _, u := range c.updates {
+		switch u.state {
+		case new, dirty:
+			patches = append(patches, Patch{Key: u.key, Action: u.state.String(), Value: u.v})
+		case old:
+			patches = append(patches, Patch{Key: u.key, Action: u.state.String()})
+		}
+	}
+	return patches
+}
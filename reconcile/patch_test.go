@@ -0,0 +1,146 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestApplyPatches(t *testing.T) {
+	base := NewMapState(map[string]interface{}{"a": "1", "b": "1"})
+	tests := []struct {
+		name    string
+		patches []Patch
+		lenient bool
+		want    map[string]interface{}
+		err     error
+		index   int
+	}{
+		{
+			name: "in order",
+			patches: []Patch{
+				{Key: "c", Action: "Add", Value: "1"},
+				{Key: "a", Action: "Update", Value: "2"},
+				{Key: "b", Action: "Delete"},
+				{Key: "c", Action: "Update", Value: "3"},
+			},
+			want: map[string]interface{}{"a": "2", "c": "3"},
+		},
+		{
+			name:    "add existing",
+			patches: []Patch{{Key: "c", Action: "Add", Value: "1"}, {Key: "a", Action: "Add", Value: "2"}},
+			err:     ErrKeyExists, index: 1,
+		},
+		{
+			name:    "update missing",
+			patches: []Patch{{Key: "c", Action: "Update", Value: "1"}},
+			err:     ErrKeyNotFound,
+		},
+		{
+			name:    "delete deleted",
+			patches: []Patch{{Key: "a", Action: "Delete"}, {Key: "a", Action: "Delete"}},
+			err:     ErrKeyNotFound, index: 1,
+		},
+		{
+			name: "lenient",
+			patches: []Patch{
+				{Key: "a", Action: "Add", Value: "2"},
+				{Key: "c", Action: "Update", Value: "1"},
+				{Key: "d", Action: "Delete"},
+			},
+			lenient: true,
+			want:    map[string]interface{}{"a": "2", "b": "1", "c": "1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []PatchOption
+			if test.lenient {
+				opts = append(opts, LenientPatches())
+			}
+			s, err := ApplyPatches(base, test.patches, opts...)
+			if test.err != nil {
+				var perr *PatchError
+				if !errors.Is(err, test.err) || !errors.As(err, &perr) || perr.Index != test.index {
+					t.Logf("got %v, expected %v at patch %d", err, test.err, test.index)
+					t.Fail()
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c := NewChangeSet(s, NewMapState(test.want)); c.Len() != 0 {
+				t.Logf("the patched state is off by\n%s", c)
+				t.Fail()
+			}
+		})
+	}
+	if base.Len() != 2 || base.Get("a") != "1" {
+		t.Logf("the base changed to %v", base.Keys())
+		t.Fail()
+	}
+}
+
+func TestApplyPatchesInvalid(t *testing.T) {
+	base := &MapState{}
+	for _, p := range []Patch{{Key: "a", Action: "Upsert", Value: "1"}, {Key: "a", Action: "Add"}} {
+		if _, err := ApplyPatches(base, []Patch{p}, LenientPatches()); err == nil {
+			t.Logf("%+v was applied", p)
+			t.Fail()
+		}
+	}
+}
+
+// TestPatchesRoundTrip checks that the ChangeSet from a state to the
+// state patches make of it is the patches.
+func TestPatchesRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		base := &MapState{}
+		for k := 0; k < r.Intn(20); k++ {
+			base.Add(fmt.Sprintf("key/%d", r.Intn(30)), r.Intn(3))
+		}
+		// at most one patch per key, the ChangeSet can't tell the
+		// patches of a key apart
+		var patches []Patch
+		for k := 0; k < 30; k++ {
+			key := fmt.Sprintf("key/%d", k)
+			v := base.Get(key)
+			switch n := r.Intn(4); {
+			case v == nil && n == 0:
+				patches = append(patches, Patch{Key: key, Action: "Add", Value: r.Intn(3)})
+			case v != nil && n == 0:
+				patches = append(patches, Patch{Key: key, Action: "Update", Value: v.(int) + 1 + r.Intn(3)})
+			case v != nil && n == 1:
+				patches = append(patches, Patch{Key: key, Action: "Delete"})
+			}
+		}
+		r.Shuffle(len(patches), func(i, j int) { patches[i], patches[j] = patches[j], patches[i] })
+		desired, err := ApplyPatches(base, patches)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := NewChangeSet(base, desired).Patches()
+		sortPatches(got)
+		sortPatches(patches)
+		if len(got) != len(patches) || len(got) > 0 && !reflect.DeepEqual(got, patches) {
+			t.Fatalf("round %d: got\n%v\nexpected\n%v", i, got, patches)
+		}
+		// and applying them gets the same state
+		again, err := ApplyPatches(base, got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := NewChangeSet(again, desired); c.Len() != 0 {
+			t.Fatalf("round %d: the patches of the ChangeSet are off by\n%s", i, c)
+		}
+	}
+}
+
+func sortPatches(patches []Patch) {
+	sort.Slice(patches, func(i, j int) bool { return patches[i].Key < patches[j].Key })
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// PauseStore is implemented by the StatusStores that keep the pauses of
+// a Runner, so they survive restarts. The stores of NewStatusStore and
+// NewFileStatusStore do.
+type PauseStore interface {
+	Pauses() []string
+	SetPauses(patterns []string)
+}
+
+// matchPause reports whether key is paused by pattern, a glob like
+// path.Match's if it has *, ? or [ and a prefix of the keys otherwise.
+func matchPause(pattern, key string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	}
+	return strings.HasPrefix(key, pattern)
+}
+
+// Pause stops the passes of r applying the keys pattern matches, a key
+// prefix or a glob like path.Match's. Paused keys are still compared,
+// their updates are the Paused of the Result. Pauses are kept in the
+// WithStatusStore of the Options if it is a PauseStore.
+func (r *Runner) Pause(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	patterns := r.loadPauses()
+	i := sort.SearchStrings(patterns, pattern)
+	if i < len(patterns) && patterns[i] == pattern {
+		return nil
+	}
+	patterns = append(patterns, "")
+	copy(patterns[i+1:], patterns[i:])
+	patterns[i] = pattern
+	return r.savePauses(patterns)
+}
+
+// Resume undoes the Pause of pattern, keys other patterns match stay
+// paused.
+func (r *Runner) Resume(pattern string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	patterns := r.loadPauses()
+	i := sort.SearchStrings(patterns, pattern)
+	if i == len(patterns) || patterns[i] != pattern {
+		return nil
+	}
+	return r.savePauses(append(patterns[:i:i], patterns[i+1:]...))
+}
+
+// Paused returns the patterns of the keys r doesn't apply, sorted.
+func (r *Runner) Paused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.loadPauses()...)
+}
+
+// paused reports whether key is paused, it is the WithPause of the
+// passes.
+func (r *Runner) paused(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.loadPauses() {
+		if matchPause(p, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// pauseStore returns the PauseStore of the Options, if any.
+func (r *Runner) pauseStore() PauseStore {
+	s, _ := newOptions(r.Options).status.(PauseStore)
+	return s
+}
+
+// loadPauses returns the pauses, read from the PauseStore the first
+// time. r.mu is held.
+func (r *Runner) loadPauses() []string {
+	if !r.pausesLoaded {
+		if s := r.pauseStore(); s != nil {
+			r.pauses = s.Pauses()
+			sort.Strings(r.pauses)
+		}
+		r.pausesLoaded = true
+	}
+	return r.pauses
+}
+
+// savePauses replaces the pauses and writes them to the PauseStore,
+// flushing it if it buffers them. r.mu is held.
+func (r *Runner) savePauses(patterns []string) error {
+	r.pauses = patterns
+	s := r.pauseStore()
+	if s == nil {
+		return nil
+	}
+	s.SetPauses(patterns)
+	if f, ok := s.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package reconcile
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPause(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"payments/a": "1", "payments/b": "1", "web/lease": "1", "web/x": "1"})
+	desired := NewMapState(map[string]interface{}{"payments/a": "2", "web/lease": "2", "web/x": "2", "web/y": "1"})
+	r := &Runner{Current: current, Desired: desired}
+	for _, p := range []string{"payments/", "*/lease", "payments/"} {
+		if err := r.Pause(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Pause("[a-"); err == nil {
+		t.Log("was expecting an error pausing a bad glob")
+		t.Fail()
+	}
+	if got := r.Paused(); !reflect.DeepEqual(got, []string{"*/lease", "payments/"}) {
+		t.Logf("was expecting the two pauses got %v", got)
+		t.Fail()
+	}
+
+	result := r.pass()
+	sort.Strings(result.Paused)
+	if !reflect.DeepEqual(result.Paused, []string{"payments/a", "payments/b", "web/lease"}) || !reflect.DeepEqual(result.Updated, []string{"web/x"}) || !reflect.DeepEqual(result.Added, []string{"web/y"}) {
+		t.Logf("got %+v", result)
+		t.Fail()
+	}
+	if current.Get("payments/a") != "1" || current.Get("payments/b") != "1" || current.Get("web/lease") != "1" {
+		t.Logf("paused keys were applied: %v", current.Keys())
+		t.Fail()
+	}
+
+	if err := r.Resume("payments/"); err != nil {
+		t.Fatal(err)
+	}
+	result = r.pass()
+	if !reflect.DeepEqual(result.Paused, []string{"web/lease"}) || current.Get("payments/a") != "2" || current.Get("payments/b") != nil {
+		t.Logf("got %+v, %v", result, current.Keys())
+		t.Fail()
+	}
+}
+
+func TestPausePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status")
+	store, err := NewFileStatusStore(path, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Options: []Option{WithStatusStore(store)}}
+	if err := r.Pause("payments/"); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewFileStatusStore(path, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := NewMapState(map[string]interface{}{"payments/a": "1"})
+	restarted := &Runner{Current: current, Desired: NewMapState(nil), Options: []Option{WithStatusStore(store)}}
+	if got := restarted.Paused(); !reflect.DeepEqual(got, []string{"payments/"}) {
+		t.Fatalf("was expecting the pause to survive the restart got %v", got)
+	}
+	result := restarted.pass()
+	if current.Get("payments/a") == nil || result.Statuses["payments/a"].LastResult != "Paused" {
+		t.Logf("got %+v", result)
+		t.Fail()
+	}
+}
@@ -35,6 +35,12 @@ func (i state) String() string {
 		return "Delete"
 	case 2:
 		return "Update"
+	case 3:
+		return "PendingDelete"
+	case 4:
+		return "Touch"
+	case 5:
+		return "Panicked"
 	}
 	return ""
 }
@@ -43,11 +49,49 @@ const (
 	new state = iota
 	old
 	dirty
+	// pending are deletions waiting for their WithDeleteGrace.
+	pending
+	// touched are keys in sync that WithTouch rewrites.
+	touched
+	// panicked are keys whose comparison panicked, see
+	// WithRecoverPanics.
+	panicked
 )
 
-// Reconcile takes two states and applies updates to them until they are the same
-func Reconcile(current, desired State, verbose bool) {
-	fix(current, diff(current, desired), verbose)
+// Reconcile takes two states and applies updates to them until they are the same.
+// States that can't be reconciled, see Plan, are left alone and the
+// error is the Err of the Result. Verbose logs the updates, like the
+// Verbose option.
+func Reconcile(current, desired State, verbose bool, opts ...Option) Result {
+	if err := checkStates(current, desired); err != nil {
+		return Result{Err: err}
+	}
+	o := newOptions(opts)
+	o.verbose = o.verbose || verbose
+	var inSync []KV
+	if o.status != nil && !o.dryRun {
+		o.inSync = func(kv KV) { inSync = append(inSync, kv) }
+	}
+	updates, err := planRecovered(current, desired, o)
+	if err != nil {
+		return Result{Err: err}
+	}
+	r := fix(current, updates, o)
+	r.trackInSync(inSync, o)
+	r.flushStatus(o)
+	return r
+}
+
+// plan returns the updates that turn current into desired.
+func plan(current, desired State, o options) []update {
+	updates := diffKeys(current, desired, o)
+	if o.deleteGrace > 0 {
+		updates = graceDeletes(updates, candidatesFor(current, o), o)
+	}
+	if o.observe != nil {
+		observeUpdates(updates, o)
+	}
+	return describe(updates, o)
 }
 
 type update struct {
@@ -55,6 +99,49 @@ type update struct {
 	state state
 	v     interface{}
 	why   string
+	// was is the value the current state had when the update was
+	// planned, nil for additions.
+	was interface{}
+	// textDiff is a unified diff of was and v, see WithValueDiff.
+	textDiff string
+	// annotations are the Annotations of v.
+	annotations map[string]string
+	// method is how the values were compared, see Decision.
+	method string
+	// panic is the panic of panicked updates.
+	panic *PanicError
+}
+
+func (u update) export() Update {
+	return Update{
+		Key:         u.key,
+		Action:      u.state.String(),
+		Reason:      u.why,
+		TextDiff:    u.textDiff,
+		Annotations: u.annotations,
+	}
+}
+
+// describe adds the text diffs of WithValueDiff to the dirty updates.
+func describe(updates []update, o options) []update {
+	if o.marshal == nil {
+		return updates
+	}
+	for i, u := range updates {
+		if u.state != dirty {
+			continue
+		}
+		a, err := o.marshal(u.was)
+		if err != nil {
+			continue
+		}
+		b, err := o.marshal(u.v)
+		if err != nil {
+			continue
+		}
+		updates[i].textDiff = truncateDiff(unifiedDiff(string(a), string(b)), o.diffLimit)
+	}
+	return updates
 }
 
 // Checksumed returns a unique Hash for an object for comparison
@@ -65,74 +152,365 @@ type Checksumed interface {
 var (
 	ErrHashMismatch      = errors.New("checksum mismatch")
 	ErrDeepEqualMismatch = errors.New("reflect.Deepequal mismatch")
+	ErrStringMismatch    = errors.New("string mismatch")
 )
 
+// equaler is implemented by values that compare themselves, like the
+// ones of SyncMaps.
+type equaler interface {
+	equal(other interface{}) bool
+}
+
+var errEqualMismatch = errors.New("values aren't equal")
+
 // compare takes two interfaces and returns true if they are the same
 func compare(a, b interface{}) error {
+	_, err := compareBy(a, b)
+	return err
+}
+
+// compareBy is compare, it also returns how a and b were compared, see
+// Decision.
+func compareBy(a, b interface{}) (string, error) {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			if as != bs {
+				return "string", ErrStringMismatch
+			}
+			return "string", nil
+		}
+	}
+	a, b = withoutAnnotations(a), withoutAnnotations(b)
+	if eq, ok := a.(equaler); ok {
+		if !eq.equal(b) {
+			return "equal", errEqualMismatch
+		}
+		return "equal", nil
+	}
 	hashableA, aok := a.(Checksumed)
 	hashableB, bok := b.(Checksumed)
 	if aok && bok {
 		desiredSum := hashableA.Sum()
 		currentSum := hashableB.Sum()
 		if bytes.Compare(desiredSum, currentSum) != 0 {
-			return fmt.Errorf("%v sum=%x != %v sum=%x", a, desiredSum[:5], b, currentSum[:5])
-		}
-	} else {
-		if !reflect.DeepEqual(a, b) {
-			return ErrDeepEqualMismatch
+			return "checksum", fmt.Errorf("%v sum=%x != %v sum=%x", a, desiredSum[:5], b, currentSum[:5])
 		}
+		return "checksum", nil
 	}
-	return nil
+	if !reflect.DeepEqual(a, b) {
+		return "deep-equal", ErrDeepEqualMismatch
+	}
+	return "deep-equal", nil
 }
 
 func diff(current, desired State) []update {
+	return diffKeys(current, desired, options{})
+}
+
+// diffKeys is diff for the keys of WithShard, comparing current values
+// through the WithCurrentTransform. States that are Listers are
+// compared through their listings.
+func diffKeys(current, desired State, o options) []update {
+	if updates, ok := diffListings(current, desired, o); ok {
+		return updates
+	}
+	keep := o.keep
 	var updates []update
 	desired.Walk(func(key string, v interface{}) {
-		n := update{
-			key: key,
-			v:   v,
-		}
-		currentValue := current.Get(key)
-		if currentValue == nil {
-			n.state = new
-			n.why = fmt.Sprintf("current state doesn't have %s doesn't exist", key)
-			updates = append(updates, n)
+		defer o.recoverKey(key, &updates)
+		if keep != nil && !keep(key) {
+			o.filteredOut(key)
 			return
 		}
-		if err := compare(currentValue, v); err != nil {
-			n.state = dirty
-			n.why = err.Error()
-			updates = append(updates, n)
-			return
+		if u, ok := compareKey(key, v, current.Get(key), o); ok {
+			updates = append(updates, u)
 		}
 	})
 	current.Walk(func(key string, v interface{}) {
-		if desired.Get(key) == nil {
-			n := update{
-				key: key,
-				v:   nil,
+		defer o.recoverKey(key, &updates)
+		if keep != nil && !keep(key) {
+			if o.observe != nil && desired.Get(key) == nil {
+				o.filteredOut(key)
 			}
-			n.state = old
-			n.why = fmt.Sprintf("currentValue is with key %s marked for deletion", key)
-			updates = append(updates, n)
+			return
+		}
+		if desired.Get(key) == nil {
+			updates = append(updates, deleteKey(key, v))
 		}
 	})
 
 	return updates
 }
 
-func fix(current State, updates []update, verbose bool) {
+// compareKey returns the update that turns currentValue, the value key
+// has in the current state, into v, its desired value, if it needs one.
+func compareKey(key string, v, currentValue interface{}, o options) (update, bool) {
+	n := update{
+		key:         key,
+		v:           v,
+		annotations: annotations(v),
+	}
+	if currentValue == nil {
+		n.state = new
+		n.why = fmt.Sprintf("current state doesn't have %s doesn't exist", key)
+		return n, true
+	}
+	compared := currentValue
+	if o.currentTransform != nil {
+		t, err := o.currentTransform(key, currentValue)
+		if err != nil {
+			n.state = dirty
+			n.why = fmt.Sprintf("transforming the current value of %s: %v", key, err)
+			n.was = currentValue
+			return n, true
+		}
+		compared = t
+	}
+	method, err := compareBy(compared, v)
+	if err != nil {
+		n.state = dirty
+		n.why = err.Error()
+		n.was = currentValue
+		n.method = method
+		return n, true
+	}
+	return touchKey(key, currentValue, method, o)
+}
+
+// touchKey returns the touch of key, which is in sync by method, if
+// WithTouch says it needs one; the keys that don't are observed clean.
+func touchKey(key string, currentValue interface{}, method string, o options) (update, bool) {
+	if o.touch == nil || !o.touch(key, currentValue) {
+		if o.observe != nil {
+			o.observe(key, Decision{Kind: Clean, Method: method})
+		}
+		if o.inSync != nil {
+			o.inSync(KV{Key: key, Value: currentValue})
+		}
+		return update{}, false
+	}
+	return update{
+		key:    key,
+		state:  touched,
+		v:      currentValue,
+		was:    currentValue,
+		why:    fmt.Sprintf("%s is in sync and needs a touch", key),
+		method: method,
+	}, true
+}
+
+// filteredOut observes that key isn't compared.
+func (o options) filteredOut(key string) {
+	if o.observe != nil {
+		o.observe(key, Decision{Kind: FilteredOut})
+	}
+}
+
+// deleteKey returns the deletion of key, whose current value is v.
+func deleteKey(key string, v interface{}) update {
+	return update{
+		key:   key,
+		state: old,
+		was:   v,
+		why:   fmt.Sprintf("currentValue is with key %s marked for deletion", key),
+	}
+}
+
+// Flusher is implemented by states that buffer their changes, Reconcile
+// and ApplyTo call Flush after applying their updates.
+type Flusher interface {
+	Flush() error
+}
+
+// Closer is implemented by states that hold resources, a Runner that
+// owns its states closes them when it stops.
+type Closer interface {
+	Close() error
+}
+
+// CASer is implemented by states that can update a key only if it still
+// has the value it had when the update was planned. Old is nil for
+// additions and new is nil for deletions.
+type CASer interface {
+	CAS(key string, old, new interface{}) error
+}
+
+func fix(current State, updates []update, o options) Result {
+	var r Result
+	cas, native := current.(CASer)
+	native = native && o.cas
 	for _, update := range updates {
-		if verbose {
-			log.Printf("key:%s state:%s\n\twhy:%s\n ", update.key, update.state, update.why)
+		if o.recoverPanics {
+			r.fixRecovered(current, update, o, cas, native)
+			continue
+		}
+		r.fixKey(current, update, o, cas, native)
+	}
+	if f, ok := current.(Flusher); ok && !o.dryRun {
+		func() {
+			defer o.recovered("", func(err *PanicError) { r.Err = err })
+			r.Err = f.Flush()
+		}()
+	}
+	r.DryRun = o.dryRun
+	return r
+}
+
+// fixKey applies update to current.
+func (r *Result) fixKey(current State, update update, o options, cas CASer, native bool) {
+	if update.state == panicked {
+		r.panicked(update.panic)
+		r.track(o, update.key, "Panicked", update.panic, false, nil)
+		return
+	}
+	if update.state == pending {
+		r.Pending = append(r.Pending, update.key)
+		r.track(o, update.key, "PendingDelete", nil, false, nil)
+		return
+	}
+	if o.paused != nil && o.paused(update.key) {
+		if o.verbose {
+			log.Printf("key:%s is paused\n ", update.key)
+		}
+		r.Paused = append(r.Paused, update.key)
+		r.track(o, update.key, "Paused", nil, false, nil)
+		return
+	}
+	if o.verify && !unchanged(current, update) {
+		if o.verbose {
+			log.Printf("key:%s diverged from the planned state\n ", update.key)
+		}
+		r.Diverged = append(r.Diverged, update.key)
+		r.track(o, update.key, "Diverged", errDiverged, false, nil)
+		return
+	}
+	if o.cas && !native && !unchanged(current, update) {
+		if o.verbose {
+			log.Printf("key:%s changed since it was compared\n ", update.key)
 		}
-		switch update.state {
-		case new:
-			current.Add(update.key, update.v)
-		case old:
-			current.Delete(update.key)
-		case dirty:
-			current.Update(update.key, update.v)
+		r.Conflicts = append(r.Conflicts, update.key)
+		r.track(o, update.key, "Conflict", errConflict, false, nil)
+		return
+	}
+	if err := missingAnnotation(update, o.required); err != nil {
+		if o.verbose {
+			log.Printf("key:%s: %v\n ", update.key, err)
+		}
+		r.reject(update.key, err)
+		r.track(o, update.key, "Rejected", err, false, nil)
+		return
+	}
+	if o.verbose {
+		log.Printf("key:%s state:%s\n\twhy:%s\n%s ", update.key, update.state, update.why, update.textDiff)
+	}
+	applied, err := transform(update, o)
+	if err != nil {
+		if o.verbose {
+			log.Printf("key:%s: %v\n ", update.key, err)
+		}
+		r.reject(update.key, err)
+		r.track(o, update.key, "Rejected", err, false, nil)
+		return
+	}
+	switch {
+	case o.dryRun:
+		if dry, ok := current.(DryRunnable); ok {
+			if err := dryRun(dry, applied); err != nil {
+				if o.verbose {
+					log.Printf("key:%s: %v\n ", update.key, err)
+				}
+				r.reject(update.key, err)
+				return
+			}
 		}
+	case native:
+		if err := cas.CAS(update.key, update.was, applied.v); err != nil {
+			if o.verbose {
+				log.Printf("key:%s: %v\n ", update.key, err)
+			}
+			r.Conflicts = append(r.Conflicts, update.key)
+			r.track(o, update.key, "Conflict", err, false, nil)
+			return
+		}
+	default:
+		if err := apply(current, applied); err != nil {
+			if o.verbose {
+				log.Printf("key:%s: %v\n ", update.key, err)
+			}
+			r.reject(update.key, err)
+			r.track(o, update.key, "Rejected", err, false, nil)
+			return
+		}
+	}
+	r.record(update)
+	r.track(o, update.key, update.state.String(), nil, true, applied.v)
+	if o.hook != nil && !o.dryRun {
+		o.hook(update.export())
+	}
+}
+
+// transform returns u with the value the WithTransform makes of its
+// desired one.
+func transform(u update, o options) (update, error) {
+	if o.transform == nil || u.state == old || u.state == touched {
+		return u, nil
+	}
+	v, err := o.transform(u.key, u.v)
+	if err != nil {
+		return u, fmt.Errorf("transforming %s: %w", u.key, err)
+	}
+	u.v = v
+	return u, nil
+}
+
+// DryRunnable is implemented by states whose backend can validate a
+// change without making it, see WithServerDryRun.
+type DryRunnable interface {
+	AddDryRun(key string, v interface{}) error
+	UpdateDryRun(key string, v interface{}) error
+	DeleteDryRun(key string) error
+}
+
+func dryRun(current DryRunnable, u update) error {
+	switch u.state {
+	case new:
+		return current.AddDryRun(u.key, u.v)
+	case old:
+		return current.DeleteDryRun(u.key)
+	case dirty:
+		return current.UpdateDryRun(u.key, u.v)
+	}
+	return nil
+}
+
+// Toucher is implemented by states that can refresh a key without
+// writing its value, see WithTouch.
+type Toucher interface {
+	Touch(key string) error
+}
+
+func apply(current State, u update) error {
+	switch u.state {
+	case new:
+		current.Add(u.key, u.v)
+	case old:
+		current.Delete(u.key)
+	case dirty:
+		current.Update(u.key, u.v)
+	case touched:
+		if t, ok := current.(Toucher); ok {
+			return t.Touch(u.key)
+		}
+		current.Update(u.key, u.v)
+	}
+	return nil
+}
+
+// unchanged reports whether s still has the value u was planned against.
+func unchanged(s State, u update) bool {
+	v := s.Get(u.key)
+	if v == nil || u.was == nil {
+		return v == nil && u.was == nil
 	}
+	return compare(v, u.was) == nil
 }
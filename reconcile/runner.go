@@ -0,0 +1,146 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Runner reconciles a current state with a desired one periodically.
+type Runner struct {
+	Current, Desired State
+	// Interval is the time between two passes, or the first one if
+	// Adaptive is set.
+	Interval time.Duration
+	// Adaptive, if set, adapts the interval to how often the states
+	// drift.
+	Adaptive *AdaptiveInterval
+	// Options are the options of every pass.
+	Options []Option
+	// OnResult, if set, is called with the result of every pass.
+	OnResult func(Result)
+	// OwnsStates makes Run close the states that implement Closer
+	// when it returns.
+	OwnsStates bool
+
+	// candidates is the store of WithDeleteGrace for states that don't
+	// have their own.
+	candidates DeleteCandidates
+
+	mu       sync.Mutex
+	interval time.Duration
+	drift    float64
+	trigger  chan struct{}
+	// pauses are the patterns of Pause, sorted, read from the
+	// PauseStore once pausesLoaded is set.
+	pauses       []string
+	pausesLoaded bool
+}
+
+// CurrentInterval returns the time until the next pass, which is Interval
+// unless Adaptive changed it.
+func (r *Runner) CurrentInterval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.interval == 0 {
+		return r.Interval
+	}
+	return r.interval
+}
+
+// Drift returns the moving average of the passes that changed the
+// current state, between 0 and 1; it is only kept with Adaptive.
+func (r *Runner) Drift() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.drift
+}
+
+// Trigger makes Run pass right away instead of waiting for the
+// interval. Triggers while a pass is running make one pass after it.
+func (r *Runner) Trigger() {
+	select {
+	case r.triggers() <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Runner) triggers() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.trigger == nil {
+		r.trigger = make(chan struct{}, 1)
+	}
+	return r.trigger
+}
+
+// Run reconciles the states right away and then every Interval, or on
+// Trigger, until ctx is done. It returns the context's error, or the
+// error closing the states if that failed.
+func (r *Runner) Run(ctx context.Context) error {
+	trigger := r.triggers()
+	for {
+		t := time.NewTimer(r.adapt(r.pass()))
+		select {
+		case <-trigger:
+			t.Stop()
+		case <-ctx.Done():
+			t.Stop()
+			if r.OwnsStates {
+				if err := r.close(); err != nil {
+					return err
+				}
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// adapt returns the interval until the pass after one with result.
+func (r *Runner) adapt(result Result) time.Duration {
+	if r.Adaptive == nil {
+		return r.Interval
+	}
+	a := r.Adaptive.withDefaults(r.Interval)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.interval == 0 {
+		r.interval = r.Interval
+	}
+	r.interval, r.drift = a.next(r.interval, r.drift, result.changed())
+	return r.interval
+}
+
+func (r *Runner) pass() Result {
+	if r.candidates == nil {
+		r.candidates = NewDeleteCandidates()
+	}
+	opts := append([]Option{WithRecoverPanics(true), WithPause(r.paused)}, r.Options...)
+	if _, ok := r.Current.(DeleteCandidates); !ok {
+		opts = append([]Option{WithDeleteCandidates(r.candidates)}, opts...)
+	}
+	result := Reconcile(r.Current, r.Desired, false, opts...)
+	if r.OnResult != nil {
+		r.OnResult(result)
+	}
+	return result
+}
+
+// close closes the states that implement Closer and returns the first
+// error.
+func (r *Runner) close() error {
+	var first error
+	for _, s := range []State{r.Current, r.Desired} {
+		if c, ok := s.(Closer); ok {
+			if err := c.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
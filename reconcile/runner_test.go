@@ -0,0 +1,96 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// lifecycleState is a MapState that records its Flush and Close calls.
+type lifecycleState struct {
+	MapState
+	flushErr, closeErr error
+	flushes, closes    int
+}
+
+func (s *lifecycleState) Flush() error {
+	s.flushes++
+	return s.flushErr
+}
+
+func (s *lifecycleState) Close() error {
+	s.closes++
+	return s.closeErr
+}
+
+func TestFlush(t *testing.T) {
+	errDisk := errors.New("disk full")
+	tests := []struct {
+		name    string
+		current *lifecycleState
+		err     error
+	}{
+		{name: "flushed", current: &lifecycleState{}},
+		{name: "failed", current: &lifecycleState{flushErr: errDisk}, err: errDisk},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			desired := NewMapState(map[string]interface{}{"a": "1"})
+			r := Reconcile(test.current, desired, false)
+			if r.Err != test.err {
+				t.Logf("got %v, expected %v", r.Err, test.err)
+				t.Fail()
+			}
+			if test.current.flushes != 1 || test.current.Get("a") != "1" {
+				t.Logf("flushed %d times with state %v", test.current.flushes, test.current.Keys())
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestRunner(t *testing.T) {
+	errClose := errors.New("close failed")
+	tests := []struct {
+		name   string
+		owns   bool
+		closes int
+		err    error
+	}{
+		{name: "borrowed", err: context.Canceled},
+		{name: "owned", owns: true, closes: 1, err: context.Canceled},
+		{name: "close error", owns: true, closes: 1, err: errClose},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := &lifecycleState{}
+			if test.err == errClose {
+				current.closeErr = errClose
+			}
+			desired := NewMapState(map[string]interface{}{"a": "1"})
+			ctx, cancel := context.WithCancel(context.Background())
+			passes := 0
+			r := &Runner{
+				Current:  current,
+				Desired:  desired,
+				Interval: time.Millisecond,
+				OnResult: func(Result) {
+					passes++
+					if passes == 3 {
+						cancel()
+					}
+				},
+				OwnsStates: test.owns,
+			}
+			if err := r.Run(ctx); err != test.err {
+				t.Logf("got %v, expected %v", err, test.err)
+				t.Fail()
+			}
+			if passes != 3 || current.flushes != 3 || current.closes != test.closes {
+				t.Logf("got %d passes, %d flushes and %d closes", passes, current.flushes, current.closes)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package reconcile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShards(t *testing.T) {
+	desired := &MapState{}
+	for i := 0; i < 1000; i++ {
+		desired.Add(fmt.Sprintf("key/%d", i), "v")
+	}
+	// every key is added by exactly one of the shards
+	owners := make(map[string]int)
+	for shard := 0; shard < 3; shard++ {
+		current := &MapState{}
+		r := Reconcile(current, desired, false, WithShard(shard, 3, nil))
+		for _, k := range r.Added {
+			owners[k]++
+		}
+		if n := len(r.Added); n < 250 || n > 420 {
+			t.Logf("shard %d got %d of 1000 keys", shard, n)
+			t.Fail()
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		if k := fmt.Sprintf("key/%d", i); owners[k] != 1 {
+			t.Logf("%s was added by %d shards", k, owners[k])
+			t.Fail()
+		}
+	}
+}
+
+func TestShardsDontPrune(t *testing.T) {
+	current := &MapState{}
+	for i := 0; i < 20; i++ {
+		current.Add(fmt.Sprintf("key/%d", i), "v")
+	}
+	desired := &MapState{}
+	r := Reconcile(current, desired, false, WithShard(0, 4, nil))
+	for _, k := range r.Deleted {
+		if jumpHash(fnv1a(k), 4) != 0 {
+			t.Logf("shard 0 deleted %s of shard %d", k, jumpHash(fnv1a(k), 4))
+			t.Fail()
+		}
+	}
+	// the keys of the other shards are still there
+	left := 0
+	current.Walk(func(key string, v interface{}) {
+		if jumpHash(fnv1a(key), 4) == 0 {
+			t.Logf("shard 0 didn't delete %s", key)
+			t.Fail()
+		}
+		left++
+	})
+	if left+len(r.Deleted) != 20 || left == 0 || len(r.Deleted) == 0 {
+		t.Logf("shard 0 deleted %d and left %d of 20 keys", len(r.Deleted), left)
+		t.Fail()
+	}
+}
+
+func TestWithKeys(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"web/a": "1", "web/old": "1", "db/old": "1"})
+	desired := NewMapState(map[string]interface{}{"web/a": "2", "db/new": "1"})
+	r := Reconcile(current, desired, false, WithKeys(func(key string) bool { return strings.HasPrefix(key, "web/") }))
+	if len(r.Added) != 0 || !reflect.DeepEqual(r.Updated, []string{"web/a"}) || !reflect.DeepEqual(r.Deleted, []string{"web/old"}) {
+		t.Logf("was expecting only the web keys to be reconciled got %+v", r)
+		t.Fail()
+	}
+	if current.Get("db/old") == nil {
+		t.Log("db/old was deleted")
+		t.Fail()
+	}
+}
+
+func TestJumpHashMovesFewKeys(t *testing.T) {
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		h := fnv1a(fmt.Sprintf("key/%d", i))
+		if jumpHash(h, 4) != jumpHash(h, 5) {
+			moved++
+		}
+	}
+	// about a fifth of the keys move to the new shard
+	if moved < 120 || moved > 280 {
+		t.Logf("%d of 1000 keys moved going from 4 to 5 shards", moved)
+		t.Fail()
+	}
+}
+
+func TestWithShardRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Log("shard 3 of 3 didn't panic")
+			t.Fail()
+		}
+	}()
+	WithShard(3, 3, nil)
+}
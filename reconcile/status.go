@@ -0,0 +1,252 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyStatus is what a StatusStore knows of a key, see WithStatusStore.
+type KeyStatus struct {
+	// LastSyncTime is when the key was last applied or found in sync.
+	LastSyncTime time.Time
+	// LastResult is the outcome of the last pass that planned the key:
+	// Add, Update, Delete, Touch, InSync, PendingDelete, Paused,
+	// Diverged, Conflict or Rejected.
+	LastResult string
+	// LastError is why the key was rejected or wasn't applied.
+	LastError string
+	// ObservedSum is the checksum of the value the key had when it was
+	// last in sync, the Sum of Checksumed values or a SHA-256 of the
+	// value as canonical JSON otherwise. It is empty for deleted keys
+	// and values that can't be encoded as JSON.
+	ObservedSum []byte
+}
+
+func init() {
+	RegisterType("reconcile.KeyStatus", func() interface{} { return KeyStatus{} })
+}
+
+// StatusStore keeps a KeyStatus for every key, see WithStatusStore.
+// Stores that buffer their records implement Flusher, the ones that
+// keep the pauses of a Runner PauseStore.
+type StatusStore interface {
+	Get(key string) (KeyStatus, bool)
+	Set(key string, s KeyStatus)
+	// Walk calls f for the records of the store in the order of their
+	// keys.
+	Walk(f func(key string, s KeyStatus))
+}
+
+// NewStatusStore returns an in memory StatusStore that is safe for
+// concurrent use.
+func NewStatusStore() StatusStore {
+	return &memoryStatus{records: make(map[string]KeyStatus)}
+}
+
+type memoryStatus struct {
+	mu      sync.RWMutex
+	records map[string]KeyStatus
+	pauses  []string
+}
+
+func (m *memoryStatus) Pauses() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.pauses...)
+}
+
+func (m *memoryStatus) SetPauses(patterns []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pauses = append([]string(nil), patterns...)
+}
+
+func (m *memoryStatus) Get(key string) (KeyStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.records[key]
+	return s, ok
+}
+
+func (m *memoryStatus) Set(key string, s KeyStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = s
+}
+
+func (m *memoryStatus) Walk(f func(key string, s KeyStatus)) {
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.records))
+	for k := range m.records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	records := make([]KeyStatus, len(keys))
+	for i, k := range keys {
+		records[i] = m.records[k]
+	}
+	m.mu.RUnlock()
+	for i, k := range keys {
+		f(k, records[i])
+	}
+}
+
+// NewFileStatusStore returns a StatusStore that keeps its records, and
+// the pauses of a Runner, in the file at path, encoded with c. The file
+// is read when it is opened, a missing file is an empty store; Flush
+// writes it back, Reconcile and ApplyTo call it after every pass.
+func NewFileStatusStore(path string, c Codec) (StatusStore, error) {
+	s := &fileStatus{memoryStatus: memoryStatus{records: make(map[string]KeyStatus)}, path: path, codec: c}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f statusFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.pauses = f.Pauses
+	for k, b := range f.Records {
+		v, err := c.Unmarshal(b, "reconcile.KeyStatus")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, k, err)
+		}
+		if status, ok := v.(KeyStatus); ok {
+			s.records[k] = status
+		}
+	}
+	return s, nil
+}
+
+// statusFile is the content of the file of a file status store, the
+// records are encoded with its codec so any codec can be used.
+type statusFile struct {
+	Records map[string][]byte `json:"records"`
+	Pauses  []string          `json:"pauses,omitempty"`
+}
+
+type fileStatus struct {
+	memoryStatus
+	path  string
+	codec Codec
+}
+
+// Flush writes the records to the file, replacing it.
+func (f *fileStatus) Flush() error {
+	encoded := make(map[string][]byte)
+	var err error
+	f.Walk(func(key string, s KeyStatus) {
+		if err != nil {
+			return
+		}
+		encoded[key], err = f.codec.Marshal(s)
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(statusFile{Records: encoded, Pauses: f.Pauses()})
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+var (
+	errDiverged = errors.New("diverged from the planned state")
+	errConflict = errors.New("changed since it was compared")
+)
+
+// observedSum returns the ObservedSum of v.
+func observedSum(v interface{}) []byte {
+	if v == nil {
+		return nil
+	}
+	if c, ok := withoutAnnotations(v).(Checksumed); ok {
+		return c.Sum()
+	}
+	data, err := canonicalJSON(v)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(data))
+	return sum[:]
+}
+
+// track records the outcome of key in the WithStatusStore. Keys that
+// are in sync get the time of the pass and the sum of v, the others
+// keep the ones they had.
+func (r *Result) track(o options, key, result string, err error, inSync bool, v interface{}) {
+	if o.status == nil || o.dryRun {
+		return
+	}
+	s, _ := o.status.Get(key)
+	s.LastResult = result
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	if inSync {
+		s.LastSyncTime = o.now()
+		s.ObservedSum = observedSum(v)
+	}
+	o.status.Set(key, s)
+	if r.Statuses == nil {
+		r.Statuses = make(map[string]KeyStatus)
+	}
+	r.Statuses[key] = s
+}
+
+// trackInSync records the keys the plan found in sync with the current
+// values it compared, without reading them again.
+func (r *Result) trackInSync(inSync []KV, o options) {
+	for _, kv := range inSync {
+		v := kv.Value
+		if kv.Sum != nil {
+			v = listedSum(kv.Sum)
+		}
+		r.track(o, kv.Key, "InSync", nil, true, v)
+	}
+}
+
+// listedSum is the Sum a key was listed with, see KV.
+type listedSum []byte
+
+func (s listedSum) Sum() []byte { return s }
+
+// flushStatus flushes the WithStatusStore if it buffers its records.
+func (r *Result) flushStatus(o options) {
+	f, ok := o.status.(Flusher)
+	if !ok || o.dryRun {
+		return
+	}
+	if err := f.Flush(); err != nil && r.Err == nil {
+		r.Err = fmt.Errorf("status store: %w", err)
+	}
+}
@@ -0,0 +1,123 @@
+package reconcile
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatusStore(t *testing.T) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	store := NewStatusStore()
+	current := NewMapState(map[string]interface{}{"a": "1", "b": "1", "c": "1"})
+	desired := NewMapState(map[string]interface{}{"a": "1", "b": "2", "d": "1"})
+	if r := Reconcile(current, desired, false, WithStatusStore(store), at(t0)); len(r.Statuses) != 4 {
+		t.Fatalf("was expecting the statuses of 4 keys got %v", r.Statuses)
+	}
+
+	desired.Update("a", "2")
+	refuse := errors.New("refused")
+	r := Reconcile(current, desired, false, WithStatusStore(store), at(t1), WithTransform(func(key string, v interface{}) (interface{}, error) {
+		return nil, refuse
+	}))
+	if s := r.Statuses["a"]; s.LastResult != "Rejected" {
+		t.Logf("was expecting a to be rejected got %+v", s)
+		t.Fail()
+	}
+
+	want := map[string]KeyStatus{
+		"a": {LastSyncTime: t0, LastResult: "Rejected", LastError: "transforming a: refused", ObservedSum: observedSum("1")},
+		// b was updated at t0 and is in sync at t1
+		"b": {LastSyncTime: t1, LastResult: "InSync", ObservedSum: observedSum("2")},
+		"c": {LastSyncTime: t0, LastResult: "Delete"},
+		"d": {LastSyncTime: t1, LastResult: "InSync", ObservedSum: observedSum("1")},
+	}
+	n := 0
+	store.Walk(func(key string, s KeyStatus) {
+		n++
+		w := want[key]
+		if !s.LastSyncTime.Equal(w.LastSyncTime) || s.LastResult != w.LastResult || s.LastError != w.LastError || string(s.ObservedSum) != string(w.ObservedSum) {
+			t.Logf("%s: was expecting %+v got %+v", key, w, s)
+			t.Fail()
+		}
+	})
+	if n != len(want) {
+		t.Logf("was expecting %d records got %d", len(want), n)
+		t.Fail()
+	}
+}
+
+func TestStatusStoreInSync(t *testing.T) {
+	values := map[string]interface{}{"a": "1", "b": "2", "c": "3"}
+	for _, sums := range []bool{false, true} {
+		store := NewStatusStore()
+		current := &listState{MapState: NewMapState(values), sums: sums}
+		r := Reconcile(current, NewMapState(values), false, WithStatusStore(store))
+		if current.gets != 0 || len(r.Statuses) != len(values) {
+			t.Logf("sums %v: was expecting %d keys in sync without reading them got %d reads and %v", sums, len(values), current.gets, r.Statuses)
+			t.Fail()
+		}
+		for key, s := range r.Statuses {
+			if s.LastResult != "InSync" || string(s.ObservedSum) != string(observedSum(values[key])) {
+				t.Logf("sums %v: was expecting %s to be in sync with the sum of %v got %+v", sums, key, values[key], s)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestStatusStoreDryRun(t *testing.T) {
+	store := NewStatusStore()
+	current := NewMapState(map[string]interface{}{"a": "1"})
+	desired := NewMapState(map[string]interface{}{"a": "2", "b": "1"})
+	if r := Reconcile(current, desired, false, WithStatusStore(store), WithServerDryRun(true)); r.Statuses != nil {
+		t.Logf("dry runs recorded %v", r.Statuses)
+		t.Fail()
+	}
+	store.Walk(func(key string, s KeyStatus) {
+		t.Logf("dry runs recorded %s: %+v", key, s)
+		t.Fail()
+	})
+}
+
+func TestFileStatusStore(t *testing.T) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, codec := range []Codec{JSON, Gob} {
+		path := filepath.Join(t.TempDir(), "status")
+		store, err := NewFileStatusStore(path, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current := NewMapState(map[string]interface{}{"a": "1"})
+		desired := NewMapState(map[string]interface{}{"a": "1", "b": 2})
+		if r := Reconcile(current, desired, false, WithStatusStore(store), at(t0)); r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		reopened, err := NewFileStatusStore(path, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, result := range map[string]string{"a": "InSync", "b": "Add"} {
+			s, ok := reopened.Get(key)
+			if !ok || s.LastResult != result || !s.LastSyncTime.Equal(t0) || len(s.ObservedSum) == 0 {
+				t.Logf("%T: %s: was expecting %s at %s got %+v", codec, key, result, t0, s)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestFileStatusStoreFlushError(t *testing.T) {
+	store, err := NewFileStatusStore(filepath.Join(t.TempDir(), "missing", "status"), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := NewMapState(map[string]interface{}{})
+	desired := NewMapState(map[string]interface{}{"a": "1"})
+	if r := Reconcile(current, desired, false, WithStatusStore(store)); r.Err == nil {
+		t.Log("was expecting the error writing the status")
+		t.Fail()
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTeardownNotConfirmed is returned by Teardown without
+// WithConfirmTeardown(true).
+var ErrTeardownNotConfirmed = errors.New("teardown isn't confirmed")
+
+// WithConfirmTeardown confirms that Teardown may delete every key of the
+// current state, it refuses to otherwise.
+func WithConfirmTeardown(confirmed bool) Option {
+	return func(o *options) { o.confirmTeardown = confirmed }
+}
+
+// Teardown deletes every key of current, or of WithShard if it is set,
+// like reconciling it with an empty desired state. Keys are deleted in
+// the reverse of the order current walks them, children before the
+// parents they depend on, and the options apply like they do to
+// Reconcile: deletes wait for WithDeleteGrace, paused keys are left
+// alone and so on.
+//
+// Teardown returns ErrTeardownNotConfirmed without
+// WithConfirmTeardown(true), and the error of ctx if it is done, before
+// anything is planned. Errors of the pass are the Err of the Result.
+// The keys current has afterwards, the pending, paused and failed ones,
+// are counted in its Remaining; every Teardown plans from the keys left,
+// so it can be called until none are.
+func Teardown(ctx context.Context, current State, opts ...Option) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	if isNil(current) {
+		return Result{}, fmt.Errorf("current state: %w", ErrNilState)
+	}
+	o := newOptions(opts)
+	if !o.confirmTeardown {
+		return Result{}, ErrTeardownNotConfirmed
+	}
+	updates, err := planRecovered(current, &MapState{}, o)
+	if err != nil {
+		return Result{Err: err}, nil
+	}
+	for i, j := 0, len(updates)-1; i < j; i, j = i+1, j-1 {
+		updates[i], updates[j] = updates[j], updates[i]
+	}
+	r := fix(current, updates, o)
+	r.flushStatus(o)
+	current.Walk(func(key string, _ interface{}) {
+		if o.keep == nil || o.keep(key) {
+			r.Remaining++
+		}
+	})
+	return r, nil
+}
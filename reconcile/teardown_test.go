@@ -0,0 +1,90 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTeardown(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"app": "1", "app/db": "1", "app/web": "1", "other": "1"})
+	if _, err := Teardown(context.Background(), current); !errors.Is(err, ErrTeardownNotConfirmed) {
+		t.Logf("was expecting ErrTeardownNotConfirmed got %v", err)
+		t.Fail()
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Teardown(cancelled, current, WithConfirmTeardown(true)); !errors.Is(err, context.Canceled) {
+		t.Logf("was expecting context.Canceled got %v", err)
+		t.Fail()
+	}
+	if current.Len() != 4 {
+		t.Fatalf("keys were deleted without a teardown: %v", current.Keys())
+	}
+
+	var order []string
+	hook := WithApplyHook(func(u Update) { order = append(order, u.Key) })
+	r, err := Teardown(context.Background(), current, WithConfirmTeardown(true), hook,
+		WithPause(func(key string) bool { return key == "other" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app/web", "app/db", "app"}; !reflect.DeepEqual(order, want) {
+		t.Logf("was expecting the deletes in the order %v got %v", want, order)
+		t.Fail()
+	}
+	if r.Remaining != 1 || !reflect.DeepEqual(r.Paused, []string{"other"}) || len(r.Deleted) != 3 {
+		t.Logf("was expecting the paused key to remain got %+v", r)
+		t.Fail()
+	}
+
+	r, err = Teardown(context.Background(), current, WithConfirmTeardown(true))
+	if err != nil || r.Remaining != 0 || !reflect.DeepEqual(r.Deleted, []string{"other"}) {
+		t.Logf("was expecting the rest to be deleted got %+v, %v", r, err)
+		t.Fail()
+	}
+}
+
+func TestTeardownGrace(t *testing.T) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := NewMapState(map[string]interface{}{"a": "1", "b": "1"})
+	candidates := NewDeleteCandidates()
+	for _, pass := range []struct {
+		at        time.Duration
+		remaining int
+	}{
+		{0, 2},
+		{30 * time.Second, 2},
+		{time.Minute, 0},
+	} {
+		r, err := Teardown(context.Background(), current, WithConfirmTeardown(true),
+			WithDeleteGrace(time.Minute), WithDeleteCandidates(candidates), at(t0.Add(pass.at)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Remaining != pass.remaining || pass.remaining > 0 && len(r.Pending) != 2 {
+			t.Logf("%v: was expecting %d keys to remain got %+v", pass.at, pass.remaining, r)
+			t.Fail()
+		}
+	}
+}
+
+func TestTeardownShard(t *testing.T) {
+	current := NewMapState(map[string]interface{}{"a": "1", "b": "1", "c": "1", "d": "1", "e": "1", "f": "1"})
+	var kept []string
+	for _, k := range current.Keys() {
+		if jumpHash(fnv1a(k), 2) != 0 {
+			kept = append(kept, k)
+		}
+	}
+	r, err := Teardown(context.Background(), current, WithConfirmTeardown(true), WithShard(0, 2, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Remaining != 0 || len(kept) == 0 || !reflect.DeepEqual(current.Keys(), kept) {
+		t.Logf("was expecting the keys of the other shard, %v, to be kept got %+v, %v", kept, r, current.Keys())
+		t.Fail()
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 Sevki <s@sevki.org>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes in a
+// hunk of a unified diff.
+const diffContext = 3
+
+// maxDiffLines is the number of lines above which values are shown as
+// replaced as a whole instead of compared line by line.
+const maxDiffLines = 2000
+
+// unifiedDiff returns the lines of a and b that differ as a unified
+// diff, or an empty string if they are the same.
+func unifiedDiff(a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+	var sb strings.Builder
+	sb.WriteString("--- current\n+++ desired\n")
+	for i := 0; i < len(ops); {
+		// find the next change and the hunk around it
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				if run-end > diffContext {
+					run = end + diffContext
+				}
+				end = run
+				break
+			}
+			end = run
+		}
+		writeHunk(&sb, ops[start:end])
+		i = end
+	}
+	return sb.String()
+}
+
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// x and y are the indexes of the line in a and b.
+	x, y int
+}
+
+func writeHunk(sb *strings.Builder, ops []lineOp) {
+	var xs, ys int
+	for _, op := range ops {
+		if op.kind != '+' {
+			xs++
+		}
+		if op.kind != '-' {
+			ys++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[0].x, xs), hunkRange(ops[0].y, ys))
+	for _, op := range ops {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the start and length of a hunk the way diff -u
+// does, lines count from 1 and empty ranges start before their line.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the edits that turn x into y using their longest
+// common subsequence.
+func diffLines(x, y []string) []lineOp {
+	var ops []lineOp
+	if len(x) > maxDiffLines || len(y) > maxDiffLines {
+		for i, l := range x {
+			ops = append(ops, lineOp{'-', l, i, 0})
+		}
+		for j, l := range y {
+			ops = append(ops, lineOp{'+', l, len(x), j})
+		}
+		return ops
+	}
+	// lcs[i][j] is the length of the common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, lineOp{' ', x[i], i, j})
+			i++
+			j++
+		case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, lineOp{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', y[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// truncateDiff cuts d at the last line that fits in limit bytes.
+func truncateDiff(d string, limit int) string {
+	if limit <= 0 || len(d) <= limit {
+		return d
+	}
+	cut := strings.LastIndexByte(d[:limit], '\n') + 1
+	return d[:cut] + "... diff truncated\n"
+}
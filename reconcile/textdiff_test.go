@@ -0,0 +1,120 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "same",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: "--- current\n+++ desired\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name: "two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			b:    "1\nX\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\nY\n15\n",
+			want: "--- current\n+++ desired\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n" +
+				"@@ -11,5 +11,5 @@\n 11\n 12\n 13\n-14\n+Y\n 15\n",
+		},
+		{
+			name: "merged hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\nX\n3\n4\n5\n6\n7\nY\n9\n",
+			want: "--- current\n+++ desired\n@@ -1,9 +1,9 @@\n 1\n-2\n+X\n 3\n 4\n 5\n 6\n 7\n-8\n+Y\n 9\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\nb\n",
+			want: "--- current\n+++ desired\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "append",
+			a:    "a\nb\nc\nd\ne\nf\ng\n",
+			b:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			want: "--- current\n+++ desired\n@@ -5,3 +5,4 @@\n e\n f\n g\n+h\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unifiedDiff(test.a, test.b); got != test.want {
+				t.Logf("got\n%s\nexpected\n%s", got, test.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	d := "--- current\n+++ desired\n@@ -1 +1 @@\n-a\n+b\n"
+	if got, want := truncateDiff(d, 30), "--- current\n+++ desired\n... diff truncated\n"; got != want {
+		t.Logf("got %q, expected %q", got, want)
+		t.Fail()
+	}
+	if got := truncateDiff(d, 0); got != d {
+		t.Logf("got %q with no limit", got)
+		t.Fail()
+	}
+}
+
+func TestValueDiff(t *testing.T) {
+	marshal := func(v interface{}) ([]byte, error) {
+		if _, ok := v.(func()); ok {
+			return nil, errors.New("can't marshal a func")
+		}
+		return json.MarshalIndent(v, "", "  ")
+	}
+	current := NewMapState(map[string]interface{}{
+		"svc":  map[string]interface{}{"image": "web:1", "replicas": 2},
+		"hook": func() {},
+	})
+	desired := NewMapState(map[string]interface{}{
+		"svc":  map[string]interface{}{"image": "web:2", "replicas": 2},
+		"hook": "none",
+	})
+	cs := NewChangeSet(current, desired, WithValueDiff(marshal))
+	updates := cs.Updates()
+	if len(updates) != 2 {
+		t.Logf("got %+v, expected 2 updates", updates)
+		t.FailNow()
+	}
+	hook, svc := updates[0], updates[1]
+	if hook.TextDiff != "" || hook.Reason == "" {
+		t.Logf("got %+v, expected the reason without a diff", hook)
+		t.Fail()
+	}
+	want := "--- current\n+++ desired\n@@ -1,4 +1,4 @@\n {\n-  \"image\": \"web:1\",\n+  \"image\": \"web:2\",\n   \"replicas\": 2\n }\n"
+	if svc.TextDiff != want {
+		t.Logf("got\n%s\nexpected\n%s", svc.TextDiff, want)
+		t.Fail()
+	}
+	if !strings.Contains(cs.String(), "Update svc: ") || !strings.Contains(cs.String(), "\t+  \"image\": \"web:2\",\n") {
+		t.Logf("formatted plan doesn't have the diff:\n%s", cs)
+		t.Fail()
+	}
+	out, err := json.Marshal(cs)
+	if err != nil || !strings.Contains(string(out), `"textDiff":"--- current`) {
+		t.Logf("got %s, %v", out, err)
+		t.Fail()
+	}
+	if NewChangeSet(current, desired).Updates()[1].TextDiff != "" {
+		t.Log("got a text diff without WithValueDiff")
+		t.Fail()
+	}
+}
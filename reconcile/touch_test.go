@@ -0,0 +1,97 @@
+package reconcile
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// touchState counts the updates and touches of a MapState.
+type touchState struct {
+	*MapState
+	updates []string
+}
+
+func (s *touchState) Update(key string, v interface{}) {
+	s.updates = append(s.updates, key)
+	s.MapState.Update(key, v)
+}
+
+type toucherState struct {
+	touchState
+	touches []string
+	refuse  error
+}
+
+func (s *toucherState) Touch(key string) error {
+	if s.refuse != nil {
+		return s.refuse
+	}
+	s.touches = append(s.touches, key)
+	return nil
+}
+
+func leases(key string, current interface{}) bool { return key == "lease" || key == "b" }
+
+func TestTouch(t *testing.T) {
+	current := &touchState{MapState: NewMapState(map[string]interface{}{"lease": "1", "a": "1", "b": "1"})}
+	desired := NewMapState(map[string]interface{}{"lease": "1", "a": "1", "b": "2"})
+	var hooked []Update
+	r := Reconcile(current, desired, false, WithTouch(leases), WithApplyHook(func(u Update) { hooked = append(hooked, u) }),
+		WithTransform(func(key string, v interface{}) (interface{}, error) { return v.(string) + "!", nil }))
+	if !reflect.DeepEqual(r.Touched, []string{"lease"}) || !reflect.DeepEqual(r.Updated, []string{"b"}) {
+		t.Logf("was expecting lease to be touched and b updated got %+v", r)
+		t.Fail()
+	}
+	sort.Strings(current.updates)
+	if !reflect.DeepEqual(current.updates, []string{"b", "lease"}) || current.Get("lease") != "1" || current.Get("b") != "2!" {
+		t.Logf("was expecting lease to be rewritten with its value got %v %v", current.updates, current.Keys())
+		t.Fail()
+	}
+	actions := make(map[string]string)
+	for _, u := range hooked {
+		actions[u.Key] = u.Action
+	}
+	if actions["lease"] != "Touch" || actions["b"] != "Update" {
+		t.Logf("got the hooks %v", actions)
+		t.Fail()
+	}
+}
+
+func TestToucher(t *testing.T) {
+	current := &toucherState{touchState: touchState{MapState: NewMapState(map[string]interface{}{"lease": "1"})}}
+	desired := NewMapState(map[string]interface{}{"lease": "1"})
+	r := Reconcile(current, desired, false, WithTouch(leases))
+	if !reflect.DeepEqual(r.Touched, []string{"lease"}) || !reflect.DeepEqual(current.touches, []string{"lease"}) || len(current.updates) != 0 {
+		t.Logf("was expecting Touch to be called got %+v, %v", r, current.updates)
+		t.Fail()
+	}
+	if r.changed() {
+		t.Log("touches count as changes of the state")
+		t.Fail()
+	}
+
+	current.refuse = errors.New("lease expired")
+	r = Reconcile(current, desired, false, WithTouch(leases))
+	if len(r.Touched) != 0 || !errors.Is(r.Rejected["lease"], current.refuse) {
+		t.Logf("was expecting the failed touch to be rejected got %+v", r)
+		t.Fail()
+	}
+
+	current.refuse = nil
+	current.touches = nil
+	if r := Reconcile(current, desired, false, WithTouch(leases), WithServerDryRun(true)); len(current.touches) != 0 || !reflect.DeepEqual(r.Touched, []string{"lease"}) {
+		t.Logf("dry runs touched %v, %+v", current.touches, r)
+		t.Fail()
+	}
+
+	cs, err := Plan(current, desired, WithTouch(leases))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := cs.Updates(); len(u) != 1 || u[0].Action != "Touch" || len(cs.Patches()) != 0 {
+		t.Logf("was expecting a touch and no patches got %v", u)
+		t.Fail()
+	}
+}
@@ -0,0 +1,90 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	secrets := map[string]string{"secret:db": "hunter2", "secret:api": "swordfish"}
+	errNoSecret := errors.New("no such secret")
+	expand := WithTransform(func(key string, v interface{}) (interface{}, error) {
+		s := v.(string)
+		if !strings.HasPrefix(s, "secret:") {
+			return s, nil
+		}
+		if secret, ok := secrets[s]; ok {
+			return secret, nil
+		}
+		return nil, errNoSecret
+	})
+	current := NewMapState(map[string]interface{}{"api": "old"})
+	desired := NewMapState(map[string]interface{}{
+		"api":  "secret:api",
+		"db":   "secret:db",
+		"host": "db.internal",
+		"mq":   "secret:mq",
+	})
+	c := NewChangeSet(current, desired, WithValueDiff(func(v interface{}) ([]byte, error) {
+		return []byte(fmt.Sprint(v)), nil
+	}))
+	if plan := c.String(); strings.Contains(plan, "swordfish") || !strings.Contains(plan, "+secret:api") {
+		t.Logf("the plan should show the desired values, got\n%s", plan)
+		t.Fail()
+	}
+	r := c.ApplyTo(current, expand)
+	if !errors.Is(r.Rejected["mq"], errNoSecret) || len(r.Rejected) != 1 {
+		t.Logf("got rejections %v", r.Rejected)
+		t.Fail()
+	}
+	want := map[string]interface{}{"api": "swordfish", "db": "hunter2", "host": "db.internal"}
+	for k, v := range want {
+		if got := current.Get(k); got != v {
+			t.Logf("%s: got %v, expected %v", k, got, v)
+			t.Fail()
+		}
+	}
+	if current.Get("mq") != nil {
+		t.Log("mq shouldn't have been added")
+		t.Fail()
+	}
+}
+
+func TestCurrentTransform(t *testing.T) {
+	refs := map[string]string{"hunter2": "secret:db"}
+	current := NewMapState(map[string]interface{}{"db": "hunter2", "host": "db.internal"})
+	desired := NewMapState(map[string]interface{}{"db": "secret:db", "host": "db.internal"})
+
+	// without it the applied secret never looks like its reference
+	if c := NewChangeSet(current, desired); c.Len() != 1 {
+		t.Logf("got %d updates, expected 1", c.Len())
+		t.Fail()
+	}
+	unexpand := WithCurrentTransform(func(key string, v interface{}) (interface{}, error) {
+		if ref, ok := refs[v.(string)]; ok {
+			return ref, nil
+		}
+		return v, nil
+	})
+	if c := NewChangeSet(current, desired, unexpand); c.Len() != 0 {
+		t.Logf("got updates %s", c)
+		t.Fail()
+	}
+	// a reference that changed is still an update
+	desired.Update("db", "secret:db2")
+	if c := NewChangeSet(current, desired, unexpand); c.Len() != 1 {
+		t.Logf("got %d updates, expected 1", c.Len())
+		t.Fail()
+	}
+
+	failing := WithCurrentTransform(func(key string, v interface{}) (interface{}, error) {
+		return nil, errors.New("vault sealed")
+	})
+	updates := NewChangeSet(current, desired, failing).Updates()
+	if len(updates) != 2 || !strings.Contains(updates[0].Reason, "vault sealed") {
+		t.Logf("keys whose transform fails should be updated, got %v", updates)
+		t.Fail()
+	}
+}